@@ -3,6 +3,9 @@ package main
 import (
 	"avito-merch/internal/app"
 	"avito-merch/internal/config"
+
+	// База часовых поясов для расписаний переводов: итоговый образ собирается из scratch
+	_ "time/tzdata"
 )
 
 func main() {
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+
+	"avito-merch/internal/config"
+	"avito-merch/internal/repository"
+	"avito-merch/internal/usecase"
+	"avito-merch/pkg/database"
+)
+
+// Разовая сверка балансов с главной книгой.
+// Печатает отчет в stdout и завершается с кодом 1, если найдены расхождения
+func main() {
+	os.Exit(run())
+}
+
+// run выполняет сверку и возвращает код завершения. os.Exit вызывается только в main,
+// чтобы отложенное закрытие пула соединений успело выполниться
+func run() int {
+	cfg := config.LoadConfig()
+
+	db, err := database.NewPostgresDB(cfg.DBConfig)
+	if err != nil {
+		slog.Error("Failed to connect to the database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	reconciliationUseCase := usecase.NewReconciliationUseCase(repository.NewReconciliationRepository(db))
+
+	report, err := reconciliationUseCase.Reconcile(context.Background())
+	if err != nil {
+		slog.Error("Reconciliation failed", "error", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		slog.Error("Failed to encode report", "error", err)
+		return 1
+	}
+
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
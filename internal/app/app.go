@@ -22,6 +22,7 @@ type App struct {
 	cfg    *config.Config
 	router *mux.Router
 	server *http.Server
+	jobs   []job
 }
 
 func NewApp(cfg *config.Config) *App {
@@ -42,12 +43,64 @@ func NewApp(cfg *config.Config) *App {
 	userRepo := repository.NewUserRepository(db)
 	itemRepo := repository.NewItemRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
+	preorderRepo := repository.NewPreorderRepository(db)
+	reconciliationRepo := repository.NewReconciliationRepository(db)
+	coinRequestRepo := repository.NewCoinRequestRepository(db)
+	scheduledTransferRepo := repository.NewScheduledTransferRepository(db)
+	grantRepo := repository.NewGrantRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	allowanceRepo := repository.NewAllowanceRepository(db)
+	coinLotRepo := repository.NewCoinLotRepository(db)
+	leaderboardRepo := repository.NewLeaderboardRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	achievementRepo := repository.NewAchievementRepository(db)
+	campaignRepo := repository.NewCampaignRepository(db)
+	auctionRepo := repository.NewAuctionRepository(db)
+	marketplaceRepo := repository.NewMarketplaceRepository(db)
 
 	// Инициализируем usecases
 	authUseCase := usecase.NewAuthUseCase(userRepo)
-	buyUseCase := usecase.NewBuyUseCase(userRepo, itemRepo)
-	sendCoinUseCase := usecase.NewSendCoinUseCase(userRepo, transactionRepo)
-	infoUseCase := usecase.NewInfoUseCase(userRepo, transactionRepo)
+	achievements := cfg.Achievements
+	if achievements == nil {
+		achievements = usecase.DefaultAchievements
+	}
+	achievementUseCase := usecase.NewAchievementUseCase(achievementRepo, achievements)
+	buyUseCase := usecase.NewBuyUseCase(userRepo, itemRepo, achievementUseCase)
+	sendCoinUseCase := usecase.NewSendCoinUseCase(userRepo, transactionRepo, usecase.SendCoinConfig{
+		Limits:             cfg.TransferLimits,
+		EscrowThreshold:    cfg.EscrowThreshold,
+		PendingTransferTTL: cfg.PendingTransferTTL,
+		KudosBudget:        cfg.KudosMonthlyBudget,
+	}, achievementUseCase)
+	coinExpiry := usecase.CoinExpiryConfig{TTL: cfg.CoinTTL, WarningPeriod: cfg.CoinExpiryWarning}
+	infoUseCase := usecase.NewInfoUseCase(userRepo, transactionRepo, coinLotRepo, achievementRepo, usecase.InfoConfig{
+		CoinExpiry:  coinExpiry,
+		KudosBudget: cfg.KudosMonthlyBudget,
+	})
+	historyUseCase := usecase.NewHistoryUseCase(transactionRepo)
+	reconciliationUseCase := usecase.NewReconciliationUseCase(reconciliationRepo)
+	coinRequestUseCase := usecase.NewCoinRequestUseCase(userRepo, coinRequestRepo, sendCoinUseCase, cfg.CoinRequestTTL)
+	reversalUseCase := usecase.NewReversalUseCase(transactionRepo)
+	receiptUseCase := usecase.NewReceiptUseCase(transactionRepo)
+	exportUseCase := usecase.NewExportUseCase(transactionRepo)
+	scheduledTransferUseCase := usecase.NewScheduledTransferUseCase(userRepo, scheduledTransferRepo, sendCoinUseCase)
+	preorderUseCase := usecase.NewPreorderUseCase(preorderRepo, cfg.PreorderTTL)
+	grantUseCase := usecase.NewGrantUseCase(grantRepo, usecase.GrantConfig{
+		MonthlyBudget:     cfg.GrantMonthlyBudget,
+		ApprovalThreshold: cfg.GrantApprovalThreshold,
+	})
+	auditUseCase := usecase.NewAuditUseCase(auditRepo)
+	coinExpiryUseCase := usecase.NewCoinExpiryUseCase(coinLotRepo, usecase.NewLogExpiryNotifier(os.Stdout), coinExpiry)
+	allowanceUseCase := usecase.NewAllowanceUseCase(allowanceRepo, usecase.AllowanceConfig{
+		Amount: cfg.AllowanceAmount,
+		Roles:  cfg.AllowanceRoles,
+	})
+	leaderboardUseCase := usecase.NewLeaderboardUseCase(leaderboardRepo, cfg.LeaderboardSize)
+	teamUseCase := usecase.NewTeamUseCase(teamRepo, sendCoinUseCase)
+	campaignUseCase := usecase.NewCampaignUseCase(campaignRepo)
+	auctionUseCase := usecase.NewAuctionUseCase(auctionRepo, cfg.AuctionMinIncrement, cfg.AuctionExtension, achievementUseCase)
+	marketplaceUseCase := usecase.NewMarketplaceUseCase(marketplaceRepo, sendCoinUseCase)
+	userUseCase := usecase.NewUserUseCase(userRepo)
 
 	// Инициализируем handlers
 	handlers := &Handlers{
@@ -55,10 +108,50 @@ func NewApp(cfg *config.Config) *App {
 		buyHandler:      handlers.NewBuyHandler(buyUseCase),
 		sendCoinHandler: handlers.NewSendCoinHandler(sendCoinUseCase),
 		infoHandler:     handlers.NewInfoHandler(infoUseCase),
+		preorderHandler: handlers.NewPreorderHandler(preorderUseCase),
+		historyHandler:  handlers.NewHistoryHandler(historyUseCase),
+
+		reconciliationHandler: handlers.NewReconciliationHandler(reconciliationUseCase),
+		coinRequestHandler:    handlers.NewCoinRequestHandler(coinRequestUseCase),
+
+		scheduledTransferHandler: handlers.NewScheduledTransferHandler(scheduledTransferUseCase),
+		reversalHandler:          handlers.NewReversalHandler(reversalUseCase),
+		receiptHandler:           handlers.NewReceiptHandler(receiptUseCase),
+		exportHandler:            handlers.NewExportHandler(exportUseCase),
+		grantHandler:             handlers.NewGrantHandler(grantUseCase),
+		auditHandler:             handlers.NewAuditHandler(auditUseCase),
+		allowanceHandler:         handlers.NewAllowanceHandler(allowanceUseCase),
+		leaderboardHandler:       handlers.NewLeaderboardHandler(leaderboardUseCase),
+		teamHandler:              handlers.NewTeamHandler(teamUseCase),
+		campaignHandler:          handlers.NewCampaignHandler(campaignUseCase),
+		auctionHandler:           handlers.NewAuctionHandler(auctionUseCase),
+		marketplaceHandler:       handlers.NewMarketplaceHandler(marketplaceUseCase),
+		userHandler:              handlers.NewUserHandler(userUseCase),
+	}
+
+	// Фоновые задачи
+	jobs := []job{
+		{name: "fulfill-preorders", interval: cfg.JobInterval, run: preorderUseCase.FulfillPreorders},
+		{name: "expire-preorders", interval: cfg.JobInterval, run: preorderUseCase.ExpirePreorders},
+		{name: "expire-coin-requests", interval: cfg.JobInterval, run: coinRequestUseCase.ExpireCoinRequests},
+		{name: "expire-pending-transfers", interval: cfg.JobInterval, run: sendCoinUseCase.ExpirePendingTransfers},
+		{name: "scheduled-transfers", interval: cfg.SchedulerInterval, run: scheduledTransferUseCase.ExecuteDueTransfers},
+		{name: "refresh-leaderboards", interval: cfg.LeaderboardRefreshInterval, run: leaderboardUseCase.RefreshLeaderboards, immediate: true},
+		{name: "close-campaigns", interval: cfg.JobInterval, run: campaignUseCase.CloseExpiredCampaigns},
+		{name: "settle-auctions", interval: cfg.JobInterval, run: auctionUseCase.SettleAuctions},
+	}
+	if cfg.ReconciliationInterval > 0 {
+		jobs = append(jobs, job{name: "reconciliation", interval: cfg.ReconciliationInterval, run: reconciliationUseCase.RunReconciliation})
+	}
+	if cfg.AllowanceAmount > 0 {
+		jobs = append(jobs, job{name: "allowance", interval: cfg.JobInterval, run: allowanceUseCase.RunAllowance})
+	}
+	if cfg.CoinTTL > 0 {
+		jobs = append(jobs, job{name: "coin-expiry", interval: cfg.CoinExpiryInterval, run: coinExpiryUseCase.RunCoinExpiry})
 	}
 
 	// Настраиваем роутер
-	router := setupRouter(handlers)
+	router := setupRouter(handlers, userRepo)
 
 	// Инициализируем сервер
 	server := &http.Server{
@@ -72,6 +165,7 @@ func NewApp(cfg *config.Config) *App {
 		cfg:    cfg,
 		router: router,
 		server: server,
+		jobs:   jobs,
 	}
 }
 
@@ -80,6 +174,13 @@ func (a *App) Run() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	// Запускаем фоновые задачи
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	for _, j := range a.jobs {
+		go runJob(jobsCtx, j)
+	}
+
 	go func() {
 		slog.Info("Server started", "port", a.cfg.ServerPort)
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -92,6 +193,7 @@ func (a *App) Run() {
 	<-stop
 
 	slog.Info("Shutting down the service...")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
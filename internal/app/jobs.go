@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// job фоновая задача, выполняемая с заданным периодом
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	// immediate задача выполняется сразу при запуске, а не через interval
+	immediate bool
+}
+
+// runJob выполняет задачу по таймеру, пока не будет отменен контекст
+func runJob(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	if j.immediate {
+		if err := j.run(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Background job failed", "job", j.name, "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.run(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Background job failed", "job", j.name, "error", err)
+			}
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"avito-merch/internal/entity"
 	"avito-merch/internal/handlers"
 	"avito-merch/pkg/auth"
+	"expvar"
 	"log/slog"
 	"net/http"
 
@@ -14,9 +16,28 @@ type Handlers struct {
 	buyHandler      *handlers.BuyHandler
 	sendCoinHandler *handlers.SendCoinHandler
 	infoHandler     *handlers.InfoHandler
+	preorderHandler *handlers.PreorderHandler
+	historyHandler  *handlers.HistoryHandler
+
+	reconciliationHandler *handlers.ReconciliationHandler
+	coinRequestHandler    *handlers.CoinRequestHandler
+
+	scheduledTransferHandler *handlers.ScheduledTransferHandler
+	reversalHandler          *handlers.ReversalHandler
+	receiptHandler           *handlers.ReceiptHandler
+	exportHandler            *handlers.ExportHandler
+	grantHandler             *handlers.GrantHandler
+	auditHandler             *handlers.AuditHandler
+	allowanceHandler         *handlers.AllowanceHandler
+	leaderboardHandler       *handlers.LeaderboardHandler
+	teamHandler              *handlers.TeamHandler
+	campaignHandler          *handlers.CampaignHandler
+	auctionHandler           *handlers.AuctionHandler
+	marketplaceHandler       *handlers.MarketplaceHandler
+	userHandler              *handlers.UserHandler
 }
 
-func setupRouter(handlers *Handlers) *mux.Router {
+func setupRouter(handlers *Handlers, users auth.ActiveUsers) *mux.Router {
 	r := mux.NewRouter()
 
 	// Регистрируем эндпоинт для аутентификации
@@ -25,10 +46,85 @@ func setupRouter(handlers *Handlers) *mux.Router {
 
 	// Регистрируем защищенные эндпоинты
 	apiRouter := r.PathPrefix("/api").Subrouter()
-	apiRouter.Use(auth.AuthMiddleware)
+	apiRouter.Use(auth.AuthMiddleware, auth.RequireActiveUser(users))
 	apiRouter.HandleFunc("/buy/{item}", handlers.buyHandler.BuyItem).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/sendCoin", handlers.sendCoinHandler.SendCoins).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/sendCoin/batch", handlers.sendCoinHandler.SendBatch).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/info", handlers.infoHandler.GetUserInfo).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/history", handlers.historyHandler.GetHistory).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/history/export", handlers.exportHandler.ExportHistory).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/transfers/{id}", handlers.receiptHandler.GetTransfer).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/transfers/{id}/receipt", handlers.receiptHandler.GetTransferReceipt).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/transfers/{id}/accept", handlers.sendCoinHandler.AcceptPendingTransfer).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/transfers/{id}/reject", handlers.sendCoinHandler.RejectPendingTransfer).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/transfers/{id}/cancel", handlers.sendCoinHandler.CancelPendingTransfer).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/purchases/{id}/receipt", handlers.receiptHandler.GetPurchaseReceipt).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/coinRequests", handlers.coinRequestHandler.CreateCoinRequest).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/coinRequests", handlers.coinRequestHandler.GetCoinRequests).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/coinRequests/{id}/accept", handlers.coinRequestHandler.AcceptCoinRequest).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/coinRequests/{id}/decline", handlers.coinRequestHandler.DeclineCoinRequest).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/coinRequests/{id}/cancel", handlers.coinRequestHandler.CancelCoinRequest).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/scheduledTransfers", handlers.scheduledTransferHandler.CreateScheduledTransfer).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/scheduledTransfers", handlers.scheduledTransferHandler.GetScheduledTransfers).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/scheduledTransfers/{id}", handlers.scheduledTransferHandler.GetScheduledTransfer).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/scheduledTransfers/{id}", handlers.scheduledTransferHandler.UpdateScheduledTransfer).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/scheduledTransfers/{id}", handlers.scheduledTransferHandler.CancelScheduledTransfer).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/scheduledTransfers/{id}/runs", handlers.scheduledTransferHandler.GetRuns).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/leaderboards", handlers.leaderboardHandler.GetLeaderboard).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/leaderboards/optOut", handlers.leaderboardHandler.SetOptOut).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/teams", handlers.teamHandler.CreateTeam).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams", handlers.teamHandler.GetTeams).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/teams/{id}", handlers.teamHandler.GetTeam).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/teams/{id}/members", handlers.teamHandler.SetMember).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/teams/{id}/members/{user}", handlers.teamHandler.RemoveMember).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/teams/{id}/deposit", handlers.teamHandler.Deposit).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams/{id}/withdraw", handlers.teamHandler.Withdraw).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams/{id}/buy/{item}", handlers.teamHandler.BuyItem).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/campaigns", handlers.campaignHandler.GetCampaigns).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/campaigns/{id}", handlers.campaignHandler.GetCampaign).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/campaigns/{id}/donate", handlers.campaignHandler.Donate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auctions", handlers.auctionHandler.GetAuctions).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/auctions/{id}", handlers.auctionHandler.GetAuction).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/auctions/{id}/bids", handlers.auctionHandler.PlaceBid).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/marketplace", handlers.marketplaceHandler.GetListings).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/marketplace", handlers.marketplaceHandler.CreateListing).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/marketplace/my", handlers.marketplaceHandler.GetMyListings).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/marketplace/{id}", handlers.marketplaceHandler.GetListing).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/marketplace/{id}", handlers.marketplaceHandler.CancelListing).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/marketplace/{id}/buy", handlers.marketplaceHandler.Buy).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/preorders", handlers.preorderHandler.GetPreorders).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/preorders/{item}", handlers.preorderHandler.CreatePreorder).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/preorders/{id}", handlers.preorderHandler.CancelPreorder).Methods(http.MethodDelete)
+
+	// Регистрируем эндпоинты администрирования
+	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
+	adminOrAuditor := auth.RequireRole(entity.RoleAdmin, entity.RoleAuditor)
+	adminOnly := auth.RequireRole(entity.RoleAdmin)
+	adminRouter.Handle("/reconciliation", adminOrAuditor(http.HandlerFunc(handlers.reconciliationHandler.GetLastReport))).Methods(http.MethodGet)
+	adminRouter.Handle("/reconciliation", adminOnly(http.HandlerFunc(handlers.reconciliationHandler.Reconcile))).Methods(http.MethodPost)
+	adminRouter.Handle("/history/export", adminOrAuditor(http.HandlerFunc(handlers.exportHandler.ExportAllHistory))).Methods(http.MethodGet)
+	adminRouter.Handle("/transfers/{id}/reverse", adminOnly(http.HandlerFunc(handlers.reversalHandler.ReverseTransfer))).Methods(http.MethodPost)
+	adminRouter.Handle("/transfers/{id}/approve", adminOnly(http.HandlerFunc(handlers.sendCoinHandler.ApprovePendingTransfer))).Methods(http.MethodPost)
+	adminRouter.Handle("/transfers/{id}/decline", adminOnly(http.HandlerFunc(handlers.sendCoinHandler.DeclinePendingTransfer))).Methods(http.MethodPost)
+	adminRouter.Handle("/grants", adminOnly(http.HandlerFunc(handlers.grantHandler.CreateGrant))).Methods(http.MethodPost)
+	adminRouter.Handle("/grants", adminOrAuditor(http.HandlerFunc(handlers.grantHandler.GetGrants))).Methods(http.MethodGet)
+	adminRouter.Handle("/grants/budget", adminOrAuditor(http.HandlerFunc(handlers.grantHandler.GetBudget))).Methods(http.MethodGet)
+	adminRouter.Handle("/grants/{id}", adminOrAuditor(http.HandlerFunc(handlers.grantHandler.GetGrant))).Methods(http.MethodGet)
+	adminRouter.Handle("/grants/{id}/approve", adminOnly(http.HandlerFunc(handlers.grantHandler.ApproveGrant))).Methods(http.MethodPost)
+	adminRouter.Handle("/grants/{id}/reject", adminOnly(http.HandlerFunc(handlers.grantHandler.RejectGrant))).Methods(http.MethodPost)
+	adminRouter.Handle("/audit", adminOrAuditor(http.HandlerFunc(handlers.auditHandler.GetAuditLog))).Methods(http.MethodGet)
+	adminRouter.Handle("/allowance/runs", adminOrAuditor(http.HandlerFunc(handlers.allowanceHandler.GetRuns))).Methods(http.MethodGet)
+	adminRouter.Handle("/allowance/runs", adminOnly(http.HandlerFunc(handlers.allowanceHandler.PayAllowance))).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns", adminOnly(http.HandlerFunc(handlers.campaignHandler.CreateCampaign))).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns/{id}/close", adminOnly(http.HandlerFunc(handlers.campaignHandler.CloseCampaign))).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns/{id}/report", adminOrAuditor(http.HandlerFunc(handlers.campaignHandler.GetReport))).Methods(http.MethodGet)
+	adminRouter.Handle("/auctions", adminOnly(http.HandlerFunc(handlers.auctionHandler.CreateAuction))).Methods(http.MethodPost)
+	adminRouter.Handle("/auctions/{id}/cancel", adminOnly(http.HandlerFunc(handlers.auctionHandler.CancelAuction))).Methods(http.MethodPost)
+	adminRouter.Handle("/users/{user}/deactivate", adminOnly(http.HandlerFunc(handlers.userHandler.DeactivateUser))).Methods(http.MethodPost)
+
+	// Метрики доступны только администраторам и аудиторам
+	activeOnly := auth.RequireActiveUser(users)
+	r.Handle("/debug/vars", auth.AuthMiddleware(activeOnly(adminOrAuditor(expvar.Handler())))).Methods(http.MethodGet)
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
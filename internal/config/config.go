@@ -1,13 +1,62 @@
 package config
 
 import (
+	"avito-merch/internal/entity"
 	"avito-merch/pkg/database"
+	"encoding/json"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	DBConfig   database.Config
 	ServerPort string
+	// JobInterval период запуска фоновых задач
+	JobInterval time.Duration
+	// PreorderTTL срок жизни предзаказа, после которого монеты размораживаются
+	PreorderTTL time.Duration
+	// CoinRequestTTL срок, в течение которого запрос монет можно оплатить
+	CoinRequestTTL time.Duration
+	// SchedulerInterval период проверки запланированных переводов. Точность расписаний - минута,
+	// поэтому проверять чаще раза в минуту не нужно
+	SchedulerInterval time.Duration
+	// ReconciliationInterval период фоновой сверки балансов, 0 - сверка только по запросу
+	ReconciliationInterval time.Duration
+	// TransferLimits профили лимитов на отправку монет по ролям
+	TransferLimits map[string]entity.TransferLimits
+	// EscrowThreshold сумма, начиная с которой перевод ждет подтверждения получателя, 0 - только по запросу отправителя
+	EscrowThreshold int
+	// PendingTransferTTL срок, после которого неподтвержденный перевод возвращается отправителю
+	PendingTransferTTL time.Duration
+	// GrantMonthlyBudget сколько монет можно выпустить начислениями за месяц, 0 - без ограничения
+	GrantMonthlyBudget int
+	// GrantApprovalThreshold сумма начисления, требующая подтверждения второго администратора, 0 - не требуется
+	GrantApprovalThreshold int
+	// AllowanceAmount ежемесячное пособие пользователю, 0 - пособие не начисляется
+	AllowanceAmount int
+	// AllowanceRoles роли пользователей, получающих пособие
+	AllowanceRoles []string
+	// CoinTTL срок жизни монет от дня получения, 0 - монеты не сгорают
+	CoinTTL time.Duration
+	// CoinExpiryWarning за сколько до сгорания предупреждать пользователя
+	CoinExpiryWarning time.Duration
+	// CoinExpiryInterval период задачи сгорания монет
+	CoinExpiryInterval time.Duration
+	// KudosMonthlyBudget месячный бюджет благодарностей пользователя, 0 - благодарности отключены
+	KudosMonthlyBudget int
+	// LeaderboardSize сколько мест показывать в рейтинге
+	LeaderboardSize int
+	// LeaderboardRefreshInterval период пересчета рейтингов
+	LeaderboardRefreshInterval time.Duration
+	// Achievements правила достижений, nil - набор по умолчанию
+	Achievements []entity.Achievement
+	// AuctionMinIncrement минимальный шаг ставки на аукционе
+	AuctionMinIncrement int
+	// AuctionExtension окно перед окончанием аукциона: ставка в этом окне продлевает аукцион на его длину
+	AuctionExtension time.Duration
 }
 
 func LoadConfig() *Config {
@@ -20,6 +69,40 @@ func LoadConfig() *Config {
 			DBName:     getEnv("DATABASE_NAME", "shop"),
 		},
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+
+		JobInterval:            getDuration("JOB_INTERVAL", time.Minute),
+		PreorderTTL:            getDuration("PREORDER_TTL", 30*24*time.Hour),
+		CoinRequestTTL:         getDuration("COIN_REQUEST_TTL", 7*24*time.Hour),
+		SchedulerInterval:      getDuration("SCHEDULER_INTERVAL", 30*time.Second),
+		ReconciliationInterval: getDuration("RECONCILIATION_INTERVAL", 0),
+
+		// Аудиторы используют профиль обычного пользователя
+		TransferLimits: map[string]entity.TransferLimits{
+			entity.RoleUser:    getTransferLimits("TRANSFER_"),
+			entity.RoleAdmin:   getTransferLimits("ADMIN_TRANSFER_"),
+			entity.RoleService: getTransferLimits("SERVICE_TRANSFER_"),
+		},
+		EscrowThreshold:    getInt("ESCROW_THRESHOLD", 0),
+		PendingTransferTTL: getDuration("PENDING_TRANSFER_TTL", 7*24*time.Hour),
+
+		GrantMonthlyBudget:     getInt("GRANT_MONTHLY_BUDGET", 0),
+		GrantApprovalThreshold: getInt("GRANT_APPROVAL_THRESHOLD", 0),
+
+		AllowanceAmount: getInt("ALLOWANCE_AMOUNT", 0),
+		AllowanceRoles:  getList("ALLOWANCE_ROLES", []string{entity.RoleUser, entity.RoleAdmin}),
+
+		CoinTTL:            getDuration("COIN_TTL", 0),
+		CoinExpiryWarning:  getDuration("COIN_EXPIRY_WARNING", 30*24*time.Hour),
+		CoinExpiryInterval: getDuration("COIN_EXPIRY_INTERVAL", 24*time.Hour),
+
+		KudosMonthlyBudget: getInt("KUDOS_MONTHLY_BUDGET", 0),
+
+		LeaderboardSize:            getInt("LEADERBOARD_SIZE", 10),
+		LeaderboardRefreshInterval: getDuration("LEADERBOARD_REFRESH_INTERVAL", 5*time.Minute),
+		Achievements:               getAchievements("ACHIEVEMENTS_FILE"),
+
+		AuctionMinIncrement: getInt("AUCTION_MIN_INCREMENT", 1),
+		AuctionExtension:    getDuration("AUCTION_EXTENSION", 2*time.Minute),
 	}
 }
 
@@ -29,3 +112,76 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		slog.Error("Invalid number in config, using default", "key", key, "value", value)
+		return defaultValue
+	}
+	return number
+}
+
+// getList читает список значений через запятую
+func getList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getTransferLimits читает профиль лимитов из переменных с префиксом prefix.
+// Не заданные переменные означают отсутствие ограничения
+func getTransferLimits(prefix string) entity.TransferLimits {
+	return entity.TransferLimits{
+		MaxPerTransfer:    getInt(prefix+"MAX_AMOUNT", 0),
+		Daily:             getInt(prefix+"DAILY_LIMIT", 0),
+		Weekly:            getInt(prefix+"WEEKLY_LIMIT", 0),
+		DailyPerRecipient: getInt(prefix+"DAILY_RECIPIENT_LIMIT", 0),
+		MinAccountAge:     getDuration(prefix+"MIN_ACCOUNT_AGE", 0),
+	}
+}
+
+// getAchievements читает правила достижений из JSON-файла, путь к которому задан в key.
+// Если путь не задан или файл не читается, возвращает nil
+func getAchievements(key string) []entity.Achievement {
+	path := getEnv(key, "")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("Failed to read achievements, using defaults", "path", path, "error", err)
+		return nil
+	}
+	achievements := []entity.Achievement{}
+	if err := json.Unmarshal(data, &achievements); err != nil {
+		slog.Error("Invalid achievements file, using defaults", "path", path, "error", err)
+		return nil
+	}
+	return achievements
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error("Invalid duration in config, using default", "key", key, "value", value)
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// События, по которым проверяются достижения
+const (
+	EventPurchase         = "purchase"
+	EventTransferSent     = "transfer_sent"
+	EventTransferReceived = "transfer_received"
+)
+
+// Показатели пользователя, с которыми сравниваются пороги достижений
+const (
+	MetricPurchases         = "purchases"
+	MetricTransfersSent     = "transfers_sent"
+	MetricTransfersReceived = "transfers_received"
+	MetricCoinsSent         = "coins_sent"
+	MetricCoinsReceived     = "coins_received"
+	MetricAccountAgeDays    = "account_age_days"
+)
+
+// Event событие сценария, после которого проверяются достижения пользователя UserName
+type Event struct {
+	Kind     string
+	UserName string
+}
+
+// Achievement правило достижения: пользователь получает его, когда показатель Metric достигает Threshold.
+// Непустой Event ограничивает проверку событиями этого вида. Bounty - монеты, выпускаемые в награду
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Event       string `json:"event,omitempty"`
+	Metric      string `json:"metric"`
+	Threshold   int    `json:"threshold"`
+	Bounty      int    `json:"bounty,omitempty"`
+}
+
+// EarnedAchievement достижение, полученное пользователем
+type EarnedAchievement struct {
+	ID            uuid.UUID `json:"-"`
+	AchievementID string    `json:"id"`
+	Name          string    `json:"name"`
+	Bounty        int       `json:"bounty,omitempty"`
+	EarnedAt      time.Time `json:"earnedAt"`
+}
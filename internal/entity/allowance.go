@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AllowancePayment выплата ежемесячного пособия пользователю за период
+type AllowancePayment struct {
+	Period   time.Time
+	UserName string
+	Amount   int
+	// Prorated пособие уменьшено пропорционально дням, оставшимся до конца месяца после регистрации
+	Prorated bool
+	RunID    uuid.UUID
+}
+
+// AllowanceRun отчет о запуске начисления пособия
+type AllowanceRun struct {
+	RunID      uuid.UUID `json:"runId"`
+	Period     time.Time `json:"period"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// UsersPaid сколько пользователей получили пособие, UsersProrated - из них с уменьшенной суммой
+	UsersPaid     int `json:"usersPaid"`
+	UsersProrated int `json:"usersProrated"`
+	TotalAmount   int `json:"totalAmount"`
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuctionStatusActive    = "active"
+	AuctionStatusSold      = "sold"
+	AuctionStatusUnsold    = "unsold"
+	AuctionStatusCancelled = "cancelled"
+)
+
+// Auction аукцион товара. Лидирующая ставка TopBid заморожена у TopBidder холдом TopHoldID.
+// Резервная цена не раскрывается участникам, ReserveMet показывает, достигнута ли она
+type Auction struct {
+	ID           uuid.UUID  `json:"id"`
+	ItemName     string     `json:"item"`
+	ReservePrice int        `json:"-"`
+	ReserveMet   bool       `json:"reserveMet"`
+	StartsAt     time.Time  `json:"startsAt"`
+	EndsAt       time.Time  `json:"endsAt"`
+	Status       string     `json:"status"`
+	TopBid       int        `json:"topBid,omitempty"`
+	TopBidder    string     `json:"topBidder,omitempty"`
+	TopHoldID    *uuid.UUID `json:"-"`
+	BidCount     int        `json:"bidCount"`
+	Extensions   int        `json:"extensions"`
+	PurchaseID   *uuid.UUID `json:"purchaseId,omitempty"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	SettledAt    *time.Time `json:"settledAt,omitempty"`
+}
+
+// AuctionBid ставка на аукционе
+type AuctionBid struct {
+	ID        uuid.UUID `json:"id"`
+	AuctionID uuid.UUID `json:"auctionId"`
+	UserName  string    `json:"user"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AuctionInfo аукцион с последними ставками
+type AuctionInfo struct {
+	Auction
+	Bids []AuctionBid `json:"bids"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Действия, которые записываются в журнал аудита
+const (
+	AuditActionGrantCreated  = "grant.created"
+	AuditActionGrantApproved = "grant.approved"
+	AuditActionGrantRejected = "grant.rejected"
+
+	AuditActionCampaignCreated = "campaign.created"
+	AuditActionCampaignClosed  = "campaign.closed"
+
+	AuditActionAuctionCreated   = "auction.created"
+	AuditActionAuctionCancelled = "auction.cancelled"
+
+	AuditActionUserDeactivated = "user.deactivated"
+
+	AuditActionTransferApproved = "transfer.approved"
+	AuditActionTransferRejected = "transfer.rejected"
+)
+
+// AuditEntry запись журнала действий администраторов
+type AuditEntry struct {
+	ID        int64          `json:"id"`
+	Actor     string         `json:"actor"`
+	Action    string         `json:"action"`
+	TargetID  *uuid.UUID     `json:"targetId,omitempty"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
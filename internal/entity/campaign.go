@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CampaignStatusOpen   = "open"
+	CampaignStatusClosed = "closed"
+)
+
+// Campaign благотворительная кампания. Raised - кэш баланса счета кампании в главной книге,
+// Progress - собранная доля цели в процентах
+type Campaign struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Goal        int        `json:"goal"`
+	Raised      int        `json:"raised"`
+	Progress    int        `json:"progress"`
+	Donors      int        `json:"donors"`
+	Deadline    time.Time  `json:"deadline"`
+	Status      string     `json:"status"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ClosedAt    *time.Time `json:"closedAt,omitempty"`
+}
+
+// Donation пожертвование пользователя в кампанию
+type Donation struct {
+	ID         uuid.UUID `json:"id"`
+	CampaignID uuid.UUID `json:"campaignId"`
+	UserName   string    `json:"user"`
+	Amount     int       `json:"amount"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CampaignDonor сумма пожертвований пользователя в кампанию
+type CampaignDonor struct {
+	UserName  string `json:"user"`
+	Amount    int    `json:"amount"`
+	Donations int    `json:"donations"`
+}
+
+// CampaignSummary итоговый отчет кампании. Для закрытой кампании - сохраненный при закрытии,
+// для открытой - текущее состояние. ClosedBy пуст, если кампания закрыта по сроку
+type CampaignSummary struct {
+	Campaign
+	GoalReached bool            `json:"goalReached"`
+	Donations   int             `json:"donations"`
+	ClosedBy    string          `json:"closedBy,omitempty"`
+	TopDonors   []CampaignDonor `json:"topDonors"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// CoinLot партия монет пользователя, полученных в один день по UTC.
+// Срок жизни монет отсчитывается от GrantedOn
+type CoinLot struct {
+	UserName  string
+	GrantedOn time.Time
+	Amount    int
+}
+
+// ExpiringCoins монеты пользователя, которые сгорят в ExpiresAt
+type ExpiringCoins struct {
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// LotDate возвращает день по UTC, к которому относится партия монет, полученных в момент t
+func LotDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CoinRequestStatusPending   = "pending"
+	CoinRequestStatusAccepted  = "accepted"
+	CoinRequestStatusDeclined  = "declined"
+	CoinRequestStatusCancelled = "cancelled"
+	CoinRequestStatusExpired   = "expired"
+)
+
+// CoinRequest запрос монет (счет), который плательщик может оплатить или отклонить
+type CoinRequest struct {
+	ID         uuid.UUID  `json:"id"`
+	Requester  string     `json:"requester"`
+	Payer      string     `json:"payer"`
+	Amount     int        `json:"amount"`
+	Memo       string     `json:"memo,omitempty"`
+	Status     string     `json:"status"`
+	TransferID *uuid.UUID `json:"transferId,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
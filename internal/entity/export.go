@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RecordKindTransfer    = "transfer"
+	RecordKindPurchase    = "purchase"
+	RecordKindGrant       = "grant"
+	RecordKindAllowance   = "allowance"
+	RecordKindExpiry      = "expiry"
+	RecordKindKudos       = "kudos"
+	RecordKindAchievement = "achievement"
+	RecordKindDonation    = "donation"
+	RecordKindMarketplace = "marketplace_sale"
+	// Операции с кошельками команд: взнос участника, перевод из кошелька и покупка за счет команды
+	RecordKindTeamDeposit    = "team_deposit"
+	RecordKindTeamWithdrawal = "team_withdrawal"
+	RecordKindTeamPurchase   = "team_purchase"
+)
+
+// ExportFilter параметры выгрузки истории. Пустой UserName - выгрузка по всем пользователям,
+// нулевые From и To - без ограничения по времени
+type ExportFilter struct {
+	UserName string
+	From     time.Time
+	To       time.Time
+}
+
+// HistoryRecord строка выгрузки истории: перевод, покупка, начисление, пособие, награда за достижение,
+// пожертвование, сделка на маркетплейсе, сгорание монет или операция с кошельком команды
+type HistoryRecord struct {
+	Kind      string    `json:"kind"`
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// FromUser отправитель перевода, покупатель, жертвователь или владелец сгоревших монет,
+	// у начисления, пособия и награды - счет эмиссии. У пожертвования ToUser - счет кампании,
+	// у сделки на маркетплейсе - продавец. У операции с кошельком команды вторая сторона - счет команды,
+	// а получатель перевода или товара из кошелька - ToUser
+	FromUser string `json:"fromUser"`
+	ToUser   string `json:"toUser,omitempty"`
+	Item     string `json:"item,omitempty"`
+	Amount   int    `json:"amount"`
+	Memo     string `json:"memo,omitempty"`
+}
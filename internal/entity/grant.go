@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GrantStatusPending   = "pending"
+	GrantStatusCompleted = "completed"
+	GrantStatusRejected  = "rejected"
+)
+
+// Grant начисление монет администратором одному или нескольким пользователям со счета эмиссии
+type Grant struct {
+	ID         uuid.UUID   `json:"id"`
+	CreatedBy  string      `json:"createdBy"`
+	Reason     string      `json:"reason"`
+	Total      int         `json:"total"`
+	Status     string      `json:"status"`
+	Recipients []GrantItem `json:"recipients"`
+	// ResolvedBy администратор, подтвердивший или отклонивший начисление
+	ResolvedBy string     `json:"resolvedBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// GrantItem сумма начисления одному пользователю
+type GrantItem struct {
+	UserName string `json:"user"`
+	Amount   int    `json:"amount"`
+}
+
+// IssuanceBudget выпуск монет начислениями за месяц. Budget 0 - без ограничения
+type IssuanceBudget struct {
+	Period time.Time `json:"period"`
+	Issued int       `json:"issued"`
+	Budget int       `json:"budget"`
+}
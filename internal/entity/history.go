@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// HistoryCursor позиция в истории переводов, отсортированной по (EffectiveAt, ID) по убыванию
+type HistoryCursor struct {
+	EffectiveAt time.Time
+	ID          uuid.UUID
+}
+
+// HistoryFilter параметры выборки истории переводов пользователя.
+// Нулевые значения полей означают отсутствие фильтра
+type HistoryFilter struct {
+	UserName     string
+	Direction    string
+	Counterparty string
+	MinAmount    int
+	MaxAmount    int
+	From         time.Time
+	To           time.Time
+	Search       string
+	// After возвращать переводы строго старше этой позиции
+	After *HistoryCursor
+	Limit int
+}
+
+type HistoryPage struct {
+	Transfers  []Transaction `json:"transfers"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
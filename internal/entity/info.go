@@ -1,9 +1,19 @@
 package entity
 
 type InfoData struct {
+	// Coins доступные для трат монеты, HeldCoins замороженные под предзаказы и ожидающие переводы
 	Coins       int             `json:"coins"`
+	HeldCoins   int             `json:"heldCoins"`
 	Inventory   []InventoryItem `json:"inventory"`
 	CoinHistory CoinHistory     `json:"coinHistory"`
+	// PendingTransfers переводы, ожидающие решения получателя
+	PendingTransfers PendingTransfers `json:"pendingTransfers"`
+	// ExpiringSoon монеты, которые сгорят в ближайшее время, от ранних к поздним
+	ExpiringSoon []ExpiringCoins `json:"expiringSoon"`
+	// GivingBudget остаток бюджета благодарностей в текущем месяце. Его можно только подарить
+	GivingBudget int `json:"givingBudget"`
+	// Achievements полученные достижения в порядке получения
+	Achievements []EarnedAchievement `json:"achievements"`
 }
 
 type InventoryItem struct {
@@ -14,4 +24,12 @@ type InventoryItem struct {
 type CoinHistory struct {
 	Received []Transaction `json:"received"`
 	Sent     []Transaction `json:"sent"`
+	// NextCursor курсор для продолжения истории через /api/history
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type PendingTransfers struct {
+	// Incoming ожидают решения пользователя, Outgoing - решения получателей
+	Incoming []Transaction `json:"incoming"`
+	Outgoing []Transaction `json:"outgoing"`
 }
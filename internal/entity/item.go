@@ -3,4 +3,12 @@ package entity
 type Item struct {
 	Name  string `json:"name"`
 	Price int    `json:"price"`
+	// Stock остаток на складе, nil если остаток не отслеживается
+	Stock *int `json:"stock,omitempty"`
+}
+
+// BundleComponent товар, входящий в набор
+type BundleComponent struct {
+	ItemName string `json:"item"`
+	Quantity int    `json:"quantity"`
 }
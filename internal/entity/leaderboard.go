@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+const (
+	LeaderboardMetricReceived  = "received"
+	LeaderboardMetricSent      = "sent"
+	LeaderboardMetricPurchases = "purchases"
+
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+)
+
+// Leaderboard рейтинг пользователей по метрике за период. Since - начало периода,
+// UpdatedAt - время расчета рейтинга
+type Leaderboard struct {
+	Metric    string             `json:"metric"`
+	Period    string             `json:"period"`
+	Since     *time.Time         `json:"since,omitempty"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+	Entries   []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry место пользователя в рейтинге. Пользователи с равным Value делят место
+type LeaderboardEntry struct {
+	Rank  int    `json:"rank"`
+	User  string `json:"user"`
+	Value int    `json:"value"`
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Системные счета главной книги
+const (
+	// AccountMint счет эмиссии: отсюда монеты появляются в системе, его баланс отрицательный
+	AccountMint = "system:mint"
+	// AccountShop выручка магазина мерча
+	AccountShop = "system:shop"
+	// AccountExpired сгоревшие монеты
+	AccountExpired = "system:expired"
+)
+
+// Виды проводок
+const (
+	EntryKindOpeningBalance = "opening_balance"
+	EntryKindGrant          = "grant"
+	EntryKindTransfer       = "transfer"
+	EntryKindPurchase       = "purchase"
+	EntryKindRefund         = "refund"
+	EntryKindReversal       = "reversal"
+	EntryKindAllowance      = "allowance"
+	EntryKindExpiry         = "expiry"
+	EntryKindKudos          = "kudos"
+	EntryKindTeamDeposit    = "team_deposit"
+	EntryKindTeamWithdrawal = "team_withdrawal"
+	EntryKindAchievement    = "achievement"
+	EntryKindDonation       = "donation"
+	EntryKindMarketplace    = "marketplace_sale"
+)
+
+// UserAccount возвращает идентификатор счета пользователя
+func UserAccount(userName string) string {
+	return "user:" + userName
+}
+
+// TeamAccount возвращает идентификатор счета кошелька команды
+func TeamAccount(teamID uuid.UUID) string {
+	return "team:" + teamID.String()
+}
+
+// CampaignAccount возвращает идентификатор счета благотворительной кампании
+func CampaignAccount(campaignID uuid.UUID) string {
+	return "campaign:" + campaignID.String()
+}
+
+// LedgerEntry проводка: набор движений по счетам, сумма которых равна нулю
+type LedgerEntry struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	ReferenceID *uuid.UUID `json:"referenceId,omitempty"`
+	Description string     `json:"description,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Postings    []Posting  `json:"postings"`
+}
+
+// Posting движение по счету: положительная сумма зачисляется, отрицательная списывается
+type Posting struct {
+	AccountID string `json:"account"`
+	Amount    int    `json:"amount"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// TransferLimits профиль ограничений на отправку монет. Нулевое значение поля - ограничения нет
+type TransferLimits struct {
+	// MaxPerTransfer максимальная сумма одного перевода
+	MaxPerTransfer int
+	// Daily и Weekly максимальные суммы отправленного за последние сутки и неделю
+	Daily  int
+	Weekly int
+	// DailyPerRecipient максимальная сумма, отправленная одному получателю за сутки
+	DailyPerRecipient int
+	// MinAccountAge сколько должно пройти с регистрации до первого перевода
+	MinAccountAge time.Duration
+}
+
+// SentTotals суммы, отправленные пользователем за скользящие окна лимитов
+type SentTotals struct {
+	Daily            int
+	Weekly           int
+	DailyByRecipient map[string]int
+}
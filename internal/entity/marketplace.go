@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ListingStatusActive    = "active"
+	ListingStatusSold      = "sold"
+	ListingStatusCancelled = "cancelled"
+)
+
+// Listing объявление о продаже товара из инвентаря. Price - цена за единицу,
+// Remaining - сколько единиц еще продается
+type Listing struct {
+	ID        uuid.UUID  `json:"id"`
+	Seller    string     `json:"seller"`
+	ItemName  string     `json:"item"`
+	Price     int        `json:"price"`
+	Quantity  int        `json:"quantity"`
+	Remaining int        `json:"remaining"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty"`
+}
+
+// Sale покупка по объявлению, Amount - сумма сделки
+type Sale struct {
+	ID        uuid.UUID `json:"id"`
+	ListingID uuid.UUID `json:"listingId"`
+	Seller    string    `json:"seller"`
+	Buyer     string    `json:"buyer"`
+	ItemName  string    `json:"item"`
+	Quantity  int       `json:"quantity"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListingFilter фильтр активных объявлений, пустые поля не ограничивают выборку
+type ListingFilter struct {
+	ItemName string
+	Seller   string
+}
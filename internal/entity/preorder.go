@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+)
+
+// Hold замороженные монеты пользователя
+type Hold struct {
+	ID       uuid.UUID `json:"id"`
+	UserName string    `json:"-"`
+	Amount   int       `json:"amount"`
+	Reason   string    `json:"reason"`
+	Status   string    `json:"status"`
+}
+
+const (
+	PreorderStatusPending   = "pending"
+	PreorderStatusFulfilled = "fulfilled"
+	PreorderStatusCancelled = "cancelled"
+	PreorderStatusExpired   = "expired"
+	// PreorderStatusFailed предзаказ не удалось выполнить, монеты разморожены
+	PreorderStatusFailed = "failed"
+)
+
+type Preorder struct {
+	ID        uuid.UUID `json:"id"`
+	UserName  string    `json:"-"`
+	ItemName  string    `json:"item"`
+	Price     int       `json:"price"`
+	HoldID    uuid.UUID `json:"-"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReceiptKindTransfer = "transfer"
+	ReceiptKindPurchase = "purchase"
+)
+
+// Receipt квитанция о переводе или покупке для приложения к отчетам о расходах
+type Receipt struct {
+	Number    uuid.UUID `json:"number"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	// FromUser плательщик: отправитель перевода или покупатель
+	FromUser string `json:"fromUser"`
+	// ToUser получатель перевода, для покупки не заполняется
+	ToUser string `json:"toUser,omitempty"`
+	// Item купленный товар, для перевода не заполняется
+	Item   string `json:"item,omitempty"`
+	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+	// ReversedAmount сколько монет перевода возвращено сторнированием
+	ReversedAmount int `json:"reversedAmount,omitempty"`
+}
@@ -0,0 +1,63 @@
+package entity
+
+import "time"
+
+// BalanceMismatch расхождение кэша users.coins с балансом счета пользователя в главной книге
+type BalanceMismatch struct {
+	UserName string `json:"username"`
+	// Cached значение users.coins, Expected баланс по главной книге
+	Cached     int64 `json:"cached"`
+	Expected   int64 `json:"expected"`
+	Difference int64 `json:"difference"`
+	// Разбивка ожидаемого баланса по видам операций
+	Issued   int64 `json:"issued"`
+	Received int64 `json:"received"`
+	Sent     int64 `json:"sent"`
+	Spent    int64 `json:"spent"`
+	Other    int64 `json:"other"`
+}
+
+// ReconciliationSnapshot согласованный срез данных для сверки
+type ReconciliationSnapshot struct {
+	UsersChecked        int
+	UsersWithoutAccount int
+	UnbalancedEntries   int
+	// LotMismatches пользователи, у которых сумма партий монет не равна users.coins
+	LotMismatches int
+	// TeamMismatches команды, у которых teams.coins не равен балансу счета команды или сумме ее партий
+	TeamMismatches int
+	// CampaignMismatches кампании, у которых собранная сумма не равна балансу счета кампании
+	CampaignMismatches int
+	// Donated сумма балансов счетов благотворительных кампаний
+	Donated int64
+	// Circulation сумма users.coins и teams.coins
+	Circulation int64
+	// LedgerTotal сумма всех движений главной книги, должна быть равна нулю
+	LedgerTotal int64
+	// SystemBalances балансы системных счетов
+	SystemBalances map[string]int64
+	Mismatches     []BalanceMismatch
+}
+
+// InvariantCheck результат проверки глобального инварианта
+type InvariantCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Details string `json:"details,omitempty"`
+}
+
+type ReconciliationReport struct {
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+	OK           bool      `json:"ok"`
+	UsersChecked int       `json:"usersChecked"`
+	// Circulation монеты на балансах пользователей и в кошельках команд, Minted выпущено,
+	// Spent потрачено в магазине, Donated пожертвовано в благотворительные кампании
+	Circulation    int64             `json:"circulation"`
+	Minted         int64             `json:"minted"`
+	Spent          int64             `json:"spent"`
+	Donated        int64             `json:"donated"`
+	SystemBalances map[string]int64  `json:"systemBalances"`
+	Invariants     []InvariantCheck  `json:"invariants"`
+	Mismatches     []BalanceMismatch `json:"mismatches"`
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusPaused    = "paused"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusFailed    = "failed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+// ScheduledTransfer перевод, выполняемый планировщиком: разово в NextRunAt
+// или повторно по cron-расписанию Schedule в часовом поясе Timezone
+type ScheduledTransfer struct {
+	ID           uuid.UUID  `json:"id"`
+	FromUser     string     `json:"fromUser"`
+	ToUser       string     `json:"toUser"`
+	Amount       int        `json:"amount"`
+	Memo         string     `json:"memo,omitempty"`
+	Schedule     string     `json:"schedule,omitempty"`
+	Timezone     string     `json:"timezone"`
+	Status       string     `json:"status"`
+	NextRunAt    *time.Time `json:"nextRunAt,omitempty"`
+	LastRunAt    *time.Time `json:"lastRunAt,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	FailureCount int        `json:"failureCount"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// Recurring сообщает, повторяется ли перевод
+func (t *ScheduledTransfer) Recurring() bool {
+	return t.Schedule != ""
+}
+
+// ScheduledTransferRun результат одного срабатывания запланированного перевода
+type ScheduledTransferRun struct {
+	ID           uuid.UUID  `json:"id"`
+	ScheduledFor time.Time  `json:"scheduledFor"`
+	TransferID   *uuid.UUID `json:"transferId,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
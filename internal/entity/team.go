@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleMember = "member"
+)
+
+// Виды операций с кошельком команды
+const (
+	TeamOperationDeposit    = "deposit"
+	TeamOperationWithdrawal = "withdrawal"
+	TeamOperationPurchase   = "purchase"
+)
+
+// Team команда с общим кошельком. Coins - кэш баланса счета команды в главной книге
+type Team struct {
+	ID        uuid.UUID    `json:"id"`
+	Name      string       `json:"name"`
+	Coins     int          `json:"coins"`
+	CreatedBy string       `json:"createdBy"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Members   []TeamMember `json:"members"`
+}
+
+// Role возвращает роль пользователя в команде или пустую строку, если он не участник
+func (t *Team) Role(userName string) string {
+	for _, member := range t.Members {
+		if member.UserName == userName {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// Owners возвращает число владельцев команды
+func (t *Team) Owners() int {
+	owners := 0
+	for _, member := range t.Members {
+		if member.Role == TeamRoleOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+type TeamMember struct {
+	UserName string    `json:"user"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// TeamOperation операция с кошельком команды. Member - участник, выполнивший операцию,
+// UserName - вносивший монеты, получатель перевода или получатель товара
+type TeamOperation struct {
+	ID        uuid.UUID `json:"id"`
+	TeamID    uuid.UUID `json:"-"`
+	Kind      string    `json:"kind"`
+	Member    string    `json:"member"`
+	UserName  string    `json:"user"`
+	ItemName  string    `json:"item,omitempty"`
+	Amount    int       `json:"amount"`
+	Memo      string    `json:"memo,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TeamInfo команда вместе с последними операциями с ее кошельком
+type TeamInfo struct {
+	Team
+	History []TeamOperation `json:"history"`
+}
@@ -1,10 +1,81 @@
 package entity
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxMemoLength максимальная длина сообщения к переводу в символах
+const MaxMemoLength = 140
+
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusCompleted = "completed"
+	TransferStatusRejected  = "rejected"
+	TransferStatusCancelled = "cancelled"
+	TransferStatusExpired   = "expired"
+)
 
 type Transaction struct {
-	ID       uuid.UUID `json:"-"`
-	FromUser string    `json:"FromUser,omitempty"`
-	ToUser   string    `json:"ToUser,omitempty"`
-	Amount   int       `json:"amount"`
+	ID        uuid.UUID `json:"id"`
+	FromUser  string    `json:"FromUser,omitempty"`
+	ToUser    string    `json:"ToUser,omitempty"`
+	Amount    int       `json:"amount"`
+	Memo      string    `json:"memo,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// EffectiveAt время движения монет: время создания, а для принятого перевода с подтверждением - время принятия.
+	// По нему упорядочена история
+	EffectiveAt time.Time `json:"-"`
+	// BatchID пакет, в составе которого выполнен перевод
+	BatchID *uuid.UUID `json:"batchId,omitempty"`
+	// ReversalOf исходный перевод, если этот перевод - его сторнирование
+	ReversalOf *uuid.UUID `json:"reversalOf,omitempty"`
+	// ReversedAmount сколько из суммы перевода возвращено сторнированием
+	ReversedAmount int `json:"reversedAmount,omitempty"`
+	// Direction направление перевода относительно пользователя, чья история запрошена
+	Direction string `json:"direction,omitempty"`
+	// Status состояние перевода. Ожидающий перевод держит монеты отправителя в холде HoldID
+	// до решения получателя или ExpiresAt
+	Status     string     `json:"status,omitempty"`
+	HoldID     *uuid.UUID `json:"-"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// Kudos благодарность: сумма списана из бюджета благодарностей отправителя,
+	// а получателю выпущены новые монеты
+	Kudos bool `json:"kudos,omitempty"`
+	// Grant начисление администратора: FromUser - счет эмиссии, ID - идентификатор начисления
+	Grant bool `json:"grant,omitempty"`
+	// Kind вид операции в истории, как в выгрузке: transfer, purchase, grant, allowance и другие
+	Kind string `json:"kind,omitempty"`
+	// Item товар покупки или сделки на маркетплейсе
+	Item string `json:"item,omitempty"`
+}
+
+// TransferBatch пакет переводов от одного отправителя, выполненный атомарно
+type TransferBatch struct {
+	ID        uuid.UUID     `json:"batchId"`
+	FromUser  string        `json:"fromUser"`
+	Total     int           `json:"total"`
+	Transfers []Transaction `json:"transfers"`
+}
+
+// Reversal результат сторнирования перевода администратором
+type Reversal struct {
+	TransferID uuid.UUID   `json:"transferId"`
+	Original   Transaction `json:"original"`
+	Reversal   Transaction `json:"reversal"`
+	// Partial получатель успел потратить часть монет, и возвращена только доступная часть
+	Partial bool `json:"partial"`
+	// AlreadyReversed перевод был сторнирован ранее, возвращен существующий результат
+	AlreadyReversed bool `json:"alreadyReversed"`
+}
+
+// Purchase запись о покупке товара
+type Purchase struct {
+	ID        uuid.UUID `json:"id"`
+	UserName  string    `json:"-"`
+	ItemName  string    `json:"item"`
+	Price     int       `json:"price"`
+	CreatedAt time.Time `json:"createdAt"`
 }
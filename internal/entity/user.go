@@ -1,7 +1,39 @@
 package entity
 
+import "time"
+
+const (
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+	RoleService = "service"
+)
+
+// User пользователь магазина. Coins - кэш баланса счета пользователя в главной книге
 type User struct {
-	Name     string `json:"username"`
-	Password string `json:"-"`
-	Coins    int    `json:"coins"` // TODO: Поменять на balance?
+	Name      string    `json:"username"`
+	Password  string    `json:"-"`
+	Coins     int       `json:"coins"` // TODO: Поменять на balance?
+	HeldCoins int       `json:"heldCoins"`
+	Role      string    `json:"-"`
+	CreatedAt time.Time `json:"-"`
+	// GivingBudget остаток бюджета благодарностей в периоде GivingPeriod
+	GivingBudget int        `json:"-"`
+	GivingPeriod *time.Time `json:"-"`
+	// DeactivatedAt время деактивации. Пользователи не удаляются, чтобы сохранить главную книгу
+	DeactivatedAt *time.Time `json:"-"`
+}
+
+// AvailableCoins возвращает монеты, которые можно потратить
+func (u *User) AvailableCoins() int {
+	return u.Coins - u.HeldCoins
+}
+
+// GivingBalance возвращает остаток бюджета благодарностей в периоде period.
+// В новом периоде бюджет равен полному budget
+func (u *User) GivingBalance(period time.Time, budget int) int {
+	if u.GivingPeriod == nil || !u.GivingPeriod.Equal(period) {
+		return budget
+	}
+	return u.GivingBudget
 }
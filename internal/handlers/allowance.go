@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type AllowanceHandler struct {
+	allowanceUseCase *usecase.AllowanceUseCase
+}
+
+func NewAllowanceHandler(allowanceUseCase *usecase.AllowanceUseCase) *AllowanceHandler {
+	return &AllowanceHandler{allowanceUseCase: allowanceUseCase}
+}
+
+// GetRuns возвращает отчеты о последних запусках начисления пособия
+func (h *AllowanceHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.allowanceUseCase.GetRuns(r.Context())
+	if err != nil {
+		slog.Error("Failed to get allowance runs", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get allowance runs")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, runs)
+}
+
+// PayAllowance запускает начисление пособия за текущий месяц вне расписания.
+// Пользователи, уже получившие пособие, повторно его не получат
+func (h *AllowanceHandler) PayAllowance(w http.ResponseWriter, r *http.Request) {
+	run, err := h.allowanceUseCase.PayAllowance(r.Context())
+	if err != nil {
+		if errors.Is(err, usecase.ErrAllowanceDisabled) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		slog.Error("Failed to pay allowance", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to pay allowance")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, run)
+}
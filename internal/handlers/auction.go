@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type AuctionHandler struct {
+	auctionUseCase *usecase.AuctionUseCase
+}
+
+func NewAuctionHandler(auctionUseCase *usecase.AuctionUseCase) *AuctionHandler {
+	return &AuctionHandler{auctionUseCase: auctionUseCase}
+}
+
+type CreateAuctionRequest struct {
+	Item         string    `json:"item"`
+	ReservePrice int       `json:"reservePrice"`
+	StartsAt     time.Time `json:"startsAt,omitempty"`
+	EndsAt       time.Time `json:"endsAt"`
+}
+
+type PlaceBidRequest struct {
+	Amount int `json:"amount"`
+}
+
+// CreateAuction выставляет товар на аукцион
+func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateAuctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	auction, err := h.auctionUseCase.CreateAuction(r.Context(), admin, req.Item, req.ReservePrice, req.StartsAt, req.EndsAt)
+	if err != nil {
+		slog.Error("Failed to create auction", "admin", admin, "item", req.Item, "error", err)
+		writeAuctionError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, auction)
+}
+
+// GetAuctions возвращает аукционы, параметр status - active (по умолчанию), sold, unsold, cancelled или all
+func (h *AuctionHandler) GetAuctions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = "active"
+	case "all":
+		status = ""
+	}
+
+	auctions, err := h.auctionUseCase.GetAuctions(r.Context(), status)
+	if err != nil {
+		slog.Error("Failed to get auctions", "error", err)
+		writeAuctionError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, auctions)
+}
+
+// GetAuction возвращает аукцион и последние ставки
+func (h *AuctionHandler) GetAuction(w http.ResponseWriter, r *http.Request) {
+	id, ok := auctionID(w, r)
+	if !ok {
+		return
+	}
+
+	auction, err := h.auctionUseCase.GetAuction(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get auction", "auctionID", id, "error", err)
+		writeAuctionError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, auction)
+}
+
+// PlaceBid делает ставку от имени текущего пользователя
+func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := auctionID(w, r)
+	if !ok {
+		return
+	}
+
+	var req PlaceBidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	bid, err := h.auctionUseCase.PlaceBid(r.Context(), userName, id, req.Amount)
+	if err != nil {
+		slog.Error("Failed to place bid", "userName", userName, "auctionID", id, "amount", req.Amount, "error", err)
+		writeAuctionError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, bid)
+}
+
+// CancelAuction отменяет активный аукцион
+func (h *AuctionHandler) CancelAuction(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := auctionID(w, r)
+	if !ok {
+		return
+	}
+
+	auction, err := h.auctionUseCase.CancelAuction(r.Context(), admin, id)
+	if err != nil {
+		slog.Error("Failed to cancel auction", "admin", admin, "auctionID", id, "error", err)
+		writeAuctionError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, auction)
+}
+
+func auctionID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid auction id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeAuctionError ставка ниже минимальной или без достаточного баланса - 400
+func writeAuctionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrAuctionNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrAuctionClosed):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	}
+}
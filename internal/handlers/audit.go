@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// defaultAuditLogLimit размер страницы журнала аудита по умолчанию
+const defaultAuditLogLimit = 50
+
+type AuditHandler struct {
+	auditUseCase *usecase.AuditUseCase
+}
+
+func NewAuditHandler(auditUseCase *usecase.AuditUseCase) *AuditHandler {
+	return &AuditHandler{auditUseCase: auditUseCase}
+}
+
+// GetAuditLog отдает журнал действий администраторов постранично: параметр before - id,
+// начиная с которого (не включительно) возвращаются более старые записи
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parseIntParam(query, "limit")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultAuditLogLimit
+	}
+
+	var before int64
+	if value := query.Get("before"); value != "" {
+		if before, err = strconv.ParseInt(value, 10, 64); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "before must be an integer")
+			return
+		}
+	}
+
+	entries, err := h.auditUseCase.GetAuditLog(r.Context(), before, limit)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAuditQuery) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("Failed to get audit log", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get audit log")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, entries)
+}
@@ -48,7 +48,7 @@ func (h *AuthHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.Name)
+	token, err := auth.GenerateToken(user.Name, user.Role)
 	if err != nil {
 		slog.Error("Failed to generate token", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "Internal server error")
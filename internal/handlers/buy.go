@@ -29,7 +29,8 @@ func (h *BuyHandler) BuyItem(w http.ResponseWriter, r *http.Request) {
 
 	itemName := mux.Vars(r)["item"]
 
-	if err := h.buyUseCase.BuyItem(r.Context(), userName, itemName); err != nil {
+	purchase, err := h.buyUseCase.BuyItem(r.Context(), userName, itemName)
+	if err != nil {
 		slog.Error("Failed to buy item", "userName", userName, "item", itemName, "error", err)
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
@@ -37,7 +38,10 @@ func (h *BuyHandler) BuyItem(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Item purchased successfully"}); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message":    "Item purchased successfully",
+		"purchaseId": purchase.ID.String(),
+	}); err != nil {
 		slog.Error("failed to encode JSON response")
 	}
 }
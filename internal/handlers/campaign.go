@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type CampaignHandler struct {
+	campaignUseCase *usecase.CampaignUseCase
+}
+
+func NewCampaignHandler(campaignUseCase *usecase.CampaignUseCase) *CampaignHandler {
+	return &CampaignHandler{campaignUseCase: campaignUseCase}
+}
+
+type CreateCampaignRequest struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Goal        int       `json:"goal"`
+	Deadline    time.Time `json:"deadline"`
+}
+
+type DonateRequest struct {
+	Amount int `json:"amount"`
+}
+
+// CreateCampaign создает благотворительную кампанию
+func (h *CampaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	campaign, err := h.campaignUseCase.CreateCampaign(r.Context(), admin, req.Name, req.Description, req.Goal, req.Deadline)
+	if err != nil {
+		slog.Error("Failed to create campaign", "admin", admin, "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, campaign)
+}
+
+// GetCampaigns возвращает кампании, необязательный параметр status - open или closed
+func (h *CampaignHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.campaignUseCase.GetCampaigns(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Failed to get campaigns", "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, campaigns)
+}
+
+// GetCampaign возвращает кампанию и собранную сумму
+func (h *CampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.campaignUseCase.GetCampaign(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get campaign", "campaignID", id, "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, campaign)
+}
+
+// Donate жертвует монеты текущего пользователя в кампанию
+func (h *CampaignHandler) Donate(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	var req DonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	donation, err := h.campaignUseCase.Donate(r.Context(), userName, id, req.Amount)
+	if err != nil {
+		slog.Error("Failed to donate", "userName", userName, "campaignID", id, "amount", req.Amount, "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, donation)
+}
+
+// CloseCampaign досрочно закрывает кампанию и возвращает итоговый отчет
+func (h *CampaignHandler) CloseCampaign(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.campaignUseCase.CloseCampaign(r.Context(), admin, id)
+	if err != nil {
+		slog.Error("Failed to close campaign", "admin", admin, "campaignID", id, "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, summary)
+}
+
+// GetReport возвращает итоговый отчет кампании
+func (h *CampaignHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.campaignUseCase.GetReport(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get campaign report", "campaignID", id, "error", err)
+		writeCampaignError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, summary)
+}
+
+func campaignID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid campaign id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeCampaignError как и при переводах, отказ в пожертвовании (например, нехватка монет) - 400
+func writeCampaignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCampaignNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrCampaignClosed):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type CoinRequestHandler struct {
+	coinRequestUseCase *usecase.CoinRequestUseCase
+}
+
+func NewCoinRequestHandler(coinRequestUseCase *usecase.CoinRequestUseCase) *CoinRequestHandler {
+	return &CoinRequestHandler{coinRequestUseCase: coinRequestUseCase}
+}
+
+type CreateCoinRequestRequest struct {
+	Payer  string `json:"payer"`
+	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+func (h *CoinRequestHandler) CreateCoinRequest(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateCoinRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Payer == "" {
+		utils.WriteError(w, http.StatusBadRequest, "payer is required")
+		return
+	}
+
+	request, err := h.coinRequestUseCase.CreateCoinRequest(r.Context(), userName, req.Payer, req.Amount, req.Memo)
+	if err != nil {
+		slog.Error("Failed to create coin request", "requester", userName, "payer", req.Payer, "error", err)
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeCoinRequest(w, http.StatusCreated, request)
+}
+
+// GetCoinRequests возвращает входящие запросы, а с direction=outgoing - исходящие
+func (h *CoinRequestHandler) GetCoinRequests(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	var incoming bool
+	switch query.Get("direction") {
+	case "", "incoming":
+		incoming = true
+	case "outgoing":
+		incoming = false
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "direction must be incoming or outgoing")
+		return
+	}
+
+	requests, err := h.coinRequestUseCase.GetCoinRequests(r.Context(), userName, incoming, query.Get("status"))
+	if err != nil {
+		slog.Error("Failed to get coin requests", "userName", userName, "error", err)
+		if errors.Is(err, usecase.ErrInvalidCoinRequest) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get coin requests")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(requests); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+func (h *CoinRequestHandler) AcceptCoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, h.coinRequestUseCase.AcceptCoinRequest)
+}
+
+func (h *CoinRequestHandler) DeclineCoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, h.coinRequestUseCase.DeclineCoinRequest)
+}
+
+func (h *CoinRequestHandler) CancelCoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, h.coinRequestUseCase.CancelCoinRequest)
+}
+
+func (h *CoinRequestHandler) resolve(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx stdcontext.Context, userName string, id uuid.UUID) (*entity.CoinRequest, error),
+) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid coin request id")
+		return
+	}
+
+	request, err := action(r.Context(), userName, id)
+	if err != nil {
+		slog.Error("Failed to resolve coin request", "userName", userName, "requestID", id, "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrCoinRequestNotFound):
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, usecase.ErrCoinRequestNotPending):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			writeTransferError(w, err)
+		}
+		return
+	}
+
+	writeCoinRequest(w, http.StatusOK, request)
+}
+
+func writeCoinRequest(w http.ResponseWriter, status int, request *entity.CoinRequest) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(request); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
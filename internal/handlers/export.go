@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// exportWriteTimeout заменяет WriteTimeout сервера для выгрузок, которые пишутся дольше обычного ответа
+	exportWriteTimeout = 10 * time.Minute
+	// exportFlushEvery через сколько строк данные отправляются клиенту
+	exportFlushEvery = 500
+)
+
+var csvHeader = []string{"kind", "id", "created_at", "from_user", "to_user", "item", "amount", "memo"}
+
+type ExportHandler struct {
+	exportUseCase *usecase.ExportUseCase
+}
+
+func NewExportHandler(exportUseCase *usecase.ExportUseCase) *ExportHandler {
+	return &ExportHandler{exportUseCase: exportUseCase}
+}
+
+// ExportHistory выгружает переводы и покупки пользователя
+func (h *ExportHandler) ExportHistory(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	h.export(w, r, userName)
+}
+
+// ExportAllHistory выгружает историю всех пользователей или одного, указанного в параметре user
+func (h *ExportHandler) ExportAllHistory(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, r.URL.Query().Get("user"))
+}
+
+func (h *ExportHandler) export(w http.ResponseWriter, r *http.Request, userName string) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	switch format {
+	case "":
+		format = "csv"
+	case "csv", "jsonl":
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+
+	filter := entity.ExportFilter{UserName: userName}
+	var err error
+	if filter.From, err = parseTimeParam(query, "from"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = parseTimeParam(query, "to"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+		slog.Error("Failed to extend write deadline for export", "error", err)
+	}
+
+	stream := newExportStream(w, rc, format, userName)
+	err = h.exportUseCase.ExportHistory(r.Context(), filter, stream.write)
+	if err == nil {
+		err = stream.finish()
+	}
+	if err == nil {
+		return
+	}
+
+	if r.Context().Err() != nil {
+		slog.Info("History export cancelled", "userName", userName, "rows", stream.rows)
+		return
+	}
+	slog.Error("History export failed", "userName", userName, "rows", stream.rows, "error", err)
+	if !stream.started {
+		if errors.Is(err, usecase.ErrInvalidHistoryFilter) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to export history")
+		return
+	}
+	// Ответ уже начат: обрываем соединение, чтобы клиент не принял неполную выгрузку за полную
+	panic(http.ErrAbortHandler)
+}
+
+// exportStream пишет строки выгрузки в ответ по мере чтения из базы.
+// Заголовки ответа отправляются с первой строкой, поэтому ошибку до нее можно вернуть обычным ответом
+type exportStream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	format   string
+	filename string
+	csv      *csv.Writer
+	json     *json.Encoder
+	started  bool
+	rows     int
+}
+
+func newExportStream(w http.ResponseWriter, rc *http.ResponseController, format, userName string) *exportStream {
+	name := userName
+	if name == "" {
+		name = "all"
+	}
+	return &exportStream{
+		w:        w,
+		rc:       rc,
+		format:   format,
+		filename: "history-" + name + "." + format,
+	}
+}
+
+func (s *exportStream) start() error {
+	s.started = true
+	if s.format == "csv" {
+		s.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	s.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": s.filename}))
+	s.w.WriteHeader(http.StatusOK)
+
+	if s.format == "csv" {
+		s.csv = csv.NewWriter(s.w)
+		return s.csv.Write(csvHeader)
+	}
+	s.json = json.NewEncoder(s.w)
+	return nil
+}
+
+func (s *exportStream) write(record *entity.HistoryRecord) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if s.csv != nil {
+		err = s.csv.Write([]string{
+			record.Kind,
+			record.ID.String(),
+			record.CreatedAt.UTC().Format(time.RFC3339Nano),
+			csvSafe(record.FromUser),
+			csvSafe(record.ToUser),
+			csvSafe(record.Item),
+			strconv.Itoa(record.Amount),
+			csvSafe(record.Memo),
+		})
+	} else {
+		err = s.json.Encode(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%exportFlushEvery == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// finish отправляет остаток данных. Пустая выгрузка содержит только заголовок CSV
+func (s *exportStream) finish() error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+func (s *exportStream) flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// csvSafe экранирует значения, которые табличные редакторы приняли бы за формулу
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
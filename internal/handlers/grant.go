@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type GrantHandler struct {
+	grantUseCase *usecase.GrantUseCase
+}
+
+func NewGrantHandler(grantUseCase *usecase.GrantUseCase) *GrantHandler {
+	return &GrantHandler{grantUseCase: grantUseCase}
+}
+
+type CreateGrantRequest struct {
+	Reason     string             `json:"reason"`
+	Recipients []entity.GrantItem `json:"recipients"`
+}
+
+type RejectGrantRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// CreateGrant создает начисление. Выполненное начисление возвращается с кодом 201,
+// ожидающее подтверждения - с кодом 202
+func (h *GrantHandler) CreateGrant(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	grant, err := h.grantUseCase.CreateGrant(r.Context(), admin, req.Reason, req.Recipients)
+	if err != nil {
+		slog.Error("Failed to create grant", "admin", admin, "recipients", len(req.Recipients), "error", err)
+		writeGrantError(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if grant.Status == entity.GrantStatusPending {
+		status = http.StatusAccepted
+	}
+	utils.WriteJSON(w, status, grant)
+}
+
+func (h *GrantHandler) GetGrants(w http.ResponseWriter, r *http.Request) {
+	grants, err := h.grantUseCase.GetGrants(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Failed to get grants", "error", err)
+		writeGrantError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, grants)
+}
+
+func (h *GrantHandler) GetGrant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid grant id")
+		return
+	}
+
+	grant, err := h.grantUseCase.GetGrant(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get grant", "grantID", id, "error", err)
+		writeGrantError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, grant)
+}
+
+// GetBudget возвращает выпуск монет начислениями в текущем месяце
+func (h *GrantHandler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	budget, err := h.grantUseCase.GetBudget(r.Context())
+	if err != nil {
+		slog.Error("Failed to get issuance budget", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get issuance budget")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, budget)
+}
+
+func (h *GrantHandler) ApproveGrant(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid grant id")
+		return
+	}
+
+	grant, err := h.grantUseCase.ApproveGrant(r.Context(), admin, id)
+	if err != nil {
+		slog.Error("Failed to approve grant", "admin", admin, "grantID", id, "error", err)
+		writeGrantError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, grant)
+}
+
+func (h *GrantHandler) RejectGrant(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid grant id")
+		return
+	}
+
+	// Комментарий необязателен, поэтому пустое тело допустимо
+	var req RejectGrantRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Error("Invalid request", "error", err)
+			utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	grant, err := h.grantUseCase.RejectGrant(r.Context(), admin, id, req.Comment)
+	if err != nil {
+		slog.Error("Failed to reject grant", "admin", admin, "grantID", id, "error", err)
+		writeGrantError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, grant)
+}
+
+func writeGrantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrGrantNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrGrantSelfApprove):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, usecase.ErrGrantNotPending), errors.Is(err, usecase.ErrIssuanceBudgetExceeded):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, usecase.ErrInvalidGrant):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to process grant")
+	}
+}
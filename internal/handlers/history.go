@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type HistoryHandler struct {
+	historyUseCase *usecase.HistoryUseCase
+}
+
+func NewHistoryHandler(historyUseCase *usecase.HistoryUseCase) *HistoryHandler {
+	return &HistoryHandler{historyUseCase: historyUseCase}
+}
+
+func (h *HistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := parseHistoryFilter(query)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.UserName = userName
+
+	page, err := h.historyUseCase.GetHistory(r.Context(), filter, query.Get("cursor"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) || errors.Is(err, usecase.ErrInvalidHistoryFilter) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("Failed to get history", "userName", userName, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+// parseHistoryFilter разбирает параметры фильтрации истории из строки запроса
+func parseHistoryFilter(query url.Values) (entity.HistoryFilter, error) {
+	filter := entity.HistoryFilter{
+		Direction:    query.Get("direction"),
+		Counterparty: query.Get("counterparty"),
+		Search:       query.Get("search"),
+	}
+
+	var err error
+	if filter.MinAmount, err = parseIntParam(query, "minAmount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = parseIntParam(query, "maxAmount"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseIntParam(query, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseTimeParam(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeParam(query, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseIntParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return result, nil
+}
+
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return result, nil
+}
@@ -27,7 +27,9 @@ func (h *InfoHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	info, err := h.infoUseCase.GetUserInfo(r.Context(), userName)
+	search := r.URL.Query().Get("search")
+
+	info, err := h.infoUseCase.GetUserInfo(r.Context(), userName, search)
 	if err != nil {
 		slog.Error("Failed to get user info", "userName", userName, "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to get user info")
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type LeaderboardHandler struct {
+	leaderboardUseCase *usecase.LeaderboardUseCase
+}
+
+func NewLeaderboardHandler(leaderboardUseCase *usecase.LeaderboardUseCase) *LeaderboardHandler {
+	return &LeaderboardHandler{leaderboardUseCase: leaderboardUseCase}
+}
+
+type LeaderboardOptOutRequest struct {
+	OptOut bool `json:"optOut"`
+}
+
+// GetLeaderboard возвращает рейтинг. По умолчанию - самые благодаримые пользователи за неделю
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = entity.LeaderboardMetricReceived
+	}
+	period := query.Get("period")
+	if period == "" {
+		period = entity.LeaderboardPeriodWeek
+	}
+
+	board, err := h.leaderboardUseCase.GetLeaderboard(r.Context(), metric, period)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidLeaderboard) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, usecase.ErrLeaderboardNotReady) {
+			utils.WriteError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		slog.Error("Failed to get leaderboard", "metric", metric, "period", period, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get leaderboard")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, board)
+}
+
+// SetOptOut скрывает текущего пользователя из рейтингов или возвращает его в них
+func (h *LeaderboardHandler) SetOptOut(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req LeaderboardOptOutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.leaderboardUseCase.SetOptOut(r.Context(), userName, req.OptOut); err != nil {
+		slog.Error("Failed to update leaderboard opt-out", "user", userName, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to update leaderboard opt-out")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MarketplaceHandler struct {
+	marketplaceUseCase *usecase.MarketplaceUseCase
+}
+
+func NewMarketplaceHandler(marketplaceUseCase *usecase.MarketplaceUseCase) *MarketplaceHandler {
+	return &MarketplaceHandler{marketplaceUseCase: marketplaceUseCase}
+}
+
+type CreateListingRequest struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+type BuyListingRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// CreateListing выставляет товар из инвентаря текущего пользователя на продажу
+func (h *MarketplaceHandler) CreateListing(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateListingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	listing, err := h.marketplaceUseCase.CreateListing(r.Context(), userName, req.Item, req.Quantity, req.Price)
+	if err != nil {
+		slog.Error("Failed to create listing", "userName", userName, "item", req.Item, "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, listing)
+}
+
+// GetListings возвращает активные объявления, необязательные параметры item и seller
+func (h *MarketplaceHandler) GetListings(w http.ResponseWriter, r *http.Request) {
+	filter := entity.ListingFilter{
+		ItemName: r.URL.Query().Get("item"),
+		Seller:   r.URL.Query().Get("seller"),
+	}
+	listings, err := h.marketplaceUseCase.GetListings(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to get listings", "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, listings)
+}
+
+// GetMyListings возвращает все объявления текущего пользователя
+func (h *MarketplaceHandler) GetMyListings(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	listings, err := h.marketplaceUseCase.GetMyListings(r.Context(), userName)
+	if err != nil {
+		slog.Error("Failed to get listings", "userName", userName, "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, listings)
+}
+
+// GetListing возвращает объявление
+func (h *MarketplaceHandler) GetListing(w http.ResponseWriter, r *http.Request) {
+	id, ok := listingID(w, r)
+	if !ok {
+		return
+	}
+
+	listing, err := h.marketplaceUseCase.GetListing(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to get listing", "listingID", id, "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, listing)
+}
+
+// Buy покупает товар по объявлению
+func (h *MarketplaceHandler) Buy(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := listingID(w, r)
+	if !ok {
+		return
+	}
+
+	var req BuyListingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sale, err := h.marketplaceUseCase.Buy(r.Context(), userName, id, req.Quantity)
+	if err != nil {
+		slog.Error("Failed to buy listing", "userName", userName, "listingID", id, "quantity", req.Quantity, "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, sale)
+}
+
+// CancelListing снимает объявление текущего пользователя с продажи
+func (h *MarketplaceHandler) CancelListing(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, ok := listingID(w, r)
+	if !ok {
+		return
+	}
+
+	listing, err := h.marketplaceUseCase.CancelListing(r.Context(), userName, id)
+	if err != nil {
+		slog.Error("Failed to cancel listing", "userName", userName, "listingID", id, "error", err)
+		writeMarketplaceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, listing)
+}
+
+func listingID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid listing id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeMarketplaceError превышение лимита переводов покупателем - 403 с кодом лимита,
+// нехватка монет у покупателя или товара у продавца - 400
+func writeMarketplaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrListingNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrListingClosed):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		writeTransferError(w, err)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type PreorderHandler struct {
+	preorderUseCase *usecase.PreorderUseCase
+}
+
+func NewPreorderHandler(preorderUseCase *usecase.PreorderUseCase) *PreorderHandler {
+	return &PreorderHandler{preorderUseCase: preorderUseCase}
+}
+
+func (h *PreorderHandler) CreatePreorder(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	itemName := mux.Vars(r)["item"]
+
+	preorder, err := h.preorderUseCase.CreatePreorder(r.Context(), userName, itemName)
+	if err != nil {
+		slog.Error("Failed to create preorder", "userName", userName, "item", itemName, "error", err)
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(preorder); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+func (h *PreorderHandler) GetPreorders(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	preorders, err := h.preorderUseCase.GetPreorders(r.Context(), userName)
+	if err != nil {
+		slog.Error("Failed to get preorders", "userName", userName, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get preorders")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(preorders); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+func (h *PreorderHandler) CancelPreorder(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid preorder id")
+		return
+	}
+
+	if err := h.preorderUseCase.CancelPreorder(r.Context(), userName, id); err != nil {
+		slog.Error("Failed to cancel preorder", "userName", userName, "preorderID", id, "error", err)
+		if errors.Is(err, usecase.ErrPreorderNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to cancel preorder")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Preorder cancelled"}); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
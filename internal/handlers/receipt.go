@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	stdcontext "context"
+	"errors"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/http"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const receiptTimeLayout = "2006-01-02 15:04:05 MST"
+
+var receiptFuncs = map[string]interface{}{
+	"time": func(t time.Time) string { return t.Format(receiptTimeLayout) },
+}
+
+var textReceiptTemplate = texttemplate.Must(texttemplate.New("receipt").Funcs(receiptFuncs).Parse(
+	`Merch Store receipt
+Number:   {{.Number}}
+Type:     {{.Kind}}
+Date:     {{time .CreatedAt}}
+{{- if eq .Kind "transfer"}}
+From:     {{.FromUser}}
+To:       {{.ToUser}}
+{{- else}}
+Buyer:    {{.FromUser}}
+Item:     {{.Item}}
+{{- end}}
+Amount:   {{.Amount}} coins
+{{- if .Memo}}
+Memo:     {{.Memo}}
+{{- end}}
+{{- if .ReversedAmount}}
+Reversed: {{.ReversedAmount}} coins
+{{- end}}
+Issued:   {{time .IssuedAt}}
+`))
+
+// HTML-шаблон экранирует пользовательские данные (сообщение к переводу) автоматически
+var htmlReceiptTemplate = htmltemplate.Must(htmltemplate.New("receipt").Funcs(receiptFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.Number}}</title>
+<style>
+body { font-family: sans-serif; max-width: 32em; margin: 2em auto; }
+th { text-align: left; padding-right: 1em; }
+</style>
+</head>
+<body>
+<h1>Merch Store receipt</h1>
+<table>
+<tr><th>Number</th><td>{{.Number}}</td></tr>
+<tr><th>Type</th><td>{{.Kind}}</td></tr>
+<tr><th>Date</th><td>{{time .CreatedAt}}</td></tr>
+{{- if eq .Kind "transfer"}}
+<tr><th>From</th><td>{{.FromUser}}</td></tr>
+<tr><th>To</th><td>{{.ToUser}}</td></tr>
+{{- else}}
+<tr><th>Buyer</th><td>{{.FromUser}}</td></tr>
+<tr><th>Item</th><td>{{.Item}}</td></tr>
+{{- end}}
+<tr><th>Amount</th><td>{{.Amount}} coins</td></tr>
+{{- if .Memo}}
+<tr><th>Memo</th><td>{{.Memo}}</td></tr>
+{{- end}}
+{{- if .ReversedAmount}}
+<tr><th>Reversed</th><td>{{.ReversedAmount}} coins</td></tr>
+{{- end}}
+</table>
+<p>Issued {{time .IssuedAt}}</p>
+</body>
+</html>
+`))
+
+type ReceiptHandler struct {
+	receiptUseCase *usecase.ReceiptUseCase
+}
+
+func NewReceiptHandler(receiptUseCase *usecase.ReceiptUseCase) *ReceiptHandler {
+	return &ReceiptHandler{receiptUseCase: receiptUseCase}
+}
+
+// GetTransfer возвращает перевод его участнику или аудитору
+func (h *ReceiptHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	userName, role, id, ok := receiptTarget(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.receiptUseCase.GetTransfer(r.Context(), userName, role, id)
+	if err != nil {
+		writeReceiptError(w, userName, id, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfer)
+}
+
+func (h *ReceiptHandler) GetTransferReceipt(w http.ResponseWriter, r *http.Request) {
+	h.writeReceipt(w, r, h.receiptUseCase.GetTransferReceipt)
+}
+
+func (h *ReceiptHandler) GetPurchaseReceipt(w http.ResponseWriter, r *http.Request) {
+	h.writeReceipt(w, r, h.receiptUseCase.GetPurchaseReceipt)
+}
+
+// writeReceipt отдает квитанцию в формате из параметра format: json (по умолчанию), text или html
+func (h *ReceiptHandler) writeReceipt(
+	w http.ResponseWriter,
+	r *http.Request,
+	get func(ctx stdcontext.Context, viewer, role string, id uuid.UUID) (*entity.Receipt, error),
+) {
+	userName, role, id, ok := receiptTarget(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json", "text", "html":
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "format must be json, text or html")
+		return
+	}
+
+	receipt, err := get(r.Context(), userName, role, id)
+	if err != nil {
+		writeReceiptError(w, userName, id, err)
+		return
+	}
+
+	var render func() error
+	switch format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="receipt-`+receipt.Number.String()+`.txt"`)
+		render = func() error { return textReceiptTemplate.Execute(w, receipt) }
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="receipt-`+receipt.Number.String()+`.html"`)
+		render = func() error { return htmlReceiptTemplate.Execute(w, receipt) }
+	default:
+		utils.WriteJSON(w, http.StatusOK, receipt)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := render(); err != nil {
+		slog.Error("failed to render receipt", "receiptNumber", receipt.Number, "error", err)
+	}
+}
+
+// receiptTarget извлекает пользователя, его роль и идентификатор документа из запроса
+func receiptTarget(w http.ResponseWriter, r *http.Request) (string, string, uuid.UUID, bool) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return "", "", uuid.Nil, false
+	}
+	role, _ := context.GetRole(r.Context())
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid id")
+		return "", "", uuid.Nil, false
+	}
+	return userName, role, id, true
+}
+
+func writeReceiptError(w http.ResponseWriter, userName string, id uuid.UUID, err error) {
+	if errors.Is(err, usecase.ErrTransferNotFound) || errors.Is(err, usecase.ErrPurchaseNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrTransferNotCompleted) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	slog.Error("Failed to get receipt", "userName", userName, "id", id, "error", err)
+	utils.WriteError(w, http.StatusInternalServerError, "Failed to get receipt")
+}
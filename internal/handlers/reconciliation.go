@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type ReconciliationHandler struct {
+	reconciliationUseCase *usecase.ReconciliationUseCase
+}
+
+func NewReconciliationHandler(reconciliationUseCase *usecase.ReconciliationUseCase) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationUseCase: reconciliationUseCase}
+}
+
+// GetLastReport возвращает результат последней сверки
+func (h *ReconciliationHandler) GetLastReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciliationUseCase.GetLastReport(r.Context())
+	if err != nil {
+		slog.Error("Failed to get reconciliation report", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get reconciliation report")
+		return
+	}
+	if report == nil {
+		utils.WriteError(w, http.StatusNotFound, "reconciliation has not been run yet")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+// Reconcile запускает сверку и возвращает ее результат
+func (h *ReconciliationHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciliationUseCase.Reconcile(r.Context())
+	if err != nil {
+		slog.Error("Failed to run reconciliation", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to run reconciliation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ReversalHandler struct {
+	reversalUseCase *usecase.ReversalUseCase
+}
+
+func NewReversalHandler(reversalUseCase *usecase.ReversalUseCase) *ReversalHandler {
+	return &ReversalHandler{reversalUseCase: reversalUseCase}
+}
+
+type ReverseTransferRequest struct {
+	Reason       string `json:"reason"`
+	AllowPartial bool   `json:"allowPartial"`
+}
+
+// ReverseTransfer сторнирует перевод. Новое сторнирование возвращается с кодом 201,
+// повторный запрос для уже сторнированного перевода - с кодом 200
+func (h *ReversalHandler) ReverseTransfer(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid transfer id")
+		return
+	}
+
+	var req ReverseTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	reversal, err := h.reversalUseCase.ReverseTransfer(r.Context(), admin, id, req.Reason, req.AllowPartial)
+	if err != nil {
+		slog.Error("Failed to reverse transfer", "admin", admin, "transferID", id, "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrTransferNotFound):
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, usecase.ErrReversalNotAllowed), errors.Is(err, usecase.ErrReversalInsufficientFunds):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if reversal.AlreadyReversed {
+		status = http.StatusOK
+	}
+	utils.WriteJSON(w, status, reversal)
+}
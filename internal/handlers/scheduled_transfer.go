@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ScheduledTransferHandler struct {
+	scheduledTransferUseCase *usecase.ScheduledTransferUseCase
+}
+
+func NewScheduledTransferHandler(scheduledTransferUseCase *usecase.ScheduledTransferUseCase) *ScheduledTransferHandler {
+	return &ScheduledTransferHandler{scheduledTransferUseCase: scheduledTransferUseCase}
+}
+
+type CreateScheduledTransferRequest struct {
+	ToUser   string     `json:"toUser"`
+	Amount   int        `json:"amount"`
+	Memo     string     `json:"memo,omitempty"`
+	Schedule string     `json:"schedule,omitempty"`
+	Timezone string     `json:"timezone,omitempty"`
+	RunAt    *time.Time `json:"runAt,omitempty"`
+}
+
+type UpdateScheduledTransferRequest struct {
+	Amount   *int       `json:"amount,omitempty"`
+	Memo     *string    `json:"memo,omitempty"`
+	Schedule *string    `json:"schedule,omitempty"`
+	Timezone *string    `json:"timezone,omitempty"`
+	RunAt    *time.Time `json:"runAt,omitempty"`
+	Status   *string    `json:"status,omitempty"`
+}
+
+func (h *ScheduledTransferHandler) CreateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ToUser == "" {
+		utils.WriteError(w, http.StatusBadRequest, "toUser is required")
+		return
+	}
+
+	transfer, err := h.scheduledTransferUseCase.CreateScheduledTransfer(r.Context(), userName, usecase.ScheduledTransferParams{
+		ToUser:   req.ToUser,
+		Amount:   req.Amount,
+		Memo:     req.Memo,
+		Schedule: req.Schedule,
+		Timezone: req.Timezone,
+		RunAt:    req.RunAt,
+	})
+	if err != nil {
+		slog.Error("Failed to create scheduled transfer", "fromUser", userName, "toUser", req.ToUser, "error", err)
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, transfer)
+}
+
+func (h *ScheduledTransferHandler) GetScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	transfers, err := h.scheduledTransferUseCase.GetScheduledTransfers(r.Context(), userName)
+	if err != nil {
+		slog.Error("Failed to get scheduled transfers", "userName", userName, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get scheduled transfers")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfers)
+}
+
+func (h *ScheduledTransferHandler) GetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := scheduledTransferTarget(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.scheduledTransferUseCase.GetScheduledTransfer(r.Context(), userName, id)
+	if err != nil {
+		writeScheduledTransferError(w, userName, id, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfer)
+}
+
+// GetRuns возвращает историю срабатываний перевода
+func (h *ScheduledTransferHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := scheduledTransferTarget(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := h.scheduledTransferUseCase.GetRuns(r.Context(), userName, id)
+	if err != nil {
+		writeScheduledTransferError(w, userName, id, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, runs)
+}
+
+func (h *ScheduledTransferHandler) UpdateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := scheduledTransferTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	transfer, err := h.scheduledTransferUseCase.UpdateScheduledTransfer(r.Context(), userName, id, usecase.ScheduledTransferUpdate{
+		Amount:   req.Amount,
+		Memo:     req.Memo,
+		Schedule: req.Schedule,
+		Timezone: req.Timezone,
+		RunAt:    req.RunAt,
+		Status:   req.Status,
+	})
+	if err != nil {
+		writeScheduledTransferError(w, userName, id, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfer)
+}
+
+func (h *ScheduledTransferHandler) CancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := scheduledTransferTarget(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.scheduledTransferUseCase.CancelScheduledTransfer(r.Context(), userName, id)
+	if err != nil {
+		writeScheduledTransferError(w, userName, id, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfer)
+}
+
+// scheduledTransferTarget извлекает пользователя и идентификатор перевода из запроса
+func scheduledTransferTarget(w http.ResponseWriter, r *http.Request) (string, uuid.UUID, bool) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return "", uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid scheduled transfer id")
+		return "", uuid.Nil, false
+	}
+	return userName, id, true
+}
+
+func writeScheduledTransferError(w http.ResponseWriter, userName string, id uuid.UUID, err error) {
+	slog.Error("Scheduled transfer request failed", "userName", userName, "scheduledTransferID", id, "error", err)
+	switch {
+	case errors.Is(err, usecase.ErrScheduledTransferNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrScheduledTransferFinished):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"avito-merch/internal/entity"
 	"avito-merch/internal/usecase"
 	"avito-merch/internal/utils"
 	"avito-merch/pkg/context"
+	stdcontext "context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 type SendCoinHandler struct {
@@ -20,6 +27,21 @@ func NewSendCoinHandler(sendCoinUseCase *usecase.SendCoinUseCase) *SendCoinHandl
 type SendCoinRequest struct {
 	ToUser string `json:"toUser"`
 	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+	// RequireAcceptance перевод ждет подтверждения получателя независимо от суммы
+	RequireAcceptance bool `json:"requireAcceptance,omitempty"`
+	// Kudos благодарность из бюджета благодарностей вместо перевода своих монет
+	Kudos bool `json:"kudos,omitempty"`
+}
+
+type BatchTransferItem struct {
+	ToUser string `json:"toUser"`
+	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+type SendBatchRequest struct {
+	Transfers []BatchTransferItem `json:"transfers"`
 }
 
 func (h *SendCoinHandler) SendCoins(w http.ResponseWriter, r *http.Request) {
@@ -48,16 +70,145 @@ func (h *SendCoinHandler) SendCoins(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Kudos && req.RequireAcceptance {
+		utils.WriteError(w, http.StatusBadRequest, "kudos cannot require acceptance")
+		return
+	}
+
 	// Выполняем перевод
-	if err := h.sendCoinUseCase.SendCoins(r.Context(), fromUsername, req.ToUser, req.Amount); err != nil {
+	var transfer *entity.Transaction
+	var err error
+	if req.Kudos {
+		transfer, err = h.sendCoinUseCase.SendKudos(r.Context(), fromUsername, req.ToUser, req.Amount, req.Memo)
+	} else {
+		transfer, err = h.sendCoinUseCase.SendCoins(r.Context(), fromUsername, req.ToUser, req.Amount, req.Memo, req.RequireAcceptance)
+	}
+	if err != nil {
 		slog.Error("Failed to send coins", "fromUsername", fromUsername, "toUser", req.ToUser, "amount", req.Amount, "error", err)
-		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		writeTransferError(w, err)
+		return
+	}
+
+	// Ожидающий перевод принят к исполнению, но монеты еще не перешли получателю
+	if transfer.Status == entity.TransferStatusPending {
+		utils.WriteJSON(w, http.StatusAccepted, map[string]string{
+			"message":    "Transfer is awaiting recipient acceptance",
+			"transferId": transfer.ID.String(),
+			"status":     transfer.Status,
+			"expiresAt":  transfer.ExpiresAt.Format(time.RFC3339),
+		})
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Coins transferred successfully"}); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message":    "Coins transferred successfully",
+		"transferId": transfer.ID.String(),
+		"status":     transfer.Status,
+	}); err != nil {
 		slog.Error("failed to encode JSON response")
 	}
 }
+
+// AcceptPendingTransfer получатель принимает ожидающий перевод
+func (h *SendCoinHandler) AcceptPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolvePendingTransfer(w, r, h.sendCoinUseCase.AcceptPendingTransfer)
+}
+
+// RejectPendingTransfer получатель отклоняет ожидающий перевод
+func (h *SendCoinHandler) RejectPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolvePendingTransfer(w, r, h.sendCoinUseCase.RejectPendingTransfer)
+}
+
+// ApprovePendingTransfer руководитель принимает ожидающий перевод вместо получателя
+func (h *SendCoinHandler) ApprovePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolvePendingTransfer(w, r, h.sendCoinUseCase.ApprovePendingTransfer)
+}
+
+// DeclinePendingTransfer руководитель отклоняет ожидающий перевод вместо получателя
+func (h *SendCoinHandler) DeclinePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolvePendingTransfer(w, r, h.sendCoinUseCase.DeclinePendingTransfer)
+}
+
+// CancelPendingTransfer отправитель отзывает ожидающий перевод
+func (h *SendCoinHandler) CancelPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	h.resolvePendingTransfer(w, r, h.sendCoinUseCase.CancelPendingTransfer)
+}
+
+func (h *SendCoinHandler) resolvePendingTransfer(
+	w http.ResponseWriter,
+	r *http.Request,
+	resolve func(ctx stdcontext.Context, userName string, id uuid.UUID) (*entity.Transaction, error),
+) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid transfer id")
+		return
+	}
+
+	transfer, err := resolve(r.Context(), userName, id)
+	if err != nil {
+		slog.Error("Failed to resolve pending transfer", "userName", userName, "transferID", id, "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrTransferNotFound):
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, usecase.ErrTransferNotPending):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to resolve pending transfer")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, transfer)
+}
+
+// SendBatch выполняет переводы нескольким получателям атомарно
+func (h *SendCoinHandler) SendBatch(w http.ResponseWriter, r *http.Request) {
+	fromUsername, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User ID not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SendBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	items := make([]usecase.BatchTransferItem, len(req.Transfers))
+	for i, transfer := range req.Transfers {
+		items[i] = usecase.BatchTransferItem{ToUser: transfer.ToUser, Amount: transfer.Amount, Memo: transfer.Memo}
+	}
+
+	batch, err := h.sendCoinUseCase.SendBatch(r.Context(), fromUsername, items)
+	if err != nil {
+		slog.Error("Failed to send batch", "fromUsername", fromUsername, "transfers", len(items), "error", err)
+		writeTransferError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, batch)
+}
+
+// writeTransferError отвечает на ошибку перевода: превышение лимита - 403 с кодом лимита,
+// остальные ошибки - 400
+func writeTransferError(w http.ResponseWriter, err error) {
+	var limitErr *usecase.TransferLimitError
+	if errors.As(err, &limitErr) {
+		utils.WriteErrorCode(w, http.StatusForbidden, limitErr.Code, limitErr.Error())
+		return
+	}
+	utils.WriteError(w, http.StatusBadRequest, err.Error())
+}
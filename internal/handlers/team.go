@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type TeamHandler struct {
+	teamUseCase *usecase.TeamUseCase
+}
+
+func NewTeamHandler(teamUseCase *usecase.TeamUseCase) *TeamHandler {
+	return &TeamHandler{teamUseCase: teamUseCase}
+}
+
+type CreateTeamRequest struct {
+	Name string `json:"name"`
+}
+
+type SetTeamMemberRequest struct {
+	UserName string `json:"user"`
+	Role     string `json:"role"`
+}
+
+type TeamDepositRequest struct {
+	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+type TeamWithdrawRequest struct {
+	ToUser string `json:"toUser"`
+	Amount int    `json:"amount"`
+	Memo   string `json:"memo,omitempty"`
+}
+
+type TeamBuyRequest struct {
+	// Receiver участник команды, получающий товар. По умолчанию - покупающий владелец
+	Receiver string `json:"receiver,omitempty"`
+}
+
+func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	team, err := h.teamUseCase.CreateTeam(r.Context(), userName, req.Name)
+	if err != nil {
+		slog.Error("Failed to create team", "userName", userName, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, team)
+}
+
+// GetTeams возвращает команды текущего пользователя
+func (h *TeamHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	teams, err := h.teamUseCase.GetTeams(r.Context(), userName)
+	if err != nil {
+		slog.Error("Failed to get teams", "userName", userName, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, teams)
+}
+
+// GetTeam возвращает команду, ее кошелек и историю операций с ним
+func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+	role, _ := context.GetRole(r.Context())
+
+	info, err := h.teamUseCase.GetTeamInfo(r.Context(), userName, role, id)
+	if err != nil {
+		slog.Error("Failed to get team", "userName", userName, "teamID", id, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, info)
+}
+
+// SetMember добавляет участника в команду или меняет его роль
+func (h *TeamHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.UserName == "" {
+		utils.WriteError(w, http.StatusBadRequest, "user is required")
+		return
+	}
+
+	team, err := h.teamUseCase.SetMember(r.Context(), userName, id, req.UserName, req.Role)
+	if err != nil {
+		slog.Error("Failed to set team member", "userName", userName, "teamID", id, "member", req.UserName, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, team)
+}
+
+// RemoveMember исключает участника из команды или выводит из нее текущего пользователя
+func (h *TeamHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+	member := mux.Vars(r)["user"]
+
+	if err := h.teamUseCase.RemoveMember(r.Context(), userName, id, member); err != nil {
+		slog.Error("Failed to remove team member", "userName", userName, "teamID", id, "member", member, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deposit переводит монеты текущего пользователя в кошелек команды
+func (h *TeamHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req TeamDepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	op, err := h.teamUseCase.Deposit(r.Context(), userName, id, req.Amount, req.Memo)
+	if err != nil {
+		slog.Error("Failed to deposit to team wallet", "userName", userName, "teamID", id, "amount", req.Amount, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, op)
+}
+
+// Withdraw переводит монеты из кошелька команды пользователю
+func (h *TeamHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req TeamWithdrawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid request", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ToUser == "" {
+		utils.WriteError(w, http.StatusBadRequest, "toUser is required")
+		return
+	}
+
+	op, err := h.teamUseCase.Withdraw(r.Context(), userName, id, req.ToUser, req.Amount, req.Memo)
+	if err != nil {
+		slog.Error("Failed to withdraw from team wallet", "userName", userName, "teamID", id, "toUser", req.ToUser, "amount", req.Amount, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, op)
+}
+
+// BuyItem покупает товар из кошелька команды. Тело запроса необязательно
+func (h *TeamHandler) BuyItem(w http.ResponseWriter, r *http.Request) {
+	userName, id, ok := teamTarget(w, r)
+	if !ok {
+		return
+	}
+	itemName := mux.Vars(r)["item"]
+
+	var req TeamBuyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Error("Invalid request", "error", err)
+			utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	op, err := h.teamUseCase.BuyItem(r.Context(), userName, id, itemName, req.Receiver)
+	if err != nil {
+		slog.Error("Failed to buy item for team", "userName", userName, "teamID", id, "item", itemName, "error", err)
+		writeTeamError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, op)
+}
+
+// teamTarget извлекает пользователя и идентификатор команды из запроса
+func teamTarget(w http.ResponseWriter, r *http.Request) (string, uuid.UUID, bool) {
+	userName, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return "", uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid team id")
+		return "", uuid.Nil, false
+	}
+	return userName, id, true
+}
+
+// writeTeamError как и при переводах, превышение лимита - 403 с кодом лимита,
+// остальной отказ в операции с монетами (например, нехватка монет) - 400
+func writeTeamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrTeamNotFound), errors.Is(err, usecase.ErrTeamMemberNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrTeamForbidden):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, usecase.ErrTeamNameTaken), errors.Is(err, usecase.ErrLastTeamOwner):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		writeTransferError(w, err)
+	}
+}
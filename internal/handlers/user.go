@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"avito-merch/internal/usecase"
+	"avito-merch/internal/utils"
+	"avito-merch/pkg/context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type UserHandler struct {
+	userUseCase *usecase.UserUseCase
+}
+
+func NewUserHandler(userUseCase *usecase.UserUseCase) *UserHandler {
+	return &UserHandler{userUseCase: userUseCase}
+}
+
+// DeactivateUser деактивирует учетную запись вместо удаления
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	admin, ok := context.GetUserName(r.Context())
+	if !ok {
+		slog.Error("User not found in context")
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userName := mux.Vars(r)["user"]
+	if err := h.userUseCase.DeactivateUser(r.Context(), admin, userName); err != nil {
+		slog.Error("Failed to deactivate user", "admin", admin, "userName", userName, "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AchievementRepository полученные пользователями достижения и показатели, по которым они выдаются
+type AchievementRepository struct {
+	db DB
+}
+
+func NewAchievementRepository(db DB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+func AchievementRepoWithTx(tx pgx.Tx) *AchievementRepository {
+	return NewAchievementRepository(tx)
+}
+
+func (r *AchievementRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+// Выражения показателей пользователя $1. Сторнирования не считаются переводами, покупками считаются
+// и покупки на маркетплейсе, и товары, купленные пользователю из кошелька команды
+var achievementMetrics = map[string]string{
+	entity.MetricPurchases: `((SELECT COUNT(*) FROM purchase_history WHERE user_name = $1)
+		+ (SELECT COUNT(*) FROM marketplace_sales WHERE buyer = $1)
+		+ (SELECT COUNT(*) FROM team_history WHERE user_name = $1 AND kind = 'purchase'))`,
+	entity.MetricTransfersSent: `(SELECT COUNT(*) FROM transfer_history
+		WHERE from_user_name = $1 AND status = 'completed' AND reversal_of IS NULL)`,
+	entity.MetricTransfersReceived: `(SELECT COUNT(*) FROM transfer_history
+		WHERE to_user_name = $1 AND status = 'completed' AND reversal_of IS NULL)`,
+	entity.MetricCoinsSent: `(SELECT COALESCE(SUM(amount), 0) FROM transfer_history
+		WHERE from_user_name = $1 AND status = 'completed' AND reversal_of IS NULL)`,
+	entity.MetricCoinsReceived: `(SELECT COALESCE(SUM(amount), 0) FROM transfer_history
+		WHERE to_user_name = $1 AND status = 'completed' AND reversal_of IS NULL)`,
+}
+
+// GetMetrics считает показатели пользователя одним запросом. Возраст учетной записи
+// считается по GetSignupDates
+func (r *AchievementRepository) GetMetrics(ctx context.Context, userName string, metrics []string) (map[string]int, error) {
+	if len(metrics) == 0 {
+		return map[string]int{}, nil
+	}
+
+	columns := make([]string, len(metrics))
+	for i, metric := range metrics {
+		expr, ok := achievementMetrics[metric]
+		if !ok {
+			return nil, fmt.Errorf("unknown achievement metric: %s", metric)
+		}
+		columns[i] = expr + "::int"
+	}
+
+	values := make([]int, len(metrics))
+	dest := make([]interface{}, len(metrics))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := r.db.QueryRow(ctx, "SELECT "+strings.Join(columns, ", "), userName).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to get achievement metrics: %w", err)
+	}
+
+	result := make(map[string]int, len(metrics))
+	for i, metric := range metrics {
+		result[metric] = values[i]
+	}
+	return result, nil
+}
+
+// GetSignupDates возвращает дату регистрации пользователя и время его первого известного действия:
+// открытия счета в главной книге, перевода или покупки. Пользователи, зарегистрированные до появления
+// users.created_at, имеют дату регистрации 'epoch', и для них это единственная оценка даты регистрации
+func (r *AchievementRepository) GetSignupDates(ctx context.Context, userName string) (time.Time, *time.Time, error) {
+	query := `SELECT u.created_at, LEAST(
+			(SELECT created_at FROM ledger_accounts WHERE user_name = $1),
+			(SELECT min(created_at) FROM transfer_history WHERE from_user_name = $1),
+			(SELECT min(created_at) FROM transfer_history WHERE to_user_name = $1),
+			(SELECT min(created_at) FROM purchase_history WHERE user_name = $1))
+		FROM users u WHERE u.username = $1`
+	var registeredAt time.Time
+	var firstSeenAt *time.Time
+	if err := r.db.QueryRow(ctx, query, userName).Scan(&registeredAt, &firstSeenAt); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to get signup dates: %w", err)
+	}
+	return registeredAt, firstSeenAt, nil
+}
+
+// GetEarned возвращает достижения пользователя в порядке получения
+func (r *AchievementRepository) GetEarned(ctx context.Context, userName string) ([]entity.EarnedAchievement, error) {
+	query := `SELECT id, achievement_id, name, bounty, earned_at FROM user_achievements
+		WHERE user_name = $1
+		ORDER BY earned_at, achievement_id`
+	rows, err := r.db.Query(ctx, query, userName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	defer rows.Close()
+
+	earned := []entity.EarnedAchievement{}
+	for rows.Next() {
+		var a entity.EarnedAchievement
+		if err := rows.Scan(&a.ID, &a.AchievementID, &a.Name, &a.Bounty, &a.EarnedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan achievement: %w", err)
+		}
+		earned = append(earned, a)
+	}
+	return earned, rows.Err()
+}
+
+// Award выдает достижение пользователю и возвращает запись о нем. Если достижение уже выдано,
+// в том числе параллельной транзакцией, возвращается nil
+func (r *AchievementRepository) Award(ctx context.Context, userName string, achievement entity.Achievement) (*entity.EarnedAchievement, error) {
+	earned := entity.EarnedAchievement{AchievementID: achievement.ID, Name: achievement.Name, Bounty: achievement.Bounty}
+	query := `INSERT INTO user_achievements (user_name, achievement_id, name, bounty) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_name, achievement_id) DO NOTHING
+		RETURNING id, earned_at`
+	err := r.db.QueryRow(ctx, query, userName, achievement.ID, achievement.Name, achievement.Bounty).Scan(&earned.ID, &earned.EarnedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to award achievement: %w", err)
+	}
+	return &earned, nil
+}
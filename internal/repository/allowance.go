@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type AllowanceRepository struct {
+	db DB
+}
+
+func NewAllowanceRepository(db DB) *AllowanceRepository {
+	return &AllowanceRepository{db: db}
+}
+
+func AllowanceRepoWithTx(tx pgx.Tx) *AllowanceRepository {
+	return NewAllowanceRepository(tx)
+}
+
+func (r *AllowanceRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+// LockUnpaidUsers блокирует активных пользователей с указанными ролями, зарегистрированных до конца периода
+// и еще не получивших пособие за него. Заблокированные другой репликой пользователи пропускаются
+func (r *AllowanceRepository) LockUnpaidUsers(ctx context.Context, period, periodEnd time.Time, roles []string, limit int) ([]entity.User, error) {
+	query := `SELECT u.username, u.created_at FROM users u
+		WHERE u.role = ANY($1) AND u.created_at < $3 AND u.deactivated_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM allowance_payments p WHERE p.period = $2 AND p.user_name = u.username)
+		ORDER BY u.username
+		LIMIT $4
+		FOR UPDATE OF u SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, roles, period, periodEnd, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock users for allowance: %w", err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+	for rows.Next() {
+		var user entity.User
+		if err := rows.Scan(&user.Name, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// CreatePayment фиксирует выплату. Возвращает false, если пользователь уже получил пособие за период
+func (r *AllowanceRepository) CreatePayment(ctx context.Context, payment *entity.AllowancePayment) (bool, error) {
+	query := `INSERT INTO allowance_payments (period, user_name, amount, prorated, run_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (period, user_name) DO NOTHING`
+	result, err := r.db.Exec(ctx, query, payment.Period, payment.UserName, payment.Amount, payment.Prorated, payment.RunID)
+	if err != nil {
+		return false, fmt.Errorf("failed to create allowance payment: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// GetRuns возвращает отчеты о последних запусках, собранные по выплатам. Запуски,
+// в которых никому не начислено пособие, не сохраняются
+func (r *AllowanceRepository) GetRuns(ctx context.Context, limit int) ([]entity.AllowanceRun, error) {
+	query := `SELECT run_id, period, min(created_at), max(created_at),
+			count(*) FILTER (WHERE amount > 0),
+			count(*) FILTER (WHERE prorated AND amount > 0),
+			COALESCE(sum(amount), 0)
+		FROM allowance_payments
+		GROUP BY run_id, period
+		ORDER BY min(created_at) DESC
+		LIMIT $1`
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowance runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]entity.AllowanceRun, 0)
+	for rows.Next() {
+		var run entity.AllowanceRun
+		err := rows.Scan(&run.RunID, &run.Period, &run.StartedAt, &run.FinishedAt,
+			&run.UsersPaid, &run.UsersProrated, &run.TotalAmount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan allowance run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
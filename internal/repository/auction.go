@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuctionRepository аукционы и ставки. Ставки на один аукцион и его закрытие сериализуются
+// блокировкой строки аукциона; холды и балансы участников меняет вызывающий в той же транзакции
+type AuctionRepository struct {
+	db DB
+}
+
+func NewAuctionRepository(db DB) *AuctionRepository {
+	return &AuctionRepository{db: db}
+}
+
+func AuctionRepoWithTx(tx pgx.Tx) *AuctionRepository {
+	return NewAuctionRepository(tx)
+}
+
+func (r *AuctionRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const auctionColumns = `id, item_name, reserve_price, top_bid >= reserve_price, starts_at, ends_at, status,
+	top_bid, COALESCE(top_bidder, ''), top_hold_id, bid_count, extensions, purchase_id, created_by, created_at, settled_at`
+
+func scanAuction(row pgx.Row) (*entity.Auction, error) {
+	var a entity.Auction
+	err := row.Scan(&a.ID, &a.ItemName, &a.ReservePrice, &a.ReserveMet, &a.StartsAt, &a.EndsAt, &a.Status,
+		&a.TopBid, &a.TopBidder, &a.TopHoldID, &a.BidCount, &a.Extensions, &a.PurchaseID, &a.CreatedBy, &a.CreatedAt, &a.SettledAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAuction создает аукцион
+func (r *AuctionRepository) CreateAuction(ctx context.Context, auction *entity.Auction) error {
+	query := `INSERT INTO auctions (item_name, reserve_price, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at`
+	err := r.db.QueryRow(ctx, query, auction.ItemName, auction.ReservePrice, auction.StartsAt, auction.EndsAt, auction.CreatedBy).
+		Scan(&auction.ID, &auction.Status, &auction.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create auction", "item", auction.ItemName, "error", err)
+		return fmt.Errorf("failed to create auction: %w", err)
+	}
+	return nil
+}
+
+// GetAuction возвращает аукцион или nil, если его нет
+func (r *AuctionRepository) GetAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error) {
+	return r.getAuction(ctx, `SELECT `+auctionColumns+` FROM auctions WHERE id = $1`, id)
+}
+
+// LockAuction блокирует строку аукциона до конца транзакции и возвращает аукцион или nil, если его нет.
+// Участников нужно блокировать после аукциона
+func (r *AuctionRepository) LockAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error) {
+	return r.getAuction(ctx, `SELECT `+auctionColumns+` FROM auctions WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *AuctionRepository) getAuction(ctx context.Context, query string, id uuid.UUID) (*entity.Auction, error) {
+	auction, err := scanAuction(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction: %w", err)
+	}
+	return auction, nil
+}
+
+// GetAuctions возвращает аукционы со статусом status (пустой - все): активные по времени окончания,
+// затем завершенные, новые первыми
+func (r *AuctionRepository) GetAuctions(ctx context.Context, status string) ([]entity.Auction, error) {
+	query := `SELECT ` + auctionColumns + ` FROM auctions
+		WHERE $1 = '' OR status = $1
+		ORDER BY status = 'active' DESC,
+			CASE WHEN status = 'active' THEN ends_at END,
+			settled_at DESC`
+	rows, err := r.db.Query(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auctions: %w", err)
+	}
+	defer rows.Close()
+
+	auctions := []entity.Auction{}
+	for rows.Next() {
+		auction, err := scanAuction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan auction: %w", err)
+		}
+		auctions = append(auctions, *auction)
+	}
+	return auctions, rows.Err()
+}
+
+// GetEndedAuctions возвращает активные аукционы, завершившиеся к моменту now
+func (r *AuctionRepository) GetEndedAuctions(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	query := `SELECT id FROM auctions WHERE status = 'active' AND ends_at <= $1 ORDER BY ends_at`
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ended auctions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan auction id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AddBid записывает ставку и делает ее лидирующей. endsAt - время окончания с учетом продления
+func (r *AuctionRepository) AddBid(ctx context.Context, bid *entity.AuctionBid, holdID uuid.UUID, endsAt time.Time) error {
+	query := `INSERT INTO auction_bids (auction_id, user_name, amount) VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, bid.AuctionID, bid.UserName, bid.Amount).Scan(&bid.ID, &bid.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create bid", "auctionID", bid.AuctionID, "userName", bid.UserName, "error", err)
+		return fmt.Errorf("failed to create bid: %w", err)
+	}
+
+	query = `UPDATE auctions
+		SET top_bid = $1, top_bidder = $2, top_hold_id = $3, bid_count = bid_count + 1,
+			extensions = extensions + CASE WHEN ends_at < $4 THEN 1 ELSE 0 END,
+			ends_at = GREATEST(ends_at, $4)
+		WHERE id = $5`
+	if _, err := r.db.Exec(ctx, query, bid.Amount, bid.UserName, holdID, endsAt, bid.AuctionID); err != nil {
+		return fmt.Errorf("failed to update auction top bid: %w", err)
+	}
+	return nil
+}
+
+// GetBids возвращает последние limit ставок аукциона, новые первыми
+func (r *AuctionRepository) GetBids(ctx context.Context, id uuid.UUID, limit int) ([]entity.AuctionBid, error) {
+	query := `SELECT id, auction_id, user_name, amount, created_at FROM auction_bids
+		WHERE auction_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction bids: %w", err)
+	}
+	defer rows.Close()
+
+	bids := []entity.AuctionBid{}
+	for rows.Next() {
+		var bid entity.AuctionBid
+		if err := rows.Scan(&bid.ID, &bid.AuctionID, &bid.UserName, &bid.Amount, &bid.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan auction bid: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
+
+// SetResult завершает аукцион со статусом status. purchaseID указывается для проданного товара
+func (r *AuctionRepository) SetResult(ctx context.Context, id uuid.UUID, status string, purchaseID *uuid.UUID) error {
+	query := `UPDATE auctions SET status = $1, purchase_id = $2, settled_at = now() WHERE id = $3`
+	if _, err := r.db.Exec(ctx, query, status, purchaseID, id); err != nil {
+		return fmt.Errorf("failed to set auction result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditRepository журнал действий администраторов. Записи только добавляются
+type AuditRepository struct {
+	db DB
+}
+
+func NewAuditRepository(db DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func AuditRepoWithTx(tx pgx.Tx) *AuditRepository {
+	return NewAuditRepository(tx)
+}
+
+// Record добавляет запись в журнал. Внутри транзакции запись фиксируется вместе с действием
+func (r *AuditRepository) Record(ctx context.Context, entry *entity.AuditEntry) error {
+	details := entry.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	query := `INSERT INTO audit_log (actor, action, target_id, details)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, entry.Actor, entry.Action, entry.TargetID, details).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	slog.Info("Audit", "actor", entry.Actor, "action", entry.Action, "targetID", entry.TargetID, "details", details)
+	return nil
+}
+
+// GetEntries возвращает записи журнала, новые первыми. beforeID > 0 - только записи старше указанной
+func (r *AuditRepository) GetEntries(ctx context.Context, beforeID int64, limit int) ([]entity.AuditEntry, error) {
+	query := `SELECT id, actor, action, target_id, details, created_at FROM audit_log
+		WHERE $1 = 0 OR id < $1
+		ORDER BY id DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []entity.AuditEntry
+	for rows.Next() {
+		var entry entity.AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.TargetID, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
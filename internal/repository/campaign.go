@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CampaignRepository благотворительные кампании и пожертвования. Собранная сумма меняется
+// в той же транзакции, что и проводка на счет кампании; пожертвования в одну кампанию
+// и ее закрытие сериализуются блокировкой строки кампании
+type CampaignRepository struct {
+	db DB
+}
+
+func NewCampaignRepository(db DB) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+func CampaignRepoWithTx(tx pgx.Tx) *CampaignRepository {
+	return NewCampaignRepository(tx)
+}
+
+func (r *CampaignRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const campaignColumns = `c.id, c.name, c.description, c.goal, c.raised, c.raised * 100 / c.goal,
+	(SELECT COUNT(DISTINCT d.user_name) FROM charity_donations d WHERE d.campaign_id = c.id),
+	c.deadline, c.status, c.created_by, c.created_at, c.closed_at`
+
+func scanCampaign(row pgx.Row) (*entity.Campaign, error) {
+	var c entity.Campaign
+	err := row.Scan(&c.ID, &c.Name, &c.Description, &c.Goal, &c.Raised, &c.Progress, &c.Donors,
+		&c.Deadline, &c.Status, &c.CreatedBy, &c.CreatedAt, &c.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCampaign создает кампанию и ее счет в главной книге
+func (r *CampaignRepository) CreateCampaign(ctx context.Context, campaign *entity.Campaign) error {
+	query := `INSERT INTO charity_campaigns (name, description, goal, deadline, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at`
+	err := r.db.QueryRow(ctx, query, campaign.Name, campaign.Description, campaign.Goal, campaign.Deadline, campaign.CreatedBy).
+		Scan(&campaign.ID, &campaign.Status, &campaign.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create campaign", "name", campaign.Name, "error", err)
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+	return NewLedgerRepository(r.db).CreateCampaignAccount(ctx, campaign.ID)
+}
+
+// GetCampaign возвращает кампанию или nil, если ее нет
+func (r *CampaignRepository) GetCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error) {
+	return r.getCampaign(ctx, `SELECT `+campaignColumns+` FROM charity_campaigns c WHERE c.id = $1`, id)
+}
+
+// LockCampaign блокирует строку кампании до конца транзакции и возвращает кампанию
+// или nil, если ее нет. Жертвователя нужно блокировать раньше кампании
+func (r *CampaignRepository) LockCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error) {
+	return r.getCampaign(ctx, `SELECT `+campaignColumns+` FROM charity_campaigns c WHERE c.id = $1 FOR UPDATE`, id)
+}
+
+func (r *CampaignRepository) getCampaign(ctx context.Context, query string, id uuid.UUID) (*entity.Campaign, error) {
+	campaign, err := scanCampaign(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return campaign, nil
+}
+
+// GetCampaigns возвращает кампании со статусом status (пустой - все): открытые по сроку окончания,
+// затем закрытые, новые первыми
+func (r *CampaignRepository) GetCampaigns(ctx context.Context, status string) ([]entity.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM charity_campaigns c
+		WHERE $1 = '' OR c.status = $1
+		ORDER BY c.status = 'open' DESC,
+			CASE WHEN c.status = 'open' THEN c.deadline END,
+			c.closed_at DESC`
+	rows, err := r.db.Query(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := []entity.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, *campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+// GetExpiredCampaigns возвращает открытые кампании, срок которых истек к моменту now
+func (r *CampaignRepository) GetExpiredCampaigns(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	query := `SELECT id FROM charity_campaigns WHERE status = 'open' AND deadline <= $1 ORDER BY deadline`
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AddDonation записывает пожертвование и увеличивает собранную сумму.
+// Монеты у пользователя и проводку по главной книге списывает и записывает вызывающий
+func (r *CampaignRepository) AddDonation(ctx context.Context, donation *entity.Donation) error {
+	query := `INSERT INTO charity_donations (campaign_id, user_name, amount) VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, donation.CampaignID, donation.UserName, donation.Amount).
+		Scan(&donation.ID, &donation.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create donation", "campaignID", donation.CampaignID, "userName", donation.UserName, "error", err)
+		return fmt.Errorf("failed to create donation: %w", err)
+	}
+
+	query = `UPDATE charity_campaigns SET raised = raised + $1 WHERE id = $2`
+	if _, err := r.db.Exec(ctx, query, donation.Amount, donation.CampaignID); err != nil {
+		return fmt.Errorf("failed to update campaign total: %w", err)
+	}
+	return nil
+}
+
+// GetDonors возвращает суммы пожертвований в кампанию по пользователям, от больших к меньшим
+func (r *CampaignRepository) GetDonors(ctx context.Context, id uuid.UUID) ([]entity.CampaignDonor, error) {
+	query := `SELECT user_name, SUM(amount)::int, COUNT(*)::int FROM charity_donations
+		WHERE campaign_id = $1
+		GROUP BY user_name
+		ORDER BY SUM(amount) DESC, user_name`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign donors: %w", err)
+	}
+	defer rows.Close()
+
+	var donors []entity.CampaignDonor
+	for rows.Next() {
+		var donor entity.CampaignDonor
+		if err := rows.Scan(&donor.UserName, &donor.Amount, &donor.Donations); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign donor: %w", err)
+		}
+		donors = append(donors, donor)
+	}
+	return donors, rows.Err()
+}
+
+// CloseCampaign закрывает кампанию и сохраняет итоговый отчет
+func (r *CampaignRepository) CloseCampaign(ctx context.Context, summary *entity.CampaignSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal campaign summary: %w", err)
+	}
+
+	query := `UPDATE charity_campaigns SET status = 'closed', closed_at = $1, summary = $2 WHERE id = $3`
+	if _, err := r.db.Exec(ctx, query, summary.ClosedAt, data, summary.ID); err != nil {
+		return fmt.Errorf("failed to close campaign: %w", err)
+	}
+	return nil
+}
+
+// GetSummary возвращает отчет, сохраненный при закрытии кампании, или nil, если кампания не закрыта
+func (r *CampaignRepository) GetSummary(ctx context.Context, id uuid.UUID) (*entity.CampaignSummary, error) {
+	var data []byte
+	query := `SELECT summary FROM charity_campaigns WHERE id = $1 AND summary IS NOT NULL`
+	err := r.db.QueryRow(ctx, query, id).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign summary: %w", err)
+	}
+
+	var summary entity.CampaignSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal campaign summary: %w", err)
+	}
+	return &summary, nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrLotsExhausted = errors.New("coin lots do not cover the debit")
+
+// CoinLotRepository партии монет пользователей. Партии меняются в той же транзакции, что и users.coins,
+// поэтому сумма партий пользователя равна его балансу. Изменения партий одного пользователя
+// сериализуются блокировкой его строки в users
+type CoinLotRepository struct {
+	db DB
+}
+
+func NewCoinLotRepository(db DB) *CoinLotRepository {
+	return &CoinLotRepository{db: db}
+}
+
+func CoinLotRepoWithTx(tx pgx.Tx) *CoinLotRepository {
+	return NewCoinLotRepository(tx)
+}
+
+func (r *CoinLotRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+// AddLots зачисляет партии. Партии одного пользователя за один день объединяются
+func (r *CoinLotRepository) AddLots(ctx context.Context, lots []entity.CoinLot) error {
+	if len(lots) == 0 {
+		return nil
+	}
+	users := make([]string, len(lots))
+	dates := make([]time.Time, len(lots))
+	amounts := make([]int32, len(lots))
+	for i, lot := range lots {
+		users[i] = lot.UserName
+		dates[i] = lot.GrantedOn
+		amounts[i] = int32(lot.Amount)
+	}
+
+	query := `INSERT INTO coin_lots (user_name, granted_on, remaining)
+		SELECT user_name, granted_on, sum(amount)
+		FROM unnest($1::text[], $2::date[], $3::int[]) AS l(user_name, granted_on, amount)
+		GROUP BY user_name, granted_on
+		ON CONFLICT (user_name, granted_on) DO UPDATE SET remaining = coin_lots.remaining + EXCLUDED.remaining`
+	if _, err := r.db.Exec(ctx, query, users, dates, amounts); err != nil {
+		return fmt.Errorf("failed to add coin lots: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLots списывает amount монет с самых старых партий пользователя и возвращает списанные части
+func (r *CoinLotRepository) ConsumeLots(ctx context.Context, userName string, amount int) ([]entity.CoinLot, error) {
+	consumed, err := r.consume(ctx, userName, amount, nil)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, lot := range consumed {
+		total += lot.Amount
+	}
+	if total != amount {
+		return nil, fmt.Errorf("%w: %s: consumed %d of %d", ErrLotsExhausted, userName, total, amount)
+	}
+	return consumed, nil
+}
+
+// ExpireLots списывает не больше limit монет из партий, полученных не позже cutoff, и возвращает списанную сумму
+func (r *CoinLotRepository) ExpireLots(ctx context.Context, userName string, cutoff time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	consumed, err := r.consume(ctx, userName, limit, &cutoff)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, lot := range consumed {
+		total += lot.Amount
+	}
+	return total, nil
+}
+
+// consume списывает до amount монет, начиная с самых старых партий. Непустой cutoff ограничивает
+// списание партиями, полученными не позже этого дня
+func (r *CoinLotRepository) consume(ctx context.Context, userName string, amount int, cutoff *time.Time) ([]entity.CoinLot, error) {
+	query := `
+		WITH ordered AS (
+			SELECT granted_on, remaining,
+				sum(remaining) OVER (ORDER BY granted_on) - remaining AS before
+			FROM coin_lots
+			WHERE user_name = $1 AND remaining > 0 AND ($3::date IS NULL OR granted_on <= $3::date)
+		), taken AS (
+			SELECT granted_on, LEAST(remaining, $2 - before) AS amount FROM ordered WHERE before < $2
+		)
+		UPDATE coin_lots l
+		SET remaining = l.remaining - t.amount
+		FROM taken t
+		WHERE l.user_name = $1 AND l.granted_on = t.granted_on
+		RETURNING t.granted_on, t.amount`
+	rows, err := r.db.Query(ctx, query, userName, amount, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume coin lots: %w", err)
+	}
+	defer rows.Close()
+
+	var consumed []entity.CoinLot
+	for rows.Next() {
+		lot := entity.CoinLot{UserName: userName}
+		if err := rows.Scan(&lot.GrantedOn, &lot.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan coin lot: %w", err)
+		}
+		consumed = append(consumed, lot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to consume coin lots: %w", err)
+	}
+
+	sort.Slice(consumed, func(i, j int) bool { return consumed[i].GrantedOn.Before(consumed[j].GrantedOn) })
+	return consumed, nil
+}
+
+// GetLots возвращает непустые партии пользователя, полученные не позже grantedBefore, от старых к новым
+func (r *CoinLotRepository) GetLots(ctx context.Context, userName string, grantedBefore time.Time) ([]entity.CoinLot, error) {
+	query := `SELECT user_name, granted_on, remaining FROM coin_lots
+		WHERE user_name = $1 AND remaining > 0 AND granted_on <= $2::date
+		ORDER BY granted_on`
+	rows, err := r.db.Query(ctx, query, userName, grantedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coin lots: %w", err)
+	}
+	return collectLots(rows)
+}
+
+// LockUnwarnedLots блокирует до limit партий, полученных не позже cutoff, о сгорании которых
+// пользователь еще не предупрежден, в порядке (дата получения, пользователь) после партии after.
+// Нулевой after - с начала. Заблокированные другими транзакциями пропускаются
+func (r *CoinLotRepository) LockUnwarnedLots(ctx context.Context, cutoff time.Time, after entity.CoinLot, limit int) ([]entity.CoinLot, error) {
+	query := `SELECT user_name, granted_on, remaining FROM coin_lots
+		WHERE remaining > 0 AND warned_at IS NULL AND granted_on <= $1::date
+			AND (granted_on, user_name) > ($2::date, $3)
+		ORDER BY granted_on, user_name
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, cutoff, after.GrantedOn, after.UserName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock unwarned coin lots: %w", err)
+	}
+	return collectLots(rows)
+}
+
+// MarkLotWarned отмечает, что пользователь получил предупреждение о сгорании партии
+func (r *CoinLotRepository) MarkLotWarned(ctx context.Context, lot entity.CoinLot) error {
+	query := `UPDATE coin_lots SET warned_at = now() WHERE user_name = $1 AND granted_on = $2`
+	if _, err := r.db.Exec(ctx, query, lot.UserName, lot.GrantedOn); err != nil {
+		return fmt.Errorf("failed to mark coin lot warned: %w", err)
+	}
+	return nil
+}
+
+// LockUsersWithExpiredLots блокирует до limit пользователей с именем больше after, у которых есть
+// монеты из партий, полученных не позже cutoff. Заблокированные другими транзакциями пропускаются
+func (r *CoinLotRepository) LockUsersWithExpiredLots(ctx context.Context, cutoff time.Time, after string, limit int) ([]string, error) {
+	query := `SELECT u.username FROM users u
+		WHERE u.username > $2 AND EXISTS (
+			SELECT 1 FROM coin_lots l
+			WHERE l.user_name = u.username AND l.remaining > 0 AND l.granted_on <= $1::date)
+		ORDER BY u.username
+		LIMIT $3
+		FOR UPDATE OF u SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, cutoff, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock users with expired coins: %w", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, username)
+	}
+	return users, rows.Err()
+}
+
+// DeleteEmptyLots удаляет полностью израсходованные партии пользователей и кошельков команд
+func (r *CoinLotRepository) DeleteEmptyLots(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM coin_lots WHERE remaining = 0`); err != nil {
+		return fmt.Errorf("failed to delete empty coin lots: %w", err)
+	}
+	if _, err := r.db.Exec(ctx, `DELETE FROM team_coin_lots WHERE remaining = 0`); err != nil {
+		return fmt.Errorf("failed to delete empty team coin lots: %w", err)
+	}
+	return nil
+}
+
+func collectLots(rows pgx.Rows) ([]entity.CoinLot, error) {
+	defer rows.Close()
+
+	var lots []entity.CoinLot
+	for rows.Next() {
+		var lot entity.CoinLot
+		if err := rows.Scan(&lot.UserName, &lot.GrantedOn, &lot.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan coin lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+// transferLots переносит списанные партии получателям в порядке списания, сохраняя дату получения
+func transferLots(consumed []entity.CoinLot, recipients []string, amounts []int) []entity.CoinLot {
+	var lots []entity.CoinLot
+	i := 0
+	for j, recipient := range recipients {
+		need := amounts[j]
+		for need > 0 && i < len(consumed) {
+			take := min(need, consumed[i].Amount)
+			lots = append(lots, entity.CoinLot{UserName: recipient, GrantedOn: consumed[i].GrantedOn, Amount: take})
+			consumed[i].Amount -= take
+			need -= take
+			if consumed[i].Amount == 0 {
+				i++
+			}
+		}
+	}
+	return lots
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CoinRequestRepository struct {
+	db DB
+}
+
+func NewCoinRequestRepository(db DB) *CoinRequestRepository {
+	return &CoinRequestRepository{db: db}
+}
+
+func CoinRequestRepoWithTx(tx pgx.Tx) *CoinRequestRepository {
+	return NewCoinRequestRepository(tx)
+}
+
+func (r *CoinRequestRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const coinRequestColumns = `id, requester_name, payer_name, amount, memo, status, transfer_id, created_at, expires_at, resolved_at`
+
+func scanCoinRequest(row pgx.Row) (*entity.CoinRequest, error) {
+	var request entity.CoinRequest
+	err := row.Scan(
+		&request.ID,
+		&request.Requester,
+		&request.Payer,
+		&request.Amount,
+		&request.Memo,
+		&request.Status,
+		&request.TransferID,
+		&request.CreatedAt,
+		&request.ExpiresAt,
+		&request.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CreateCoinRequest создает запрос монет
+func (r *CoinRequestRepository) CreateCoinRequest(ctx context.Context, request *entity.CoinRequest) error {
+	query := `INSERT INTO coin_requests (requester_name, payer_name, amount, memo, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at`
+	err := r.db.QueryRow(ctx, query,
+		request.Requester,
+		request.Payer,
+		request.Amount,
+		request.Memo,
+		request.ExpiresAt,
+	).Scan(&request.ID, &request.Status, &request.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create coin request", "requester", request.Requester, "payer", request.Payer, "error", err)
+		return err
+	}
+
+	slog.Info("Coin request created", "requester", request.Requester, "payer", request.Payer, "amount", request.Amount)
+	return nil
+}
+
+// GetCoinRequestForUpdate блокирует запрос до конца транзакции,
+// чтобы параллельные оплата и отклонение не могли пройти одновременно
+func (r *CoinRequestRepository) GetCoinRequestForUpdate(ctx context.Context, id uuid.UUID) (*entity.CoinRequest, error) {
+	query := `SELECT ` + coinRequestColumns + ` FROM coin_requests WHERE id = $1 FOR UPDATE`
+	request, err := scanCoinRequest(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coin request: %w", err)
+	}
+	return request, nil
+}
+
+// Resolve переводит ожидающий запрос в итоговый статус
+func (r *CoinRequestRepository) Resolve(ctx context.Context, request *entity.CoinRequest, status string, transferID *uuid.UUID) error {
+	query := `UPDATE coin_requests
+		SET status = $2, transfer_id = $3, resolved_at = now()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, transfer_id, resolved_at`
+	err := r.db.QueryRow(ctx, query, request.ID, status, transferID).Scan(&request.Status, &request.TransferID, &request.ResolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to resolve coin request: %w", err)
+	}
+	return nil
+}
+
+// GetCoinRequests возвращает входящие (пользователь - плательщик) или исходящие
+// запросы пользователя, новые первыми. Пустой status возвращает запросы в любом статусе
+func (r *CoinRequestRepository) GetCoinRequests(ctx context.Context, userName string, incoming bool, status string) ([]entity.CoinRequest, error) {
+	column := "requester_name"
+	if incoming {
+		column = "payer_name"
+	}
+	query := `SELECT ` + coinRequestColumns + ` FROM coin_requests
+		WHERE ` + column + ` = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(ctx, query, userName, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coin requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []entity.CoinRequest
+	for rows.Next() {
+		request, err := scanCoinRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coin request: %w", err)
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// ExpirePending помечает просроченными все ожидающие запросы с истекшим сроком
+func (r *CoinRequestRepository) ExpirePending(ctx context.Context) (int64, error) {
+	query := `UPDATE coin_requests
+		SET status = 'expired', resolved_at = now()
+		WHERE status = 'pending' AND expires_at <= now()`
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire coin requests: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrIssuanceBudgetExceeded = errors.New("monthly issuance budget exceeded")
+
+type GrantRepository struct {
+	db DB
+}
+
+func NewGrantRepository(db DB) *GrantRepository {
+	return &GrantRepository{db: db}
+}
+
+func GrantRepoWithTx(tx pgx.Tx) *GrantRepository {
+	return NewGrantRepository(tx)
+}
+
+func (r *GrantRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const grantColumns = `id, created_by, reason, total, status, COALESCE(resolved_by, ''), created_at, resolved_at`
+
+func scanGrant(row pgx.Row) (*entity.Grant, error) {
+	var grant entity.Grant
+	err := row.Scan(
+		&grant.ID,
+		&grant.CreatedBy,
+		&grant.Reason,
+		&grant.Total,
+		&grant.Status,
+		&grant.ResolvedBy,
+		&grant.CreatedAt,
+		&grant.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// CreateGrant создает начисление вместе с суммами по получателям.
+// Начисление в статусе completed сразу считается выполненным
+func (r *GrantRepository) CreateGrant(ctx context.Context, grant *entity.Grant) error {
+	query := `INSERT INTO coin_grants (created_by, reason, total, status, resolved_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $4 = 'completed' THEN now() END)
+		RETURNING id, created_at, resolved_at`
+	err := r.db.QueryRow(ctx, query, grant.CreatedBy, grant.Reason, grant.Total, grant.Status).
+		Scan(&grant.ID, &grant.CreatedAt, &grant.ResolvedAt)
+	if err != nil {
+		slog.Error("Failed to create grant", "createdBy", grant.CreatedBy, "error", err)
+		return fmt.Errorf("failed to create grant: %w", err)
+	}
+
+	users := make([]string, len(grant.Recipients))
+	amounts := make([]int32, len(grant.Recipients))
+	for i, item := range grant.Recipients {
+		users[i] = item.UserName
+		amounts[i] = int32(item.Amount)
+	}
+	query = `INSERT INTO coin_grant_items (grant_id, user_name, amount)
+		SELECT $1, user_name, amount FROM unnest($2::text[], $3::int[]) AS i(user_name, amount)`
+	if _, err := r.db.Exec(ctx, query, grant.ID, users, amounts); err != nil {
+		return fmt.Errorf("failed to create grant items: %w", err)
+	}
+	return nil
+}
+
+// GetGrant возвращает начисление с получателями или nil, если его нет
+func (r *GrantRepository) GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	return r.getGrant(ctx, `SELECT `+grantColumns+` FROM coin_grants WHERE id = $1`, id)
+}
+
+// GetGrantForUpdate блокирует начисление до конца транзакции
+func (r *GrantRepository) GetGrantForUpdate(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	return r.getGrant(ctx, `SELECT `+grantColumns+` FROM coin_grants WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *GrantRepository) getGrant(ctx context.Context, query string, id uuid.UUID) (*entity.Grant, error) {
+	grant, err := scanGrant(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grant: %w", err)
+	}
+	if err := r.loadRecipients(ctx, []*entity.Grant{grant}); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// GetGrants возвращает последние начисления, пустой status - в любом статусе
+func (r *GrantRepository) GetGrants(ctx context.Context, status string, limit int) ([]entity.Grant, error) {
+	query := `SELECT ` + grantColumns + ` FROM coin_grants
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grants: %w", err)
+	}
+	defer rows.Close()
+
+	var grants []entity.Grant
+	for rows.Next() {
+		grant, err := scanGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan grant: %w", err)
+		}
+		grants = append(grants, *grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	refs := make([]*entity.Grant, len(grants))
+	for i := range grants {
+		refs[i] = &grants[i]
+	}
+	if err := r.loadRecipients(ctx, refs); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// loadRecipients заполняет получателей начислений одним запросом
+func (r *GrantRepository) loadRecipients(ctx context.Context, grants []*entity.Grant) error {
+	if len(grants) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*entity.Grant, len(grants))
+	ids := make([]uuid.UUID, len(grants))
+	for i, grant := range grants {
+		byID[grant.ID] = grant
+		ids[i] = grant.ID
+		grant.Recipients = []entity.GrantItem{}
+	}
+
+	query := `SELECT grant_id, user_name, amount FROM coin_grant_items
+		WHERE grant_id = ANY($1)
+		ORDER BY user_name`
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get grant items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grantID uuid.UUID
+		var item entity.GrantItem
+		if err := rows.Scan(&grantID, &item.UserName, &item.Amount); err != nil {
+			return fmt.Errorf("failed to scan grant item: %w", err)
+		}
+		grant := byID[grantID]
+		grant.Recipients = append(grant.Recipients, item)
+	}
+	return rows.Err()
+}
+
+// ResolveGrant переводит ожидающее начисление в итоговый статус
+func (r *GrantRepository) ResolveGrant(ctx context.Context, grant *entity.Grant, status, resolvedBy string) error {
+	query := `UPDATE coin_grants SET status = $2, resolved_by = $3, resolved_at = now()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING resolved_at`
+	err := r.db.QueryRow(ctx, query, grant.ID, status, resolvedBy).Scan(&grant.ResolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to resolve grant %s: %w", grant.ID, err)
+	}
+	grant.Status = status
+	grant.ResolvedBy = resolvedBy
+	return nil
+}
+
+// ReserveIssuance учитывает выпуск amount монет в периоде period.
+// Если выпуск превысит budget, возвращается ErrIssuanceBudgetExceeded. budget 0 - без ограничения
+func (r *GrantRepository) ReserveIssuance(ctx context.Context, period time.Time, amount, budget int) error {
+	query := `INSERT INTO issuance_budget AS b (period, issued)
+		SELECT $1, $2 WHERE $3 = 0 OR $2 <= $3
+		ON CONFLICT (period) DO UPDATE SET issued = b.issued + EXCLUDED.issued
+			WHERE $3 = 0 OR b.issued + EXCLUDED.issued <= $3
+		RETURNING issued`
+	var issued int
+	err := r.db.QueryRow(ctx, query, period, amount, budget).Scan(&issued)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrIssuanceBudgetExceeded
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve issuance: %w", err)
+	}
+	return nil
+}
+
+// GetIssued возвращает, сколько монет выпущено начислениями в периоде
+func (r *GrantRepository) GetIssued(ctx context.Context, period time.Time) (int, error) {
+	var issued int
+	query := `SELECT COALESCE((SELECT issued FROM issuance_budget WHERE period = $1), 0)`
+	if err := r.db.QueryRow(ctx, query, period).Scan(&issued); err != nil {
+		return 0, fmt.Errorf("failed to get issued coins: %w", err)
+	}
+	return issued, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type HoldRepository struct {
+	db DB
+}
+
+func NewHoldRepository(db DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+func HoldRepoWithTx(tx pgx.Tx) *HoldRepository {
+	return NewHoldRepository(tx)
+}
+
+// CreateHold создает запись о холде. Сами монеты замораживает UserRepository.HoldCoins
+func (r *HoldRepository) CreateHold(ctx context.Context, hold *entity.Hold) error {
+	query := `INSERT INTO coin_holds (user_name, amount, reason)
+		VALUES ($1, $2, $3) RETURNING id, status`
+	err := r.db.QueryRow(ctx, query, hold.UserName, hold.Amount, hold.Reason).Scan(&hold.ID, &hold.Status)
+	if err != nil {
+		slog.Error("Failed to create hold", "userName", hold.UserName, "error", err)
+		return err
+	}
+
+	slog.Info("Hold created", "userName", hold.UserName, "amount", hold.Amount, "reason", hold.Reason)
+	return nil
+}
+
+// ResolveHold переводит активный холд в итоговый статус и возвращает его
+func (r *HoldRepository) ResolveHold(ctx context.Context, id uuid.UUID, status string) (*entity.Hold, error) {
+	var hold entity.Hold
+	query := `
+		UPDATE coin_holds
+		SET status = $2, resolved_at = now()
+		WHERE id = $1 AND status = 'active'
+		RETURNING id, user_name, amount, reason, status`
+	err := r.db.QueryRow(ctx, query, id, status).Scan(
+		&hold.ID,
+		&hold.UserName,
+		&hold.Amount,
+		&hold.Reason,
+		&hold.Status,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve hold %s: %w", id, err)
+	}
+	return &hold, nil
+}
@@ -4,11 +4,17 @@ import (
 	"avito-merch/internal/entity"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrOutOfStock           = errors.New("item is out of stock")
+	ErrNotEnoughInInventory = errors.New("not enough items in inventory")
+)
+
 type ItemRepository struct {
 	db DB
 }
@@ -29,11 +35,12 @@ func (r *ItemRepository) Begin(ctx context.Context) (pgx.Tx, error) {
 // GetItemByName возвращает товар по названию
 func (r *ItemRepository) GetItemByName(ctx context.Context, name string) (*entity.Item, error) {
 	var item entity.Item
-	query := `SELECT name, price FROM merch_items WHERE name = $1`
+	query := `SELECT name, price, stock FROM merch_items WHERE name = $1`
 
 	err := r.db.QueryRow(ctx, query, name).Scan(
 		&item.Name,
 		&item.Price,
+		&item.Stock,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -49,16 +56,91 @@ func (r *ItemRepository) GetItemByName(ctx context.Context, name string) (*entit
 	return &item, nil
 }
 
+// GetBundleComponents возвращает состав набора, для обычного товара список пуст.
+// Компоненты отсортированы по названию, чтобы блокировки остатков
+// при покупке брались в одном порядке
+func (r *ItemRepository) GetBundleComponents(ctx context.Context, bundleName string) ([]entity.BundleComponent, error) {
+	query := `SELECT item_name, quantity FROM bundle_components WHERE bundle_name = $1 ORDER BY item_name`
+	rows, err := r.db.Query(ctx, query, bundleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle components: %w", err)
+	}
+	defer rows.Close()
+
+	var components []entity.BundleComponent
+	for rows.Next() {
+		var component entity.BundleComponent
+		if err := rows.Scan(&component.ItemName, &component.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan bundle component: %w", err)
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// ReserveStock списывает товар со склада, если его остаток отслеживается
+func (r *ItemRepository) ReserveStock(ctx context.Context, itemName string, quantity int) error {
+	query := `
+		UPDATE merch_items
+		SET stock = stock - $2
+		WHERE name = $1 AND (stock IS NULL OR stock >= $2)`
+	result, err := r.db.Exec(ctx, query, itemName, quantity)
+	if err != nil {
+		slog.Error("Failed to reserve stock", "item", itemName, "error", err)
+		return err
+	}
+
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("%w: %s", ErrOutOfStock, itemName)
+	}
+	return nil
+}
+
+// ReleaseStock возвращает товар на склад, если его остаток отслеживается
+func (r *ItemRepository) ReleaseStock(ctx context.Context, itemName string, quantity int) error {
+	query := `UPDATE merch_items SET stock = stock + $2 WHERE name = $1 AND stock IS NOT NULL`
+	if _, err := r.db.Exec(ctx, query, itemName, quantity); err != nil {
+		slog.Error("Failed to release stock", "item", itemName, "error", err)
+		return err
+	}
+	return nil
+}
+
 // AddToInventory добавляет товар в инвентарь пользователя
-func (r *ItemRepository) AddToInventory(ctx context.Context, userName string, itemName string) error {
+func (r *ItemRepository) AddToInventory(ctx context.Context, userName string, itemName string, quantity int) error {
 	query := `INSERT INTO inventory (user_name, item_name, quantity) 
-	VALUES ($1, $2, 1) ON CONFLICT (user_name, item_name) DO UPDATE SET quantity = inventory.quantity + 1`
-	_, err := r.db.Exec(ctx, query, userName, itemName)
+	VALUES ($1, $2, $3) ON CONFLICT (user_name, item_name) DO UPDATE SET quantity = inventory.quantity + $3`
+	_, err := r.db.Exec(ctx, query, userName, itemName, quantity)
 	if err != nil {
 		slog.Error("Failed to add item to inventory", "userName", userName, "item", itemName, "error", err)
 		return err
 	}
 
-	slog.Info("Item added to inventory", "userName", userName, "item", itemName)
+	slog.Info("Item added to inventory", "userName", userName, "item", itemName, "quantity", quantity)
+	return nil
+}
+
+// RemoveFromInventory списывает товар из инвентаря пользователя, если его хватает.
+// Позиция, в которой не осталось товара, удаляется
+func (r *ItemRepository) RemoveFromInventory(ctx context.Context, userName string, itemName string, quantity int) error {
+	query := `UPDATE inventory SET quantity = quantity - $3
+		WHERE user_name = $1 AND item_name = $2 AND quantity >= $3`
+	result, err := r.db.Exec(ctx, query, userName, itemName, quantity)
+	if err != nil {
+		slog.Error("Failed to remove item from inventory", "userName", userName, "item", itemName, "error", err)
+		return err
+	}
+
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("%w: %s", ErrNotEnoughInInventory, itemName)
+	}
+
+	// Пустая позиция не остается в инвентаре
+	query = `DELETE FROM inventory WHERE user_name = $1 AND item_name = $2 AND quantity = 0`
+	if _, err := r.db.Exec(ctx, query, userName, itemName); err != nil {
+		slog.Error("Failed to remove empty inventory item", "userName", userName, "item", itemName, "error", err)
+		return err
+	}
+	slog.Info("Item removed from inventory", "userName", userName, "item", itemName, "quantity", quantity)
 	return nil
 }
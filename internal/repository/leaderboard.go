@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"time"
+)
+
+type LeaderboardRepository struct {
+	db DB
+}
+
+func NewLeaderboardRepository(db DB) *LeaderboardRepository {
+	return &LeaderboardRepository{db: db}
+}
+
+// Выборки лидеров по метрикам. Сторнированная часть перевода не учитывается,
+// скрывшие себя пользователи и сервисные учетные записи в рейтинг не попадают
+var leaderboardQueries = map[string]string{
+	entity.LeaderboardMetricReceived: transferLeadersQuery("to_user_name"),
+	entity.LeaderboardMetricSent:     transferLeadersQuery("from_user_name"),
+	entity.LeaderboardMetricPurchases: `SELECT p.user_name, SUM(p.price) AS value
+		FROM purchase_history p
+		JOIN users u ON u.username = p.user_name
+		WHERE p.created_at >= $1 AND NOT u.leaderboard_opt_out AND u.role <> 'service'
+		GROUP BY p.user_name
+		ORDER BY value DESC, p.user_name
+		LIMIT $2`,
+}
+
+func transferLeadersQuery(userColumn string) string {
+	return `SELECT t.` + userColumn + `, SUM(t.amount - COALESCE(r.amount, 0)) AS value
+		FROM transfer_history t
+		LEFT JOIN transfer_history r ON r.reversal_of = t.id
+		JOIN users u ON u.username = t.` + userColumn + `
+		WHERE t.status = 'completed' AND t.reversal_of IS NULL AND t.effective_at >= $1
+			AND NOT u.leaderboard_opt_out AND u.role <> 'service'
+		GROUP BY t.` + userColumn + `
+		HAVING SUM(t.amount - COALESCE(r.amount, 0)) > 0
+		ORDER BY value DESC, t.` + userColumn + `
+		LIMIT $2`
+}
+
+// GetTop возвращает до limit лидеров по метрике с момента since, по убыванию значения
+func (r *LeaderboardRepository) GetTop(ctx context.Context, metric string, since time.Time, limit int) ([]entity.LeaderboardEntry, error) {
+	query, ok := leaderboardQueries[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard metric: %s", metric)
+	}
+
+	rows, err := r.db.Query(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]entity.LeaderboardEntry, 0, limit)
+	for rows.Next() {
+		var entry entity.LeaderboardEntry
+		if err := rows.Scan(&entry.User, &entry.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetOptedOut возвращает пользователей из userNames, скрывших себя из рейтингов
+func (r *LeaderboardRepository) GetOptedOut(ctx context.Context, userNames []string) ([]string, error) {
+	query := `SELECT username FROM users WHERE username = ANY($1) AND leaderboard_opt_out`
+	rows, err := r.db.Query(ctx, query, userNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard opt-outs: %w", err)
+	}
+	defer rows.Close()
+
+	var optedOut []string
+	for rows.Next() {
+		var userName string
+		if err := rows.Scan(&userName); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard opt-out: %w", err)
+		}
+		optedOut = append(optedOut, userName)
+	}
+	return optedOut, rows.Err()
+}
+
+// SetOptOut скрывает пользователя из рейтингов или возвращает его в них
+func (r *LeaderboardRepository) SetOptOut(ctx context.Context, userName string, optOut bool) error {
+	query := `UPDATE users SET leaderboard_opt_out = $1 WHERE username = $2`
+	result, err := r.db.Exec(ctx, query, optOut, userName)
+	if err != nil {
+		return fmt.Errorf("failed to update leaderboard opt-out: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("user not found: %s", userName)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUnbalancedEntry = errors.New("ledger entry is not balanced")
+
+// LedgerRepository главная книга с двойной записью.
+// Проводки пишутся в той же транзакции, что и изменение users.coins,
+// поэтому баланс пользователя всегда можно пересчитать по его счету
+type LedgerRepository struct {
+	db DB
+}
+
+func NewLedgerRepository(db DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+func LedgerRepoWithTx(tx pgx.Tx) *LedgerRepository {
+	return NewLedgerRepository(tx)
+}
+
+// CreateUserAccount заводит счет пользователя
+func (r *LedgerRepository) CreateUserAccount(ctx context.Context, userName string) error {
+	query := `INSERT INTO ledger_accounts (id, kind, user_name) VALUES ($1, 'user', $2) ON CONFLICT DO NOTHING`
+	if _, err := r.db.Exec(ctx, query, entity.UserAccount(userName), userName); err != nil {
+		return fmt.Errorf("failed to create ledger account: %w", err)
+	}
+	return nil
+}
+
+// CreateTeamAccount заводит счет кошелька команды
+func (r *LedgerRepository) CreateTeamAccount(ctx context.Context, teamID uuid.UUID) error {
+	query := `INSERT INTO ledger_accounts (id, kind) VALUES ($1, 'team') ON CONFLICT DO NOTHING`
+	if _, err := r.db.Exec(ctx, query, entity.TeamAccount(teamID)); err != nil {
+		return fmt.Errorf("failed to create team ledger account: %w", err)
+	}
+	return nil
+}
+
+// CreateCampaignAccount заводит счет благотворительной кампании
+func (r *LedgerRepository) CreateCampaignAccount(ctx context.Context, campaignID uuid.UUID) error {
+	query := `INSERT INTO ledger_accounts (id, kind) VALUES ($1, 'campaign') ON CONFLICT DO NOTHING`
+	if _, err := r.db.Exec(ctx, query, entity.CampaignAccount(campaignID)); err != nil {
+		return fmt.Errorf("failed to create campaign ledger account: %w", err)
+	}
+	return nil
+}
+
+// Record записывает проводку. Сбалансированность проверяется здесь
+// и еще раз триггером базы при коммите
+func (r *LedgerRepository) Record(ctx context.Context, entry *entity.LedgerEntry) error {
+	total := 0
+	accounts := make([]string, 0, len(entry.Postings))
+	amounts := make([]int32, 0, len(entry.Postings))
+	for _, posting := range entry.Postings {
+		if posting.Amount == 0 {
+			continue
+		}
+		total += posting.Amount
+		accounts = append(accounts, posting.AccountID)
+		amounts = append(amounts, int32(posting.Amount))
+	}
+	if total != 0 || len(accounts) < 2 {
+		return fmt.Errorf("%w: kind %s", ErrUnbalancedEntry, entry.Kind)
+	}
+
+	query := `INSERT INTO ledger_entries (kind, reference_id, description)
+		VALUES ($1, $2, $3) RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, entry.Kind, entry.ReferenceID, entry.Description).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create ledger entry", "kind", entry.Kind, "error", err)
+		return fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+
+	query = `INSERT INTO ledger_postings (entry_id, account_id, amount)
+		SELECT $1, account_id, amount FROM unnest($2::text[], $3::int[]) AS p(account_id, amount)`
+	if _, err := r.db.Exec(ctx, query, entry.ID, accounts, amounts); err != nil {
+		slog.Error("Failed to create ledger postings", "entryID", entry.ID, "error", err)
+		return fmt.Errorf("failed to create ledger postings: %w", err)
+	}
+
+	slog.Info("Ledger entry recorded", "entryID", entry.ID, "kind", entry.Kind)
+	return nil
+}
+
+// RecordTransfer проводит перевод между пользователями
+func (r *LedgerRepository) RecordTransfer(ctx context.Context, transfer *entity.Transaction) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindTransfer,
+		ReferenceID: &transfer.ID,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(transfer.FromUser), Amount: -transfer.Amount},
+			{AccountID: entity.UserAccount(transfer.ToUser), Amount: transfer.Amount},
+		},
+	})
+}
+
+// RecordReversal проводит сторнирование перевода: монеты возвращаются от получателя отправителю
+func (r *LedgerRepository) RecordReversal(ctx context.Context, reversal *entity.Transaction, description string) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindReversal,
+		ReferenceID: &reversal.ID,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(reversal.FromUser), Amount: -reversal.Amount},
+			{AccountID: entity.UserAccount(reversal.ToUser), Amount: reversal.Amount},
+		},
+	})
+}
+
+// RecordPurchase проводит оплату покупки в выручку магазина
+func (r *LedgerRepository) RecordPurchase(ctx context.Context, purchase *entity.Purchase) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindPurchase,
+		ReferenceID: &purchase.ID,
+		Description: purchase.ItemName,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(purchase.UserName), Amount: -purchase.Price},
+			{AccountID: entity.AccountShop, Amount: purchase.Price},
+		},
+	})
+}
+
+// RecordGrant проводит начисление монет пользователю со счета эмиссии
+func (r *LedgerRepository) RecordGrant(ctx context.Context, userName string, amount int, referenceID *uuid.UUID, description string) error {
+	return r.RecordIssuance(ctx, entity.EntryKindGrant, userName, amount, referenceID, description)
+}
+
+// RecordIssuance проводит выпуск монет пользователю со счета эмиссии с указанным видом проводки
+func (r *LedgerRepository) RecordIssuance(ctx context.Context, kind, userName string, amount int, referenceID *uuid.UUID, description string) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        kind,
+		ReferenceID: referenceID,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountID: entity.AccountMint, Amount: -amount},
+			{AccountID: entity.UserAccount(userName), Amount: amount},
+		},
+	})
+}
+
+// RecordExpiry проводит сгорание монет пользователя
+func (r *LedgerRepository) RecordExpiry(ctx context.Context, userName string, amount int, description string) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindExpiry,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(userName), Amount: -amount},
+			{AccountID: entity.AccountExpired, Amount: amount},
+		},
+	})
+}
+
+// RecordTeamExpiry проводит сгорание монет кошелька команды
+func (r *LedgerRepository) RecordTeamExpiry(ctx context.Context, teamID uuid.UUID, amount int, description string) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindExpiry,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountID: entity.TeamAccount(teamID), Amount: -amount},
+			{AccountID: entity.AccountExpired, Amount: amount},
+		},
+	})
+}
+
+// RecordTeamOperation проводит операцию с кошельком команды: взнос участника,
+// перевод из кошелька пользователю или оплату покупки в выручку магазина
+func (r *LedgerRepository) RecordTeamOperation(ctx context.Context, op *entity.TeamOperation) error {
+	team := entity.TeamAccount(op.TeamID)
+	entry := &entity.LedgerEntry{ReferenceID: &op.ID, Description: op.Memo}
+	switch op.Kind {
+	case entity.TeamOperationDeposit:
+		entry.Kind = entity.EntryKindTeamDeposit
+		entry.Postings = []entity.Posting{
+			{AccountID: entity.UserAccount(op.UserName), Amount: -op.Amount},
+			{AccountID: team, Amount: op.Amount},
+		}
+	case entity.TeamOperationWithdrawal:
+		entry.Kind = entity.EntryKindTeamWithdrawal
+		entry.Postings = []entity.Posting{
+			{AccountID: team, Amount: -op.Amount},
+			{AccountID: entity.UserAccount(op.UserName), Amount: op.Amount},
+		}
+	case entity.TeamOperationPurchase:
+		entry.Kind = entity.EntryKindPurchase
+		entry.Description = op.ItemName
+		entry.Postings = []entity.Posting{
+			{AccountID: team, Amount: -op.Amount},
+			{AccountID: entity.AccountShop, Amount: op.Amount},
+		}
+	default:
+		return fmt.Errorf("unknown team operation: %s", op.Kind)
+	}
+	return r.Record(ctx, entry)
+}
+
+// RecordDonation проводит пожертвование со счета пользователя на счет кампании
+func (r *LedgerRepository) RecordDonation(ctx context.Context, donation *entity.Donation, description string) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindDonation,
+		ReferenceID: &donation.ID,
+		Description: description,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(donation.UserName), Amount: -donation.Amount},
+			{AccountID: entity.CampaignAccount(donation.CampaignID), Amount: donation.Amount},
+		},
+	})
+}
+
+// RecordSale проводит оплату покупки на маркетплейсе со счета покупателя на счет продавца
+func (r *LedgerRepository) RecordSale(ctx context.Context, sale *entity.Sale) error {
+	return r.Record(ctx, &entity.LedgerEntry{
+		Kind:        entity.EntryKindMarketplace,
+		ReferenceID: &sale.ID,
+		Description: sale.ItemName,
+		Postings: []entity.Posting{
+			{AccountID: entity.UserAccount(sale.Buyer), Amount: -sale.Amount},
+			{AccountID: entity.UserAccount(sale.Seller), Amount: sale.Amount},
+		},
+	})
+}
+
+// GetAccountBalance возвращает баланс счета как сумму всех движений по нему
+func (r *LedgerRepository) GetAccountBalance(ctx context.Context, accountID string) (int, error) {
+	var balance int
+	query := `SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE account_id = $1`
+	if err := r.db.QueryRow(ctx, query, accountID).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to get account balance: %w", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MarketplaceRepository объявления о продаже товаров между пользователями и сделки по ним.
+// Покупки по одному объявлению и его снятие сериализуются блокировкой строки объявления;
+// монеты и инвентарь участников меняет вызывающий в той же транзакции
+type MarketplaceRepository struct {
+	db DB
+}
+
+func NewMarketplaceRepository(db DB) *MarketplaceRepository {
+	return &MarketplaceRepository{db: db}
+}
+
+func MarketplaceRepoWithTx(tx pgx.Tx) *MarketplaceRepository {
+	return NewMarketplaceRepository(tx)
+}
+
+func (r *MarketplaceRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const listingColumns = `id, seller, item_name, price, quantity, remaining, status, created_at, closed_at`
+
+func scanListing(row pgx.Row) (*entity.Listing, error) {
+	var l entity.Listing
+	err := row.Scan(&l.ID, &l.Seller, &l.ItemName, &l.Price, &l.Quantity, &l.Remaining, &l.Status, &l.CreatedAt, &l.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// CreateListing создает объявление. Товар из инвентаря продавца списывает вызывающий
+func (r *MarketplaceRepository) CreateListing(ctx context.Context, listing *entity.Listing) error {
+	query := `INSERT INTO marketplace_listings (seller, item_name, price, quantity, remaining)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING id, remaining, status, created_at`
+	err := r.db.QueryRow(ctx, query, listing.Seller, listing.ItemName, listing.Price, listing.Quantity).
+		Scan(&listing.ID, &listing.Remaining, &listing.Status, &listing.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create listing", "seller", listing.Seller, "item", listing.ItemName, "error", err)
+		return fmt.Errorf("failed to create listing: %w", err)
+	}
+	return nil
+}
+
+// GetListing возвращает объявление или nil, если его нет
+func (r *MarketplaceRepository) GetListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error) {
+	return r.getListing(ctx, `SELECT `+listingColumns+` FROM marketplace_listings WHERE id = $1`, id)
+}
+
+// LockListing блокирует строку объявления до конца транзакции и возвращает объявление
+// или nil, если его нет. Участников сделки нужно блокировать после объявления
+func (r *MarketplaceRepository) LockListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error) {
+	return r.getListing(ctx, `SELECT `+listingColumns+` FROM marketplace_listings WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *MarketplaceRepository) getListing(ctx context.Context, query string, id uuid.UUID) (*entity.Listing, error) {
+	listing, err := scanListing(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing: %w", err)
+	}
+	return listing, nil
+}
+
+// GetActiveListings возвращает активные объявления по фильтру, самые дешевые первыми
+func (r *MarketplaceRepository) GetActiveListings(ctx context.Context, filter entity.ListingFilter) ([]entity.Listing, error) {
+	query := `SELECT ` + listingColumns + ` FROM marketplace_listings
+		WHERE status = 'active'
+			AND ($1 = '' OR item_name = $1)
+			AND ($2 = '' OR seller = $2)
+		ORDER BY item_name, price, created_at`
+	return r.queryListings(ctx, query, filter.ItemName, filter.Seller)
+}
+
+// GetListingsBySeller возвращает все объявления продавца, новые первыми
+func (r *MarketplaceRepository) GetListingsBySeller(ctx context.Context, seller string) ([]entity.Listing, error) {
+	query := `SELECT ` + listingColumns + ` FROM marketplace_listings
+		WHERE seller = $1
+		ORDER BY created_at DESC`
+	return r.queryListings(ctx, query, seller)
+}
+
+func (r *MarketplaceRepository) queryListings(ctx context.Context, query string, args ...any) ([]entity.Listing, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listings: %w", err)
+	}
+	defer rows.Close()
+
+	listings := []entity.Listing{}
+	for rows.Next() {
+		listing, err := scanListing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan listing: %w", err)
+		}
+		listings = append(listings, *listing)
+	}
+	return listings, rows.Err()
+}
+
+// AddSale записывает сделку и уменьшает остаток объявления. Объявление с нулевым остатком
+// становится проданным
+func (r *MarketplaceRepository) AddSale(ctx context.Context, sale *entity.Sale) error {
+	query := `INSERT INTO marketplace_sales (listing_id, seller, buyer, item_name, quantity, amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, sale.ListingID, sale.Seller, sale.Buyer, sale.ItemName, sale.Quantity, sale.Amount).
+		Scan(&sale.ID, &sale.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create sale", "listingID", sale.ListingID, "buyer", sale.Buyer, "error", err)
+		return fmt.Errorf("failed to create sale: %w", err)
+	}
+
+	query = `UPDATE marketplace_listings
+		SET remaining = remaining - $1,
+			status = CASE WHEN remaining = $1 THEN 'sold' ELSE status END,
+			closed_at = CASE WHEN remaining = $1 THEN now() ELSE closed_at END
+		WHERE id = $2`
+	if _, err := r.db.Exec(ctx, query, sale.Quantity, sale.ListingID); err != nil {
+		return fmt.Errorf("failed to update listing: %w", err)
+	}
+	return nil
+}
+
+// CancelListing снимает объявление с продажи. Непроданный остаток возвращает в инвентарь вызывающий
+func (r *MarketplaceRepository) CancelListing(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE marketplace_listings SET status = 'cancelled', closed_at = now() WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to cancel listing: %w", err)
+	}
+	return nil
+}
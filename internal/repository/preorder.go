@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type PreorderRepository struct {
+	db DB
+}
+
+func NewPreorderRepository(db DB) *PreorderRepository {
+	return &PreorderRepository{db: db}
+}
+
+func PreorderRepoWithTx(tx pgx.Tx) *PreorderRepository {
+	return NewPreorderRepository(tx)
+}
+
+func (r *PreorderRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const preorderColumns = `id, user_name, item_name, price, hold_id, status, created_at, expires_at`
+
+func scanPreorder(row pgx.Row) (*entity.Preorder, error) {
+	var preorder entity.Preorder
+	err := row.Scan(
+		&preorder.ID,
+		&preorder.UserName,
+		&preorder.ItemName,
+		&preorder.Price,
+		&preorder.HoldID,
+		&preorder.Status,
+		&preorder.CreatedAt,
+		&preorder.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &preorder, nil
+}
+
+func collectPreorders(rows pgx.Rows) ([]entity.Preorder, error) {
+	defer rows.Close()
+
+	var preorders []entity.Preorder
+	for rows.Next() {
+		preorder, err := scanPreorder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan preorder: %w", err)
+		}
+		preorders = append(preorders, *preorder)
+	}
+	return preorders, rows.Err()
+}
+
+// CreatePreorder создает предзаказ
+func (r *PreorderRepository) CreatePreorder(ctx context.Context, preorder *entity.Preorder) error {
+	query := `INSERT INTO preorders (user_name, item_name, price, hold_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at`
+	err := r.db.QueryRow(ctx, query,
+		preorder.UserName,
+		preorder.ItemName,
+		preorder.Price,
+		preorder.HoldID,
+		preorder.ExpiresAt,
+	).Scan(&preorder.ID, &preorder.Status, &preorder.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create preorder", "userName", preorder.UserName, "item", preorder.ItemName, "error", err)
+		return err
+	}
+
+	slog.Info("Preorder created", "userName", preorder.UserName, "item", preorder.ItemName)
+	return nil
+}
+
+// GetPendingPreorderForUpdate блокирует ожидающий предзаказ пользователя
+func (r *PreorderRepository) GetPendingPreorderForUpdate(ctx context.Context, id uuid.UUID, userName string) (*entity.Preorder, error) {
+	query := `SELECT ` + preorderColumns + ` FROM preorders
+		WHERE id = $1 AND user_name = $2 AND status = 'pending'
+		FOR UPDATE`
+	preorder, err := scanPreorder(r.db.QueryRow(ctx, query, id, userName))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preorder: %w", err)
+	}
+	return preorder, nil
+}
+
+// GetPreordersByUsername возвращает предзаказы пользователя, новые первыми
+func (r *PreorderRepository) GetPreordersByUsername(ctx context.Context, userName string) ([]entity.Preorder, error) {
+	query := `SELECT ` + preorderColumns + ` FROM preorders
+		WHERE user_name = $1
+		ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(ctx, query, userName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preorders: %w", err)
+	}
+	return collectPreorders(rows)
+}
+
+// LockFulfillablePreorders блокирует ожидающие предзаказы, созданные после after, товары которых появились на складе.
+// Набор выбирается, только если на складе хватает всех его компонентов, поэтому наборы с нехваткой
+// не занимают место в выборке. Заблокированные другим экземпляром сервиса строки пропускаются
+func (r *PreorderRepository) LockFulfillablePreorders(ctx context.Context, after entity.Preorder, limit int) ([]entity.Preorder, error) {
+	query := `SELECT p.id, p.user_name, p.item_name, p.price, p.hold_id, p.status, p.created_at, p.expires_at
+		FROM preorders p
+		JOIN merch_items m ON m.name = p.item_name
+		WHERE p.status = 'pending' AND p.expires_at > now() AND m.stock > 0
+			AND (p.created_at, p.id) > ($1, $2)
+			AND NOT EXISTS (
+				SELECT 1 FROM bundle_components c
+				JOIN merch_items ci ON ci.name = c.item_name
+				WHERE c.bundle_name = p.item_name AND ci.stock < c.quantity)
+		ORDER BY p.created_at, p.id
+		LIMIT $3
+		FOR UPDATE OF p SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock fulfillable preorders: %w", err)
+	}
+	return collectPreorders(rows)
+}
+
+// LockExpiredPreorders блокирует ожидающие предзаказы с истекшим сроком
+func (r *PreorderRepository) LockExpiredPreorders(ctx context.Context, now time.Time, limit int) ([]entity.Preorder, error) {
+	query := `SELECT ` + preorderColumns + ` FROM preorders
+		WHERE status = 'pending' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock expired preorders: %w", err)
+	}
+	return collectPreorders(rows)
+}
+
+// SetStatus завершает предзаказ
+func (r *PreorderRepository) SetStatus(ctx context.Context, id uuid.UUID, status string) error {
+	query := `UPDATE preorders SET status = $2, resolved_at = now() WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id, status); err != nil {
+		return fmt.Errorf("failed to update preorder status: %w", err)
+	}
+	return nil
+}
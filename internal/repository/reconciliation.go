@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ReconciliationRepository struct {
+	db DB
+}
+
+func NewReconciliationRepository(db DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// GetSnapshot собирает данные для сверки в одной транзакции REPEATABLE READ,
+// чтобы параллельные переводы не давали ложных расхождений
+func (r *ReconciliationRepository) GetSnapshot(ctx context.Context) (*entity.ReconciliationSnapshot, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+		return nil, fmt.Errorf("failed to set isolation level: %w", err)
+	}
+
+	snapshot := &entity.ReconciliationSnapshot{SystemBalances: map[string]int64{}}
+
+	query := `SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COALESCE(SUM(coins), 0) FROM users) + (SELECT COALESCE(SUM(coins), 0) FROM teams),
+			(SELECT COUNT(*) FROM users u
+				WHERE NOT EXISTS (SELECT 1 FROM ledger_accounts a WHERE a.user_name = u.username)),
+			(SELECT COALESCE(SUM(amount), 0) FROM ledger_postings),
+			(SELECT COUNT(*) FROM (
+				SELECT entry_id FROM ledger_postings
+				GROUP BY entry_id
+				HAVING SUM(amount) <> 0 OR COUNT(*) < 2) unbalanced),
+			(SELECT COUNT(*) FROM users u
+				WHERE u.coins <> (SELECT COALESCE(SUM(remaining), 0) FROM coin_lots l WHERE l.user_name = u.username)),
+			(SELECT COUNT(*) FROM teams t
+				WHERE t.coins <> (SELECT COALESCE(SUM(remaining), 0) FROM team_coin_lots l WHERE l.team_id = t.id)
+					OR t.coins <> (SELECT COALESCE(SUM(amount), 0) FROM ledger_postings p WHERE p.account_id = 'team:' || t.id)),
+			(SELECT COUNT(*) FROM charity_campaigns c
+				WHERE c.raised <> (SELECT COALESCE(SUM(amount), 0) FROM ledger_postings p WHERE p.account_id = 'campaign:' || c.id)),
+			(SELECT COALESCE(SUM(p.amount), 0) FROM ledger_postings p
+				JOIN ledger_accounts a ON a.id = p.account_id
+				WHERE a.kind = 'campaign')`
+	err = tx.QueryRow(ctx, query).Scan(
+		&snapshot.UsersChecked,
+		&snapshot.Circulation,
+		&snapshot.UsersWithoutAccount,
+		&snapshot.LedgerTotal,
+		&snapshot.UnbalancedEntries,
+		&snapshot.LotMismatches,
+		&snapshot.TeamMismatches,
+		&snapshot.CampaignMismatches,
+		&snapshot.Donated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totals: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT a.id, COALESCE(SUM(p.amount), 0)
+		FROM ledger_accounts a
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		WHERE a.kind = 'system'
+		GROUP BY a.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system balances: %w", err)
+	}
+	for rows.Next() {
+		var account string
+		var balance int64
+		if err := rows.Scan(&account, &balance); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan system balance: %w", err)
+		}
+		snapshot.SystemBalances[account] = balance
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT u.username, u.coins,
+			COALESCE(l.balance, 0),
+			COALESCE(l.issued, 0),
+			COALESCE(l.received, 0),
+			COALESCE(l.sent, 0),
+			COALESCE(l.spent, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT a.user_name,
+				SUM(p.amount) AS balance,
+				SUM(p.amount) FILTER (WHERE e.kind IN ('opening_balance', 'grant', 'allowance', 'kudos', 'achievement')) AS issued,
+				SUM(p.amount) FILTER (WHERE e.kind IN ('transfer', 'reversal', 'team_withdrawal', 'marketplace_sale') AND p.amount > 0) AS received,
+				-SUM(p.amount) FILTER (WHERE e.kind IN ('transfer', 'reversal', 'team_deposit', 'marketplace_sale') AND p.amount < 0) AS sent,
+				-SUM(p.amount) FILTER (WHERE e.kind IN ('purchase', 'donation')) AS spent
+			FROM ledger_postings p
+			JOIN ledger_entries e ON e.id = p.entry_id
+			JOIN ledger_accounts a ON a.id = p.account_id
+			WHERE a.kind = 'user'
+			GROUP BY a.user_name
+		) l ON l.user_name = u.username
+		WHERE u.coins <> COALESCE(l.balance, 0)
+		ORDER BY u.username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m entity.BalanceMismatch
+		if err := rows.Scan(&m.UserName, &m.Cached, &m.Expected, &m.Issued, &m.Received, &m.Sent, &m.Spent); err != nil {
+			return nil, fmt.Errorf("failed to scan balance mismatch: %w", err)
+		}
+		m.Difference = m.Cached - m.Expected
+		m.Other = m.Expected - m.Issued - m.Received + m.Sent + m.Spent
+		snapshot.Mismatches = append(snapshot.Mismatches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// SaveReport сохраняет результат сверки
+func (r *ReconciliationRepository) SaveReport(ctx context.Context, report *entity.ReconciliationReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	query := `INSERT INTO reconciliation_runs (started_at, finished_at, ok, report) VALUES ($1, $2, $3, $4)`
+	if _, err := r.db.Exec(ctx, query, report.StartedAt, report.FinishedAt, report.OK, data); err != nil {
+		return fmt.Errorf("failed to save reconciliation report: %w", err)
+	}
+	return nil
+}
+
+// GetLastReport возвращает результат последней сверки или nil, если сверок не было
+func (r *ReconciliationRepository) GetLastReport(ctx context.Context) (*entity.ReconciliationReport, error) {
+	var data []byte
+	query := `SELECT report FROM reconciliation_runs ORDER BY finished_at DESC LIMIT 1`
+	err := r.db.QueryRow(ctx, query).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last reconciliation report: %w", err)
+	}
+
+	var report entity.ReconciliationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %w", err)
+	}
+	return &report, nil
+}
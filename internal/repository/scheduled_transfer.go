@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ScheduledTransferRepository struct {
+	db DB
+}
+
+func NewScheduledTransferRepository(db DB) *ScheduledTransferRepository {
+	return &ScheduledTransferRepository{db: db}
+}
+
+func ScheduledTransferRepoWithTx(tx pgx.Tx) *ScheduledTransferRepository {
+	return NewScheduledTransferRepository(tx)
+}
+
+func (r *ScheduledTransferRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+const scheduledTransferColumns = `id, from_user_name, to_user_name, amount, memo, schedule, timezone, status,
+	next_run_at, last_run_at, last_error, failure_count, created_at`
+
+func scanScheduledTransfer(row pgx.Row) (*entity.ScheduledTransfer, error) {
+	var transfer entity.ScheduledTransfer
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.FromUser,
+		&transfer.ToUser,
+		&transfer.Amount,
+		&transfer.Memo,
+		&transfer.Schedule,
+		&transfer.Timezone,
+		&transfer.Status,
+		&transfer.NextRunAt,
+		&transfer.LastRunAt,
+		&transfer.LastError,
+		&transfer.FailureCount,
+		&transfer.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func collectScheduledTransfers(rows pgx.Rows) ([]entity.ScheduledTransfer, error) {
+	defer rows.Close()
+
+	var transfers []entity.ScheduledTransfer
+	for rows.Next() {
+		transfer, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// CreateScheduledTransfer сохраняет новый запланированный перевод
+func (r *ScheduledTransferRepository) CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	query := `INSERT INTO scheduled_transfers (from_user_name, to_user_name, amount, memo, schedule, timezone, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, status, created_at`
+	err := r.db.QueryRow(ctx, query,
+		transfer.FromUser,
+		transfer.ToUser,
+		transfer.Amount,
+		transfer.Memo,
+		transfer.Schedule,
+		transfer.Timezone,
+		transfer.NextRunAt,
+	).Scan(&transfer.ID, &transfer.Status, &transfer.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create scheduled transfer", "fromUser", transfer.FromUser, "toUser", transfer.ToUser, "error", err)
+		return err
+	}
+
+	slog.Info("Scheduled transfer created", "fromUser", transfer.FromUser, "toUser", transfer.ToUser, "amount", transfer.Amount)
+	return nil
+}
+
+// GetScheduledTransfer возвращает перевод владельца или nil, если его нет
+func (r *ScheduledTransferRepository) GetScheduledTransfer(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE id = $1 AND from_user_name = $2`
+	transfer, err := scanScheduledTransfer(r.db.QueryRow(ctx, query, id, owner))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetScheduledTransferForUpdate блокирует перевод владельца до конца транзакции,
+// чтобы изменение не пересеклось с выполнением планировщиком
+func (r *ScheduledTransferRepository) GetScheduledTransferForUpdate(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+		WHERE id = $1 AND from_user_name = $2 FOR UPDATE`
+	transfer, err := scanScheduledTransfer(r.db.QueryRow(ctx, query, id, owner))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetScheduledTransfers возвращает запланированные переводы пользователя, новые первыми
+func (r *ScheduledTransferRepository) GetScheduledTransfers(ctx context.Context, owner string) ([]entity.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+		WHERE from_user_name = $1
+		ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(ctx, query, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfers: %w", err)
+	}
+	return collectScheduledTransfers(rows)
+}
+
+// LockDueTransfers блокирует активные переводы, время которых наступило.
+// Строки, заблокированные другим экземпляром сервиса, пропускаются
+func (r *ScheduledTransferRepository) LockDueTransfers(ctx context.Context, now time.Time, limit int) ([]entity.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+		WHERE status = 'active' AND next_run_at <= $1
+		ORDER BY next_run_at, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock due scheduled transfers: %w", err)
+	}
+	return collectScheduledTransfers(rows)
+}
+
+// Update сохраняет изменяемые поля перевода
+func (r *ScheduledTransferRepository) Update(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	query := `UPDATE scheduled_transfers
+		SET amount = $2, memo = $3, schedule = $4, timezone = $5, status = $6, next_run_at = $7,
+			last_run_at = $8, last_error = $9, failure_count = $10, updated_at = now()
+		WHERE id = $1`
+	_, err := r.db.Exec(ctx, query,
+		transfer.ID,
+		transfer.Amount,
+		transfer.Memo,
+		transfer.Schedule,
+		transfer.Timezone,
+		transfer.Status,
+		transfer.NextRunAt,
+		transfer.LastRunAt,
+		transfer.LastError,
+		transfer.FailureCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled transfer: %w", err)
+	}
+	return nil
+}
+
+// CreateRun записывает результат срабатывания. Повторная запись того же срабатывания
+// нарушает уникальность и возвращает ошибку
+func (r *ScheduledTransferRepository) CreateRun(ctx context.Context, scheduledTransferID uuid.UUID, run *entity.ScheduledTransferRun) error {
+	query := `INSERT INTO scheduled_transfer_runs (scheduled_transfer_id, scheduled_for, transfer_id, error)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, scheduledTransferID, run.ScheduledFor, run.TransferID, run.Error).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record scheduled transfer run: %w", err)
+	}
+	return nil
+}
+
+// GetRuns возвращает историю срабатываний перевода, последние первыми
+func (r *ScheduledTransferRepository) GetRuns(ctx context.Context, scheduledTransferID uuid.UUID, limit int) ([]entity.ScheduledTransferRun, error) {
+	query := `SELECT id, scheduled_for, transfer_id, error, created_at
+		FROM scheduled_transfer_runs
+		WHERE scheduled_transfer_id = $1
+		ORDER BY scheduled_for DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, scheduledTransferID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []entity.ScheduledTransferRun
+	for rows.Next() {
+		var run entity.ScheduledTransferRun
+		if err := rows.Scan(&run.ID, &run.ScheduledFor, &run.TransferID, &run.Error, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
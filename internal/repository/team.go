@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTeamNameTaken         = errors.New("team name is already taken")
+	ErrInsufficientTeamCoins = errors.New("insufficient coins in team wallet")
+)
+
+// TeamRepository команды, их участники и кошельки. Баланс и партии кошелька меняются
+// в той же транзакции, что и проводки по счету команды; изменения одного кошелька
+// сериализуются блокировкой строки команды
+type TeamRepository struct {
+	db DB
+}
+
+func NewTeamRepository(db DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+func TeamRepoWithTx(tx pgx.Tx) *TeamRepository {
+	return NewTeamRepository(tx)
+}
+
+func (r *TeamRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+// CreateTeam создает команду с создателем в роли владельца и счет ее кошелька в главной книге
+func (r *TeamRepository) CreateTeam(ctx context.Context, team *entity.Team) error {
+	query := `INSERT INTO teams (name, created_by) VALUES ($1, $2) RETURNING id, created_at`
+	if err := r.db.QueryRow(ctx, query, team.Name, team.CreatedBy).Scan(&team.ID, &team.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s", ErrTeamNameTaken, team.Name)
+		}
+		slog.Error("Failed to create team", "name", team.Name, "error", err)
+		return fmt.Errorf("failed to create team: %w", err)
+	}
+
+	member := entity.TeamMember{UserName: team.CreatedBy, Role: entity.TeamRoleOwner}
+	query = `INSERT INTO team_members (team_id, user_name, role) VALUES ($1, $2, $3) RETURNING joined_at`
+	if err := r.db.QueryRow(ctx, query, team.ID, member.UserName, member.Role).Scan(&member.JoinedAt); err != nil {
+		return fmt.Errorf("failed to add team owner: %w", err)
+	}
+	team.Members = []entity.TeamMember{member}
+
+	return NewLedgerRepository(r.db).CreateTeamAccount(ctx, team.ID)
+}
+
+// GetTeam возвращает команду с участниками или nil, если ее нет
+func (r *TeamRepository) GetTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error) {
+	return r.getTeam(ctx, `SELECT id, name, coins, created_by, created_at FROM teams WHERE id = $1`, id)
+}
+
+// LockTeam блокирует строку команды до конца транзакции и возвращает команду с участниками
+// или nil, если ее нет. Пользователей, участвующих в операции, нужно блокировать раньше команды
+func (r *TeamRepository) LockTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error) {
+	return r.getTeam(ctx, `SELECT id, name, coins, created_by, created_at FROM teams WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *TeamRepository) getTeam(ctx context.Context, query string, id uuid.UUID) (*entity.Team, error) {
+	var team entity.Team
+	err := r.db.QueryRow(ctx, query, id).Scan(&team.ID, &team.Name, &team.Coins, &team.CreatedBy, &team.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+
+	members, err := r.getMembers(ctx, []uuid.UUID{team.ID})
+	if err != nil {
+		return nil, err
+	}
+	team.Members = members[team.ID]
+	return &team, nil
+}
+
+// GetUserTeams возвращает команды, в которых состоит пользователь
+func (r *TeamRepository) GetUserTeams(ctx context.Context, userName string) ([]entity.Team, error) {
+	query := `SELECT t.id, t.name, t.coins, t.created_by, t.created_at
+		FROM teams t
+		JOIN team_members m ON m.team_id = t.id
+		WHERE m.user_name = $1
+		ORDER BY t.name`
+	rows, err := r.db.Query(ctx, query, userName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := []entity.Team{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var team entity.Team
+		if err := rows.Scan(&team.ID, &team.Name, &team.Coins, &team.CreatedBy, &team.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		teams = append(teams, team)
+		ids = append(ids, team.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user teams: %w", err)
+	}
+	rows.Close()
+
+	members, err := r.getMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range teams {
+		teams[i].Members = members[teams[i].ID]
+	}
+	return teams, nil
+}
+
+// getMembers возвращает участников команд: сначала владельцы, затем остальные по имени
+func (r *TeamRepository) getMembers(ctx context.Context, teamIDs []uuid.UUID) (map[uuid.UUID][]entity.TeamMember, error) {
+	query := `SELECT team_id, user_name, role, joined_at FROM team_members
+		WHERE team_id = ANY($1)
+		ORDER BY role = 'owner' DESC, user_name`
+	rows, err := r.db.Query(ctx, query, teamIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID][]entity.TeamMember, len(teamIDs))
+	for rows.Next() {
+		var teamID uuid.UUID
+		var member entity.TeamMember
+		if err := rows.Scan(&teamID, &member.UserName, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+		members[teamID] = append(members[teamID], member)
+	}
+	return members, rows.Err()
+}
+
+// SetMember добавляет пользователя в команду или меняет его роль
+func (r *TeamRepository) SetMember(ctx context.Context, teamID uuid.UUID, userName, role string) error {
+	query := `INSERT INTO team_members (team_id, user_name, role) VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, user_name) DO UPDATE SET role = EXCLUDED.role`
+	if _, err := r.db.Exec(ctx, query, teamID, userName, role); err != nil {
+		return fmt.Errorf("failed to set team member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember исключает пользователя из команды
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID uuid.UUID, userName string) error {
+	query := `DELETE FROM team_members WHERE team_id = $1 AND user_name = $2`
+	if _, err := r.db.Exec(ctx, query, teamID, userName); err != nil {
+		return fmt.Errorf("failed to remove team member: %w", err)
+	}
+	return nil
+}
+
+// CreditCoins зачисляет в кошелек команды монеты партиями с исходной датой получения.
+// Проводку по главной книге записывает вызывающий
+func (r *TeamRepository) CreditCoins(ctx context.Context, teamID uuid.UUID, lots []entity.CoinLot) error {
+	dates := make([]time.Time, len(lots))
+	amounts := make([]int32, len(lots))
+	total := 0
+	for i, lot := range lots {
+		dates[i] = lot.GrantedOn
+		amounts[i] = int32(lot.Amount)
+		total += lot.Amount
+	}
+
+	query := `UPDATE teams SET coins = coins + $1 WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, total, teamID)
+	if err != nil {
+		return fmt.Errorf("failed to credit team coins: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("team not found: %s", teamID)
+	}
+
+	query = `INSERT INTO team_coin_lots (team_id, granted_on, remaining)
+		SELECT $1, granted_on, sum(amount)
+		FROM unnest($2::date[], $3::int[]) AS l(granted_on, amount)
+		GROUP BY granted_on
+		ON CONFLICT (team_id, granted_on) DO UPDATE SET remaining = team_coin_lots.remaining + EXCLUDED.remaining`
+	if _, err := r.db.Exec(ctx, query, teamID, dates, amounts); err != nil {
+		return fmt.Errorf("failed to add team coin lots: %w", err)
+	}
+	return nil
+}
+
+// DebitCoins списывает монеты из кошелька команды, начиная с самых старых партий,
+// и возвращает списанные партии. Проводку по главной книге записывает вызывающий
+func (r *TeamRepository) DebitCoins(ctx context.Context, teamID uuid.UUID, amount int) ([]entity.CoinLot, error) {
+	query := `UPDATE teams SET coins = coins - $1 WHERE id = $2 AND coins >= $1`
+	result, err := r.db.Exec(ctx, query, amount, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit team coins: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return nil, ErrInsufficientTeamCoins
+	}
+
+	query = `
+		WITH ordered AS (
+			SELECT granted_on, remaining,
+				sum(remaining) OVER (ORDER BY granted_on) - remaining AS before
+			FROM team_coin_lots
+			WHERE team_id = $1 AND remaining > 0
+		), taken AS (
+			SELECT granted_on, LEAST(remaining, $2 - before) AS amount FROM ordered WHERE before < $2
+		)
+		UPDATE team_coin_lots l
+		SET remaining = l.remaining - t.amount
+		FROM taken t
+		WHERE l.team_id = $1 AND l.granted_on = t.granted_on
+		RETURNING t.granted_on, t.amount`
+	rows, err := r.db.Query(ctx, query, teamID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume team coin lots: %w", err)
+	}
+	lots, err := collectTeamLots(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, lot := range lots {
+		total += lot.Amount
+	}
+	if total != amount {
+		return nil, fmt.Errorf("%w: team %s: consumed %d of %d", ErrLotsExhausted, teamID, total, amount)
+	}
+	return lots, nil
+}
+
+// LockTeamsWithExpiredLots блокирует до limit команд с идентификатором больше after, в кошельках которых
+// есть монеты из партий, полученных не позже cutoff. Заблокированные другими транзакциями пропускаются
+func (r *TeamRepository) LockTeamsWithExpiredLots(ctx context.Context, cutoff time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `SELECT t.id FROM teams t
+		WHERE t.id > $2 AND EXISTS (
+			SELECT 1 FROM team_coin_lots l
+			WHERE l.team_id = t.id AND l.remaining > 0 AND l.granted_on <= $1::date)
+		ORDER BY t.id
+		LIMIT $3
+		FOR UPDATE OF t SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, cutoff, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock teams with expired coins: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		teams = append(teams, id)
+	}
+	return teams, rows.Err()
+}
+
+// ExpireCoins списывает из кошелька команды все монеты партий, полученных не позже cutoff,
+// и возвращает списанную сумму. Проводку по главной книге записывает вызывающий
+func (r *TeamRepository) ExpireCoins(ctx context.Context, teamID uuid.UUID, cutoff time.Time) (int, error) {
+	query := `
+		WITH expired AS (
+			SELECT granted_on, remaining FROM team_coin_lots
+			WHERE team_id = $1 AND remaining > 0 AND granted_on <= $2::date
+		)
+		UPDATE team_coin_lots l
+		SET remaining = 0
+		FROM expired e
+		WHERE l.team_id = $1 AND l.granted_on = e.granted_on
+		RETURNING e.granted_on, e.remaining`
+	rows, err := r.db.Query(ctx, query, teamID, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire team coin lots: %w", err)
+	}
+	lots, err := collectTeamLots(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		expired += lot.Amount
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+
+	query = `UPDATE teams SET coins = coins - $1 WHERE id = $2`
+	if _, err := r.db.Exec(ctx, query, expired, teamID); err != nil {
+		return 0, fmt.Errorf("failed to expire team coins: %w", err)
+	}
+	return expired, nil
+}
+
+// CreateOperation записывает операцию с кошельком команды в историю
+func (r *TeamRepository) CreateOperation(ctx context.Context, op *entity.TeamOperation) error {
+	query := `INSERT INTO team_history (team_id, kind, member, user_name, item_name, amount, memo)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, op.TeamID, op.Kind, op.Member, op.UserName, op.ItemName, op.Amount, op.Memo).
+		Scan(&op.ID, &op.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create team operation", "teamID", op.TeamID, "kind", op.Kind, "error", err)
+		return fmt.Errorf("failed to create team operation: %w", err)
+	}
+	return nil
+}
+
+// GetHistory возвращает последние limit операций с кошельком команды, новые первыми
+func (r *TeamRepository) GetHistory(ctx context.Context, teamID uuid.UUID, limit int) ([]entity.TeamOperation, error) {
+	query := `SELECT id, team_id, kind, member, user_name, COALESCE(item_name, ''), amount, memo, created_at
+		FROM team_history
+		WHERE team_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, teamID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []entity.TeamOperation{}
+	for rows.Next() {
+		var op entity.TeamOperation
+		if err := rows.Scan(&op.ID, &op.TeamID, &op.Kind, &op.Member, &op.UserName, &op.ItemName, &op.Amount, &op.Memo, &op.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan team operation: %w", err)
+		}
+		history = append(history, op)
+	}
+	return history, rows.Err()
+}
+
+// collectTeamLots читает списанные партии кошелька в порядке получения
+func collectTeamLots(rows pgx.Rows) ([]entity.CoinLot, error) {
+	defer rows.Close()
+
+	var lots []entity.CoinLot
+	for rows.Next() {
+		var lot entity.CoinLot
+		if err := rows.Scan(&lot.GrantedOn, &lot.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan team coin lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to consume team coin lots: %w", err)
+	}
+
+	sort.Slice(lots, func(i, j int) bool { return lots[i].GrantedOn.Before(lots[j].GrantedOn) })
+	return lots, nil
+}
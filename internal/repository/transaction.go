@@ -3,8 +3,13 @@ package repository
 import (
 	"avito-merch/internal/entity"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -24,11 +29,25 @@ func (r *TransactionRepository) Begin(ctx context.Context) (pgx.Tx, error) {
 	return r.db.Begin(ctx)
 }
 
-// Создаем запись о переводе
+// Создаем запись о переводе. Перевод без статуса считается завершенным
 func (r *TransactionRepository) CreateTransfer(ctx context.Context, transfer *entity.Transaction) error {
-	query := `INSERT INTO transfer_history (from_user_name, to_user_name, amount)
-		VALUES ($1, $2, $3) RETURNING id`
-	err := r.db.QueryRow(ctx, query, transfer.FromUser, transfer.ToUser, transfer.Amount).Scan(&transfer.ID)
+	if transfer.Status == "" {
+		transfer.Status = entity.TransferStatusCompleted
+	}
+	query := `INSERT INTO transfer_history (from_user_name, to_user_name, amount, memo, batch_id, reversal_of, status, hold_id, expires_at, kudos)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, effective_at`
+	err := r.db.QueryRow(ctx, query,
+		transfer.FromUser,
+		transfer.ToUser,
+		transfer.Amount,
+		transfer.Memo,
+		transfer.BatchID,
+		transfer.ReversalOf,
+		transfer.Status,
+		transfer.HoldID,
+		transfer.ExpiresAt,
+		transfer.Kudos,
+	).Scan(&transfer.ID, &transfer.CreatedAt, &transfer.EffectiveAt)
 	if err != nil {
 		slog.Error("Failed to create transfer", "error", err)
 		return err
@@ -37,17 +56,285 @@ func (r *TransactionRepository) CreateTransfer(ctx context.Context, transfer *en
 	return nil
 }
 
-// Получаем историю переводов пользователя
-func (r *TransactionRepository) GetTransfersByUsername(ctx context.Context, userName string) ([]entity.Transaction, error) {
-	query := `SELECT 
-				id, from_user_name,
-				to_user_name,
-				amount
-			FROM transfer_history 
-			WHERE from_user_name = $1 OR to_user_name = $1`
-	rows, err := r.db.Query(ctx, query, userName)
+const transferColumns = `id, from_user_name, to_user_name, amount, memo, batch_id, reversal_of, created_at, effective_at,
+	status, hold_id, expires_at, resolved_at, kudos`
+
+func scanTransfer(row pgx.Row) (*entity.Transaction, error) {
+	var transfer entity.Transaction
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.FromUser,
+		&transfer.ToUser,
+		&transfer.Amount,
+		&transfer.Memo,
+		&transfer.BatchID,
+		&transfer.ReversalOf,
+		&transfer.CreatedAt,
+		&transfer.EffectiveAt,
+		&transfer.Status,
+		&transfer.HoldID,
+		&transfer.ExpiresAt,
+		&transfer.ResolvedAt,
+		&transfer.Kudos,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func collectTransfers(rows pgx.Rows) ([]entity.Transaction, error) {
+	defer rows.Close()
+
+	var transfers []entity.Transaction
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// GetTransfer возвращает перевод вместе с возвращенной сторнированием суммой или nil, если его нет
+func (r *TransactionRepository) GetTransfer(ctx context.Context, id uuid.UUID) (*entity.Transaction, error) {
+	query := `SELECT ` + transferColumns + `,
+			COALESCE((SELECT r.amount FROM transfer_history r WHERE r.reversal_of = t.id), 0)
+		FROM transfer_history t WHERE id = $1`
+	var transfer entity.Transaction
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&transfer.ID,
+		&transfer.FromUser,
+		&transfer.ToUser,
+		&transfer.Amount,
+		&transfer.Memo,
+		&transfer.BatchID,
+		&transfer.ReversalOf,
+		&transfer.CreatedAt,
+		&transfer.EffectiveAt,
+		&transfer.Status,
+		&transfer.HoldID,
+		&transfer.ExpiresAt,
+		&transfer.ResolvedAt,
+		&transfer.Kudos,
+		&transfer.ReversedAmount,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	return &transfer, nil
+}
+
+// GetTransferForUpdate блокирует перевод до конца транзакции
+func (r *TransactionRepository) GetTransferForUpdate(ctx context.Context, id uuid.UUID) (*entity.Transaction, error) {
+	query := `SELECT ` + transferColumns + ` FROM transfer_history WHERE id = $1 FOR UPDATE`
+	transfer, err := scanTransfer(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetPendingTransfers возвращает ожидающие решения переводы, в которых участвует пользователь, новые первыми
+func (r *TransactionRepository) GetPendingTransfers(ctx context.Context, username string) ([]entity.Transaction, error) {
+	query := `SELECT ` + transferColumns + ` FROM transfer_history
+		WHERE status = 'pending' AND (from_user_name = $1 OR to_user_name = $1)
+		ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transfers: %w", err)
+	}
+	return collectTransfers(rows)
+}
+
+// LockExpiredPendingTransfers блокирует ожидающие переводы с истекшим сроком.
+// Переводы, заблокированные другим экземпляром, пропускаются
+func (r *TransactionRepository) LockExpiredPendingTransfers(ctx context.Context, now time.Time, limit int) ([]entity.Transaction, error) {
+	query := `SELECT ` + transferColumns + ` FROM transfer_history
+		WHERE status = 'pending' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	rows, err := r.db.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock expired pending transfers: %w", err)
+	}
+	return collectTransfers(rows)
+}
+
+// ResolvePendingTransfer переводит ожидающий перевод в итоговый статус. Время создания не меняется,
+// а принятый перевод получает время принятия как время движения монет: по нему он попадает
+// в историю после уже выданных страниц, а не позади курсора
+func (r *TransactionRepository) ResolvePendingTransfer(ctx context.Context, transfer *entity.Transaction, status string) error {
+	query := `UPDATE transfer_history
+		SET status = $2::text, resolved_at = now(),
+			effective_at = CASE WHEN $2::text = 'completed' THEN now() ELSE effective_at END
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, effective_at, resolved_at`
+	err := r.db.QueryRow(ctx, query, transfer.ID, status).Scan(&transfer.Status, &transfer.EffectiveAt, &transfer.ResolvedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("transfer %s is not pending", transfer.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve pending transfer: %w", err)
+	}
+	return nil
+}
+
+// GetReversal возвращает сторнирование перевода или nil, если перевод не сторнирован
+func (r *TransactionRepository) GetReversal(ctx context.Context, originalID uuid.UUID) (*entity.Transaction, error) {
+	query := `SELECT ` + transferColumns + ` FROM transfer_history WHERE reversal_of = $1`
+	transfer, err := scanTransfer(r.db.QueryRow(ctx, query, originalID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reversal: %w", err)
+	}
+	return transfer, nil
+}
+
+// sentCoins переводы пользователя $1 с момента $3, учитываемые лимитами: переводы пользователям,
+// взносы в кошельки команд, переводы из кошельков команд, выполненные им как владельцем,
+// и оплата покупок на маркетплейсе. Получателем взноса считается счет команды, оплаты - продавец
+const sentCoins = `WITH sent AS (
+		SELECT to_user_name AS recipient, amount, created_at
+		FROM transfer_history
+		WHERE from_user_name = $1 AND created_at > $3 AND reversal_of IS NULL AND NOT kudos
+			AND status IN ('pending', 'completed')
+		UNION ALL
+		SELECT CASE WHEN kind = 'deposit' THEN 'team:' || team_id ELSE user_name END, amount, created_at
+		FROM team_history
+		WHERE member = $1 AND created_at > $3 AND kind IN ('deposit', 'withdrawal')
+		UNION ALL
+		SELECT seller, amount, created_at
+		FROM marketplace_sales
+		WHERE buyer = $1 AND created_at > $3
+	)`
+
+// GetSentTotals считает, сколько пользователь отправил с daySince и weekSince,
+// а также сколько с daySince получил каждый из recipients. Ожидающие переводы учитываются
+// сразу по времени создания, и принятие не учитывает их повторно, отклоненные и отмененные - нет. Сторнирования не учитываются:
+// их инициирует администратор, а не отправитель. Благодарности ограничены своим бюджетом и тоже не учитываются.
+// Операции с кошельками команд и оплата покупок на маркетплейсе учитываются как переводы
+func (r *TransactionRepository) GetSentTotals(ctx context.Context, fromUsername string, recipients []string, daySince, weekSince time.Time) (*entity.SentTotals, error) {
+	totals := &entity.SentTotals{DailyByRecipient: make(map[string]int, len(recipients))}
+
+	query := sentCoins + `
+		SELECT coalesce(sum(amount) FILTER (WHERE created_at > $2), 0), coalesce(sum(amount), 0) FROM sent`
+	if err := r.db.QueryRow(ctx, query, fromUsername, daySince, weekSince).Scan(&totals.Daily, &totals.Weekly); err != nil {
+		return nil, fmt.Errorf("failed to get sent totals: %w", err)
+	}
+
+	query = sentCoins + `
+		SELECT recipient, sum(amount) FROM sent
+		WHERE recipient = ANY($2)
+		GROUP BY recipient`
+	rows, err := r.db.Query(ctx, query, fromUsername, recipients, daySince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sent totals by recipient: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipient string
+		var amount int
+		if err := rows.Scan(&recipient, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan sent total: %w", err)
+		}
+		totals.DailyByRecipient[recipient] = amount
+	}
+	return totals, rows.Err()
+}
+
+// historySources выборки, из которых собираются история пользователя и выгрузка: переводы, покупки,
+// начисления, пособия, награды за достижения, пожертвования, сделки на маркетплейсе, сгорания монет
+// и операции с кошельками команд.
+// Каждая выборка отдает одинаковые столбцы kind, id, created_at, effective_at, from_user_name, to_user_name,
+// item, amount, memo, batch_id, reversal_of. effective_at - время движения монет, по нему упорядочена история.
+// Одна операция попадает в историю отправителя по from_user_name и получателя по to_user_name
+var historySources = []string{
+	// Принятый перевод с подтверждением попадает в историю в момент принятия
+	`SELECT CASE WHEN kudos THEN '` + entity.RecordKindKudos + `' ELSE '` + entity.RecordKindTransfer + `' END AS kind,
+		id, created_at, effective_at, from_user_name, to_user_name, '' AS item, amount, memo, batch_id, reversal_of
+	FROM transfer_history
+	WHERE status = 'completed'`,
+	`SELECT '` + entity.RecordKindPurchase + `', id, created_at, created_at, user_name, '', item_name, price, '', NULL::uuid, NULL::uuid
+	FROM purchase_history`,
+	// Начисление попадает в историю в момент выполнения, по строке на получателя
+	`SELECT '` + entity.RecordKindGrant + `', g.id, g.resolved_at, g.resolved_at, '` + entity.AccountMint + `', i.user_name, '',
+		i.amount, g.reason, NULL::uuid, NULL::uuid
+	FROM coin_grant_items i JOIN coin_grants g ON g.id = i.grant_id
+	WHERE g.status = 'completed'`,
+	`SELECT '` + entity.RecordKindAllowance + `', run_id, created_at, created_at, '` + entity.AccountMint + `', user_name, '',
+		amount, 'monthly allowance', NULL::uuid, NULL::uuid
+	FROM allowance_payments
+	WHERE amount > 0`,
+	`SELECT '` + entity.RecordKindAchievement + `', id, earned_at, earned_at, '` + entity.AccountMint + `', user_name, '',
+		bounty, name, NULL::uuid, NULL::uuid
+	FROM user_achievements
+	WHERE bounty > 0`,
+	`SELECT '` + entity.RecordKindDonation + `', d.id, d.created_at, d.created_at, d.user_name, 'campaign:' || d.campaign_id, '',
+		d.amount, c.name, NULL::uuid, NULL::uuid
+	FROM charity_donations d JOIN charity_campaigns c ON c.id = d.campaign_id`,
+	`SELECT '` + entity.RecordKindMarketplace + `', id, created_at, created_at, buyer, seller, item_name, amount, '', NULL::uuid, NULL::uuid
+	FROM marketplace_sales`,
+	`SELECT '` + entity.RecordKindExpiry + `', e.id, e.created_at, e.created_at, a.user_name, '` + entity.AccountExpired + `', '',
+		-p.amount, e.description, NULL::uuid, NULL::uuid
+	FROM ledger_entries e
+	JOIN ledger_postings p ON p.entry_id = e.id
+	JOIN ledger_accounts a ON a.id = p.account_id
+	WHERE e.kind = 'expiry' AND a.kind = 'user'`,
+	// Взнос идет от участника на счет команды, перевод и покупка - со счета команды получателю
+	`SELECT 'team_' || kind, id, created_at, created_at,
+		CASE WHEN kind = '` + entity.TeamOperationDeposit + `' THEN user_name ELSE 'team:' || team_id END,
+		CASE WHEN kind = '` + entity.TeamOperationDeposit + `' THEN 'team:' || team_id ELSE user_name END,
+		COALESCE(item_name, ''), amount, memo, NULL::uuid, NULL::uuid
+	FROM team_history`,
+}
+
+// historyColumns имена столбцов выборок historySources
+const historyColumns = `kind, id, created_at, effective_at, from_user_name, to_user_name, item, amount, memo, batch_id, reversal_of`
+
+// historySource оборачивает выборку, чтобы к ее столбцам можно было обращаться по общим именам
+func historySource(source string) string {
+	return `(` + source + `) AS s(` + historyColumns + `)`
+}
+
+// GetTransferHistory возвращает страницу истории пользователя по времени движения монет, новые первыми.
+// История собирается из тех же выборок, что и выгрузка. Отправленные и полученные операции каждой выборки
+// идут отдельными ветками UNION ALL, чтобы каждая шла по своему индексу без сортировки всей истории.
+// В историю попадают только завершенные переводы, ожидающие отдаются GetPendingTransfers
+func (r *TransactionRepository) GetTransferHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.Transaction, error) {
+	args := []interface{}{filter.UserName, filter.Limit}
+	branches := make([]string, 0, 2*len(historySources))
+	for _, source := range historySources {
+		if filter.Direction != entity.DirectionReceived {
+			branches = append(branches, historyBranch(source, entity.DirectionSent, "from_user_name", "to_user_name", filter, &args))
+		}
+		if filter.Direction != entity.DirectionSent {
+			branches = append(branches, historyBranch(source, entity.DirectionReceived, "to_user_name", "from_user_name", filter, &args))
+		}
+	}
+
+	query := `SELECT kind, id, from_user_name, to_user_name, item, amount, memo, batch_id, reversal_of,
+			CASE WHEN kind IN ('` + entity.RecordKindTransfer + `', '` + entity.RecordKindKudos + `')
+				THEN COALESCE((SELECT r.amount FROM transfer_history r WHERE r.reversal_of = h.id), 0)
+				ELSE 0 END AS reversed_amount,
+			created_at, effective_at, direction
+		FROM (` + strings.Join(branches, " UNION ALL ") + `) h
+		ORDER BY effective_at DESC, id DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		slog.Error("Failed to get transfers", "userName", userName, "error", err)
+		slog.Error("Failed to get transfers", "userName", filter.UserName, "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -55,13 +342,173 @@ func (r *TransactionRepository) GetTransfersByUsername(ctx context.Context, user
 	var transfers []entity.Transaction
 	for rows.Next() {
 		var transfer entity.Transaction
-		if err := rows.Scan(&transfer.ID, &transfer.FromUser, &transfer.ToUser, &transfer.Amount); err != nil {
-			slog.Error("Failed to scan transfer", "userName", userName, "error", err)
+		if err := rows.Scan(
+			&transfer.Kind,
+			&transfer.ID,
+			&transfer.FromUser,
+			&transfer.ToUser,
+			&transfer.Item,
+			&transfer.Amount,
+			&transfer.Memo,
+			&transfer.BatchID,
+			&transfer.ReversalOf,
+			&transfer.ReversedAmount,
+			&transfer.CreatedAt,
+			&transfer.EffectiveAt,
+			&transfer.Direction,
+		); err != nil {
+			slog.Error("Failed to scan transfer", "userName", filter.UserName, "error", err)
 			return nil, err
 		}
+		transfer.Kudos = transfer.Kind == entity.RecordKindKudos
+		transfer.Grant = transfer.Kind == entity.RecordKindGrant
 		transfers = append(transfers, transfer)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	slog.Info("Transfers retrieved", "userName", userName)
+	slog.Info("Transfers retrieved", "userName", filter.UserName, "count", len(transfers))
 	return transfers, nil
 }
+
+// historyBranch собирает выборку операций source одного направления.
+// $1 - имя пользователя, $2 - лимит, остальные параметры добавляются в args
+func historyBranch(source, direction, userColumn, counterpartyColumn string, filter entity.HistoryFilter, args *[]interface{}) string {
+	conditions := append([]string{userColumn + " = $1"}, historyConditions(counterpartyColumn, filter, args)...)
+	return `(SELECT ` + historyColumns + `, '` + direction + `' AS direction
+		FROM ` + historySource(source) + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY effective_at DESC, id DESC
+		LIMIT $2)`
+}
+
+// historyConditions переводит фильтр истории в условия выборки, параметры добавляются в args
+func historyConditions(counterpartyColumn string, filter entity.HistoryFilter, args *[]interface{}) []string {
+	arg := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	var conditions []string
+	if filter.Counterparty != "" {
+		conditions = append(conditions, counterpartyColumn+" = "+arg(filter.Counterparty))
+	}
+	if filter.MinAmount > 0 {
+		conditions = append(conditions, "amount >= "+arg(filter.MinAmount))
+	}
+	if filter.MaxAmount > 0 {
+		conditions = append(conditions, "amount <= "+arg(filter.MaxAmount))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "effective_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "effective_at < "+arg(filter.To))
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, "memo ILIKE '%' || "+arg(escapeLike(filter.Search))+" || '%'")
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "(effective_at, id) < ("+arg(filter.After.EffectiveAt)+", "+arg(filter.After.ID)+")")
+	}
+	return conditions
+}
+
+// StreamHistory построчно передает в fn операции из historySources по фильтру в порядке времени движения монет.
+// Строки читаются из курсора по мере обработки и не накапливаются в памяти.
+// Ошибка fn или отмена ctx прерывают выборку
+func (r *TransactionRepository) StreamHistory(ctx context.Context, filter entity.ExportFilter, fn func(record *entity.HistoryRecord) error) error {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var user string
+	if filter.UserName != "" {
+		user = arg(filter.UserName)
+	}
+	branch := func(source string, conditions ...string) string {
+		if !filter.From.IsZero() {
+			conditions = append(conditions, "effective_at >= "+arg(filter.From))
+		}
+		if !filter.To.IsZero() {
+			conditions = append(conditions, "effective_at < "+arg(filter.To))
+		}
+		query := `SELECT kind, id, effective_at AS created_at, from_user_name, to_user_name, item, amount, memo FROM ` + historySource(source)
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		return query
+	}
+
+	branches := make([]string, 0, 2*len(historySources))
+	for _, source := range historySources {
+		if user == "" {
+			branches = append(branches, branch(source))
+			continue
+		}
+		// Отправленные и полученные операции отдельными ветками, чтобы каждая шла по своему индексу
+		branches = append(branches, branch(source, "from_user_name = "+user), branch(source, "to_user_name = "+user))
+	}
+	query := strings.Join(branches, " UNION ALL ") + " ORDER BY created_at, id"
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var record entity.HistoryRecord
+	for rows.Next() {
+		if err := rows.Scan(
+			&record.Kind,
+			&record.ID,
+			&record.CreatedAt,
+			&record.FromUser,
+			&record.ToUser,
+			&record.Item,
+			&record.Amount,
+			&record.Memo,
+		); err != nil {
+			return fmt.Errorf("failed to scan history record: %w", err)
+		}
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CreatePurchase создает запись о покупке
+func (r *TransactionRepository) CreatePurchase(ctx context.Context, purchase *entity.Purchase) error {
+	query := `INSERT INTO purchase_history (user_name, item_name, price)
+		VALUES ($1, $2, $3) RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, query, purchase.UserName, purchase.ItemName, purchase.Price).Scan(&purchase.ID, &purchase.CreatedAt)
+	if err != nil {
+		slog.Error("Failed to create purchase", "error", err)
+		return err
+	}
+	slog.Info("Purchase created", "userName", purchase.UserName, "item", purchase.ItemName, "price", purchase.Price)
+	return nil
+}
+
+// GetPurchase возвращает покупку или nil, если ее нет
+func (r *TransactionRepository) GetPurchase(ctx context.Context, id uuid.UUID) (*entity.Purchase, error) {
+	query := `SELECT id, user_name, item_name, price, created_at FROM purchase_history WHERE id = $1`
+	var purchase entity.Purchase
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&purchase.ID,
+		&purchase.UserName,
+		&purchase.ItemName,
+		&purchase.Price,
+		&purchase.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase: %w", err)
+	}
+	return &purchase, nil
+}
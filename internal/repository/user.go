@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
+var ErrGivingBudgetExceeded = errors.New("giving budget exceeded")
+
 type UserRepository struct {
 	db DB
 }
@@ -29,12 +32,20 @@ func (r *UserRepository) Begin(ctx context.Context) (pgx.Tx, error) {
 
 func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
 	var user entity.User
-	query := `SELECT username, password_hash, coins FROM users WHERE username = $1`
+	query := `SELECT username, password_hash, coins, held_coins, role, created_at, giving_budget, giving_period,
+		deactivated_at
+		FROM users WHERE username = $1`
 
 	err := r.db.QueryRow(ctx, query, username).Scan(
 		&user.Name,
 		&user.Password,
 		&user.Coins,
+		&user.HeldCoins,
+		&user.Role,
+		&user.CreatedAt,
+		&user.GivingBudget,
+		&user.GivingPeriod,
+		&user.DeactivatedAt,
 	)
 
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -50,18 +61,130 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 	return &user, nil
 }
 
+// IsActive возвращает true, если пользователь существует и не деактивирован
+func (r *UserRepository) IsActive(ctx context.Context, username string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 AND deactivated_at IS NULL)`
+	var active bool
+	if err := r.db.QueryRow(ctx, query, username).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return active, nil
+}
+
+// Deactivate деактивирует пользователя. Возвращает false, если пользователь не найден
+// или уже деактивирован
+func (r *UserRepository) Deactivate(ctx context.Context, username string) (bool, error) {
+	query := `UPDATE users SET deactivated_at = now() WHERE username = $1 AND deactivated_at IS NULL`
+	result, err := r.db.Exec(ctx, query, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// Create создает пользователя вместе со счетом в главной книге.
+// Стартовый баланс проводится как начисление со счета эмиссии
 func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
-	query := `INSERT INTO users (username, password_hash, coins) VALUES ($1, $2, $3) RETURNING username`
-	err := r.db.QueryRow(ctx, query, user.Name, user.Password, user.Coins).Scan(&user.Name)
+	// Внутри внешней транзакции Begin создает точку сохранения
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	query := `INSERT INTO users (username, password_hash, coins) VALUES ($1, $2, $3) RETURNING username, role`
+	err = tx.QueryRow(ctx, query, user.Name, user.Password, user.Coins).Scan(&user.Name, &user.Role)
 	if err != nil {
 		slog.Error("Failed to create user", "username", user.Name, "error", err)
 		return err
 	}
 
+	ledgerRepo := LedgerRepoWithTx(tx)
+	if err := ledgerRepo.CreateUserAccount(ctx, user.Name); err != nil {
+		return err
+	}
+	if user.Coins > 0 {
+		if err := ledgerRepo.RecordGrant(ctx, user.Name, user.Coins, nil, "signup bonus"); err != nil {
+			return err
+		}
+		if err := CoinLotRepoWithTx(tx).AddLots(ctx, []entity.CoinLot{newLot(user.Name, user.Coins)}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	slog.Info("User successfully created in db", "username", user.Name)
 	return nil
 }
 
+// LockUsers блокирует строки пользователей до конца транзакции и возвращает имена найденных.
+// Строки блокируются в порядке имен, поэтому переводы с пересекающимися участниками
+// ждут друг друга, а не взаимоблокируются. Деактивированные пользователи считаются отсутствующими
+func (r *UserRepository) LockUsers(ctx context.Context, usernames ...string) ([]string, error) {
+	query := `SELECT username FROM users WHERE username = ANY($1) AND deactivated_at IS NULL ORDER BY username FOR UPDATE`
+	rows, err := r.db.Query(ctx, query, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	defer rows.Close()
+
+	var locked []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		locked = append(locked, username)
+	}
+	return locked, rows.Err()
+}
+
+// UpdateUsersAfterBatchTransfer списывает сумму пакета с отправителя и зачисляет монеты получателям
+// одним запросом. Получатели в пакете не повторяются
+func (r *UserRepository) UpdateUsersAfterBatchTransfer(ctx context.Context, fromUsername string, recipients []string, amounts []int) error {
+	query := `
+		WITH credits AS (
+			SELECT * FROM unnest($2::text[], $3::int[]) AS c(username, amount)
+		), deltas AS (
+			SELECT username, amount AS delta FROM credits
+			UNION ALL
+			SELECT $1, -sum(amount) FROM credits
+		)
+		UPDATE users u
+		SET coins = u.coins + d.delta
+		FROM deltas d
+		WHERE u.username = d.username AND u.deactivated_at IS NULL`
+	result, err := r.db.Exec(ctx, query, fromUsername, recipients, amounts)
+	if err != nil {
+		if isCheckViolation(err) {
+			return fmt.Errorf("insufficient coins")
+		}
+		return fmt.Errorf("failed to update balances: %w", err)
+	}
+
+	if result.RowsAffected() != int64(len(recipients)+1) {
+		return fmt.Errorf("recipient does not exist")
+	}
+
+	total := 0
+	for _, amount := range amounts {
+		total += amount
+	}
+	if err := r.moveLots(ctx, fromUsername, total, recipients, amounts); err != nil {
+		return err
+	}
+
+	slog.Info("User coins successfully updated after batch transfer", "FromUser", fromUsername, "recipients", len(recipients))
+	return nil
+}
+
 // UpdateUserAfterTransfer обновляет балансы пользователей после перевода перевода
 func (r *UserRepository) UpdateUserAfterTransfer(ctx context.Context, fromUsername, toUsername string, amount int) error {
 	query := `
@@ -71,10 +194,14 @@ func (r *UserRepository) UpdateUserAfterTransfer(ctx context.Context, fromUserna
 			WHEN username = $2 THEN coins + $3 
 			ELSE coins 
 		END
-		WHERE username IN ($1, $2);`
+		WHERE username IN ($1, $2) AND deactivated_at IS NULL;`
 	result, err := r.db.Exec(ctx, query, fromUsername, toUsername, amount)
 	if err != nil {
-		return fmt.Errorf("insufficient coins")
+		// Ограничения coins >= 0 и held_coins <= coins не дают потратить замороженные монеты
+		if isCheckViolation(err) {
+			return fmt.Errorf("insufficient coins")
+		}
+		return fmt.Errorf("failed to update balances: %w", err)
 	}
 
 	// Проверяем, что обновление действительно произошло
@@ -83,32 +210,174 @@ func (r *UserRepository) UpdateUserAfterTransfer(ctx context.Context, fromUserna
 		return fmt.Errorf("recipient does not exist")
 	}
 
+	if err := r.moveLots(ctx, fromUsername, amount, []string{toUsername}, []int{amount}); err != nil {
+		return err
+	}
+
 	slog.Info("User coins successfully updated", "FromUser", fromUsername, "ToUser", toUsername)
 	return nil
 }
 
 // UpdateUserAfterPurchase обновляет баланс пользователя с проверкой на достаточность средств
 func (r *UserRepository) UpdateUserAfterPurchase(ctx context.Context, username string, amount int) error {
+	_, err := r.DebitCoins(ctx, username, amount)
+	return err
+}
+
+// DebitCoins списывает доступные (не замороженные) монеты пользователя и возвращает списанные партии.
+// Проводку по главной книге записывает вызывающий
+func (r *UserRepository) DebitCoins(ctx context.Context, username string, amount int) ([]entity.CoinLot, error) {
 	query := `
         UPDATE users 
         SET coins = coins - $1 
-        WHERE username = $2 AND coins >= $1;
+        WHERE username = $2 AND coins - held_coins >= $1;
     `
 	result, err := r.db.Exec(ctx, query, amount, username)
 	if err != nil {
-		return fmt.Errorf("failed to update user balance: %w", err)
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return nil, fmt.Errorf("insufficient coins or user not found: %s", username)
+	}
+
+	return NewCoinLotRepository(r.db).ConsumeLots(ctx, username, amount)
+}
+
+// HoldCoins замораживает монеты пользователя, если хватает доступного баланса
+func (r *UserRepository) HoldCoins(ctx context.Context, username string, amount int) error {
+	query := `
+		UPDATE users
+		SET held_coins = held_coins + $1
+		WHERE username = $2 AND coins - held_coins >= $1`
+	result, err := r.db.Exec(ctx, query, amount, username)
+	if err != nil {
+		return fmt.Errorf("failed to hold coins: %w", err)
 	}
 
 	if result.RowsAffected() != 1 {
 		return fmt.Errorf("insufficient coins or user not found: %s", username)
 	}
+	return nil
+}
+
+// ReleaseHeldCoins размораживает монеты, не списывая их
+func (r *UserRepository) ReleaseHeldCoins(ctx context.Context, username string, amount int) error {
+	query := `UPDATE users SET held_coins = held_coins - $1 WHERE username = $2 AND held_coins >= $1`
+	result, err := r.db.Exec(ctx, query, amount, username)
+	if err != nil {
+		return fmt.Errorf("failed to release held coins: %w", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("not enough held coins or user not found: %s", username)
+	}
+	return nil
+}
+
+// CaptureHeldCoins списывает ранее замороженные монеты
+func (r *UserRepository) CaptureHeldCoins(ctx context.Context, username string, amount int) error {
+	query := `
+		UPDATE users
+		SET coins = coins - $1, held_coins = held_coins - $1
+		WHERE username = $2 AND held_coins >= $1`
+	result, err := r.db.Exec(ctx, query, amount, username)
+	if err != nil {
+		return fmt.Errorf("failed to capture held coins: %w", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("not enough held coins or user not found: %s", username)
+	}
+	if _, err := NewCoinLotRepository(r.db).ConsumeLots(ctx, username, amount); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreditCoins зачисляет монеты пользователю. Проводку по главной книге записывает вызывающий
+func (r *UserRepository) CreditCoins(ctx context.Context, username string, amount int) error {
+	return r.CreditLots(ctx, username, []entity.CoinLot{newLot(username, amount)})
+}
+
+// CreditLots зачисляет пользователю монеты партиями с исходной датой получения.
+// Деактивированному пользователю монеты не зачисляются. Проводку по главной книге записывает вызывающий
+func (r *UserRepository) CreditLots(ctx context.Context, username string, lots []entity.CoinLot) error {
+	amount := 0
+	for i := range lots {
+		lots[i].UserName = username
+		amount += lots[i].Amount
+	}
+
+	query := `UPDATE users SET coins = coins + $1 WHERE username = $2 AND deactivated_at IS NULL`
+	result, err := r.db.Exec(ctx, query, amount, username)
+	if err != nil {
+		return fmt.Errorf("failed to credit coins: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return fmt.Errorf("user not found: %s", username)
+	}
+	return NewCoinLotRepository(r.db).AddLots(ctx, lots)
+}
 
+// SpendGivingBudget списывает amount из бюджета благодарностей за период period.
+// Остаток прошлого периода не переносится: при первой трате в новом периоде бюджет равен budget
+func (r *UserRepository) SpendGivingBudget(ctx context.Context, username string, period time.Time, budget, amount int) error {
+	query := `UPDATE users
+		SET giving_budget = CASE WHEN giving_period = $2 THEN giving_budget ELSE $3 END - $4,
+			giving_period = $2
+		WHERE username = $1 AND CASE WHEN giving_period = $2 THEN giving_budget ELSE $3 END >= $4`
+	result, err := r.db.Exec(ctx, query, username, period, budget, amount)
+	if err != nil {
+		return fmt.Errorf("failed to spend giving budget: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return ErrGivingBudgetExceeded
+	}
 	return nil
 }
 
+// ExpireCoins списывает монеты из партий, полученных не позже cutoff, и возвращает списанную сумму.
+// Замороженные монеты не сгорают, пока заморозка не снята. Строка пользователя должна быть заблокирована.
+// Проводку по главной книге записывает вызывающий
+func (r *UserRepository) ExpireCoins(ctx context.Context, username string, cutoff time.Time) (int, error) {
+	var available int
+	query := `SELECT coins - held_coins FROM users WHERE username = $1`
+	if err := r.db.QueryRow(ctx, query, username).Scan(&available); err != nil {
+		return 0, fmt.Errorf("failed to get user balance: %w", err)
+	}
+
+	expired, err := NewCoinLotRepository(r.db).ExpireLots(ctx, username, cutoff, available)
+	if err != nil || expired == 0 {
+		return 0, err
+	}
+
+	query = `UPDATE users SET coins = coins - $1 WHERE username = $2`
+	if _, err := r.db.Exec(ctx, query, expired, username); err != nil {
+		return 0, fmt.Errorf("failed to expire coins: %w", err)
+	}
+	return expired, nil
+}
+
+// moveLots списывает партии отправителя и переносит их получателям с исходной датой получения,
+// поэтому перевод не продлевает срок жизни монет
+func (r *UserRepository) moveLots(ctx context.Context, fromUsername string, total int, recipients []string, amounts []int) error {
+	lotRepo := NewCoinLotRepository(r.db)
+	consumed, err := lotRepo.ConsumeLots(ctx, fromUsername, total)
+	if err != nil {
+		return err
+	}
+	return lotRepo.AddLots(ctx, transferLots(consumed, recipients, amounts))
+}
+
+// newLot возвращает партию монет, полученных сейчас
+func newLot(username string, amount int) entity.CoinLot {
+	return entity.CoinLot{UserName: username, GrantedOn: entity.LotDate(time.Now()), Amount: amount}
+}
+
 // GetUserInventory возвращает инвентарь пользователя
 func (r *UserRepository) GetUserInventory(ctx context.Context, username string) ([]entity.InventoryItem, error) {
-	query := `SELECT item_name, quantity FROM inventory WHERE user_name = $1`
+	query := `SELECT item_name, quantity FROM inventory WHERE user_name = $1 AND quantity > 0`
 	rows, err := r.db.Query(ctx, query, username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user inventory: %w", err)
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	achievementEvents  = []string{entity.EventPurchase, entity.EventTransferSent, entity.EventTransferReceived}
+	achievementMetrics = []string{
+		entity.MetricPurchases,
+		entity.MetricTransfersSent,
+		entity.MetricTransfersReceived,
+		entity.MetricCoinsSent,
+		entity.MetricCoinsReceived,
+		entity.MetricAccountAgeDays,
+	}
+)
+
+// DefaultAchievements достижения, если набор не задан в настройках
+var DefaultAchievements = []entity.Achievement{
+	{ID: "first_purchase", Name: "First purchase", Event: entity.EventPurchase, Metric: entity.MetricPurchases, Threshold: 1},
+	{ID: "ten_transfers", Name: "Generous", Description: "Sent 10 transfers", Event: entity.EventTransferSent, Metric: entity.MetricTransfersSent, Threshold: 10, Bounty: 50},
+	{ID: "anniversary", Name: "One year with us", Metric: entity.MetricAccountAgeDays, Threshold: 365, Bounty: 100},
+}
+
+// EventHandler получает события сценариев после фиксации их транзакций
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event entity.Event)
+}
+
+// publishEvents передает события обработчику, если он задан. Обработка не зависит от отмены
+// запроса: транзакция события уже зафиксирована
+func publishEvents(ctx context.Context, handler EventHandler, events ...entity.Event) {
+	if handler == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, event := range events {
+		handler.HandleEvent(ctx, event)
+	}
+}
+
+// AchievementUseCase движок правил достижений. После каждого события пересчитываются показатели
+// пользователя, нужные еще не полученным достижениям, и выдаются достижения, чей порог достигнут.
+// Условия накопительные, поэтому достижение, не выданное из-за ошибки, будет выдано при следующем событии
+type AchievementUseCase struct {
+	achievementRepo AchievementRuleRepository
+	achievements    []entity.Achievement
+}
+
+// AchievementRuleRepository показатели пользователя и выдача достижений
+type AchievementRuleRepository interface {
+	AchievementRepository
+	Begin(ctx context.Context) (pgx.Tx, error)
+	GetMetrics(ctx context.Context, userName string, metrics []string) (map[string]int, error)
+	GetSignupDates(ctx context.Context, userName string) (time.Time, *time.Time, error)
+}
+
+// NewAchievementUseCase создает движок с набором правил. Некорректные правила пропускаются
+func NewAchievementUseCase(achievementRepo AchievementRuleRepository, achievements []entity.Achievement) *AchievementUseCase {
+	return &AchievementUseCase{achievementRepo: achievementRepo, achievements: validAchievements(achievements)}
+}
+
+// HandleEvent проверяет достижения пользователя после события. Ошибки только логируются:
+// событие уже произошло, и отказ в выдаче достижения не должен его отменять
+func (uc *AchievementUseCase) HandleEvent(ctx context.Context, event entity.Event) {
+	if err := uc.evaluate(ctx, event); err != nil {
+		slog.Error("Failed to evaluate achievements", "userName", event.UserName, "event", event.Kind, "error", err)
+	}
+}
+
+func (uc *AchievementUseCase) evaluate(ctx context.Context, event entity.Event) error {
+	candidates := candidateAchievements(uc.achievements, event.Kind)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	earned, err := uc.achievementRepo.GetEarned(ctx, event.UserName)
+	if err != nil {
+		return err
+	}
+	candidates = slices.DeleteFunc(candidates, func(a entity.Achievement) bool {
+		return slices.ContainsFunc(earned, func(e entity.EarnedAchievement) bool { return e.AchievementID == a.ID })
+	})
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var metrics []string
+	accountAge := false
+	for _, a := range candidates {
+		if a.Metric == entity.MetricAccountAgeDays {
+			accountAge = true
+		} else if !slices.Contains(metrics, a.Metric) {
+			metrics = append(metrics, a.Metric)
+		}
+	}
+	values, err := uc.achievementRepo.GetMetrics(ctx, event.UserName, metrics)
+	if err != nil {
+		return err
+	}
+	if accountAge {
+		registeredAt, firstSeenAt, err := uc.achievementRepo.GetSignupDates(ctx, event.UserName)
+		if err != nil {
+			return err
+		}
+		values[entity.MetricAccountAgeDays] = accountAgeDays(registeredAt, firstSeenAt, time.Now())
+	}
+
+	for _, a := range candidates {
+		if values[a.Metric] < a.Threshold {
+			continue
+		}
+		if err := uc.award(ctx, event.UserName, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// award выдает достижение и награду в одной транзакции. Повторная выдача, в том числе
+// параллельная, ничего не делает
+func (uc *AchievementUseCase) award(ctx context.Context, userName string, achievement entity.Achievement) error {
+	tx, err := uc.achievementRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	earned, err := repository.AchievementRepoWithTx(tx).Award(ctx, userName, achievement)
+	if err != nil || earned == nil {
+		return err
+	}
+	if achievement.Bounty > 0 {
+		if err := mintCoins(ctx, NewTxRepositories(tx), entity.EntryKindAchievement, userName, achievement.Bounty, &earned.ID, achievement.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Achievement earned", "userName", userName, "achievement", achievement.ID, "bounty", achievement.Bounty)
+	return nil
+}
+
+// accountAgeDays возвращает возраст учетной записи в полных днях. Пользователи, зарегистрированные
+// до появления даты регистрации, получили дату 'epoch', и их возраст отсчитывается от первого
+// известного действия; без действий возраст неизвестен и считается нулевым
+func accountAgeDays(registeredAt time.Time, firstSeenAt *time.Time, now time.Time) int {
+	signup := registeredAt
+	if !registeredAt.After(time.Unix(0, 0)) {
+		if firstSeenAt == nil {
+			return 0
+		}
+		signup = *firstSeenAt
+	}
+	if !now.After(signup) {
+		return 0
+	}
+	return int(now.Sub(signup) / (24 * time.Hour))
+}
+
+// candidateAchievements возвращает правила, которые проверяются при событии вида kind
+func candidateAchievements(achievements []entity.Achievement, kind string) []entity.Achievement {
+	var candidates []entity.Achievement
+	for _, a := range achievements {
+		if a.Event == "" || a.Event == kind {
+			candidates = append(candidates, a)
+		}
+	}
+	return candidates
+}
+
+// validAchievements отбрасывает правила с пустым или повторяющимся идентификатором,
+// неизвестным событием или показателем и неположительным порогом
+func validAchievements(achievements []entity.Achievement) []entity.Achievement {
+	var valid []entity.Achievement
+	for _, a := range achievements {
+		var problem string
+		switch {
+		case a.ID == "" || len(a.ID) > 64:
+			problem = "id must be 1 to 64 characters"
+		case slices.ContainsFunc(valid, func(v entity.Achievement) bool { return v.ID == a.ID }):
+			problem = "duplicate id"
+		case a.Event != "" && !slices.Contains(achievementEvents, a.Event):
+			problem = "unknown event"
+		case !slices.Contains(achievementMetrics, a.Metric):
+			problem = "unknown metric"
+		case a.Threshold <= 0:
+			problem = "threshold must be positive"
+		case a.Bounty < 0:
+			problem = "bounty must not be negative"
+		}
+		if problem != "" {
+			slog.Error("Invalid achievement skipped", "id", a.ID, "problem", problem)
+			continue
+		}
+		if a.Name == "" {
+			a.Name = a.ID
+		}
+		valid = append(valid, a)
+	}
+	return valid
+}
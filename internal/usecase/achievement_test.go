@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockAchievementRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockAchievementRepository) GetMetrics(ctx context.Context, userName string, metrics []string) (map[string]int, error) {
+	args := m.Called(ctx, userName, metrics)
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockAchievementRepository) GetSignupDates(ctx context.Context, userName string) (time.Time, *time.Time, error) {
+	args := m.Called(ctx, userName)
+	return args.Get(0).(time.Time), args.Get(1).(*time.Time), args.Error(2)
+}
+
+func TestValidAchievements(t *testing.T) {
+	valid := entity.Achievement{ID: "first_purchase", Name: "First purchase", Event: entity.EventPurchase, Metric: entity.MetricPurchases, Threshold: 1}
+
+	for name, tc := range map[string]struct {
+		achievement entity.Achievement
+		valid       bool
+	}{
+		"valid":            {entity.Achievement{ID: "buyer", Event: entity.EventPurchase, Metric: entity.MetricPurchases, Threshold: 5}, true},
+		"any event":        {entity.Achievement{ID: "veteran", Metric: entity.MetricAccountAgeDays, Threshold: 365}, true},
+		"empty id":         {entity.Achievement{Metric: entity.MetricPurchases, Threshold: 1}, false},
+		"unknown event":    {entity.Achievement{ID: "x", Event: "login", Metric: entity.MetricPurchases, Threshold: 1}, false},
+		"unknown metric":   {entity.Achievement{ID: "x", Metric: "logins", Threshold: 1}, false},
+		"zero threshold":   {entity.Achievement{ID: "x", Metric: entity.MetricPurchases}, false},
+		"negative bounty":  {entity.Achievement{ID: "x", Metric: entity.MetricPurchases, Threshold: 1, Bounty: -5}, false},
+		"duplicate id":     {entity.Achievement{ID: "first_purchase", Metric: entity.MetricCoinsSent, Threshold: 100}, false},
+		"positive bounty":  {entity.Achievement{ID: "x", Metric: entity.MetricCoinsSent, Threshold: 100, Bounty: 10}, true},
+		"name defaults id": {entity.Achievement{ID: "x", Metric: entity.MetricTransfersReceived, Threshold: 1}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := validAchievements([]entity.Achievement{valid, tc.achievement})
+			if !tc.valid {
+				assert.Equal(t, []entity.Achievement{valid}, result)
+				return
+			}
+			if assert.Len(t, result, 2) {
+				assert.Equal(t, tc.achievement.ID, result[1].ID)
+				assert.NotEmpty(t, result[1].Name)
+			}
+		})
+	}
+
+	assert.Len(t, validAchievements(DefaultAchievements), len(DefaultAchievements))
+}
+
+func TestCandidateAchievements(t *testing.T) {
+	achievements := []entity.Achievement{
+		{ID: "buyer", Event: entity.EventPurchase},
+		{ID: "sender", Event: entity.EventTransferSent},
+		{ID: "veteran"},
+	}
+
+	ids := func(achievements []entity.Achievement) []string {
+		var ids []string
+		for _, a := range achievements {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"buyer", "veteran"}, ids(candidateAchievements(achievements, entity.EventPurchase)))
+	assert.Equal(t, []string{"sender", "veteran"}, ids(candidateAchievements(achievements, entity.EventTransferSent)))
+	assert.Equal(t, []string{"veteran"}, ids(candidateAchievements(achievements, entity.EventTransferReceived)))
+}
+
+func TestPublishEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Без обработчика события просто отбрасываются
+	publishEvents(ctx, nil, entity.Event{Kind: entity.EventPurchase, UserName: "alice"})
+
+	handler := &recordingEventHandler{}
+	publishEvents(ctx, handler,
+		entity.Event{Kind: entity.EventTransferSent, UserName: "alice"},
+		entity.Event{Kind: entity.EventTransferReceived, UserName: "bob"},
+	)
+	assert.Equal(t, []entity.Event{
+		{Kind: entity.EventTransferSent, UserName: "alice"},
+		{Kind: entity.EventTransferReceived, UserName: "bob"},
+	}, handler.events)
+}
+
+func TestAccountAgeDays(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	epoch := time.Unix(0, 0).UTC()
+	monthAgo := now.AddDate(0, -1, 0)
+
+	assert.Equal(t, 400, accountAgeDays(now.AddDate(0, 0, -400), nil, now))
+	// Первое действие не меняет известную дату регистрации
+	assert.Equal(t, 400, accountAgeDays(now.AddDate(0, 0, -400), &monthAgo, now))
+	// Дата регистрации неизвестна: возраст считается от первого действия
+	assert.Equal(t, 31, accountAgeDays(epoch, &monthAgo, now))
+	assert.Equal(t, 0, accountAgeDays(epoch, nil, now))
+	assert.Equal(t, 0, accountAgeDays(now.Add(time.Hour), nil, now))
+}
+
+func TestAchievementUseCase_HandleEvent_BackfilledUserIsNotAnniversary(t *testing.T) {
+	repo := new(MockAchievementRepository)
+	uc := NewAchievementUseCase(repo, DefaultAchievements)
+	monthAgo := time.Now().AddDate(0, -1, 0)
+
+	repo.On("GetEarned", mock.Anything, "alice").Return([]entity.EarnedAchievement{
+		{AchievementID: "first_purchase"}, {AchievementID: "ten_transfers"},
+	}, nil)
+	repo.On("GetMetrics", mock.Anything, "alice", []string(nil)).Return(map[string]int{}, nil)
+	repo.On("GetSignupDates", mock.Anything, "alice").Return(time.Unix(0, 0).UTC(), &monthAgo, nil)
+
+	require.NoError(t, uc.evaluate(context.Background(), entity.Event{Kind: entity.EventTransferReceived, UserName: "alice"}))
+
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "Begin", mock.Anything)
+}
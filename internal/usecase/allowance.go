@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// allowanceBatchSize сколько пользователей получают пособие за одну транзакцию
+	allowanceBatchSize = 100
+	// allowanceRunsLimit сколько последних запусков возвращается в отчете
+	allowanceRunsLimit = 50
+)
+
+var ErrAllowanceDisabled = errors.New("allowance is disabled")
+
+// AllowanceConfig настройки ежемесячного пособия. Amount 0 - пособие не начисляется
+type AllowanceConfig struct {
+	Amount int
+	// Roles роли пользователей, получающих пособие
+	Roles []string
+}
+
+// AllowanceRepository выплаты пособия и отчеты о запусках
+type AllowanceRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	LockUnpaidUsers(ctx context.Context, period, periodEnd time.Time, roles []string, limit int) ([]entity.User, error)
+	CreatePayment(ctx context.Context, payment *entity.AllowancePayment) (bool, error)
+	GetRuns(ctx context.Context, limit int) ([]entity.AllowanceRun, error)
+}
+
+type AllowanceUseCase struct {
+	allowanceRepo AllowanceRepository
+	cfg           AllowanceConfig
+	txRepos       func(tx pgx.Tx) *TxRepositories
+}
+
+func NewAllowanceUseCase(allowanceRepo AllowanceRepository, cfg AllowanceConfig) *AllowanceUseCase {
+	return &AllowanceUseCase{allowanceRepo: allowanceRepo, cfg: cfg, txRepos: NewTxRepositories}
+}
+
+// PayAllowance начисляет пособие за текущий месяц всем, кто его еще не получил, и возвращает отчет о запуске.
+// Каждая пачка пользователей обрабатывается в своей транзакции, поэтому прерванный запуск
+// продолжится со следующего
+func (uc *AllowanceUseCase) PayAllowance(ctx context.Context) (*entity.AllowanceRun, error) {
+	if uc.cfg.Amount <= 0 {
+		return nil, ErrAllowanceDisabled
+	}
+
+	now := time.Now()
+	run := &entity.AllowanceRun{RunID: uuid.New(), Period: issuancePeriod(now), StartedAt: now}
+	for {
+		processed, err := uc.payBatch(ctx, run)
+		if err != nil {
+			return nil, err
+		}
+		if processed < allowanceBatchSize {
+			break
+		}
+	}
+	run.FinishedAt = time.Now()
+
+	if run.UsersPaid > 0 {
+		slog.Info("Allowance paid", "runID", run.RunID, "period", run.Period.Format(time.DateOnly),
+			"users", run.UsersPaid, "prorated", run.UsersProrated, "total", run.TotalAmount)
+	}
+	return run, nil
+}
+
+// RunAllowance фоновая задача начисления пособия
+func (uc *AllowanceUseCase) RunAllowance(ctx context.Context) error {
+	_, err := uc.PayAllowance(ctx)
+	return err
+}
+
+// GetRuns возвращает отчеты о последних запусках начисления пособия
+func (uc *AllowanceUseCase) GetRuns(ctx context.Context) ([]entity.AllowanceRun, error) {
+	return uc.allowanceRepo.GetRuns(ctx, allowanceRunsLimit)
+}
+
+// payBatch начисляет пособие очередной пачке пользователей и возвращает ее размер
+func (uc *AllowanceUseCase) payBatch(ctx context.Context, run *entity.AllowanceRun) (int, error) {
+	tx, err := uc.allowanceRepo.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	periodEnd := run.Period.AddDate(0, 1, 0)
+	users, err := repos.Allowance.LockUnpaidUsers(ctx, run.Period, periodEnd, uc.cfg.Roles, allowanceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	description := "monthly allowance " + run.Period.Format("2006-01")
+	paid, prorated, total := 0, 0, 0
+	for _, user := range users {
+		payment := &entity.AllowancePayment{Period: run.Period, UserName: user.Name, RunID: run.RunID}
+		payment.Amount, payment.Prorated = proratedAllowance(uc.cfg.Amount, user.CreatedAt, run.Period)
+
+		// Выплата с нулевой суммой тоже сохраняется, чтобы не выбирать пользователя повторно
+		created, err := repos.Allowance.CreatePayment(ctx, payment)
+		if err != nil {
+			return 0, err
+		}
+		if !created || payment.Amount == 0 {
+			continue
+		}
+		if err := mintCoins(ctx, repos, entity.EntryKindAllowance, user.Name, payment.Amount, &run.RunID, description); err != nil {
+			return 0, err
+		}
+
+		paid++
+		total += payment.Amount
+		if payment.Prorated {
+			prorated++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	run.UsersPaid += paid
+	run.UsersProrated += prorated
+	run.TotalAmount += total
+	return len(users), nil
+}
+
+// proratedAllowance возвращает пособие за период с началом period. Пользователь, зарегистрированный
+// внутри периода, получает долю пособия за дни с даты регистрации до конца месяца включительно
+func proratedAllowance(amount int, joinedAt, period time.Time) (int, bool) {
+	if !joinedAt.After(period) {
+		return amount, false
+	}
+	days := period.AddDate(0, 1, -1).Day()
+	remaining := days - joinedAt.UTC().Day() + 1
+	if remaining >= days {
+		return amount, false
+	}
+	return amount * remaining / days, true
+}
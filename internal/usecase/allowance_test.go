@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAllowanceRepository struct {
+	mock.Mock
+}
+
+func (m *MockAllowanceRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockAllowanceRepository) LockUnpaidUsers(ctx context.Context, period, periodEnd time.Time, roles []string, limit int) ([]entity.User, error) {
+	args := m.Called(ctx, period, periodEnd, roles, limit)
+	return args.Get(0).([]entity.User), args.Error(1)
+}
+
+func (m *MockAllowanceRepository) CreatePayment(ctx context.Context, payment *entity.AllowancePayment) (bool, error) {
+	args := m.Called(ctx, payment)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAllowanceRepository) GetRuns(ctx context.Context, limit int) ([]entity.AllowanceRun, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]entity.AllowanceRun), args.Error(1)
+}
+
+func TestProratedAllowance(t *testing.T) {
+	period := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		joinedAt time.Time
+		amount   int
+		prorated bool
+	}{
+		"before period":   {time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), 300, false},
+		"at period start": {period, 300, false},
+		"first day":       {time.Date(2025, 4, 1, 15, 0, 0, 0, time.UTC), 300, false},
+		"middle of month": {time.Date(2025, 4, 16, 9, 0, 0, 0, time.UTC), 150, true},
+		"last day":        {time.Date(2025, 4, 30, 23, 0, 0, 0, time.UTC), 10, true},
+		"other time zone": {time.Date(2025, 4, 17, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60)), 150, true},
+		"last ten days":   {time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC), 100, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			amount, prorated := proratedAllowance(300, tc.joinedAt, period)
+			assert.Equal(t, tc.amount, amount)
+			assert.Equal(t, tc.prorated, prorated)
+		})
+	}
+}
+
+func TestAllowanceUseCase_PayAllowance(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAllowanceUseCase(repos, AllowanceConfig{Amount: 300, Roles: []string{entity.RoleUser}})
+
+	users := []entity.User{{Name: "bob", CreatedAt: time.Unix(0, 0)}, {Name: "carol", CreatedAt: time.Unix(0, 0)}}
+	repos.allowance.On("LockUnpaidUsers", mock.Anything, mock.Anything, mock.Anything, []string{entity.RoleUser}, allowanceBatchSize).
+		Return(users, nil)
+	repos.allowance.On("CreatePayment", mock.Anything, paymentFor("bob")).Return(true, nil)
+	// Выплату carol параллельно сделал другой запуск
+	repos.allowance.On("CreatePayment", mock.Anything, paymentFor("carol")).Return(false, nil)
+	expectMint(repos, entity.EntryKindAllowance, "bob", 300)
+
+	run, err := uc.PayAllowance(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.UsersPaid)
+	assert.Equal(t, 300, run.TotalAmount)
+	assert.True(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "CreditCoins", mock.Anything, "carol", mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestAllowanceUseCase_PayAllowance_Disabled(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAllowanceUseCase(repos, AllowanceConfig{})
+
+	_, err := uc.PayAllowance(context.Background())
+
+	assert.ErrorIs(t, err, ErrAllowanceDisabled)
+	repos.allowance.AssertNotCalled(t, "Begin", mock.Anything)
+}
+
+func TestAllowanceUseCase_PayAllowance_MintFailureRollsBackBatch(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAllowanceUseCase(repos, AllowanceConfig{Amount: 300})
+
+	repos.allowance.On("LockUnpaidUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything, allowanceBatchSize).
+		Return([]entity.User{{Name: "bob"}}, nil)
+	repos.allowance.On("CreatePayment", mock.Anything, paymentFor("bob")).Return(true, nil)
+	repos.users.On("CreditCoins", mock.Anything, "bob", 300).Return(errors.New("user not found: bob"))
+
+	_, err := uc.PayAllowance(context.Background())
+
+	assert.Error(t, err)
+	assert.False(t, repos.tx.committed)
+}
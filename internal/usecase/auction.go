@@ -0,0 +1,427 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	holdReasonAuction = "auction"
+	// auctionRecentBids сколько последних ставок показывается в карточке аукциона
+	auctionRecentBids = 20
+)
+
+var (
+	ErrInvalidAuction  = errors.New("invalid auction request")
+	ErrAuctionNotFound = errors.New("auction not found")
+	ErrAuctionClosed   = errors.New("auction is not accepting bids")
+	ErrBidTooLow       = errors.New("bid is too low")
+)
+
+// AuctionRepository аукционы и ставки
+type AuctionRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateAuction(ctx context.Context, auction *entity.Auction) error
+	GetAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error)
+	LockAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error)
+	GetAuctions(ctx context.Context, status string) ([]entity.Auction, error)
+	GetEndedAuctions(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	AddBid(ctx context.Context, bid *entity.AuctionBid, holdID uuid.UUID, endsAt time.Time) error
+	GetBids(ctx context.Context, id uuid.UUID, limit int) ([]entity.AuctionBid, error)
+	SetResult(ctx context.Context, id uuid.UUID, status string, purchaseID *uuid.UUID) error
+}
+
+type AuctionUseCase struct {
+	auctionRepo  AuctionRepository
+	minIncrement int
+	extension    time.Duration
+	events       EventHandler
+	txRepos      func(tx pgx.Tx) *TxRepositories
+}
+
+func NewAuctionUseCase(auctionRepo AuctionRepository, minIncrement int, extension time.Duration, events EventHandler) *AuctionUseCase {
+	return &AuctionUseCase{
+		auctionRepo:  auctionRepo,
+		minIncrement: max(minIncrement, 1),
+		extension:    extension,
+		events:       events,
+		txRepos:      NewTxRepositories,
+	}
+}
+
+// CreateAuction выставляет единицу товара на аукцион. Товар сразу списывается со склада,
+// чтобы его нельзя было купить в магазине, и возвращается, если аукцион не состоится
+func (uc *AuctionUseCase) CreateAuction(ctx context.Context, admin, itemName string, reservePrice int, startsAt, endsAt time.Time) (*entity.Auction, error) {
+	now := time.Now()
+	if startsAt.IsZero() {
+		startsAt = now
+	}
+	auction := &entity.Auction{
+		ItemName:     strings.TrimSpace(itemName),
+		ReservePrice: reservePrice,
+		StartsAt:     startsAt,
+		EndsAt:       endsAt,
+		CreatedBy:    admin,
+	}
+	if err := validateAuction(auction, now); err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.auctionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	itemRepo := repos.Items
+	item, err := itemRepo.GetItemByName(ctx, auction.ItemName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil {
+		return nil, fmt.Errorf("%w: item not found: %s", ErrInvalidAuction, auction.ItemName)
+	}
+	components, err := itemRepo.GetBundleComponents(ctx, item.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle components: %w", err)
+	}
+	if len(components) > 0 {
+		return nil, fmt.Errorf("%w: bundles cannot be auctioned: %s", ErrInvalidAuction, item.Name)
+	}
+	if err := itemRepo.ReserveStock(ctx, item.Name, 1); err != nil {
+		return nil, fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	if err := repos.Auctions.CreateAuction(ctx, auction); err != nil {
+		return nil, err
+	}
+	if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+		Actor:    admin,
+		Action:   entity.AuditActionAuctionCreated,
+		TargetID: &auction.ID,
+		Details: map[string]any{
+			"item":         auction.ItemName,
+			"reservePrice": auction.ReservePrice,
+			"startsAt":     auction.StartsAt,
+			"endsAt":       auction.EndsAt,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Auction created", "auctionID", auction.ID, "admin", admin, "item", auction.ItemName)
+	return auction, nil
+}
+
+// GetAuctions возвращает аукционы со статусом status, пустой - все
+func (uc *AuctionUseCase) GetAuctions(ctx context.Context, status string) ([]entity.Auction, error) {
+	switch status {
+	case "", entity.AuctionStatusActive, entity.AuctionStatusSold, entity.AuctionStatusUnsold, entity.AuctionStatusCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidAuction, status)
+	}
+	return uc.auctionRepo.GetAuctions(ctx, status)
+}
+
+// GetAuction возвращает аукцион с последними ставками
+func (uc *AuctionUseCase) GetAuction(ctx context.Context, id uuid.UUID) (*entity.AuctionInfo, error) {
+	auction, err := uc.auctionRepo.GetAuction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil {
+		return nil, ErrAuctionNotFound
+	}
+	bids, err := uc.auctionRepo.GetBids(ctx, id, auctionRecentBids)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AuctionInfo{Auction: *auction, Bids: bids}, nil
+}
+
+// PlaceBid делает ставку: замораживает ее сумму у участника и размораживает ставку предыдущего лидера.
+// Ставки на один аукцион сериализуются блокировкой аукциона, поэтому минимальная ставка
+// проверяется по актуальному лидеру. Ставка незадолго до окончания продлевает аукцион
+func (uc *AuctionUseCase) PlaceBid(ctx context.Context, userName string, id uuid.UUID, amount int) (*entity.AuctionBid, error) {
+	tx, err := uc.auctionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	auctionRepo := repos.Auctions
+	userRepo := repos.Users
+	holdRepo := repos.Holds
+
+	// Аукцион блокируется раньше участников: так ставки на один аукцион выстраиваются
+	// в очередь, не удерживая блокировки пользователей
+	auction, err := auctionRepo.LockAuction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil {
+		return nil, ErrAuctionNotFound
+	}
+	now := time.Now()
+	if !auctionAcceptsBids(auction, now) {
+		return nil, ErrAuctionClosed
+	}
+	if minBid := minNextBid(auction, uc.minIncrement); amount < minBid {
+		return nil, fmt.Errorf("%w: minimum bid is %d", ErrBidTooLow, minBid)
+	}
+
+	locked, err := userRepo.LockUsers(ctx, userName, auction.TopBidder)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(locked, userName) {
+		return nil, fmt.Errorf("user does not exist: %s", userName)
+	}
+
+	// Ставка предыдущего лидера размораживается до заморозки новой, чтобы лидер мог
+	// поднять собственную ставку, не замораживая обе суммы
+	if auction.TopHoldID != nil {
+		hold, err := holdRepo.ResolveHold(ctx, *auction.TopHoldID, entity.HoldStatusReleased)
+		if err != nil {
+			return nil, err
+		}
+		if err := userRepo.ReleaseHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+			return nil, err
+		}
+	}
+	if err := userRepo.HoldCoins(ctx, userName, amount); err != nil {
+		return nil, fmt.Errorf("failed to hold coins: %w", err)
+	}
+	hold := &entity.Hold{UserName: userName, Amount: amount, Reason: holdReasonAuction}
+	if err := holdRepo.CreateHold(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	bid := &entity.AuctionBid{AuctionID: id, UserName: userName, Amount: amount}
+	endsAt := extendedEnd(auction.EndsAt, now, uc.extension)
+	if err := auctionRepo.AddBid(ctx, bid, hold.ID, endsAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Bid placed", "auctionID", id, "userName", userName, "amount", amount, "outbid", auction.TopBidder, "endsAt", endsAt)
+	return bid, nil
+}
+
+// CancelAuction отменяет активный аукцион: ставка лидера размораживается, товар возвращается на склад
+func (uc *AuctionUseCase) CancelAuction(ctx context.Context, admin string, id uuid.UUID) (*entity.Auction, error) {
+	tx, err := uc.auctionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	auction, err := repos.Auctions.LockAuction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil {
+		return nil, ErrAuctionNotFound
+	}
+	if auction.Status != entity.AuctionStatusActive {
+		return nil, ErrAuctionClosed
+	}
+
+	if err := closeUnsoldAuction(ctx, repos, auction, entity.AuctionStatusCancelled); err != nil {
+		return nil, err
+	}
+	if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+		Actor:    admin,
+		Action:   entity.AuditActionAuctionCancelled,
+		TargetID: &auction.ID,
+		Details: map[string]any{
+			"item":      auction.ItemName,
+			"topBid":    auction.TopBid,
+			"topBidder": auction.TopBidder,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Auction cancelled", "auctionID", id, "admin", admin)
+	return auction, nil
+}
+
+// SettleAuctions завершает аукционы, время которых истекло. Вызывается фоновой задачей.
+// Каждый аукцион завершается в своей транзакции: ошибка одного аукциона логируется
+// и не мешает завершить остальные, он будет завершен при следующем запуске
+func (uc *AuctionUseCase) SettleAuctions(ctx context.Context) error {
+	ids, err := uc.auctionRepo.GetEndedAuctions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, id := range ids {
+		if err := uc.settleAuction(ctx, id); err != nil {
+			slog.Error("Failed to settle auction", "auctionID", id, "error", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		slog.Warn("Some auctions were not settled", "failed", failed, "total", len(ids))
+	}
+	return nil
+}
+
+// settleAuction в одной транзакции списывает замороженную ставку победителя и выдает ему товар.
+// Если резервная цена не достигнута, ставка размораживается, а товар возвращается на склад.
+// О покупке победителя сообщается после фиксации транзакции
+func (uc *AuctionUseCase) settleAuction(ctx context.Context, id uuid.UUID) error {
+	tx, err := uc.auctionRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	auction, err := repos.Auctions.LockAuction(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Аукцион могли отменить или продлить после выборки
+	if auction == nil || auction.Status != entity.AuctionStatusActive || time.Now().Before(auction.EndsAt) {
+		return nil
+	}
+
+	sold := auction.ReserveMet && auction.TopHoldID != nil
+	if !sold {
+		if err := closeUnsoldAuction(ctx, repos, auction, entity.AuctionStatusUnsold); err != nil {
+			return err
+		}
+	} else {
+		hold, err := repos.Holds.ResolveHold(ctx, *auction.TopHoldID, entity.HoldStatusCaptured)
+		if err != nil {
+			return err
+		}
+		if err := repos.Users.CaptureHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+			return err
+		}
+		if err := repos.Items.AddToInventory(ctx, auction.TopBidder, auction.ItemName, 1); err != nil {
+			return fmt.Errorf("failed to add item to inventory: %w", err)
+		}
+		purchase := &entity.Purchase{
+			UserName: auction.TopBidder,
+			ItemName: auction.ItemName,
+			Price:    auction.TopBid,
+		}
+		if err := repos.Transfers.CreatePurchase(ctx, purchase); err != nil {
+			return err
+		}
+		if err := repos.Ledger.RecordPurchase(ctx, purchase); err != nil {
+			return err
+		}
+		if err := repos.Auctions.SetResult(ctx, id, entity.AuctionStatusSold, &purchase.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if sold {
+		publishEvents(ctx, uc.events, entity.Event{Kind: entity.EventPurchase, UserName: auction.TopBidder})
+	}
+
+	slog.Info("Auction settled", "auctionID", id, "item", auction.ItemName, "reserveMet", auction.ReserveMet,
+		"winner", auction.TopBidder, "amount", auction.TopBid)
+	return nil
+}
+
+// closeUnsoldAuction размораживает ставку лидера, возвращает товар на склад и завершает аукцион без продажи
+func closeUnsoldAuction(ctx context.Context, repos *TxRepositories, auction *entity.Auction, status string) error {
+	if auction.TopHoldID != nil {
+		hold, err := repos.Holds.ResolveHold(ctx, *auction.TopHoldID, entity.HoldStatusReleased)
+		if err != nil {
+			return err
+		}
+		if err := repos.Users.ReleaseHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+			return err
+		}
+	}
+	if err := repos.Items.ReleaseStock(ctx, auction.ItemName, 1); err != nil {
+		return err
+	}
+	auction.Status = status
+	return repos.Auctions.SetResult(ctx, auction.ID, status, nil)
+}
+
+// auctionAcceptsBids сообщает, принимает ли аукцион ставки в момент now
+func auctionAcceptsBids(auction *entity.Auction, now time.Time) bool {
+	return auction.Status == entity.AuctionStatusActive && !now.Before(auction.StartsAt) && now.Before(auction.EndsAt)
+}
+
+// minNextBid минимальная допустимая ставка: любая положительная для первой ставки,
+// иначе лидирующая ставка плюс шаг
+func minNextBid(auction *entity.Auction, increment int) int {
+	if auction.TopBidder == "" {
+		return 1
+	}
+	return auction.TopBid + increment
+}
+
+// extendedEnd время окончания аукциона после ставки в момент now: если до окончания
+// осталось меньше окна window, аукцион продлевается до now + window
+func extendedEnd(endsAt, now time.Time, window time.Duration) time.Time {
+	if extended := now.Add(window); endsAt.Before(extended) {
+		return extended
+	}
+	return endsAt
+}
+
+func validateAuction(auction *entity.Auction, now time.Time) error {
+	switch {
+	case auction.ItemName == "":
+		return fmt.Errorf("%w: item is required", ErrInvalidAuction)
+	case auction.ReservePrice <= 0:
+		return fmt.Errorf("%w: reserve price must be positive: %d", ErrInvalidAuction, auction.ReservePrice)
+	case !auction.EndsAt.After(auction.StartsAt):
+		return fmt.Errorf("%w: end must be after start", ErrInvalidAuction)
+	case !auction.EndsAt.After(now):
+		return fmt.Errorf("%w: end must be in the future", ErrInvalidAuction)
+	}
+	return nil
+}
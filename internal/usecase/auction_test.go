@@ -0,0 +1,312 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAuctionRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuctionRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockAuctionRepository) CreateAuction(ctx context.Context, auction *entity.Auction) error {
+	args := m.Called(ctx, auction)
+	return args.Error(0)
+}
+
+func (m *MockAuctionRepository) GetAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Auction), args.Error(1)
+}
+
+func (m *MockAuctionRepository) LockAuction(ctx context.Context, id uuid.UUID) (*entity.Auction, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Auction), args.Error(1)
+}
+
+func (m *MockAuctionRepository) GetAuctions(ctx context.Context, status string) ([]entity.Auction, error) {
+	args := m.Called(ctx, status)
+	return args.Get(0).([]entity.Auction), args.Error(1)
+}
+
+func (m *MockAuctionRepository) GetEndedAuctions(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockAuctionRepository) AddBid(ctx context.Context, bid *entity.AuctionBid, holdID uuid.UUID, endsAt time.Time) error {
+	args := m.Called(ctx, bid, holdID, endsAt)
+	return args.Error(0)
+}
+
+func (m *MockAuctionRepository) GetBids(ctx context.Context, id uuid.UUID, limit int) ([]entity.AuctionBid, error) {
+	args := m.Called(ctx, id, limit)
+	return args.Get(0).([]entity.AuctionBid), args.Error(1)
+}
+
+func (m *MockAuctionRepository) SetResult(ctx context.Context, id uuid.UUID, status string, purchaseID *uuid.UUID) error {
+	args := m.Called(ctx, id, status, purchaseID)
+	return args.Error(0)
+}
+
+func TestValidateAuction(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := entity.Auction{ItemName: "hoody", ReservePrice: 300, StartsAt: now, EndsAt: now.Add(24 * time.Hour)}
+
+	for name, tc := range map[string]struct {
+		modify func(a *entity.Auction)
+		valid  bool
+	}{
+		"valid":              {func(a *entity.Auction) {}, true},
+		"future start":       {func(a *entity.Auction) { a.StartsAt = now.Add(time.Hour) }, true},
+		"empty item":         {func(a *entity.Auction) { a.ItemName = "" }, false},
+		"zero reserve":       {func(a *entity.Auction) { a.ReservePrice = 0 }, false},
+		"end before start":   {func(a *entity.Auction) { a.StartsAt = a.EndsAt.Add(time.Hour) }, false},
+		"end equal to start": {func(a *entity.Auction) { a.StartsAt = a.EndsAt }, false},
+		"end in past":        {func(a *entity.Auction) { a.StartsAt = now.Add(-2 * time.Hour); a.EndsAt = now.Add(-time.Hour) }, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			auction := valid
+			tc.modify(&auction)
+			err := validateAuction(&auction, now)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidAuction)
+			}
+		})
+	}
+}
+
+func TestAuctionAcceptsBids(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	active := &entity.Auction{Status: entity.AuctionStatusActive, StartsAt: start, EndsAt: end}
+	cancelled := &entity.Auction{Status: entity.AuctionStatusCancelled, StartsAt: start, EndsAt: end}
+
+	assert.False(t, auctionAcceptsBids(active, start.Add(-time.Second)))
+	assert.True(t, auctionAcceptsBids(active, start))
+	assert.True(t, auctionAcceptsBids(active, end.Add(-time.Second)))
+	// Время истекло, но фоновая задача еще не завершила аукцион
+	assert.False(t, auctionAcceptsBids(active, end))
+	assert.False(t, auctionAcceptsBids(cancelled, start.Add(time.Minute)))
+}
+
+func TestMinNextBid(t *testing.T) {
+	assert.Equal(t, 1, minNextBid(&entity.Auction{}, 10))
+	assert.Equal(t, 110, minNextBid(&entity.Auction{TopBid: 100, TopBidder: "alice"}, 10))
+}
+
+func TestExtendedEnd(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 2 * time.Minute
+
+	// До окончания больше окна - время не меняется
+	endsAt := now.Add(10 * time.Minute)
+	assert.Equal(t, endsAt, extendedEnd(endsAt, now, window))
+
+	// Поздняя ставка продлевает аукцион на длину окна от момента ставки
+	endsAt = now.Add(30 * time.Second)
+	assert.Equal(t, now.Add(window), extendedEnd(endsAt, now, window))
+
+	// Ровно на границе окна продления нет
+	endsAt = now.Add(window)
+	assert.Equal(t, endsAt, extendedEnd(endsAt, now, window))
+
+	// Без окна аукцион не продлевается
+	assert.Equal(t, endsAt, extendedEnd(endsAt, now, 0))
+}
+
+func TestAuctionUseCase_PlaceBid_ReleasesOutbidHold(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	auction := activeAuction()
+	holdID := uuid.New()
+
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "bob"}).Return([]string{"alice", "bob"}, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *auction.TopHoldID, entity.HoldStatusReleased).
+		Return(&entity.Hold{ID: *auction.TopHoldID, UserName: "bob", Amount: 100}, nil)
+	repos.users.On("ReleaseHeldCoins", mock.Anything, "bob", 100).Return(nil)
+	repos.users.On("HoldCoins", mock.Anything, "alice", 110).Return(nil)
+	repos.holds.On("CreateHold", mock.Anything, mock.MatchedBy(func(h *entity.Hold) bool {
+		return h.UserName == "alice" && h.Amount == 110 && h.Reason == holdReasonAuction
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Hold).ID = holdID
+	}).Return(nil)
+	repos.auctions.On("AddBid", mock.Anything, mock.MatchedBy(func(b *entity.AuctionBid) bool {
+		return b.UserName == "alice" && b.Amount == 110
+	}), holdID, auction.EndsAt).Return(nil)
+
+	bid, err := uc.PlaceBid(context.Background(), "alice", auction.ID, 110)
+
+	require.NoError(t, err)
+	assert.Equal(t, 110, bid.Amount)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestAuctionUseCase_PlaceBid_FirstBidHoldsCoins(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	auction := activeAuction()
+	auction.TopBid, auction.TopBidder, auction.TopHoldID = 0, "", nil
+	// Ставка за минуту до окончания продлевает аукцион
+	auction.EndsAt = time.Now().Add(30 * time.Second)
+
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", ""}).Return([]string{"alice"}, nil)
+	repos.users.On("HoldCoins", mock.Anything, "alice", 50).Return(nil)
+	repos.holds.On("CreateHold", mock.Anything, mock.Anything).Return(nil)
+	repos.auctions.On("AddBid", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(endsAt time.Time) bool {
+		return endsAt.After(auction.EndsAt)
+	})).Return(nil)
+
+	_, err := uc.PlaceBid(context.Background(), "alice", auction.ID, 50)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.holds.AssertNotCalled(t, "ResolveHold", mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestAuctionUseCase_PlaceBid_TooLow(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	auction := activeAuction()
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+
+	_, err := uc.PlaceBid(context.Background(), "alice", auction.ID, 105)
+
+	assert.ErrorIs(t, err, ErrBidTooLow)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "HoldCoins", mock.Anything, mock.Anything, mock.Anything)
+	repos.holds.AssertNotCalled(t, "ResolveHold", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuctionUseCase_PlaceBid_InsufficientFundsKeepsLeader(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	auction := activeAuction()
+
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "bob"}).Return([]string{"alice", "bob"}, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *auction.TopHoldID, entity.HoldStatusReleased).
+		Return(&entity.Hold{UserName: "bob", Amount: 100}, nil)
+	repos.users.On("ReleaseHeldCoins", mock.Anything, "bob", 100).Return(nil)
+	repos.users.On("HoldCoins", mock.Anything, "alice", 500).Return(errors.New("insufficient funds"))
+
+	_, err := uc.PlaceBid(context.Background(), "alice", auction.ID, 500)
+
+	// Транзакция откатывается вместе с разморозкой ставки лидера
+	assert.Error(t, err)
+	assert.False(t, repos.tx.committed)
+	repos.auctions.AssertNotCalled(t, "AddBid", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuctionUseCase_SettleAuctions_Sold(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	handler := &recordingEventHandler{}
+	uc.events = handler
+	auction := endedAuction()
+	purchaseID := uuid.New()
+
+	repos.auctions.On("GetEndedAuctions", mock.Anything, mock.Anything).Return([]uuid.UUID{auction.ID}, nil)
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *auction.TopHoldID, entity.HoldStatusCaptured).
+		Return(&entity.Hold{UserName: "bob", Amount: 100}, nil)
+	repos.users.On("CaptureHeldCoins", mock.Anything, "bob", 100).Return(nil)
+	repos.items.On("AddToInventory", mock.Anything, "bob", "hoody", 1).Return(nil)
+	repos.transfers.On("CreatePurchase", mock.Anything, mock.MatchedBy(func(p *entity.Purchase) bool {
+		return p.UserName == "bob" && p.ItemName == "hoody" && p.Price == 100
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Purchase).ID = purchaseID
+	}).Return(nil)
+	repos.ledger.On("RecordPurchase", mock.Anything, mock.Anything).Return(nil)
+	repos.auctions.On("SetResult", mock.Anything, auction.ID, entity.AuctionStatusSold, &purchaseID).Return(nil)
+
+	require.NoError(t, uc.SettleAuctions(context.Background()))
+
+	assert.True(t, repos.tx.committed)
+	assert.Equal(t, []entity.Event{{Kind: entity.EventPurchase, UserName: "bob"}}, handler.events)
+	repos.items.AssertNotCalled(t, "ReleaseStock", mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestAuctionUseCase_SettleAuctions_ReserveNotMet(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	handler := &recordingEventHandler{}
+	uc.events = handler
+	auction := endedAuction()
+	auction.ReserveMet = false
+
+	repos.auctions.On("GetEndedAuctions", mock.Anything, mock.Anything).Return([]uuid.UUID{auction.ID}, nil)
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *auction.TopHoldID, entity.HoldStatusReleased).
+		Return(&entity.Hold{UserName: "bob", Amount: 100}, nil)
+	repos.users.On("ReleaseHeldCoins", mock.Anything, "bob", 100).Return(nil)
+	repos.items.On("ReleaseStock", mock.Anything, "hoody", 1).Return(nil)
+	repos.auctions.On("SetResult", mock.Anything, auction.ID, entity.AuctionStatusUnsold, (*uuid.UUID)(nil)).Return(nil)
+
+	require.NoError(t, uc.SettleAuctions(context.Background()))
+
+	assert.True(t, repos.tx.committed)
+	assert.Empty(t, handler.events)
+	repos.users.AssertNotCalled(t, "CaptureHeldCoins", mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestAuctionUseCase_SettleAuctions_FailureIsSkipped(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	broken := endedAuction()
+	auction := endedAuction()
+	auction.ReserveMet = false
+	auction.TopHoldID = nil
+
+	repos.auctions.On("GetEndedAuctions", mock.Anything, mock.Anything).Return([]uuid.UUID{broken.ID, auction.ID}, nil)
+	repos.auctions.On("LockAuction", mock.Anything, broken.ID).Return(broken, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *broken.TopHoldID, entity.HoldStatusCaptured).
+		Return((*entity.Hold)(nil), errors.New("hold is not active"))
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+	repos.items.On("ReleaseStock", mock.Anything, "hoody", 1).Return(nil)
+	repos.auctions.On("SetResult", mock.Anything, auction.ID, entity.AuctionStatusUnsold, (*uuid.UUID)(nil)).Return(nil)
+
+	require.NoError(t, uc.SettleAuctions(context.Background()))
+
+	// Следующий аукцион завершен, несмотря на ошибку предыдущего
+	assert.True(t, repos.tx.committed)
+	repos.auctions.AssertNotCalled(t, "SetResult", mock.Anything, broken.ID, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestAuctionUseCase_SettleAuctions_SkipsExtendedAuction(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestAuctionUseCase(repos)
+	// Аукцион продлили ставкой после выборки
+	auction := activeAuction()
+
+	repos.auctions.On("GetEndedAuctions", mock.Anything, mock.Anything).Return([]uuid.UUID{auction.ID}, nil)
+	repos.auctions.On("LockAuction", mock.Anything, auction.ID).Return(auction, nil)
+
+	require.NoError(t, uc.SettleAuctions(context.Background()))
+
+	repos.holds.AssertNotCalled(t, "ResolveHold", mock.Anything, mock.Anything, mock.Anything)
+	repos.auctions.AssertNotCalled(t, "SetResult", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
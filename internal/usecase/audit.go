@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// auditLogMaxLimit максимальный размер страницы журнала аудита
+const auditLogMaxLimit = 100
+
+var ErrInvalidAuditQuery = errors.New("invalid audit log query")
+
+type AuditUseCase struct {
+	auditRepo *repository.AuditRepository
+}
+
+func NewAuditUseCase(auditRepo *repository.AuditRepository) *AuditUseCase {
+	return &AuditUseCase{auditRepo: auditRepo}
+}
+
+// GetAuditLog возвращает страницу журнала аудита, новые записи первыми.
+// Следующая страница запрашивается с beforeID, равным id последней записи
+func (uc *AuditUseCase) GetAuditLog(ctx context.Context, beforeID int64, limit int) ([]entity.AuditEntry, error) {
+	if limit <= 0 || limit > auditLogMaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAuditQuery, auditLogMaxLimit)
+	}
+	if beforeID < 0 {
+		return nil, fmt.Errorf("%w: before must not be negative", ErrInvalidAuditQuery)
+	}
+
+	entries, err := uc.auditRepo.GetEntries(ctx, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []entity.AuditEntry{}
+	}
+	return entries, nil
+}
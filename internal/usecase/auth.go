@@ -3,11 +3,14 @@ package usecase
 import (
 	"avito-merch/internal/entity"
 	"context"
+	"errors"
 	"log/slog"
 )
 
 const coins = 1000
 
+var ErrUserDeactivated = errors.New("user is deactivated")
+
 type UserRepository interface {
 	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
 	Create(ctx context.Context, user *entity.User) error
@@ -41,6 +44,9 @@ func (uc *AuthUseCase) Authenticate(ctx context.Context, username, password stri
 		}
 		slog.Info("New user created", "username", username)
 	}
+	if user.DeactivatedAt != nil {
+		return nil, ErrUserDeactivated
+	}
 
 	return user, nil
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -89,3 +90,19 @@ func TestAuthUseCase_Authenticate_CreateUserError(t *testing.T) {
 
 	mockUserRepo.AssertExpectations(t)
 }
+
+func TestAuthUseCase_Authenticate_Deactivated(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+
+	deactivatedAt := time.Now()
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").
+		Return(&entity.User{Name: "testuser", DeactivatedAt: &deactivatedAt}, nil)
+
+	uc := NewAuthUseCase(mockUserRepo)
+
+	user, err := uc.Authenticate(context.Background(), "testuser", "password")
+
+	assert.ErrorIs(t, err, ErrUserDeactivated)
+	assert.Nil(t, user)
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"avito-merch/internal/entity"
 	"avito-merch/internal/repository"
 	"context"
 	"fmt"
@@ -12,19 +13,20 @@ import (
 type BuyUseCase struct {
 	userRepo *repository.UserRepository
 	itemRepo *repository.ItemRepository
+	events   EventHandler
 }
 
-func NewBuyUseCase(userRepo *repository.UserRepository, itemRepo *repository.ItemRepository) *BuyUseCase {
-	return &BuyUseCase{userRepo: userRepo, itemRepo: itemRepo}
+func NewBuyUseCase(userRepo *repository.UserRepository, itemRepo *repository.ItemRepository, events EventHandler) *BuyUseCase {
+	return &BuyUseCase{userRepo: userRepo, itemRepo: itemRepo, events: events}
 }
 
-// BuyItem выполняет покупку товара
-func (uc *BuyUseCase) BuyItem(ctx context.Context, userName string, itemName string) error {
+// BuyItem выполняет покупку товара и возвращает запись о покупке
+func (uc *BuyUseCase) BuyItem(ctx context.Context, userName string, itemName string) (*entity.Purchase, error) {
 	// Начинаем транзакцию
 	tx, err := uc.itemRepo.Begin(ctx)
 	if err != nil {
 		slog.Error("Failed to begin transaction", "error", err)
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() {
 		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
@@ -39,31 +41,79 @@ func (uc *BuyUseCase) BuyItem(ctx context.Context, userName string, itemName str
 	item, err := itemRepo.GetItemByName(ctx, itemName)
 	if err != nil {
 		slog.Error("Failed to get item", "item", itemName, "error", err)
-		return fmt.Errorf("failed to get item: %w", err)
+		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
 	if item == nil {
 		slog.Error("Item not found", "item", itemName)
-		return fmt.Errorf("item not found: %s", itemName)
+		return nil, fmt.Errorf("item not found: %s", itemName)
 	}
 
 	// Обновляем баланс пользователя с проверкой
 	if err := userRepo.UpdateUserAfterPurchase(ctx, userName, item.Price); err != nil {
 		slog.Error("Failed to update user balance", "userName", userName, "error", err)
-		return fmt.Errorf("failed to update user balance: %w", err)
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	// Добавляем товар в инвентарь
-	if err := itemRepo.AddToInventory(ctx, userName, itemName); err != nil {
-		slog.Error("Failed to add item to inventory", "userName", userName, "item", itemName, "error", err)
-		return fmt.Errorf("failed to add item to inventory: %w", err)
+	// Списываем товар со склада и выдаем пользователю
+	if err := deliverItem(ctx, itemRepo, userName, item); err != nil {
+		slog.Error("Failed to deliver item", "userName", userName, "item", itemName, "error", err)
+		return nil, err
+	}
+
+	// Записываем покупку в историю
+	purchase := &entity.Purchase{
+		UserName: userName,
+		ItemName: item.Name,
+		Price:    item.Price,
+	}
+	if err := repository.TransactionRepoWithTx(tx).CreatePurchase(ctx, purchase); err != nil {
+		slog.Error("Failed to create purchase record", "userName", userName, "item", itemName, "error", err)
+		return nil, fmt.Errorf("failed to create purchase record: %w", err)
+	}
+
+	// Проводим оплату по главной книге
+	if err := repository.LedgerRepoWithTx(tx).RecordPurchase(ctx, purchase); err != nil {
+		slog.Error("Failed to record purchase in ledger", "userName", userName, "item", itemName, "error", err)
+		return nil, fmt.Errorf("failed to record purchase in ledger: %w", err)
 	}
 
 	// Коммитим транзакцию
 	if err := tx.Commit(ctx); err != nil {
 		slog.Error("Failed to commit transaction", "error", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	slog.Info("Item purchased successfully", "userName", userName, "item", itemName)
+	publishEvents(ctx, uc.events, entity.Event{Kind: entity.EventPurchase, UserName: userName})
+	return purchase, nil
+}
+
+// deliverItem списывает товар со склада и добавляет его в инвентарь пользователя.
+// Набор раскладывается на компоненты, каждый из которых тоже списывается со склада.
+// Вложенные наборы запрещены при изменении состава, поэтому раскрывается один уровень
+func deliverItem(ctx context.Context, itemRepo ItemRepository, userName string, item *entity.Item) error {
+	if err := itemRepo.ReserveStock(ctx, item.Name, 1); err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	components, err := itemRepo.GetBundleComponents(ctx, item.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get bundle components: %w", err)
+	}
+	if len(components) == 0 {
+		components = []entity.BundleComponent{{ItemName: item.Name, Quantity: 1}}
+	} else {
+		for _, component := range components {
+			if err := itemRepo.ReserveStock(ctx, component.ItemName, component.Quantity); err != nil {
+				return fmt.Errorf("failed to reserve stock: %w", err)
+			}
+		}
+	}
+
+	for _, component := range components {
+		if err := itemRepo.AddToInventory(ctx, userName, component.ItemName, component.Quantity); err != nil {
+			return fmt.Errorf("failed to add item to inventory: %w", err)
+		}
+	}
 	return nil
 }
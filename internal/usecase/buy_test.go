@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverItem(t *testing.T) {
+	repos := newMockRepos()
+	repos.items.On("ReserveStock", mock.Anything, "cup", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "cup").Return([]entity.BundleComponent(nil), nil)
+	repos.items.On("AddToInventory", mock.Anything, "alice", "cup", 1).Return(nil)
+
+	require.NoError(t, deliverItem(context.Background(), repos.items, "alice", &entity.Item{Name: "cup", Price: 20}))
+	repos.assertExpectations(t)
+}
+
+func TestDeliverItem_Bundle(t *testing.T) {
+	repos := newMockRepos()
+	repos.items.On("ReserveStock", mock.Anything, "welcome-pack", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "welcome-pack").Return([]entity.BundleComponent{
+		{ItemName: "cup", Quantity: 1},
+		{ItemName: "pen", Quantity: 2},
+	}, nil)
+	// Каждый компонент списывается со склада в своем количестве и попадает в инвентарь вместо набора
+	repos.items.On("ReserveStock", mock.Anything, "cup", 1).Return(nil)
+	repos.items.On("ReserveStock", mock.Anything, "pen", 2).Return(nil)
+	repos.items.On("AddToInventory", mock.Anything, "alice", "cup", 1).Return(nil)
+	repos.items.On("AddToInventory", mock.Anything, "alice", "pen", 2).Return(nil)
+
+	require.NoError(t, deliverItem(context.Background(), repos.items, "alice", &entity.Item{Name: "welcome-pack", Price: 95}))
+	repos.items.AssertNotCalled(t, "AddToInventory", mock.Anything, "alice", "welcome-pack", mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestDeliverItem_BundleComponentOutOfStock(t *testing.T) {
+	repos := newMockRepos()
+	repos.items.On("ReserveStock", mock.Anything, "welcome-pack", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "welcome-pack").Return([]entity.BundleComponent{
+		{ItemName: "cup", Quantity: 1},
+		{ItemName: "pen", Quantity: 2},
+	}, nil)
+	repos.items.On("ReserveStock", mock.Anything, "cup", 1).Return(nil)
+	repos.items.On("ReserveStock", mock.Anything, "pen", 2).Return(repository.ErrOutOfStock)
+
+	err := deliverItem(context.Background(), repos.items, "alice", &entity.Item{Name: "welcome-pack", Price: 95})
+
+	assert.ErrorIs(t, err, repository.ErrOutOfStock)
+	repos.items.AssertNotCalled(t, "AddToInventory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxCampaignNameLength        = 255
+	maxCampaignDescriptionLength = 2000
+	// campaignTopDonors сколько крупнейших жертвователей попадает в отчет кампании
+	campaignTopDonors = 10
+)
+
+var (
+	ErrInvalidCampaign  = errors.New("invalid campaign request")
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCampaignClosed   = errors.New("campaign is closed")
+)
+
+// CampaignRepository благотворительные кампании, пожертвования и итоговые отчеты
+type CampaignRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateCampaign(ctx context.Context, campaign *entity.Campaign) error
+	GetCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error)
+	LockCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error)
+	GetCampaigns(ctx context.Context, status string) ([]entity.Campaign, error)
+	GetExpiredCampaigns(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	AddDonation(ctx context.Context, donation *entity.Donation) error
+	GetDonors(ctx context.Context, id uuid.UUID) ([]entity.CampaignDonor, error)
+	CloseCampaign(ctx context.Context, summary *entity.CampaignSummary) error
+	GetSummary(ctx context.Context, id uuid.UUID) (*entity.CampaignSummary, error)
+}
+
+type CampaignUseCase struct {
+	campaignRepo CampaignRepository
+	txRepos      func(tx pgx.Tx) *TxRepositories
+}
+
+func NewCampaignUseCase(campaignRepo CampaignRepository) *CampaignUseCase {
+	return &CampaignUseCase{campaignRepo: campaignRepo, txRepos: NewTxRepositories}
+}
+
+// CreateCampaign создает благотворительную кампанию с целью и сроком окончания
+func (uc *CampaignUseCase) CreateCampaign(ctx context.Context, admin, name, description string, goal int, deadline time.Time) (*entity.Campaign, error) {
+	campaign := &entity.Campaign{
+		Name:        strings.TrimSpace(name),
+		Description: strings.TrimSpace(description),
+		Goal:        goal,
+		Deadline:    deadline,
+		CreatedBy:   admin,
+	}
+	if err := validateCampaign(campaign, time.Now()); err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.campaignRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	if err := repos.Campaigns.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+	if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+		Actor:    admin,
+		Action:   entity.AuditActionCampaignCreated,
+		TargetID: &campaign.ID,
+		Details: map[string]any{
+			"name":     campaign.Name,
+			"goal":     campaign.Goal,
+			"deadline": campaign.Deadline,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Campaign created", "campaignID", campaign.ID, "admin", admin, "goal", campaign.Goal)
+	return campaign, nil
+}
+
+// GetCampaigns возвращает кампании со статусом status, пустой - все
+func (uc *CampaignUseCase) GetCampaigns(ctx context.Context, status string) ([]entity.Campaign, error) {
+	switch status {
+	case "", entity.CampaignStatusOpen, entity.CampaignStatusClosed:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidCampaign, status)
+	}
+	return uc.campaignRepo.GetCampaigns(ctx, status)
+}
+
+// GetCampaign возвращает кампанию с текущим прогрессом
+func (uc *CampaignUseCase) GetCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error) {
+	campaign, err := uc.campaignRepo.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	return campaign, nil
+}
+
+// Donate списывает монеты пользователя на счет кампании. Пожертвовать можно только
+// в открытую кампанию до срока окончания, даже если фоновая задача еще не закрыла ее
+func (uc *CampaignUseCase) Donate(ctx context.Context, userName string, id uuid.UUID, amount int) (*entity.Donation, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive: %d", ErrInvalidCampaign, amount)
+	}
+
+	tx, err := uc.campaignRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	locked, err := repos.Users.LockUsers(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if len(locked) == 0 {
+		return nil, fmt.Errorf("user does not exist: %s", userName)
+	}
+
+	campaign, err := repos.Campaigns.LockCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	if !campaignAcceptsDonations(campaign, time.Now()) {
+		return nil, ErrCampaignClosed
+	}
+
+	if _, err := repos.Users.DebitCoins(ctx, userName, amount); err != nil {
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
+	}
+	donation := &entity.Donation{CampaignID: id, UserName: userName, Amount: amount}
+	if err := repos.Campaigns.AddDonation(ctx, donation); err != nil {
+		return nil, err
+	}
+	if err := repos.Ledger.RecordDonation(ctx, donation, campaign.Name); err != nil {
+		return nil, fmt.Errorf("failed to record donation in ledger: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Coins donated", "campaignID", id, "userName", userName, "amount", amount)
+	return donation, nil
+}
+
+// CloseCampaign досрочно закрывает кампанию и возвращает итоговый отчет
+func (uc *CampaignUseCase) CloseCampaign(ctx context.Context, admin string, id uuid.UUID) (*entity.CampaignSummary, error) {
+	return uc.closeCampaign(ctx, id, admin)
+}
+
+// CloseExpiredCampaigns закрывает кампании, срок которых истек. Вызывается фоновой задачей
+func (uc *CampaignUseCase) CloseExpiredCampaigns(ctx context.Context) error {
+	ids, err := uc.campaignRepo.GetExpiredCampaigns(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		// Кампанию могли закрыть вручную после выборки
+		if _, err := uc.closeCampaign(ctx, id, ""); err != nil && !errors.Is(err, ErrCampaignClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetReport возвращает итоговый отчет закрытой кампании или текущее состояние открытой
+func (uc *CampaignUseCase) GetReport(ctx context.Context, id uuid.UUID) (*entity.CampaignSummary, error) {
+	campaign, err := uc.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status == entity.CampaignStatusClosed {
+		summary, err := uc.campaignRepo.GetSummary(ctx, id)
+		if err != nil || summary != nil {
+			return summary, err
+		}
+	}
+
+	donors, err := uc.campaignRepo.GetDonors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return buildCampaignSummary(campaign, donors, ""), nil
+}
+
+// closeCampaign закрывает кампанию и сохраняет отчет в одной транзакции с блокировкой кампании,
+// поэтому в отчет попадают все пожертвования и ни одно не проходит после закрытия
+func (uc *CampaignUseCase) closeCampaign(ctx context.Context, id uuid.UUID, admin string) (*entity.CampaignSummary, error) {
+	tx, err := uc.campaignRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	campaign, err := repos.Campaigns.LockCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	if campaign.Status != entity.CampaignStatusOpen {
+		return nil, ErrCampaignClosed
+	}
+
+	donors, err := repos.Campaigns.GetDonors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	closedAt := time.Now()
+	campaign.Status = entity.CampaignStatusClosed
+	campaign.ClosedAt = &closedAt
+	summary := buildCampaignSummary(campaign, donors, admin)
+	if err := repos.Campaigns.CloseCampaign(ctx, summary); err != nil {
+		return nil, err
+	}
+
+	if admin != "" {
+		if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+			Actor:    admin,
+			Action:   entity.AuditActionCampaignClosed,
+			TargetID: &campaign.ID,
+			Details: map[string]any{
+				"raised": campaign.Raised,
+				"goal":   campaign.Goal,
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Campaign closed",
+		"campaignID", id,
+		"closedBy", admin,
+		"raised", summary.Raised,
+		"goal", summary.Goal,
+		"goalReached", summary.GoalReached,
+		"donors", summary.Donors,
+		"donations", summary.Donations,
+	)
+	return summary, nil
+}
+
+// buildCampaignSummary собирает отчет кампании по суммам жертвователей, отсортированным от больших к меньшим
+func buildCampaignSummary(campaign *entity.Campaign, donors []entity.CampaignDonor, closedBy string) *entity.CampaignSummary {
+	summary := &entity.CampaignSummary{
+		Campaign:    *campaign,
+		GoalReached: campaign.Raised >= campaign.Goal,
+		ClosedBy:    closedBy,
+		TopDonors:   []entity.CampaignDonor{},
+	}
+	summary.Donors = len(donors)
+	for _, donor := range donors {
+		summary.Donations += donor.Donations
+	}
+	summary.TopDonors = append(summary.TopDonors, donors[:min(len(donors), campaignTopDonors)]...)
+	return summary
+}
+
+// campaignAcceptsDonations сообщает, открыта ли кампания для пожертвований в момент now
+func campaignAcceptsDonations(campaign *entity.Campaign, now time.Time) bool {
+	return campaign.Status == entity.CampaignStatusOpen && now.Before(campaign.Deadline)
+}
+
+func validateCampaign(campaign *entity.Campaign, now time.Time) error {
+	switch {
+	case campaign.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidCampaign)
+	case utf8.RuneCountInString(campaign.Name) > maxCampaignNameLength:
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidCampaign, maxCampaignNameLength)
+	case utf8.RuneCountInString(campaign.Description) > maxCampaignDescriptionLength:
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidCampaign, maxCampaignDescriptionLength)
+	case campaign.Goal <= 0:
+		return fmt.Errorf("%w: goal must be positive: %d", ErrInvalidCampaign, campaign.Goal)
+	case !campaign.Deadline.After(now):
+		return fmt.Errorf("%w: deadline must be in the future", ErrInvalidCampaign)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCampaignRepository struct {
+	mock.Mock
+}
+
+func (m *MockCampaignRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockCampaignRepository) CreateCampaign(ctx context.Context, campaign *entity.Campaign) error {
+	args := m.Called(ctx, campaign)
+	return args.Error(0)
+}
+
+func (m *MockCampaignRepository) GetCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) LockCampaign(ctx context.Context, id uuid.UUID) (*entity.Campaign, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) GetCampaigns(ctx context.Context, status string) ([]entity.Campaign, error) {
+	args := m.Called(ctx, status)
+	return args.Get(0).([]entity.Campaign), args.Error(1)
+}
+
+func (m *MockCampaignRepository) GetExpiredCampaigns(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockCampaignRepository) AddDonation(ctx context.Context, donation *entity.Donation) error {
+	args := m.Called(ctx, donation)
+	return args.Error(0)
+}
+
+func (m *MockCampaignRepository) GetDonors(ctx context.Context, id uuid.UUID) ([]entity.CampaignDonor, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]entity.CampaignDonor), args.Error(1)
+}
+
+func (m *MockCampaignRepository) CloseCampaign(ctx context.Context, summary *entity.CampaignSummary) error {
+	args := m.Called(ctx, summary)
+	return args.Error(0)
+}
+
+func (m *MockCampaignRepository) GetSummary(ctx context.Context, id uuid.UUID) (*entity.CampaignSummary, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.CampaignSummary), args.Error(1)
+}
+
+func TestValidateCampaign(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := entity.Campaign{Name: "Shelter", Goal: 1000, Deadline: now.Add(24 * time.Hour)}
+
+	for name, tc := range map[string]struct {
+		modify func(c *entity.Campaign)
+		valid  bool
+	}{
+		"valid":              {func(c *entity.Campaign) {}, true},
+		"empty name":         {func(c *entity.Campaign) { c.Name = "" }, false},
+		"long name":          {func(c *entity.Campaign) { c.Name = strings.Repeat("я", maxCampaignNameLength+1) }, false},
+		"long description":   {func(c *entity.Campaign) { c.Description = strings.Repeat("a", maxCampaignDescriptionLength+1) }, false},
+		"zero goal":          {func(c *entity.Campaign) { c.Goal = 0 }, false},
+		"deadline now":       {func(c *entity.Campaign) { c.Deadline = now }, false},
+		"deadline in past":   {func(c *entity.Campaign) { c.Deadline = now.Add(-time.Hour) }, false},
+		"max length name ok": {func(c *entity.Campaign) { c.Name = strings.Repeat("я", maxCampaignNameLength) }, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			campaign := valid
+			tc.modify(&campaign)
+			err := validateCampaign(&campaign, now)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidCampaign)
+			}
+		})
+	}
+}
+
+func TestCampaignAcceptsDonations(t *testing.T) {
+	deadline := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	open := &entity.Campaign{Status: entity.CampaignStatusOpen, Deadline: deadline}
+	closed := &entity.Campaign{Status: entity.CampaignStatusClosed, Deadline: deadline}
+
+	assert.True(t, campaignAcceptsDonations(open, deadline.Add(-time.Second)))
+	// Срок истек, но фоновая задача еще не закрыла кампанию
+	assert.False(t, campaignAcceptsDonations(open, deadline))
+	assert.False(t, campaignAcceptsDonations(closed, deadline.Add(-time.Hour)))
+}
+
+func TestBuildCampaignSummary(t *testing.T) {
+	campaign := &entity.Campaign{Name: "Shelter", Goal: 500, Raised: 600}
+
+	var donors []entity.CampaignDonor
+	for i := 0; i < campaignTopDonors+2; i++ {
+		donors = append(donors, entity.CampaignDonor{UserName: string(rune('a' + i)), Amount: 50, Donations: 2})
+	}
+
+	summary := buildCampaignSummary(campaign, donors, "admin")
+
+	assert.True(t, summary.GoalReached)
+	assert.Equal(t, campaignTopDonors+2, summary.Donors)
+	assert.Equal(t, 2*(campaignTopDonors+2), summary.Donations)
+	assert.Len(t, summary.TopDonors, campaignTopDonors)
+	assert.Equal(t, "a", summary.TopDonors[0].UserName)
+	assert.Equal(t, "admin", summary.ClosedBy)
+
+	empty := buildCampaignSummary(&entity.Campaign{Goal: 500}, nil, "")
+	assert.False(t, empty.GoalReached)
+	assert.Zero(t, empty.Donations)
+	assert.NotNil(t, empty.TopDonors)
+	assert.Empty(t, empty.TopDonors)
+}
+
+func TestCampaignUseCase_CreateCampaign(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	id := uuid.New()
+
+	repos.campaigns.On("CreateCampaign", mock.Anything, mock.MatchedBy(func(c *entity.Campaign) bool {
+		return c.Name == "Shelter" && c.Goal == 500 && c.CreatedBy == "admin"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Campaign).ID = id
+	}).Return(nil)
+	repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+		return e.Actor == "admin" && e.Action == entity.AuditActionCampaignCreated && *e.TargetID == id
+	})).Return(nil)
+
+	campaign, err := uc.CreateCampaign(context.Background(), "admin", "  Shelter ", "", 500, time.Now().Add(time.Hour))
+
+	require.NoError(t, err)
+	assert.Equal(t, id, campaign.ID)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestCampaignUseCase_Donate(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	campaign := openCampaign()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice"}).Return([]string{"alice"}, nil)
+	repos.campaigns.On("LockCampaign", mock.Anything, campaign.ID).Return(campaign, nil)
+	repos.users.On("DebitCoins", mock.Anything, "alice", 100).Return([]entity.CoinLot(nil), nil)
+	repos.campaigns.On("AddDonation", mock.Anything, mock.MatchedBy(func(d *entity.Donation) bool {
+		return d.CampaignID == campaign.ID && d.UserName == "alice" && d.Amount == 100
+	})).Return(nil)
+	repos.ledger.On("RecordDonation", mock.Anything, mock.Anything, "Shelter").Return(nil)
+
+	donation, err := uc.Donate(context.Background(), "alice", campaign.ID, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, 100, donation.Amount)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestCampaignUseCase_Donate_Closed(t *testing.T) {
+	tests := map[string]func(c *entity.Campaign){
+		"closed":           func(c *entity.Campaign) { c.Status = entity.CampaignStatusClosed },
+		"deadline reached": func(c *entity.Campaign) { c.Deadline = time.Now().Add(-time.Minute) },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			repos := newMockRepos()
+			uc := newTestCampaignUseCase(repos)
+			campaign := openCampaign()
+			modify(campaign)
+
+			repos.users.On("LockUsers", mock.Anything, []string{"alice"}).Return([]string{"alice"}, nil)
+			repos.campaigns.On("LockCampaign", mock.Anything, campaign.ID).Return(campaign, nil)
+
+			_, err := uc.Donate(context.Background(), "alice", campaign.ID, 100)
+
+			assert.ErrorIs(t, err, ErrCampaignClosed)
+			assert.False(t, repos.tx.committed)
+			repos.users.AssertNotCalled(t, "DebitCoins", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCampaignUseCase_Donate_NotFound(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	id := uuid.New()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice"}).Return([]string{"alice"}, nil)
+	repos.campaigns.On("LockCampaign", mock.Anything, id).Return((*entity.Campaign)(nil), nil)
+
+	_, err := uc.Donate(context.Background(), "alice", id, 100)
+
+	assert.ErrorIs(t, err, ErrCampaignNotFound)
+	assert.False(t, repos.tx.committed)
+}
+
+func TestCampaignUseCase_CloseCampaign(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	campaign := openCampaign()
+	campaign.Raised = 600
+	donors := []entity.CampaignDonor{{UserName: "alice", Amount: 400, Donations: 2}, {UserName: "bob", Amount: 200, Donations: 1}}
+
+	repos.campaigns.On("LockCampaign", mock.Anything, campaign.ID).Return(campaign, nil)
+	repos.campaigns.On("GetDonors", mock.Anything, campaign.ID).Return(donors, nil)
+	repos.campaigns.On("CloseCampaign", mock.Anything, mock.MatchedBy(func(s *entity.CampaignSummary) bool {
+		return s.Status == entity.CampaignStatusClosed && s.ClosedAt != nil && s.ClosedBy == "admin"
+	})).Return(nil)
+	repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+		return e.Action == entity.AuditActionCampaignClosed && *e.TargetID == campaign.ID
+	})).Return(nil)
+
+	summary, err := uc.CloseCampaign(context.Background(), "admin", campaign.ID)
+
+	require.NoError(t, err)
+	assert.True(t, summary.GoalReached)
+	assert.Equal(t, 2, summary.Donors)
+	assert.Equal(t, 3, summary.Donations)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestCampaignUseCase_CloseExpiredCampaigns(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	expired := openCampaign()
+	expired.Deadline = time.Now().Add(-time.Minute)
+	// Вторую кампанию закрыли вручную после выборки
+	closed := openCampaign()
+	closed.Status = entity.CampaignStatusClosed
+
+	repos.campaigns.On("GetExpiredCampaigns", mock.Anything, mock.Anything).Return([]uuid.UUID{closed.ID, expired.ID}, nil)
+	repos.campaigns.On("LockCampaign", mock.Anything, closed.ID).Return(closed, nil)
+	repos.campaigns.On("LockCampaign", mock.Anything, expired.ID).Return(expired, nil)
+	repos.campaigns.On("GetDonors", mock.Anything, expired.ID).Return([]entity.CampaignDonor(nil), nil)
+	repos.campaigns.On("CloseCampaign", mock.Anything, mock.MatchedBy(func(s *entity.CampaignSummary) bool {
+		return s.ID == expired.ID && s.ClosedBy == "" && !s.GoalReached
+	})).Return(nil)
+
+	require.NoError(t, uc.CloseExpiredCampaigns(context.Background()))
+
+	assert.True(t, repos.tx.committed)
+	// Фоновое закрытие не пишет журнал действий администраторов
+	repos.audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestCampaignUseCase_GetReport_ClosedUsesSavedSummary(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCampaignUseCase(repos)
+	campaign := openCampaign()
+	campaign.Status = entity.CampaignStatusClosed
+	saved := &entity.CampaignSummary{Campaign: *campaign, Donations: 7, ClosedBy: "admin"}
+
+	repos.campaigns.On("GetCampaign", mock.Anything, campaign.ID).Return(campaign, nil)
+	repos.campaigns.On("GetSummary", mock.Anything, campaign.ID).Return(saved, nil)
+
+	summary, err := uc.GetReport(context.Background(), campaign.ID)
+
+	require.NoError(t, err)
+	assert.Same(t, saved, summary)
+	repos.campaigns.AssertNotCalled(t, "GetDonors", mock.Anything, mock.Anything)
+}
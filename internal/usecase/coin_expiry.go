@@ -0,0 +1,284 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// coinExpiryBatchSize сколько пользователей или партий обрабатывается за одну транзакцию
+const coinExpiryBatchSize = 100
+
+// CoinExpiryConfig срок жизни монет. TTL 0 - монеты не сгорают
+type CoinExpiryConfig struct {
+	TTL time.Duration
+	// WarningPeriod за сколько до сгорания монеты попадают в expiringSoon и пользователь получает предупреждение
+	WarningPeriod time.Duration
+}
+
+// ExpiryNotifier доставляет пользователю предупреждение о скором сгорании монет
+type ExpiryNotifier interface {
+	NotifyExpiringCoins(ctx context.Context, userName string, amount int, expiresAt time.Time) error
+}
+
+// LogExpiryNotifier пишет предупреждения в журнал уведомлений. У журнала свой уровень,
+// поэтому предупреждения не отбрасываются уровнем основного журнала сервиса
+type LogExpiryNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogExpiryNotifier(w io.Writer) *LogExpiryNotifier {
+	return &LogExpiryNotifier{logger: slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelInfo}))}
+}
+
+func (n *LogExpiryNotifier) NotifyExpiringCoins(ctx context.Context, userName string, amount int, expiresAt time.Time) error {
+	n.logger.InfoContext(ctx, "Coins expiring soon", "user", userName, "amount", amount, "expiresAt", expiresAt)
+	return nil
+}
+
+// CoinExpiryRepository партии монет вне транзакции
+type CoinExpiryRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	DeleteEmptyLots(ctx context.Context) error
+}
+
+// ExpiringLotRepository предупреждения о сгорании партий и поиск просроченных партий в транзакции
+type ExpiringLotRepository interface {
+	LockUnwarnedLots(ctx context.Context, cutoff time.Time, after entity.CoinLot, limit int) ([]entity.CoinLot, error)
+	MarkLotWarned(ctx context.Context, lot entity.CoinLot) error
+	LockUsersWithExpiredLots(ctx context.Context, cutoff time.Time, after string, limit int) ([]string, error)
+}
+
+type CoinExpiryUseCase struct {
+	lotRepo  CoinExpiryRepository
+	notifier ExpiryNotifier
+	cfg      CoinExpiryConfig
+	txRepos  func(tx pgx.Tx) *TxRepositories
+}
+
+func NewCoinExpiryUseCase(lotRepo CoinExpiryRepository, notifier ExpiryNotifier, cfg CoinExpiryConfig) *CoinExpiryUseCase {
+	return &CoinExpiryUseCase{lotRepo: lotRepo, notifier: notifier, cfg: cfg, txRepos: NewTxRepositories}
+}
+
+// RunCoinExpiry фоновая задача: предупреждает о скором сгорании монет и списывает просроченные
+func (uc *CoinExpiryUseCase) RunCoinExpiry(ctx context.Context) error {
+	if uc.cfg.TTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	if err := uc.warnExpiringCoins(ctx, now); err != nil {
+		return err
+	}
+	return uc.expireCoins(ctx, now)
+}
+
+// warnExpiringCoins предупреждает пользователей о партиях, которые сгорят в течение WarningPeriod.
+// Партия отмечается предупрежденной только после доставки. Недоставленные предупреждения
+// пропускаются до следующего запуска и не задерживают остальные
+func (uc *CoinExpiryUseCase) warnExpiringCoins(ctx context.Context, now time.Time) error {
+	cutoff := expiryCutoff(now.Add(uc.cfg.WarningPeriod), uc.cfg.TTL)
+	var after entity.CoinLot
+	for {
+		last, full, err := uc.warnBatch(ctx, cutoff, after)
+		if err != nil {
+			return err
+		}
+		if !full {
+			return nil
+		}
+		after = last
+	}
+}
+
+// warnBatch доставляет предупреждения по пачке партий после партии after. Возвращает последнюю
+// выбранную партию и признак полной пачки
+func (uc *CoinExpiryUseCase) warnBatch(ctx context.Context, cutoff time.Time, after entity.CoinLot) (entity.CoinLot, bool, error) {
+	tx, err := uc.lotRepo.Begin(ctx)
+	if err != nil {
+		return entity.CoinLot{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	lotRepo := uc.txRepos(tx).CoinLots
+	lots, err := lotRepo.LockUnwarnedLots(ctx, cutoff, after, coinExpiryBatchSize)
+	if err != nil {
+		return entity.CoinLot{}, false, err
+	}
+
+	for _, lot := range lots {
+		if err := uc.notifier.NotifyExpiringCoins(ctx, lot.UserName, lot.Amount, lot.GrantedOn.Add(uc.cfg.TTL)); err != nil {
+			slog.Error("Failed to deliver coin expiry warning", "user", lot.UserName, "error", err)
+			continue
+		}
+		if err := lotRepo.MarkLotWarned(ctx, lot); err != nil {
+			return entity.CoinLot{}, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entity.CoinLot{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if len(lots) < coinExpiryBatchSize {
+		return entity.CoinLot{}, false, nil
+	}
+	return lots[len(lots)-1], true, nil
+}
+
+// expireCoins списывает монеты из просроченных партий пользователей и кошельков команд на счет сгоревших монет
+func (uc *CoinExpiryUseCase) expireCoins(ctx context.Context, now time.Time) error {
+	cutoff := expiryCutoff(now, uc.cfg.TTL)
+	users, total := 0, 0
+	after := ""
+	for {
+		last, processed, expired, err := uc.expireBatch(ctx, cutoff, after)
+		if err != nil {
+			return err
+		}
+		users += processed
+		total += expired
+		if last == "" {
+			break
+		}
+		after = last
+	}
+
+	teams, teamTotal := 0, 0
+	afterTeam := uuid.Nil
+	for {
+		last, processed, expired, err := uc.expireTeamBatch(ctx, cutoff, afterTeam)
+		if err != nil {
+			return err
+		}
+		teams += processed
+		teamTotal += expired
+		if last == uuid.Nil {
+			break
+		}
+		afterTeam = last
+	}
+
+	if err := uc.lotRepo.DeleteEmptyLots(ctx); err != nil {
+		return err
+	}
+	if total > 0 || teamTotal > 0 {
+		slog.Info("Coins expired", "users", users, "total", total, "teams", teams, "teamTotal", teamTotal)
+	}
+	return nil
+}
+
+// expireBatch обрабатывает пачку пользователей с именем больше after. Возвращает имя последнего
+// пользователя пачки (пустое, если пачка неполная), число пользователей со сгоревшими монетами и их сумму
+func (uc *CoinExpiryUseCase) expireBatch(ctx context.Context, cutoff time.Time, after string) (string, int, int, error) {
+	tx, err := uc.lotRepo.Begin(ctx)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	users, err := repos.CoinLots.LockUsersWithExpiredLots(ctx, cutoff, after, coinExpiryBatchSize)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	description := "coins received on or before " + cutoff.Format(time.DateOnly)
+	processed, total := 0, 0
+	for _, user := range users {
+		expired, err := repos.Users.ExpireCoins(ctx, user, cutoff)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		if expired == 0 {
+			continue
+		}
+		if err := repos.Ledger.RecordExpiry(ctx, user, expired, description); err != nil {
+			return "", 0, 0, fmt.Errorf("failed to record expiry in ledger: %w", err)
+		}
+		processed++
+		total += expired
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	last := ""
+	if len(users) == coinExpiryBatchSize {
+		last = users[len(users)-1]
+	}
+	return last, processed, total, nil
+}
+
+// expireTeamBatch обрабатывает пачку кошельков команд с идентификатором больше after так же, как expireBatch
+func (uc *CoinExpiryUseCase) expireTeamBatch(ctx context.Context, cutoff time.Time, after uuid.UUID) (uuid.UUID, int, int, error) {
+	tx, err := uc.lotRepo.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	teams, err := repos.Teams.LockTeamsWithExpiredLots(ctx, cutoff, after, coinExpiryBatchSize)
+	if err != nil {
+		return uuid.Nil, 0, 0, err
+	}
+
+	description := "coins received on or before " + cutoff.Format(time.DateOnly)
+	processed, total := 0, 0
+	for _, team := range teams {
+		expired, err := repos.Teams.ExpireCoins(ctx, team, cutoff)
+		if err != nil {
+			return uuid.Nil, 0, 0, err
+		}
+		if expired == 0 {
+			continue
+		}
+		if err := repos.Ledger.RecordTeamExpiry(ctx, team, expired, description); err != nil {
+			return uuid.Nil, 0, 0, fmt.Errorf("failed to record team expiry in ledger: %w", err)
+		}
+		processed++
+		total += expired
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	last := uuid.Nil
+	if len(teams) == coinExpiryBatchSize {
+		last = teams[len(teams)-1]
+	}
+	return last, processed, total, nil
+}
+
+// expiryCutoff возвращает последний день получения монет, сгоревших к моменту t при сроке жизни ttl
+func expiryCutoff(t time.Time, ttl time.Duration) time.Time {
+	return entity.LotDate(t.Add(-ttl))
+}
+
+// expiringCoins переводит партии в монеты со сроком сгорания
+func expiringCoins(lots []entity.CoinLot, ttl time.Duration) []entity.ExpiringCoins {
+	expiring := make([]entity.ExpiringCoins, 0, len(lots))
+	for _, lot := range lots {
+		expiring = append(expiring, entity.ExpiringCoins{Amount: lot.Amount, ExpiresAt: lot.GrantedOn.Add(ttl)})
+	}
+	return expiring
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCoinExpiryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCoinExpiryRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockCoinExpiryRepository) DeleteEmptyLots(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+type MockExpiringLotRepository struct {
+	mock.Mock
+}
+
+func (m *MockExpiringLotRepository) LockUnwarnedLots(ctx context.Context, cutoff time.Time, after entity.CoinLot, limit int) ([]entity.CoinLot, error) {
+	args := m.Called(ctx, cutoff, after, limit)
+	return args.Get(0).([]entity.CoinLot), args.Error(1)
+}
+
+func (m *MockExpiringLotRepository) MarkLotWarned(ctx context.Context, lot entity.CoinLot) error {
+	args := m.Called(ctx, lot)
+	return args.Error(0)
+}
+
+func (m *MockExpiringLotRepository) LockUsersWithExpiredLots(ctx context.Context, cutoff time.Time, after string, limit int) ([]string, error) {
+	args := m.Called(ctx, cutoff, after, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type MockExpiryNotifier struct {
+	mock.Mock
+}
+
+func (m *MockExpiryNotifier) NotifyExpiringCoins(ctx context.Context, userName string, amount int, expiresAt time.Time) error {
+	args := m.Called(ctx, userName, amount, expiresAt)
+	return args.Error(0)
+}
+
+func TestExpiryCutoff(t *testing.T) {
+	ttl := 365 * 24 * time.Hour
+	grantedOn := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	// Партия сгорает ровно через ttl после начала дня получения
+	assert.True(t, grantedOn.After(expiryCutoff(grantedOn.Add(ttl-time.Minute), ttl)))
+	assert.False(t, grantedOn.After(expiryCutoff(grantedOn.Add(ttl), ttl)))
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	assert.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		expiryCutoff(time.Date(2025, 3, 10, 1, 0, 0, 0, moscow), ttl))
+}
+
+func TestExpiringCoins(t *testing.T) {
+	ttl := 30 * 24 * time.Hour
+	grantedOn := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expiring := expiringCoins([]entity.CoinLot{{GrantedOn: grantedOn, Amount: 70}}, ttl)
+	assert.Equal(t, []entity.ExpiringCoins{{Amount: 70, ExpiresAt: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)}}, expiring)
+	assert.NotNil(t, expiringCoins(nil, ttl))
+}
+
+func TestCoinExpiryUseCase_WarnExpiringCoins_MarksOnlyDelivered(t *testing.T) {
+	repos := newMockRepos()
+	notifier := new(MockExpiryNotifier)
+	ttl := 30 * 24 * time.Hour
+	uc := newTestCoinExpiryUseCase(repos, new(MockCoinExpiryRepository), notifier, ttl)
+
+	grantedOn := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	delivered := entity.CoinLot{UserName: "bob", GrantedOn: grantedOn, Amount: 100}
+	failed := entity.CoinLot{UserName: "carol", GrantedOn: grantedOn, Amount: 50}
+	repos.coinLots.On("LockUnwarnedLots", mock.Anything, mock.Anything, entity.CoinLot{}, coinExpiryBatchSize).
+		Return([]entity.CoinLot{delivered, failed}, nil).Once()
+	notifier.On("NotifyExpiringCoins", mock.Anything, "bob", 100, grantedOn.Add(ttl)).Return(nil)
+	notifier.On("NotifyExpiringCoins", mock.Anything, "carol", 50, grantedOn.Add(ttl)).Return(errors.New("mailbox unavailable"))
+	repos.coinLots.On("MarkLotWarned", mock.Anything, delivered).Return(nil).Once()
+
+	err := uc.warnExpiringCoins(context.Background(), time.Now())
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.coinLots.AssertNotCalled(t, "MarkLotWarned", mock.Anything, failed)
+	repos.assertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestCoinExpiryUseCase_WarnExpiringCoins_SkipsUndeliveredLot(t *testing.T) {
+	repos := newMockRepos()
+	notifier := new(MockExpiryNotifier)
+	uc := newTestCoinExpiryUseCase(repos, new(MockCoinExpiryRepository), notifier, 30*24*time.Hour)
+
+	grantedOn := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	batch := make([]entity.CoinLot, coinExpiryBatchSize)
+	for i := range batch {
+		batch[i] = entity.CoinLot{UserName: fmt.Sprintf("user%03d", i), GrantedOn: grantedOn, Amount: 10}
+	}
+	next := entity.CoinLot{UserName: "zoe", GrantedOn: grantedOn, Amount: 10}
+	repos.coinLots.On("LockUnwarnedLots", mock.Anything, mock.Anything, entity.CoinLot{}, coinExpiryBatchSize).
+		Return(batch, nil).Once()
+	repos.coinLots.On("LockUnwarnedLots", mock.Anything, mock.Anything, batch[len(batch)-1], coinExpiryBatchSize).
+		Return([]entity.CoinLot{next}, nil).Once()
+	// Первая партия пачки недоставляема, но остальные и следующая пачка все равно обрабатываются
+	notifier.On("NotifyExpiringCoins", mock.Anything, batch[0].UserName, mock.Anything, mock.Anything).Return(errors.New("mailbox unavailable"))
+	notifier.On("NotifyExpiringCoins", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	repos.coinLots.On("MarkLotWarned", mock.Anything, mock.Anything).Return(nil)
+
+	err := uc.warnExpiringCoins(context.Background(), time.Now())
+
+	require.NoError(t, err)
+	repos.coinLots.AssertNumberOfCalls(t, "MarkLotWarned", coinExpiryBatchSize)
+	repos.coinLots.AssertCalled(t, "MarkLotWarned", mock.Anything, next)
+	repos.coinLots.AssertNotCalled(t, "MarkLotWarned", mock.Anything, batch[0])
+}
+
+func TestCoinExpiryUseCase_ExpireCoins_RecordsExpiredAmounts(t *testing.T) {
+	repos := newMockRepos()
+	lotRepo := new(MockCoinExpiryRepository)
+	uc := newTestCoinExpiryUseCase(repos, lotRepo, new(MockExpiryNotifier), 30*24*time.Hour)
+	now := time.Now()
+	cutoff := expiryCutoff(now, uc.cfg.TTL)
+	teamID := uuid.New()
+
+	repos.coinLots.On("LockUsersWithExpiredLots", mock.Anything, cutoff, "", coinExpiryBatchSize).
+		Return([]string{"bob", "carol"}, nil).Once()
+	repos.users.On("ExpireCoins", mock.Anything, "bob", cutoff).Return(70, nil).Once()
+	repos.users.On("ExpireCoins", mock.Anything, "carol", cutoff).Return(0, nil).Once()
+	repos.ledger.On("RecordExpiry", mock.Anything, "bob", 70, mock.Anything).Return(nil).Once()
+	repos.teams.On("LockTeamsWithExpiredLots", mock.Anything, cutoff, uuid.Nil, coinExpiryBatchSize).
+		Return([]uuid.UUID{teamID}, nil).Once()
+	repos.teams.On("ExpireCoins", mock.Anything, teamID, cutoff).Return(30, nil).Once()
+	repos.ledger.On("RecordTeamExpiry", mock.Anything, teamID, 30, mock.Anything).Return(nil).Once()
+	lotRepo.On("DeleteEmptyLots", mock.Anything).Return(nil).Once()
+
+	err := uc.expireCoins(context.Background(), now)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.ledger.AssertNotCalled(t, "RecordExpiry", mock.Anything, "carol", mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+	lotRepo.AssertExpectations(t)
+}
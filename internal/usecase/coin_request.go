@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrCoinRequestNotFound   = errors.New("coin request not found")
+	ErrCoinRequestNotPending = errors.New("coin request is no longer pending")
+	ErrInvalidCoinRequest    = errors.New("invalid coin request query")
+)
+
+type CoinRequestRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateCoinRequest(ctx context.Context, request *entity.CoinRequest) error
+	GetCoinRequestForUpdate(ctx context.Context, id uuid.UUID) (*entity.CoinRequest, error)
+	Resolve(ctx context.Context, request *entity.CoinRequest, status string, transferID *uuid.UUID) error
+	GetCoinRequests(ctx context.Context, userName string, incoming bool, status string) ([]entity.CoinRequest, error)
+	ExpirePending(ctx context.Context) (int64, error)
+}
+
+type CoinRequestUseCase struct {
+	userRepo        UserAccountRepository
+	coinRequestRepo CoinRequestRepository
+	sendCoinUseCase *SendCoinUseCase
+	txRepos         func(tx pgx.Tx) *TxRepositories
+	ttl             time.Duration
+}
+
+func NewCoinRequestUseCase(
+	userRepo UserAccountRepository,
+	coinRequestRepo CoinRequestRepository,
+	sendCoinUseCase *SendCoinUseCase,
+	ttl time.Duration,
+) *CoinRequestUseCase {
+	return &CoinRequestUseCase{
+		userRepo:        userRepo,
+		coinRequestRepo: coinRequestRepo,
+		sendCoinUseCase: sendCoinUseCase,
+		txRepos:         NewTxRepositories,
+		ttl:             ttl,
+	}
+}
+
+// CreateCoinRequest выставляет плательщику запрос на перевод монет
+func (uc *CoinRequestUseCase) CreateCoinRequest(ctx context.Context, requester, payer string, amount int, memo string) (*entity.CoinRequest, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive: %d", amount)
+	}
+	if requester == payer {
+		return nil, fmt.Errorf("cannot request coins from yourself: %s", payer)
+	}
+
+	memo, err := sanitizeMemo(memo)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByUsername(ctx, payer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payer: %w", err)
+	}
+	if user == nil || user.DeactivatedAt != nil {
+		return nil, fmt.Errorf("payer does not exist: %s", payer)
+	}
+
+	request := &entity.CoinRequest{
+		Requester: requester,
+		Payer:     payer,
+		Amount:    amount,
+		Memo:      memo,
+		ExpiresAt: time.Now().Add(uc.ttl),
+	}
+	if err := uc.coinRequestRepo.CreateCoinRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to create coin request: %w", err)
+	}
+	return request, nil
+}
+
+// GetCoinRequests возвращает входящие или исходящие запросы пользователя, пустой status - в любом статусе
+func (uc *CoinRequestUseCase) GetCoinRequests(ctx context.Context, userName string, incoming bool, status string) ([]entity.CoinRequest, error) {
+	switch status {
+	case "", entity.CoinRequestStatusPending, entity.CoinRequestStatusAccepted, entity.CoinRequestStatusDeclined,
+		entity.CoinRequestStatusCancelled, entity.CoinRequestStatusExpired:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidCoinRequest, status)
+	}
+
+	requests, err := uc.coinRequestRepo.GetCoinRequests(ctx, userName, incoming, status)
+	if err != nil {
+		return nil, err
+	}
+	if requests == nil {
+		requests = []entity.CoinRequest{}
+	}
+	return requests, nil
+}
+
+// AcceptCoinRequest оплачивает запрос переводом монет запросившему.
+// Перевод выполняется по тем же правилам, что и обычный: крупная сумма ожидает подтверждения получателя.
+// Запрос блокируется на время перевода, поэтому оплатить его дважды нельзя
+func (uc *CoinRequestUseCase) AcceptCoinRequest(ctx context.Context, payer string, id uuid.UUID) (*entity.CoinRequest, error) {
+	var transfer *entity.Transaction
+	request, err := uc.resolve(ctx, id, func(request *entity.CoinRequest) bool { return request.Payer == payer },
+		func(tx pgx.Tx, request *entity.CoinRequest) (string, *uuid.UUID, error) {
+			var err error
+			transfer, err = uc.sendCoinUseCase.sendInTx(ctx, tx, request.Payer, request.Requester, request.Amount, request.Memo, false)
+			if err != nil {
+				return "", nil, err
+			}
+			return entity.CoinRequestStatusAccepted, &transfer.ID, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.Status == entity.TransferStatusCompleted {
+		uc.sendCoinUseCase.publishTransfer(ctx, request.Payer, request.Requester)
+	}
+	return request, nil
+}
+
+// DeclineCoinRequest отклоняет входящий запрос
+func (uc *CoinRequestUseCase) DeclineCoinRequest(ctx context.Context, payer string, id uuid.UUID) (*entity.CoinRequest, error) {
+	return uc.resolve(ctx, id, func(request *entity.CoinRequest) bool { return request.Payer == payer },
+		func(pgx.Tx, *entity.CoinRequest) (string, *uuid.UUID, error) {
+			return entity.CoinRequestStatusDeclined, nil, nil
+		})
+}
+
+// CancelCoinRequest отзывает исходящий запрос
+func (uc *CoinRequestUseCase) CancelCoinRequest(ctx context.Context, requester string, id uuid.UUID) (*entity.CoinRequest, error) {
+	return uc.resolve(ctx, id, func(request *entity.CoinRequest) bool { return request.Requester == requester },
+		func(pgx.Tx, *entity.CoinRequest) (string, *uuid.UUID, error) {
+			return entity.CoinRequestStatusCancelled, nil, nil
+		})
+}
+
+// ExpireCoinRequests помечает просроченные запросы
+func (uc *CoinRequestUseCase) ExpireCoinRequests(ctx context.Context) error {
+	expired, err := uc.coinRequestRepo.ExpirePending(ctx)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		slog.Info("Coin requests expired", "count", expired)
+	}
+	return nil
+}
+
+// resolve блокирует запрос, проверяет доступ и статус и переводит запрос
+// в статус, который вернул action, в одной транзакции с действием
+func (uc *CoinRequestUseCase) resolve(
+	ctx context.Context,
+	id uuid.UUID,
+	allowed func(request *entity.CoinRequest) bool,
+	action func(tx pgx.Tx, request *entity.CoinRequest) (string, *uuid.UUID, error),
+) (*entity.CoinRequest, error) {
+	tx, err := uc.coinRequestRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	coinRequestRepo := uc.txRepos(tx).CoinRequests
+
+	request, err := coinRequestRepo.GetCoinRequestForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || !allowed(request) {
+		return nil, ErrCoinRequestNotFound
+	}
+	if request.Status != entity.CoinRequestStatusPending {
+		return nil, fmt.Errorf("%w: %s", ErrCoinRequestNotPending, request.Status)
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrCoinRequestNotPending, entity.CoinRequestStatusExpired)
+	}
+
+	status, transferID, err := action(tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := coinRequestRepo.Resolve(ctx, request, status, transferID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Coin request resolved", "requestID", request.ID, "status", status)
+	return request, nil
+}
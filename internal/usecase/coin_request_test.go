@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCoinRequestRepository struct {
+	mock.Mock
+}
+
+func (m *MockCoinRequestRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockCoinRequestRepository) CreateCoinRequest(ctx context.Context, request *entity.CoinRequest) error {
+	args := m.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (m *MockCoinRequestRepository) GetCoinRequestForUpdate(ctx context.Context, id uuid.UUID) (*entity.CoinRequest, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.CoinRequest), args.Error(1)
+}
+
+func (m *MockCoinRequestRepository) Resolve(ctx context.Context, request *entity.CoinRequest, status string, transferID *uuid.UUID) error {
+	args := m.Called(ctx, request, status, transferID)
+	return args.Error(0)
+}
+
+func (m *MockCoinRequestRepository) GetCoinRequests(ctx context.Context, userName string, incoming bool, status string) ([]entity.CoinRequest, error) {
+	args := m.Called(ctx, userName, incoming, status)
+	return args.Get(0).([]entity.CoinRequest), args.Error(1)
+}
+
+func (m *MockCoinRequestRepository) ExpirePending(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestCoinRequestUseCase_Create_DeactivatedPayer(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	deactivatedAt := time.Now()
+	repos.users.On("GetUserByUsername", mock.Anything, "bob").
+		Return(&entity.User{Name: "bob", DeactivatedAt: &deactivatedAt}, nil)
+
+	request, err := uc.CreateCoinRequest(context.Background(), "alice", "bob", 30, "lunch")
+
+	assert.Error(t, err)
+	assert.Nil(t, request)
+	repos.coinRequests.AssertNotCalled(t, "CreateCoinRequest", mock.Anything, mock.Anything)
+}
+
+func TestCoinRequestUseCase_GetCoinRequests_InvalidStatus(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+
+	requests, err := uc.GetCoinRequests(context.Background(), "bob", true, "paid")
+
+	assert.ErrorIs(t, err, ErrInvalidCoinRequest)
+	assert.Nil(t, requests)
+	repos.coinRequests.AssertNotCalled(t, "GetCoinRequests", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCoinRequestUseCase_GetCoinRequests_ValidStatus(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	repos.coinRequests.On("GetCoinRequests", mock.Anything, "bob", true, entity.CoinRequestStatusPending).
+		Return([]entity.CoinRequest(nil), nil)
+
+	requests, err := uc.GetCoinRequests(context.Background(), "bob", true, entity.CoinRequestStatusPending)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.CoinRequest{}, requests)
+	repos.assertExpectations(t)
+}
+
+func TestCoinRequestUseCase_Accept_TransfersAndResolves(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	request := pendingCoinRequest()
+	transferID := uuid.New()
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+	expectTransfer(repos, "bob", "alice", 30, transferID)
+	repos.coinRequests.On("Resolve", mock.Anything, request, entity.CoinRequestStatusAccepted, &transferID).Return(nil)
+
+	handler := &recordingEventHandler{}
+	uc.sendCoinUseCase.events = handler
+
+	resolved, err := uc.AcceptCoinRequest(context.Background(), "bob", request.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, request, resolved)
+	assert.True(t, repos.tx.committed)
+	assert.Equal(t, []entity.Event{
+		{Kind: entity.EventTransferSent, UserName: "bob"},
+		{Kind: entity.EventTransferReceived, UserName: "alice"},
+	}, handler.events)
+	repos.assertExpectations(t)
+}
+
+func TestCoinRequestUseCase_Accept_AboveEscrowThreshold(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	uc.sendCoinUseCase = newTestSendCoinUseCase(repos, SendCoinConfig{EscrowThreshold: 30, PendingTransferTTL: time.Hour})
+	handler := &recordingEventHandler{}
+	uc.sendCoinUseCase.events = handler
+	request := pendingCoinRequest()
+	transferID := uuid.New()
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "alice"}).Return([]string{"alice", "bob"}, nil)
+	repos.users.On("GetUserByUsername", mock.Anything, "bob").Return(&entity.User{Name: "bob", Role: entity.RoleUser}, nil)
+	repos.users.On("HoldCoins", mock.Anything, "bob", 30).Return(nil)
+	repos.holds.On("CreateHold", mock.Anything, mock.Anything).Return(nil)
+	repos.transfers.On("CreateTransfer", mock.Anything, mock.MatchedBy(func(t *entity.Transaction) bool {
+		return t.Status == entity.TransferStatusPending && t.Amount == 30
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Transaction).ID = transferID
+	}).Return(nil)
+	repos.coinRequests.On("Resolve", mock.Anything, request, entity.CoinRequestStatusAccepted, &transferID).Return(nil)
+
+	_, err := uc.AcceptCoinRequest(context.Background(), "bob", request.ID)
+
+	assert.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	// Перевод ждет решения получателя, монеты еще не переведены
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, handler.events)
+	repos.assertExpectations(t)
+}
+
+func TestCoinRequestUseCase_Accept_NotPayer(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	request := pendingCoinRequest()
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+
+	_, err := uc.AcceptCoinRequest(context.Background(), "alice", request.ID)
+
+	assert.ErrorIs(t, err, ErrCoinRequestNotFound)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCoinRequestUseCase_Accept_AlreadyAccepted(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	request := pendingCoinRequest()
+	request.Status = entity.CoinRequestStatusAccepted
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+
+	_, err := uc.AcceptCoinRequest(context.Background(), "bob", request.ID)
+
+	assert.ErrorIs(t, err, ErrCoinRequestNotPending)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCoinRequestUseCase_Accept_InsufficientFunds(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	request := pendingCoinRequest()
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "alice"}).Return([]string{"alice", "bob"}, nil)
+	repos.users.On("GetUserByUsername", mock.Anything, "bob").Return(&entity.User{Name: "bob"}, nil)
+	repos.users.On("UpdateUserAfterTransfer", mock.Anything, "bob", "alice", 30).Return(errors.New("insufficient coins"))
+
+	_, err := uc.AcceptCoinRequest(context.Background(), "bob", request.ID)
+
+	assert.Error(t, err)
+	assert.False(t, repos.tx.committed)
+	repos.coinRequests.AssertNotCalled(t, "Resolve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCoinRequestUseCase_Decline(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestCoinRequestUseCase(repos)
+	request := pendingCoinRequest()
+
+	repos.coinRequests.On("GetCoinRequestForUpdate", mock.Anything, request.ID).Return(request, nil)
+	repos.coinRequests.On("Resolve", mock.Anything, request, entity.CoinRequestStatusDeclined, (*uuid.UUID)(nil)).Return(nil)
+
+	_, err := uc.DeclineCoinRequest(context.Background(), "bob", request.ID)
+
+	assert.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
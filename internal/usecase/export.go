@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+)
+
+// HistoryExporter источник строк для выгрузки истории
+type HistoryExporter interface {
+	StreamHistory(ctx context.Context, filter entity.ExportFilter, fn func(record *entity.HistoryRecord) error) error
+}
+
+type ExportUseCase struct {
+	exporter HistoryExporter
+}
+
+func NewExportUseCase(exporter HistoryExporter) *ExportUseCase {
+	return &ExportUseCase{exporter: exporter}
+}
+
+// ExportHistory передает в fn переводы и покупки по фильтру, старые первыми.
+// Пустой filter.UserName выгружает историю всех пользователей
+func (uc *ExportUseCase) ExportHistory(ctx context.Context, filter entity.ExportFilter, fn func(record *entity.HistoryRecord) error) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidHistoryFilter)
+	}
+
+	return uc.exporter.StreamHistory(ctx, filter, func(record *entity.HistoryRecord) error {
+		// Отмена запроса прерывает выгрузку между строками
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(record)
+	})
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHistoryExporter struct {
+	mock.Mock
+	records []entity.HistoryRecord
+}
+
+func (m *MockHistoryExporter) StreamHistory(ctx context.Context, filter entity.ExportFilter, fn func(record *entity.HistoryRecord) error) error {
+	args := m.Called(ctx, filter)
+	for i := range m.records {
+		if err := fn(&m.records[i]); err != nil {
+			return err
+		}
+	}
+	return args.Error(0)
+}
+
+func TestExportHistory(t *testing.T) {
+	exporter := &MockHistoryExporter{records: []entity.HistoryRecord{
+		{Kind: entity.RecordKindTransfer, FromUser: "alice", ToUser: "bob", Amount: 10},
+		{Kind: entity.RecordKindPurchase, FromUser: "alice", Item: "cup", Amount: 20},
+	}}
+	uc := NewExportUseCase(exporter)
+
+	ctx := context.Background()
+	filter := entity.ExportFilter{UserName: "alice"}
+	exporter.On("StreamHistory", ctx, filter).Return(nil)
+
+	var kinds []string
+	err := uc.ExportHistory(ctx, filter, func(record *entity.HistoryRecord) error {
+		kinds = append(kinds, record.Kind)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.RecordKindTransfer, entity.RecordKindPurchase}, kinds)
+	exporter.AssertExpectations(t)
+}
+
+func TestExportHistory_Cancelled(t *testing.T) {
+	exporter := &MockHistoryExporter{records: []entity.HistoryRecord{{Kind: entity.RecordKindTransfer}, {Kind: entity.RecordKindTransfer}}}
+	uc := NewExportUseCase(exporter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.On("StreamHistory", ctx, entity.ExportFilter{}).Return(nil)
+
+	written := 0
+	err := uc.ExportHistory(ctx, entity.ExportFilter{}, func(*entity.HistoryRecord) error {
+		written++
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, written)
+}
+
+func TestExportHistory_InvalidRange(t *testing.T) {
+	uc := NewExportUseCase(&MockHistoryExporter{})
+	now := time.Now()
+
+	err := uc.ExportHistory(context.Background(), entity.ExportFilter{From: now, To: now.Add(-time.Hour)}, func(*entity.HistoryRecord) error { return nil })
+
+	assert.ErrorIs(t, err, ErrInvalidHistoryFilter)
+}
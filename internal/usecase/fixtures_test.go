@@ -0,0 +1,297 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// Сценарии, собранные на моках mockRepos, и общие для тестов сценариев данные
+
+// recordingEventHandler запоминает опубликованные события
+type recordingEventHandler struct {
+	events []entity.Event
+}
+
+func (h *recordingEventHandler) HandleEvent(ctx context.Context, event entity.Event) {
+	h.events = append(h.events, event)
+}
+
+// newTestSendCoinUseCase создает сценарий переводов, работающий с моками repos
+func newTestSendCoinUseCase(repos *mockRepos, cfg SendCoinConfig) *SendCoinUseCase {
+	repos.transfers.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewSendCoinUseCase(repos.users, repos.transfers, cfg, nil)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+// expectTransfer ожидает перевод fromUser -> toUser без лимитов и, как репозиторий, присваивает ему id
+// и статус завершенного
+func expectTransfer(repos *mockRepos, fromUser, toUser string, amount int, id uuid.UUID) {
+	repos.users.On("LockUsers", mock.Anything, []string{fromUser, toUser}).Return([]string{fromUser, toUser}, nil).Once()
+	repos.users.On("GetUserByUsername", mock.Anything, fromUser).Return(&entity.User{Name: fromUser, Role: entity.RoleUser}, nil).Once()
+	repos.users.On("UpdateUserAfterTransfer", mock.Anything, fromUser, toUser, amount).Return(nil).Once()
+	repos.transfers.On("CreateTransfer", mock.Anything, mock.MatchedBy(func(t *entity.Transaction) bool {
+		return t.FromUser == fromUser && t.ToUser == toUser && t.Amount == amount
+	})).Run(func(args mock.Arguments) {
+		transfer := args.Get(1).(*entity.Transaction)
+		transfer.ID = id
+		transfer.Status = entity.TransferStatusCompleted
+	}).Return(nil).Once()
+	repos.ledger.On("RecordTransfer", mock.Anything, mock.MatchedBy(func(t *entity.Transaction) bool { return t.ID == id })).Return(nil).Once()
+}
+
+// dailyLimits лимиты, при которых уже отправленные 80 монет оставляют 20 на сутки
+var dailyLimits = SendCoinConfig{Limits: map[string]entity.TransferLimits{entity.RoleUser: {Daily: 100}}}
+
+// expectSentToday ожидает проверку лимитов отправителя, который уже отправил sent монет за сутки
+func expectSentToday(repos *mockRepos, sender, recipient string, sent int) {
+	repos.users.On("GetUserByUsername", mock.Anything, sender).
+		Return(&entity.User{Name: sender, Role: entity.RoleUser, CreatedAt: time.Now().AddDate(-1, 0, 0)}, nil)
+	repos.transfers.On("GetSentTotals", mock.Anything, sender, []string{recipient}, mock.Anything, mock.Anything).
+		Return(&entity.SentTotals{Daily: sent, Weekly: sent, DailyByRecipient: map[string]int{}}, nil)
+}
+
+func newTestScheduledTransferUseCase(repos *mockRepos) *ScheduledTransferUseCase {
+	repos.scheduled.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewScheduledTransferUseCase(repos.users, repos.scheduled, newTestSendCoinUseCase(repos, SendCoinConfig{}))
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func newTestTeamUseCase(repos *mockRepos, cfg SendCoinConfig) *TeamUseCase {
+	repos.teams.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewTeamUseCase(repos.teams, newTestSendCoinUseCase(repos, cfg))
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func testTeam() *entity.Team {
+	return &entity.Team{
+		ID:   uuid.New(),
+		Name: "platform",
+		Members: []entity.TeamMember{
+			{UserName: "alice", Role: entity.TeamRoleOwner},
+			{UserName: "bob", Role: entity.TeamRoleMember},
+		},
+	}
+}
+
+func newTestAllowanceUseCase(repos *mockRepos, cfg AllowanceConfig) *AllowanceUseCase {
+	repos.allowance.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewAllowanceUseCase(repos.allowance, cfg)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+// paymentFor сопоставляет выплату пособия пользователю userName
+func paymentFor(userName string) interface{} {
+	return mock.MatchedBy(func(p *entity.AllowancePayment) bool { return p.UserName == userName })
+}
+
+func newTestAuctionUseCase(repos *mockRepos) *AuctionUseCase {
+	repos.auctions.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewAuctionUseCase(repos.auctions, 10, time.Minute, nil)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+// activeAuction аукцион, на который bob поставил 100 монет
+func activeAuction() *entity.Auction {
+	holdID := uuid.New()
+	return &entity.Auction{
+		ID:           uuid.New(),
+		ItemName:     "hoody",
+		ReservePrice: 100,
+		ReserveMet:   true,
+		StartsAt:     time.Now().Add(-time.Hour),
+		EndsAt:       time.Now().Add(time.Hour),
+		Status:       entity.AuctionStatusActive,
+		TopBid:       100,
+		TopBidder:    "bob",
+		TopHoldID:    &holdID,
+	}
+}
+
+// endedAuction аукцион, время которого истекло
+func endedAuction() *entity.Auction {
+	auction := activeAuction()
+	auction.EndsAt = time.Now().Add(-time.Minute)
+	return auction
+}
+
+func newTestCampaignUseCase(repos *mockRepos) *CampaignUseCase {
+	repos.campaigns.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewCampaignUseCase(repos.campaigns)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func openCampaign() *entity.Campaign {
+	return &entity.Campaign{
+		ID:       uuid.New(),
+		Name:     "Shelter",
+		Goal:     500,
+		Raised:   450,
+		Status:   entity.CampaignStatusOpen,
+		Deadline: time.Now().Add(24 * time.Hour),
+	}
+}
+
+func newTestCoinExpiryUseCase(repos *mockRepos, lotRepo *MockCoinExpiryRepository, notifier ExpiryNotifier, ttl time.Duration) *CoinExpiryUseCase {
+	lotRepo.On("Begin", mock.Anything).Return(repos.tx, nil)
+	uc := NewCoinExpiryUseCase(lotRepo, notifier, CoinExpiryConfig{TTL: ttl, WarningPeriod: 7 * 24 * time.Hour})
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func newTestCoinRequestUseCase(repos *mockRepos) *CoinRequestUseCase {
+	repos.coinRequests.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewCoinRequestUseCase(repos.users, repos.coinRequests, newTestSendCoinUseCase(repos, SendCoinConfig{}), time.Hour)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func pendingCoinRequest() *entity.CoinRequest {
+	return &entity.CoinRequest{
+		ID:        uuid.New(),
+		Requester: "alice",
+		Payer:     "bob",
+		Amount:    30,
+		Memo:      "lunch",
+		Status:    entity.CoinRequestStatusPending,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func newTestGrantUseCase(repos *mockRepos, cfg GrantConfig) *GrantUseCase {
+	repos.grants.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewGrantUseCase(repos.grants, cfg)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+// expectMint ожидает выпуск amount монет пользователю userName проводкой вида kind
+func expectMint(repos *mockRepos, kind, userName string, amount int) {
+	repos.users.On("CreditCoins", mock.Anything, userName, amount).Return(nil).Once()
+	repos.ledger.On("RecordIssuance", mock.Anything, kind, userName, amount, mock.Anything, mock.Anything).Return(nil).Once()
+}
+
+func newTestMarketplaceUseCase(repos *mockRepos, cfg SendCoinConfig) *MarketplaceUseCase {
+	repos.marketplace.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewMarketplaceUseCase(repos.marketplace, newTestSendCoinUseCase(repos, cfg))
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func activeListing() *entity.Listing {
+	return &entity.Listing{
+		ID:        uuid.New(),
+		Seller:    "alice",
+		ItemName:  "pen",
+		Price:     10,
+		Quantity:  5,
+		Remaining: 3,
+		Status:    entity.ListingStatusActive,
+	}
+}
+
+func pendingTransfer() *entity.Transaction {
+	holdID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	return &entity.Transaction{
+		ID:        uuid.New(),
+		FromUser:  "alice",
+		ToUser:    "bob",
+		Amount:    40,
+		Status:    entity.TransferStatusPending,
+		HoldID:    &holdID,
+		ExpiresAt: &expiresAt,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+}
+
+// expectSettlement ожидает завершение перевода transfer со статусом status
+func expectSettlement(repos *mockRepos, transfer *entity.Transaction, status string) {
+	holdStatus := entity.HoldStatusReleased
+	if status == entity.TransferStatusCompleted {
+		holdStatus = entity.HoldStatusCaptured
+	}
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, transfer.ID).Return(transfer, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{transfer.FromUser, transfer.ToUser}).
+		Return([]string{transfer.FromUser, transfer.ToUser}, nil)
+	repos.holds.On("ResolveHold", mock.Anything, *transfer.HoldID, holdStatus).
+		Return(&entity.Hold{ID: *transfer.HoldID, UserName: transfer.FromUser, Amount: transfer.Amount}, nil)
+	repos.users.On("ReleaseHeldCoins", mock.Anything, transfer.FromUser, transfer.Amount).Return(nil)
+	if status == entity.TransferStatusCompleted {
+		repos.users.On("UpdateUserAfterTransfer", mock.Anything, transfer.FromUser, transfer.ToUser, transfer.Amount).Return(nil)
+		repos.ledger.On("RecordTransfer", mock.Anything, transfer).Return(nil)
+	}
+	repos.transfers.On("ResolvePendingTransfer", mock.Anything, transfer, status).Return(nil)
+}
+
+func newTestPreorderUseCase(repos *mockRepos) *PreorderUseCase {
+	repos.preorders.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewPreorderUseCase(repos.preorders, time.Hour)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func pendingPreorder(userName, itemName string, createdAt time.Time) entity.Preorder {
+	return entity.Preorder{
+		ID:        uuid.New(),
+		UserName:  userName,
+		ItemName:  itemName,
+		Price:     20,
+		HoldID:    uuid.New(),
+		Status:    entity.PreorderStatusPending,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+	}
+}
+
+// expectRelease ожидает разморозку монет предзаказа и его завершение со статусом status
+func expectRelease(repos *mockRepos, preorder entity.Preorder, status string) {
+	repos.holds.On("ResolveHold", mock.Anything, preorder.HoldID, entity.HoldStatusReleased).
+		Return(&entity.Hold{ID: preorder.HoldID, UserName: preorder.UserName, Amount: preorder.Price}, nil).Once()
+	repos.users.On("ReleaseHeldCoins", mock.Anything, preorder.UserName, preorder.Price).Return(nil).Once()
+	repos.preorders.On("SetStatus", mock.Anything, preorder.ID, status).Return(nil).Once()
+}
+
+func newTestReversalUseCase(repos *mockRepos) *ReversalUseCase {
+	repos.transfers.On("Begin", mock.Anything).Return(repos.tx, nil).Maybe()
+	uc := NewReversalUseCase(repos.transfers)
+	uc.txRepos = repos.txRepos
+	return uc
+}
+
+func completedTransfer() *entity.Transaction {
+	return &entity.Transaction{
+		ID:       uuid.New(),
+		FromUser: "alice",
+		ToUser:   "bob",
+		Amount:   50,
+		Status:   entity.TransferStatusCompleted,
+	}
+}
+
+// expectReversal ожидает сторнирование original на amount монет при балансе получателя coins
+func expectReversal(repos *mockRepos, original *entity.Transaction, coins, amount int) {
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, original.ID).Return(original, nil)
+	repos.transfers.On("GetReversal", mock.Anything, original.ID).Return((*entity.Transaction)(nil), nil)
+	repos.users.On("LockUsers", mock.Anything, []string{original.FromUser, original.ToUser}).
+		Return([]string{original.FromUser, original.ToUser}, nil)
+	repos.users.On("GetUserByUsername", mock.Anything, original.ToUser).
+		Return(&entity.User{Name: original.ToUser, Coins: coins}, nil)
+	if amount == 0 {
+		return
+	}
+	repos.users.On("UpdateUserAfterTransfer", mock.Anything, original.ToUser, original.FromUser, amount).Return(nil)
+	repos.transfers.On("CreateTransfer", mock.Anything, mock.MatchedBy(func(t *entity.Transaction) bool {
+		return t.Amount == amount && t.ReversalOf != nil && *t.ReversalOf == original.ID
+	})).Return(nil)
+	repos.ledger.On("RecordReversal", mock.Anything, mock.Anything, "reversed by admin").Return(nil)
+}
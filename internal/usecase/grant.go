@@ -0,0 +1,319 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxGrantRecipients максимальное число получателей одного начисления
+	maxGrantRecipients = 1000
+	// maxGrantReasonLength максимальная длина причины начисления в символах
+	maxGrantReasonLength = 255
+	// grantsLimit сколько начислений возвращается в списке
+	grantsLimit = 100
+)
+
+var (
+	ErrInvalidGrant     = errors.New("invalid grant")
+	ErrGrantNotFound    = errors.New("grant not found")
+	ErrGrantNotPending  = errors.New("grant is not pending")
+	ErrGrantSelfApprove = errors.New("grant must be approved by another admin")
+	// ErrIssuanceBudgetExceeded начисление не укладывается в месячный бюджет эмиссии
+	ErrIssuanceBudgetExceeded = repository.ErrIssuanceBudgetExceeded
+)
+
+// GrantConfig настройки начислений
+type GrantConfig struct {
+	// MonthlyBudget сколько монет можно выпустить начислениями за календарный месяц, 0 - без ограничения
+	MonthlyBudget int
+	// ApprovalThreshold сумма начисления, начиная с которой нужно подтверждение второго администратора, 0 - не нужно
+	ApprovalThreshold int
+}
+
+// GrantRepository начисления и месячный бюджет эмиссии
+type GrantRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateGrant(ctx context.Context, grant *entity.Grant) error
+	GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error)
+	GetGrantForUpdate(ctx context.Context, id uuid.UUID) (*entity.Grant, error)
+	GetGrants(ctx context.Context, status string, limit int) ([]entity.Grant, error)
+	ResolveGrant(ctx context.Context, grant *entity.Grant, status, resolvedBy string) error
+	ReserveIssuance(ctx context.Context, period time.Time, amount, budget int) error
+	GetIssued(ctx context.Context, period time.Time) (int, error)
+}
+
+// GrantUseCase начисления монет администраторами со счета эмиссии
+type GrantUseCase struct {
+	grantRepo GrantRepository
+	cfg       GrantConfig
+	txRepos   func(tx pgx.Tx) *TxRepositories
+}
+
+func NewGrantUseCase(grantRepo GrantRepository, cfg GrantConfig) *GrantUseCase {
+	return &GrantUseCase{grantRepo: grantRepo, cfg: cfg, txRepos: NewTxRepositories}
+}
+
+// CreateGrant создает начисление. Начисление ниже порога выполняется сразу,
+// от порога - ждет подтверждения другим администратором
+func (uc *GrantUseCase) CreateGrant(ctx context.Context, admin, reason string, recipients []entity.GrantItem) (*entity.Grant, error) {
+	grant, err := validateGrant(reason, recipients)
+	if err != nil {
+		return nil, err
+	}
+	grant.CreatedBy = admin
+	grant.Status = entity.GrantStatusCompleted
+	if uc.cfg.ApprovalThreshold > 0 && grant.Total >= uc.cfg.ApprovalThreshold {
+		grant.Status = entity.GrantStatusPending
+	}
+
+	tx, err := uc.grantRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	if err := lockGrantRecipients(ctx, repos, grant); err != nil {
+		return nil, err
+	}
+
+	if err := repos.Grants.CreateGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	if grant.Status == entity.GrantStatusCompleted {
+		if err := uc.executeGrant(ctx, repos, grant); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+		Actor:    admin,
+		Action:   entity.AuditActionGrantCreated,
+		TargetID: &grant.ID,
+		Details: map[string]any{
+			"reason":     grant.Reason,
+			"total":      grant.Total,
+			"recipients": len(grant.Recipients),
+			"status":     grant.Status,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Grant created", "grantID", grant.ID, "admin", admin, "total", grant.Total, "status", grant.Status)
+	return grant, nil
+}
+
+// ApproveGrant подтверждает и выполняет ожидающее начисление. Подтвердить можно только чужое начисление
+func (uc *GrantUseCase) ApproveGrant(ctx context.Context, admin string, id uuid.UUID) (*entity.Grant, error) {
+	return uc.resolveGrant(ctx, admin, id, entity.GrantStatusCompleted, "")
+}
+
+// RejectGrant отклоняет ожидающее начисление, монеты не выпускаются
+func (uc *GrantUseCase) RejectGrant(ctx context.Context, admin string, id uuid.UUID, comment string) (*entity.Grant, error) {
+	return uc.resolveGrant(ctx, admin, id, entity.GrantStatusRejected, strings.TrimSpace(comment))
+}
+
+// GetGrant возвращает начисление
+func (uc *GrantUseCase) GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	grant, err := uc.grantRepo.GetGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+// GetGrants возвращает последние начисления, пустой status - в любом статусе
+func (uc *GrantUseCase) GetGrants(ctx context.Context, status string) ([]entity.Grant, error) {
+	switch status {
+	case "", entity.GrantStatusPending, entity.GrantStatusCompleted, entity.GrantStatusRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidGrant, status)
+	}
+
+	grants, err := uc.grantRepo.GetGrants(ctx, status, grantsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if grants == nil {
+		grants = []entity.Grant{}
+	}
+	return grants, nil
+}
+
+// GetBudget возвращает выпуск монет начислениями в текущем месяце
+func (uc *GrantUseCase) GetBudget(ctx context.Context) (*entity.IssuanceBudget, error) {
+	period := issuancePeriod(time.Now())
+	issued, err := uc.grantRepo.GetIssued(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.IssuanceBudget{Period: period, Issued: issued, Budget: uc.cfg.MonthlyBudget}, nil
+}
+
+func (uc *GrantUseCase) resolveGrant(ctx context.Context, admin string, id uuid.UUID, status, comment string) (*entity.Grant, error) {
+	tx, err := uc.grantRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	// Блокировка начисления не дает подтвердить его дважды
+	grant, err := repos.Grants.GetGrantForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, ErrGrantNotFound
+	}
+	if grant.Status != entity.GrantStatusPending {
+		return nil, fmt.Errorf("%w: grant is %s", ErrGrantNotPending, grant.Status)
+	}
+
+	action := entity.AuditActionGrantRejected
+	if status == entity.GrantStatusCompleted {
+		if grant.CreatedBy == admin {
+			return nil, ErrGrantSelfApprove
+		}
+		if err := lockGrantRecipients(ctx, repos, grant); err != nil {
+			return nil, err
+		}
+		if err := uc.executeGrant(ctx, repos, grant); err != nil {
+			return nil, err
+		}
+		action = entity.AuditActionGrantApproved
+	}
+
+	if err := repos.Grants.ResolveGrant(ctx, grant, status, admin); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"total": grant.Total, "createdBy": grant.CreatedBy}
+	if comment != "" {
+		details["comment"] = comment
+	}
+	if err := repos.Audit.Record(ctx, &entity.AuditEntry{
+		Actor:    admin,
+		Action:   action,
+		TargetID: &grant.ID,
+		Details:  details,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Grant resolved", "grantID", grant.ID, "admin", admin, "status", status)
+	return grant, nil
+}
+
+// executeGrant списывает сумму начисления с бюджета текущего месяца и зачисляет монеты получателям
+func (uc *GrantUseCase) executeGrant(ctx context.Context, repos *TxRepositories, grant *entity.Grant) error {
+	if err := repos.Grants.ReserveIssuance(ctx, issuancePeriod(time.Now()), grant.Total, uc.cfg.MonthlyBudget); err != nil {
+		return err
+	}
+	for _, item := range grant.Recipients {
+		if err := mintCoins(ctx, repos, entity.EntryKindGrant, item.UserName, item.Amount, &grant.ID, grant.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mintCoins выпускает монеты со счета эмиссии и зачисляет их пользователю проводкой вида kind
+func mintCoins(ctx context.Context, repos *TxRepositories, kind, userName string, amount int, referenceID *uuid.UUID, description string) error {
+	if err := repos.Users.CreditCoins(ctx, userName, amount); err != nil {
+		return err
+	}
+	if err := repos.Ledger.RecordIssuance(ctx, kind, userName, amount, referenceID, description); err != nil {
+		return fmt.Errorf("failed to record %s in ledger: %w", kind, err)
+	}
+	return nil
+}
+
+// lockGrantRecipients блокирует получателей начисления и проверяет, что все они существуют
+func lockGrantRecipients(ctx context.Context, repos *TxRepositories, grant *entity.Grant) error {
+	users := make([]string, len(grant.Recipients))
+	for i, item := range grant.Recipients {
+		users[i] = item.UserName
+	}
+	locked, err := repos.Users.LockUsers(ctx, users...)
+	if err != nil {
+		return err
+	}
+	if missing := missingUsers(users, locked); len(missing) > 0 {
+		return fmt.Errorf("%w: recipients do not exist: %s", ErrInvalidGrant, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// issuancePeriod возвращает начало календарного месяца по UTC, к которому относится выпуск
+func issuancePeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// validateGrant проверяет начисление до обращения к базе и считает его сумму
+func validateGrant(reason string, recipients []entity.GrantItem) (*entity.Grant, error) {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case reason == "":
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidGrant)
+	case utf8.RuneCountInString(reason) > maxGrantReasonLength:
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidGrant, maxGrantReasonLength)
+	case len(recipients) == 0:
+		return nil, fmt.Errorf("%w: at least one recipient is required", ErrInvalidGrant)
+	case len(recipients) > maxGrantRecipients:
+		return nil, fmt.Errorf("%w: at most %d recipients are allowed", ErrInvalidGrant, maxGrantRecipients)
+	}
+
+	grant := &entity.Grant{Reason: reason, Recipients: make([]entity.GrantItem, len(recipients))}
+	seen := make(map[string]bool, len(recipients))
+	for i, item := range recipients {
+		switch {
+		case item.UserName == "":
+			return nil, fmt.Errorf("%w: recipient %d: user is required", ErrInvalidGrant, i)
+		case item.Amount <= 0:
+			return nil, fmt.Errorf("%w: amount must be positive: %d", ErrInvalidGrant, item.Amount)
+		case item.Amount > math.MaxInt32-grant.Total:
+			return nil, fmt.Errorf("%w: total amount is too large", ErrInvalidGrant)
+		case seen[item.UserName]:
+			return nil, fmt.Errorf("%w: duplicate recipient: %s", ErrInvalidGrant, item.UserName)
+		}
+		seen[item.UserName] = true
+		grant.Recipients[i] = item
+		grant.Total += item.Amount
+	}
+	return grant, nil
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockGrantRepository struct {
+	mock.Mock
+}
+
+func (m *MockGrantRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockGrantRepository) CreateGrant(ctx context.Context, grant *entity.Grant) error {
+	args := m.Called(ctx, grant)
+	return args.Error(0)
+}
+
+func (m *MockGrantRepository) GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Grant), args.Error(1)
+}
+
+func (m *MockGrantRepository) GetGrantForUpdate(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Grant), args.Error(1)
+}
+
+func (m *MockGrantRepository) GetGrants(ctx context.Context, status string, limit int) ([]entity.Grant, error) {
+	args := m.Called(ctx, status, limit)
+	return args.Get(0).([]entity.Grant), args.Error(1)
+}
+
+func (m *MockGrantRepository) ResolveGrant(ctx context.Context, grant *entity.Grant, status, resolvedBy string) error {
+	args := m.Called(ctx, grant, status, resolvedBy)
+	return args.Error(0)
+}
+
+func (m *MockGrantRepository) ReserveIssuance(ctx context.Context, period time.Time, amount, budget int) error {
+	args := m.Called(ctx, period, amount, budget)
+	return args.Error(0)
+}
+
+func (m *MockGrantRepository) GetIssued(ctx context.Context, period time.Time) (int, error) {
+	args := m.Called(ctx, period)
+	return args.Int(0), args.Error(1)
+}
+
+func TestValidateGrant(t *testing.T) {
+	grant, err := validateGrant("  Q1 bonus ", []entity.GrantItem{
+		{UserName: "bob", Amount: 100},
+		{UserName: "carol", Amount: 50},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Q1 bonus", grant.Reason)
+	assert.Equal(t, 150, grant.Total)
+	assert.Len(t, grant.Recipients, 2)
+
+	for name, tc := range map[string]struct {
+		reason     string
+		recipients []entity.GrantItem
+	}{
+		"no reason":      {"  ", []entity.GrantItem{{UserName: "bob", Amount: 1}}},
+		"long reason":    {strings.Repeat("x", maxGrantReasonLength+1), []entity.GrantItem{{UserName: "bob", Amount: 1}}},
+		"no recipients":  {"bonus", nil},
+		"no user":        {"bonus", []entity.GrantItem{{Amount: 1}}},
+		"zero amount":    {"bonus", []entity.GrantItem{{UserName: "bob"}}},
+		"duplicate":      {"bonus", []entity.GrantItem{{UserName: "bob", Amount: 1}, {UserName: "bob", Amount: 2}}},
+		"total overflow": {"bonus", []entity.GrantItem{{UserName: "bob", Amount: math.MaxInt32}, {UserName: "carol", Amount: 1}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := validateGrant(tc.reason, tc.recipients)
+			assert.ErrorIs(t, err, ErrInvalidGrant)
+		})
+	}
+}
+
+func TestIssuancePeriod(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	// 1 марта 01:00 по Москве - еще февраль по UTC
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), issuancePeriod(time.Date(2025, 3, 1, 1, 0, 0, 0, moscow)))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), issuancePeriod(time.Date(2025, 3, 31, 23, 59, 0, 0, time.UTC)))
+}
+
+func TestGrantUseCase_CreateGrant_BelowThresholdMints(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestGrantUseCase(repos, GrantConfig{MonthlyBudget: 1000, ApprovalThreshold: 500})
+	recipients := []entity.GrantItem{{UserName: "bob", Amount: 100}, {UserName: "carol", Amount: 50}}
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "carol"}).Return([]string{"bob", "carol"}, nil)
+	repos.grants.On("CreateGrant", mock.Anything, mock.Anything).Return(nil)
+	repos.grants.On("ReserveIssuance", mock.Anything, mock.Anything, 150, 1000).Return(nil)
+	expectMint(repos, entity.EntryKindGrant, "bob", 100)
+	expectMint(repos, entity.EntryKindGrant, "carol", 50)
+	repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+		return e.Action == entity.AuditActionGrantCreated && e.Actor == "admin"
+	})).Return(nil)
+
+	grant, err := uc.CreateGrant(context.Background(), "admin", "Q1 bonus", recipients)
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.GrantStatusCompleted, grant.Status)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestGrantUseCase_CreateGrant_AboveThresholdWaitsForApproval(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestGrantUseCase(repos, GrantConfig{ApprovalThreshold: 100})
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+	repos.grants.On("CreateGrant", mock.Anything, mock.Anything).Return(nil)
+	repos.audit.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+	grant, err := uc.CreateGrant(context.Background(), "admin", "Q1 bonus", []entity.GrantItem{{UserName: "bob", Amount: 100}})
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.GrantStatusPending, grant.Status)
+	assert.True(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "CreditCoins", mock.Anything, mock.Anything, mock.Anything)
+	repos.grants.AssertNotCalled(t, "ReserveIssuance", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGrantUseCase_CreateGrant_UnknownRecipient(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestGrantUseCase(repos, GrantConfig{})
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "ghost"}).Return([]string{"bob"}, nil)
+
+	_, err := uc.CreateGrant(context.Background(), "admin", "Q1 bonus",
+		[]entity.GrantItem{{UserName: "bob", Amount: 10}, {UserName: "ghost", Amount: 10}})
+
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+	assert.False(t, repos.tx.committed)
+	repos.grants.AssertNotCalled(t, "CreateGrant", mock.Anything, mock.Anything)
+}
+
+func TestGrantUseCase_CreateGrant_BudgetExceeded(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestGrantUseCase(repos, GrantConfig{MonthlyBudget: 50})
+	repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+	repos.grants.On("CreateGrant", mock.Anything, mock.Anything).Return(nil)
+	repos.grants.On("ReserveIssuance", mock.Anything, mock.Anything, 100, 50).Return(ErrIssuanceBudgetExceeded)
+
+	_, err := uc.CreateGrant(context.Background(), "admin", "Q1 bonus", []entity.GrantItem{{UserName: "bob", Amount: 100}})
+
+	assert.ErrorIs(t, err, ErrIssuanceBudgetExceeded)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "CreditCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGrantUseCase_ApproveGrant(t *testing.T) {
+	pendingGrant := func() *entity.Grant {
+		return &entity.Grant{
+			ID:         uuid.New(),
+			CreatedBy:  "alice",
+			Reason:     "Q1 bonus",
+			Total:      300,
+			Status:     entity.GrantStatusPending,
+			Recipients: []entity.GrantItem{{UserName: "bob", Amount: 300}},
+		}
+	}
+
+	t.Run("by another admin", func(t *testing.T) {
+		repos := newMockRepos()
+		uc := newTestGrantUseCase(repos, GrantConfig{ApprovalThreshold: 100})
+		grant := pendingGrant()
+		repos.grants.On("GetGrantForUpdate", mock.Anything, grant.ID).Return(grant, nil)
+		repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+		repos.grants.On("ReserveIssuance", mock.Anything, mock.Anything, 300, 0).Return(nil)
+		expectMint(repos, entity.EntryKindGrant, "bob", 300)
+		repos.grants.On("ResolveGrant", mock.Anything, grant, entity.GrantStatusCompleted, "carol").Return(nil)
+		repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+			return e.Action == entity.AuditActionGrantApproved && e.Actor == "carol"
+		})).Return(nil)
+
+		_, err := uc.ApproveGrant(context.Background(), "carol", grant.ID)
+
+		require.NoError(t, err)
+		assert.True(t, repos.tx.committed)
+		repos.assertExpectations(t)
+	})
+
+	t.Run("by its author", func(t *testing.T) {
+		repos := newMockRepos()
+		uc := newTestGrantUseCase(repos, GrantConfig{ApprovalThreshold: 100})
+		grant := pendingGrant()
+		repos.grants.On("GetGrantForUpdate", mock.Anything, grant.ID).Return(grant, nil)
+
+		_, err := uc.ApproveGrant(context.Background(), "alice", grant.ID)
+
+		assert.ErrorIs(t, err, ErrGrantSelfApprove)
+		assert.False(t, repos.tx.committed)
+		repos.users.AssertNotCalled(t, "CreditCoins", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("already resolved", func(t *testing.T) {
+		repos := newMockRepos()
+		uc := newTestGrantUseCase(repos, GrantConfig{ApprovalThreshold: 100})
+		grant := pendingGrant()
+		grant.Status = entity.GrantStatusRejected
+		repos.grants.On("GetGrantForUpdate", mock.Anything, grant.ID).Return(grant, nil)
+
+		_, err := uc.ApproveGrant(context.Background(), "carol", grant.ID)
+
+		assert.ErrorIs(t, err, ErrGrantNotPending)
+		assert.False(t, repos.tx.committed)
+	})
+}
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+var (
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidHistoryFilter = errors.New("invalid history filter")
+)
+
+type HistoryUseCase struct {
+	transactionRepo TransactionRepository
+}
+
+func NewHistoryUseCase(transactionRepo TransactionRepository) *HistoryUseCase {
+	return &HistoryUseCase{transactionRepo: transactionRepo}
+}
+
+// GetHistory возвращает страницу истории переводов пользователя.
+// cursor - значение nextCursor предыдущей страницы, пустой для первой
+func (uc *HistoryUseCase) GetHistory(ctx context.Context, filter entity.HistoryFilter, cursor string) (*entity.HistoryPage, error) {
+	if err := validateHistoryFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	return fetchHistoryPage(ctx, uc.transactionRepo, filter)
+}
+
+func validateHistoryFilter(filter *entity.HistoryFilter) error {
+	switch filter.Direction {
+	case "", entity.DirectionSent, entity.DirectionReceived:
+	default:
+		return fmt.Errorf("%w: direction must be %q or %q", ErrInvalidHistoryFilter, entity.DirectionSent, entity.DirectionReceived)
+	}
+	if filter.MinAmount < 0 || filter.MaxAmount < 0 {
+		return fmt.Errorf("%w: amount bounds must not be negative", ErrInvalidHistoryFilter)
+	}
+	if filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return fmt.Errorf("%w: minAmount is greater than maxAmount", ErrInvalidHistoryFilter)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidHistoryFilter)
+	}
+
+	switch {
+	case filter.Limit < 0:
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidHistoryFilter)
+	case filter.Limit == 0:
+		filter.Limit = defaultHistoryLimit
+	case filter.Limit > maxHistoryLimit:
+		filter.Limit = maxHistoryLimit
+	}
+	return nil
+}
+
+// fetchHistoryPage запрашивает на одну запись больше лимита, чтобы понять, есть ли следующая страница
+func fetchHistoryPage(ctx context.Context, repo TransactionRepository, filter entity.HistoryFilter) (*entity.HistoryPage, error) {
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	transfers, err := repo.GetTransferHistory(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction history: %w", err)
+	}
+
+	page := &entity.HistoryPage{Transfers: transfers}
+	if len(transfers) > limit {
+		page.Transfers = transfers[:limit]
+		last := page.Transfers[limit-1]
+		page.NextCursor = encodeHistoryCursor(entity.HistoryCursor{EffectiveAt: last.EffectiveAt, ID: last.ID})
+	}
+	if page.Transfers == nil {
+		page.Transfers = []entity.Transaction{}
+	}
+	return page, nil
+}
+
+// encodeHistoryCursor упаковывает позицию в непрозрачную для клиента строку
+func encodeHistoryCursor(cursor entity.HistoryCursor) string {
+	raw := cursor.EffectiveAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(cursor string) (*entity.HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	effectiveAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var result entity.HistoryCursor
+	if result.EffectiveAt, err = time.Parse(time.RFC3339Nano, effectiveAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if result.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &result, nil
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHistoryCursor_RoundTrip(t *testing.T) {
+	cursor := entity.HistoryCursor{
+		EffectiveAt: time.Date(2025, 2, 14, 10, 30, 0, 123456000, time.UTC),
+		ID:          uuid.New(),
+	}
+
+	decoded, err := decodeHistoryCursor(encodeHistoryCursor(cursor))
+
+	assert.NoError(t, err)
+	assert.True(t, cursor.EffectiveAt.Equal(decoded.EffectiveAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestHistoryCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"!!!", "bm90LWEtY3Vyc29y", encodeHistoryCursor(entity.HistoryCursor{})[:10]} {
+		_, err := decodeHistoryCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestHistoryUseCase_GetHistory_NextPage(t *testing.T) {
+	mockTransactionRepo := new(MockTransactionRepository)
+
+	now := time.Now().UTC()
+	transfers := []entity.Transaction{
+		{ID: uuid.New(), FromUser: "testuser", ToUser: "user1", Amount: 10, CreatedAt: now, EffectiveAt: now},
+		// Перевод с подтверждением создан раньше, а принят позже
+		{ID: uuid.New(), FromUser: "user2", ToUser: "testuser", Amount: 20, CreatedAt: now.Add(-time.Hour), EffectiveAt: now.Add(-time.Minute)},
+		{ID: uuid.New(), FromUser: "testuser", ToUser: "user3", Amount: 30, CreatedAt: now.Add(-2 * time.Minute), EffectiveAt: now.Add(-2 * time.Minute)},
+	}
+
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, entity.HistoryFilter{
+		UserName:  "testuser",
+		Direction: entity.DirectionSent,
+		Limit:     3,
+	}).Return(transfers, nil)
+
+	uc := NewHistoryUseCase(mockTransactionRepo)
+
+	page, err := uc.GetHistory(context.Background(), entity.HistoryFilter{
+		UserName:  "testuser",
+		Direction: entity.DirectionSent,
+		Limit:     2,
+	}, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Transfers, 2)
+
+	cursor, err := decodeHistoryCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, transfers[1].ID, cursor.ID)
+	assert.True(t, transfers[1].EffectiveAt.Equal(cursor.EffectiveAt))
+
+	mockTransactionRepo.AssertExpectations(t)
+}
+
+func TestHistoryUseCase_GetHistory_InvalidFilter(t *testing.T) {
+	uc := NewHistoryUseCase(new(MockTransactionRepository))
+
+	filters := []entity.HistoryFilter{
+		{UserName: "testuser", Direction: "sideways"},
+		{UserName: "testuser", MinAmount: 100, MaxAmount: 10},
+		{UserName: "testuser", From: time.Now(), To: time.Now().Add(-time.Hour)},
+		{UserName: "testuser", Limit: -1},
+	}
+	for _, filter := range filters {
+		_, err := uc.GetHistory(context.Background(), filter, "")
+		assert.ErrorIs(t, err, ErrInvalidHistoryFilter)
+	}
+}
@@ -4,25 +4,63 @@ import (
 	"avito-merch/internal/entity"
 	"context"
 	"fmt"
+	"time"
 )
 
+// infoHistoryLimit сколько последних переводов возвращается в /api/info
+const infoHistoryLimit = 20
+
 type TransactionRepository interface {
-	GetTransfersByUsername(ctx context.Context, username string) ([]entity.Transaction, error)
+	GetTransferHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.Transaction, error)
+	GetPendingTransfers(ctx context.Context, username string) ([]entity.Transaction, error)
+}
+
+type CoinLotRepository interface {
+	GetLots(ctx context.Context, username string, grantedBefore time.Time) ([]entity.CoinLot, error)
+}
+
+type AchievementRepository interface {
+	GetEarned(ctx context.Context, username string) ([]entity.EarnedAchievement, error)
+}
+
+// InfoConfig настройки, от которых зависят сведения о пользователе
+type InfoConfig struct {
+	CoinExpiry CoinExpiryConfig
+	// KudosBudget месячный бюджет благодарностей
+	KudosBudget int
 }
 
 type InfoUseCase struct {
 	userRepo        UserRepository
 	transactionRepo TransactionRepository
+	lotRepo         CoinLotRepository
+	achievementRepo AchievementRepository
+	expiry          CoinExpiryConfig
+	kudosBudget     int
 }
 
-func NewInfoUseCase(userRepo UserRepository, transactionRepo TransactionRepository) *InfoUseCase {
+func NewInfoUseCase(
+	userRepo UserRepository,
+	transactionRepo TransactionRepository,
+	lotRepo CoinLotRepository,
+	achievementRepo AchievementRepository,
+	cfg InfoConfig,
+) *InfoUseCase {
 	return &InfoUseCase{
 		userRepo:        userRepo,
 		transactionRepo: transactionRepo,
+		lotRepo:         lotRepo,
+		achievementRepo: achievementRepo,
+		expiry:          cfg.CoinExpiry,
+		kudosBudget:     cfg.KudosBudget,
 	}
 }
 
-func (uc *InfoUseCase) GetUserInfo(ctx context.Context, username string) (*entity.InfoData, error) {
+// GetUserInfo возвращает баланс, остаток бюджета благодарностей, инвентарь, последние переводы,
+// переводы, ожидающие решения, монеты, которые скоро сгорят, и полученные достижения.
+// Непустой search оставляет в истории только переводы с подходящим сообщением.
+// Продолжение истории доступно через /api/history по курсору из ответа
+func (uc *InfoUseCase) GetUserInfo(ctx context.Context, username string, search string) (*entity.InfoData, error) {
 	// Получаем баланс пользователя
 	user, err := uc.userRepo.GetUserByUsername(ctx, username)
 	if err != nil {
@@ -35,20 +73,61 @@ func (uc *InfoUseCase) GetUserInfo(ctx context.Context, username string) (*entit
 		return nil, fmt.Errorf("failed to get user inventory: %w", err)
 	}
 
-	// Получаем историю транзакций
-	transactions, err := uc.transactionRepo.GetTransfersByUsername(ctx, username)
+	// Получаем последние транзакции
+	page, err := fetchHistoryPage(ctx, uc.transactionRepo, entity.HistoryFilter{
+		UserName: username,
+		Search:   search,
+		Limit:    infoHistoryLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ожидающие переводы показываются отдельно от истории: монеты по ним еще не перешли
+	pending, err := uc.transactionRepo.GetPendingTransfers(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction history: %w", err)
+		return nil, fmt.Errorf("failed to get pending transfers: %w", err)
+	}
+
+	// Монеты, сгорающие в течение периода предупреждения
+	expiring := []entity.ExpiringCoins{}
+	if uc.expiry.TTL > 0 {
+		lots, err := uc.lotRepo.GetLots(ctx, username, expiryCutoff(time.Now().Add(uc.expiry.WarningPeriod), uc.expiry.TTL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get expiring coins: %w", err)
+		}
+		expiring = expiringCoins(lots, uc.expiry.TTL)
+	}
+
+	achievements, err := uc.achievementRepo.GetEarned(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
 	}
 
 	// Формируем ответ
 	info := &entity.InfoData{
-		Coins:     user.Coins,
+		Coins:     user.AvailableCoins(),
+		HeldCoins: user.HeldCoins,
 		Inventory: inventory,
 		CoinHistory: entity.CoinHistory{
-			Received: uc.filterReceivedTransactions(transactions, username),
-			Sent:     uc.filterSentTransactions(transactions, username),
+			Received:   uc.filterReceivedTransactions(page.Transfers, username),
+			Sent:       uc.filterSentTransactions(page.Transfers, username),
+			NextCursor: page.NextCursor,
+		},
+		PendingTransfers: entity.PendingTransfers{
+			Incoming: []entity.Transaction{},
+			Outgoing: []entity.Transaction{},
 		},
+		ExpiringSoon: expiring,
+		GivingBudget: user.GivingBalance(issuancePeriod(time.Now()), uc.kudosBudget),
+		Achievements: achievements,
+	}
+	for _, transfer := range pending {
+		if transfer.ToUser == username {
+			info.PendingTransfers.Incoming = append(info.PendingTransfers.Incoming, transfer)
+		} else {
+			info.PendingTransfers.Outgoing = append(info.PendingTransfers.Outgoing, transfer)
+		}
 	}
 
 	if info.Inventory == nil {
@@ -61,6 +140,9 @@ func (uc *InfoUseCase) GetUserInfo(ctx context.Context, username string) (*entit
 	if info.CoinHistory.Sent == nil {
 		info.CoinHistory.Sent = []entity.Transaction{}
 	}
+	if info.Achievements == nil {
+		info.Achievements = []entity.EarnedAchievement{}
+	}
 
 	return info, nil
 }
@@ -70,8 +152,12 @@ func (uc *InfoUseCase) filterReceivedTransactions(transactions []entity.Transact
 	for _, tx := range transactions {
 		if tx.ToUser == username {
 			received = append(received, entity.Transaction{
-				FromUser: tx.FromUser,
-				Amount:   tx.Amount,
+				ID:        tx.ID,
+				FromUser:  tx.FromUser,
+				Amount:    tx.Amount,
+				Memo:      tx.Memo,
+				CreatedAt: tx.CreatedAt,
+				Kudos:     tx.Kudos,
 			})
 		}
 	}
@@ -83,8 +169,12 @@ func (uc *InfoUseCase) filterSentTransactions(transactions []entity.Transaction,
 	for _, tx := range transactions {
 		if tx.FromUser == username {
 			sent = append(sent, entity.Transaction{
-				ToUser: tx.ToUser,
-				Amount: tx.Amount,
+				ID:        tx.ID,
+				ToUser:    tx.ToUser,
+				Amount:    tx.Amount,
+				Memo:      tx.Memo,
+				CreatedAt: tx.CreatedAt,
+				Kudos:     tx.Kudos,
 			})
 		}
 	}
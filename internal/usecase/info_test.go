@@ -4,6 +4,7 @@ import (
 	"avito-merch/internal/entity"
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -13,11 +14,40 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) GetTransfersByUsername(ctx context.Context, username string) ([]entity.Transaction, error) {
+func (m *MockTransactionRepository) GetTransferHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.Transaction, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]entity.Transaction), args.Error(1)
+}
+
+func (m *MockTransactionRepository) GetPendingTransfers(ctx context.Context, username string) ([]entity.Transaction, error) {
 	args := m.Called(ctx, username)
 	return args.Get(0).([]entity.Transaction), args.Error(1)
 }
 
+type MockCoinLotRepository struct {
+	mock.Mock
+}
+
+func (m *MockCoinLotRepository) GetLots(ctx context.Context, username string, grantedBefore time.Time) ([]entity.CoinLot, error) {
+	args := m.Called(ctx, username, grantedBefore)
+	return args.Get(0).([]entity.CoinLot), args.Error(1)
+}
+
+type MockAchievementRepository struct {
+	mock.Mock
+}
+
+func (m *MockAchievementRepository) GetEarned(ctx context.Context, username string) ([]entity.EarnedAchievement, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).([]entity.EarnedAchievement), args.Error(1)
+}
+
+func noAchievements() *MockAchievementRepository {
+	m := new(MockAchievementRepository)
+	m.On("GetEarned", mock.Anything, mock.Anything).Return([]entity.EarnedAchievement(nil), nil)
+	return m
+}
+
 func TestInfoUseCase_GetUserInfo_Success(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockTransactionRepo := new(MockTransactionRepository)
@@ -33,18 +63,20 @@ func TestInfoUseCase_GetUserInfo_Success(t *testing.T) {
 			{Type: "t-shirt", Quantity: 1},
 		}, nil)
 
-	mockTransactionRepo.On("GetTransfersByUsername", mock.Anything, "testuser").
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, entity.HistoryFilter{UserName: "testuser", Limit: infoHistoryLimit + 1}).
 		Return([]entity.Transaction{
 			{FromUser: "user1", ToUser: "testuser", Amount: 100},
 			{FromUser: "testuser", ToUser: "user2", Amount: 50},
 		}, nil)
+	mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+		Return([]entity.Transaction(nil), nil)
 
-	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo)
+	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, new(MockCoinLotRepository), noAchievements(), InfoConfig{})
 
 	ctx := context.Background()
 	username := "testuser"
 
-	info, err := uc.GetUserInfo(ctx, username)
+	info, err := uc.GetUserInfo(ctx, username, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1000, info.Coins)
@@ -55,3 +87,169 @@ func TestInfoUseCase_GetUserInfo_Success(t *testing.T) {
 	mockUserRepo.AssertExpectations(t)
 	mockTransactionRepo.AssertExpectations(t)
 }
+
+func TestInfoUseCase_GetUserInfo_HeldCoins(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").
+		Return(&entity.User{
+			Name:      "testuser",
+			Coins:     1000,
+			HeldCoins: 300,
+		}, nil)
+
+	mockUserRepo.On("GetUserInventory", mock.Anything, "testuser").
+		Return([]entity.InventoryItem(nil), nil)
+
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, entity.HistoryFilter{UserName: "testuser", Limit: infoHistoryLimit + 1}).
+		Return([]entity.Transaction(nil), nil)
+	mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+		Return([]entity.Transaction(nil), nil)
+
+	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, new(MockCoinLotRepository), noAchievements(), InfoConfig{})
+
+	info, err := uc.GetUserInfo(context.Background(), "testuser", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 700, info.Coins)
+	assert.Equal(t, 300, info.HeldCoins)
+	assert.Empty(t, info.Inventory)
+	assert.Empty(t, info.PendingTransfers.Incoming)
+	assert.Empty(t, info.PendingTransfers.Outgoing)
+
+	mockUserRepo.AssertExpectations(t)
+	mockTransactionRepo.AssertExpectations(t)
+}
+
+func TestInfoUseCase_GetUserInfo_PendingTransfers(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").
+		Return(&entity.User{Name: "testuser", Coins: 1000, HeldCoins: 200}, nil)
+	mockUserRepo.On("GetUserInventory", mock.Anything, "testuser").
+		Return([]entity.InventoryItem(nil), nil)
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, entity.HistoryFilter{UserName: "testuser", Limit: infoHistoryLimit + 1}).
+		Return([]entity.Transaction(nil), nil)
+	mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+		Return([]entity.Transaction{
+			{FromUser: "user1", ToUser: "testuser", Amount: 500, Status: entity.TransferStatusPending},
+			{FromUser: "testuser", ToUser: "user2", Amount: 200, Status: entity.TransferStatusPending},
+		}, nil)
+
+	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, new(MockCoinLotRepository), noAchievements(), InfoConfig{})
+
+	info, err := uc.GetUserInfo(context.Background(), "testuser", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 800, info.Coins)
+	assert.Equal(t, 200, info.HeldCoins)
+	assert.Empty(t, info.CoinHistory.Received)
+	if assert.Len(t, info.PendingTransfers.Incoming, 1) {
+		assert.Equal(t, "user1", info.PendingTransfers.Incoming[0].FromUser)
+	}
+	if assert.Len(t, info.PendingTransfers.Outgoing, 1) {
+		assert.Equal(t, "user2", info.PendingTransfers.Outgoing[0].ToUser)
+	}
+
+	mockUserRepo.AssertExpectations(t)
+	mockTransactionRepo.AssertExpectations(t)
+}
+
+func TestInfoUseCase_GetUserInfo_ExpiringSoon(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockLotRepo := new(MockCoinLotRepository)
+
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").
+		Return(&entity.User{Name: "testuser", Coins: 1000}, nil)
+	mockUserRepo.On("GetUserInventory", mock.Anything, "testuser").
+		Return([]entity.InventoryItem(nil), nil)
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, entity.HistoryFilter{UserName: "testuser", Limit: infoHistoryLimit + 1}).
+		Return([]entity.Transaction(nil), nil)
+	mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+		Return([]entity.Transaction(nil), nil)
+
+	grantedOn := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	mockLotRepo.On("GetLots", mock.Anything, "testuser", mock.AnythingOfType("time.Time")).
+		Return([]entity.CoinLot{{UserName: "testuser", GrantedOn: grantedOn, Amount: 400}}, nil)
+
+	ttl := 365 * 24 * time.Hour
+	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, mockLotRepo, noAchievements(), InfoConfig{
+		CoinExpiry: CoinExpiryConfig{TTL: ttl, WarningPeriod: 30 * 24 * time.Hour},
+	})
+
+	info, err := uc.GetUserInfo(context.Background(), "testuser", "")
+
+	assert.NoError(t, err)
+	if assert.Len(t, info.ExpiringSoon, 1) {
+		assert.Equal(t, 400, info.ExpiringSoon[0].Amount)
+		assert.Equal(t, grantedOn.Add(ttl), info.ExpiringSoon[0].ExpiresAt)
+	}
+
+	mockLotRepo.AssertExpectations(t)
+}
+
+func TestInfoUseCase_GetUserInfo_GivingBudget(t *testing.T) {
+	period := issuancePeriod(time.Now())
+	previous := period.AddDate(0, -1, 0)
+
+	for name, tc := range map[string]struct {
+		user     entity.User
+		expected int
+	}{
+		"never gave":      {entity.User{Name: "testuser"}, 100},
+		"current period":  {entity.User{Name: "testuser", GivingBudget: 40, GivingPeriod: &period}, 40},
+		"previous period": {entity.User{Name: "testuser", GivingBudget: 0, GivingPeriod: &previous}, 100},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockTransactionRepo := new(MockTransactionRepository)
+
+			user := tc.user
+			mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").Return(&user, nil)
+			mockUserRepo.On("GetUserInventory", mock.Anything, "testuser").
+				Return([]entity.InventoryItem(nil), nil)
+			mockTransactionRepo.On("GetTransferHistory", mock.Anything, mock.Anything).
+				Return([]entity.Transaction(nil), nil)
+			mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+				Return([]entity.Transaction(nil), nil)
+
+			uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, new(MockCoinLotRepository), noAchievements(), InfoConfig{KudosBudget: 100})
+
+			info, err := uc.GetUserInfo(context.Background(), "testuser", "")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, info.GivingBudget)
+		})
+	}
+}
+
+func TestInfoUseCase_GetUserInfo_Achievements(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAchievementRepo := new(MockAchievementRepository)
+
+	mockUserRepo.On("GetUserByUsername", mock.Anything, "testuser").
+		Return(&entity.User{Name: "testuser", Coins: 1000}, nil)
+	mockUserRepo.On("GetUserInventory", mock.Anything, "testuser").
+		Return([]entity.InventoryItem(nil), nil)
+	mockTransactionRepo.On("GetTransferHistory", mock.Anything, mock.Anything).
+		Return([]entity.Transaction(nil), nil)
+	mockTransactionRepo.On("GetPendingTransfers", mock.Anything, "testuser").
+		Return([]entity.Transaction(nil), nil)
+	mockAchievementRepo.On("GetEarned", mock.Anything, "testuser").
+		Return([]entity.EarnedAchievement{{AchievementID: "first_purchase", Name: "First purchase"}}, nil)
+
+	uc := NewInfoUseCase(mockUserRepo, mockTransactionRepo, new(MockCoinLotRepository), mockAchievementRepo, InfoConfig{})
+
+	info, err := uc.GetUserInfo(context.Background(), "testuser", "")
+
+	assert.NoError(t, err)
+	if assert.Len(t, info.Achievements, 1) {
+		assert.Equal(t, "first_purchase", info.Achievements[0].AchievementID)
+	}
+
+	mockAchievementRepo.AssertExpectations(t)
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrKudosDisabled        = errors.New("kudos are disabled")
+	ErrGivingBudgetExceeded = repository.ErrGivingBudgetExceeded
+)
+
+// SendKudos отправляет благодарность. Сумма списывается из бюджета благодарностей отправителя,
+// который нельзя потратить в магазине, а получатель получает обычные монеты со счета эмиссии.
+// Благодарность не требует подтверждения и не учитывается в лимитах переводов
+func (uc *SendCoinUseCase) SendKudos(ctx context.Context, fromUsername string, toUsername string, amount int, memo string) (*entity.Transaction, error) {
+	if uc.kudosBudget <= 0 {
+		return nil, ErrKudosDisabled
+	}
+	memo, err := validateTransfer(fromUsername, toUsername, amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.transactionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	locked, err := repos.Users.LockUsers(ctx, fromUsername, toUsername)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingUsers([]string{toUsername}, locked)) > 0 {
+		return nil, fmt.Errorf("recipient does not exist")
+	}
+
+	if err := repos.Users.SpendGivingBudget(ctx, fromUsername, issuancePeriod(time.Now()), uc.kudosBudget, amount); err != nil {
+		return nil, err
+	}
+
+	transfer := &entity.Transaction{
+		FromUser: fromUsername,
+		ToUser:   toUsername,
+		Amount:   amount,
+		Memo:     memo,
+		Kudos:    true,
+	}
+	if err := repos.Transfers.CreateTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to create transfer record: %w", err)
+	}
+	if err := mintCoins(ctx, repos, entity.EntryKindKudos, toUsername, amount, &transfer.ID, "kudos from "+fromUsername); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Kudos sent", "fromUserName", fromUsername, "toUserName", toUsername, "amount", amount)
+	uc.publishTransfer(ctx, fromUsername, toUsername)
+	return transfer, nil
+}
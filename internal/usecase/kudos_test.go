@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCoinUseCase_SendKudos(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{KudosBudget: 100})
+	transferID := uuid.New()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "bob"}).Return([]string{"alice", "bob"}, nil)
+	repos.users.On("SpendGivingBudget", mock.Anything, "alice", mock.Anything, 100, 30).Return(nil)
+	repos.transfers.On("CreateTransfer", mock.Anything, mock.MatchedBy(func(t *entity.Transaction) bool {
+		return t.Kudos && t.FromUser == "alice" && t.ToUser == "bob" && t.Amount == 30
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Transaction).ID = transferID
+	}).Return(nil)
+	repos.users.On("CreditCoins", mock.Anything, "bob", 30).Return(nil)
+	repos.ledger.On("RecordIssuance", mock.Anything, entity.EntryKindKudos, "bob", 30, &transferID, "kudos from alice").Return(nil)
+
+	transfer, err := uc.SendKudos(context.Background(), "alice", "bob", 30, "спасибо")
+
+	require.NoError(t, err)
+	assert.True(t, transfer.Kudos)
+	assert.True(t, repos.tx.committed)
+	// Благодарность не трогает монеты отправителя и не проверяет лимиты
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repos.transfers.AssertNotCalled(t, "GetSentTotals", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestSendCoinUseCase_SendKudos_BudgetExceeded(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{KudosBudget: 100})
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "bob"}).Return([]string{"alice", "bob"}, nil)
+	repos.users.On("SpendGivingBudget", mock.Anything, "alice", mock.Anything, 100, 150).Return(ErrGivingBudgetExceeded)
+
+	_, err := uc.SendKudos(context.Background(), "alice", "bob", 150, "")
+
+	assert.ErrorIs(t, err, ErrGivingBudgetExceeded)
+	assert.False(t, repos.tx.committed)
+	repos.transfers.AssertNotCalled(t, "CreateTransfer", mock.Anything, mock.Anything)
+	repos.users.AssertNotCalled(t, "CreditCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSendCoinUseCase_SendKudos_UnknownRecipient(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{KudosBudget: 100})
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "ghost"}).Return([]string{"alice"}, nil)
+
+	_, err := uc.SendKudos(context.Background(), "alice", "ghost", 10, "")
+
+	assert.Error(t, err)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "SpendGivingBudget", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSendCoinUseCase_SendKudos_Disabled(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+
+	_, err := uc.SendKudos(context.Background(), "alice", "bob", 10, "")
+
+	assert.ErrorIs(t, err, ErrKudosDisabled)
+	repos.transfers.AssertNotCalled(t, "Begin", mock.Anything)
+}
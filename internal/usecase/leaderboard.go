@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLeaderboardSize сколько мест в рейтинге, если размер не задан в настройках
+	defaultLeaderboardSize = 10
+	// leaderboardHeadroom во сколько раз больше мест хранится в кэше, чтобы после скрытия
+	// пользователей между пересчетами в рейтинге оставалось достаточно мест
+	leaderboardHeadroom = 2
+)
+
+var (
+	ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+	// ErrLeaderboardNotReady рейтинги еще ни разу не пересчитаны после запуска
+	ErrLeaderboardNotReady = errors.New("leaderboard is not ready yet")
+)
+
+var (
+	leaderboardMetrics = []string{entity.LeaderboardMetricReceived, entity.LeaderboardMetricSent, entity.LeaderboardMetricPurchases}
+	leaderboardPeriods = []string{entity.LeaderboardPeriodWeek, entity.LeaderboardPeriodMonth, entity.LeaderboardPeriodAll}
+)
+
+type LeaderboardRepository interface {
+	GetTop(ctx context.Context, metric string, since time.Time, limit int) ([]entity.LeaderboardEntry, error)
+	GetOptedOut(ctx context.Context, userNames []string) ([]string, error)
+	SetOptOut(ctx context.Context, userName string, optOut bool) error
+}
+
+// LeaderboardUseCase отдает рейтинги из кэша в памяти, который пересчитывается только фоновой задачей,
+// чтобы запросы не агрегировали историю. Скрывшие себя пользователи отфильтровываются при каждом
+// запросе по отметке в базе, поэтому скрытие действует сразу на всех репликах
+type LeaderboardUseCase struct {
+	leaderboardRepo LeaderboardRepository
+	size            int
+
+	// refreshMu не дает пересчитывать рейтинги параллельно
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+	boards    map[string]*entity.Leaderboard
+}
+
+func NewLeaderboardUseCase(leaderboardRepo LeaderboardRepository, size int) *LeaderboardUseCase {
+	if size <= 0 {
+		size = defaultLeaderboardSize
+	}
+	return &LeaderboardUseCase{leaderboardRepo: leaderboardRepo, size: size}
+}
+
+// GetLeaderboard возвращает рейтинг по метрике за период из последнего успешного пересчета
+func (uc *LeaderboardUseCase) GetLeaderboard(ctx context.Context, metric, period string) (*entity.Leaderboard, error) {
+	if !slices.Contains(leaderboardMetrics, metric) {
+		return nil, fmt.Errorf("%w: unknown metric: %s", ErrInvalidLeaderboard, metric)
+	}
+	if !slices.Contains(leaderboardPeriods, period) {
+		return nil, fmt.Errorf("%w: unknown period: %s", ErrInvalidLeaderboard, period)
+	}
+
+	uc.mu.RLock()
+	board := uc.boards[leaderboardKey(metric, period)]
+	uc.mu.RUnlock()
+	if board == nil {
+		return nil, ErrLeaderboardNotReady
+	}
+
+	users := make([]string, len(board.Entries))
+	for i, entry := range board.Entries {
+		users[i] = entry.User
+	}
+	optedOut, err := uc.leaderboardRepo.GetOptedOut(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	return visibleLeaderboard(board, optedOut, uc.size), nil
+}
+
+// SetOptOut скрывает пользователя из рейтингов или возвращает его в них. Скрытие действует сразу,
+// вернувшийся пользователь появится в рейтингах после следующего пересчета
+func (uc *LeaderboardUseCase) SetOptOut(ctx context.Context, userName string, optOut bool) error {
+	return uc.leaderboardRepo.SetOptOut(ctx, userName, optOut)
+}
+
+// RefreshLeaderboards фоновая задача пересчета рейтингов. Рейтинги заменяются только целиком:
+// при ошибке запросы продолжают получать результат последнего успешного пересчета
+func (uc *LeaderboardUseCase) RefreshLeaderboards(ctx context.Context) error {
+	uc.refreshMu.Lock()
+	defer uc.refreshMu.Unlock()
+
+	now := time.Now()
+	boards := make(map[string]*entity.Leaderboard, len(leaderboardMetrics)*len(leaderboardPeriods))
+	for _, period := range leaderboardPeriods {
+		since := leaderboardPeriodStart(period, now)
+		for _, metric := range leaderboardMetrics {
+			entries, err := uc.leaderboardRepo.GetTop(ctx, metric, since, uc.size*leaderboardHeadroom)
+			if err != nil {
+				return fmt.Errorf("failed to refresh leaderboards, serving previous snapshot: %w", err)
+			}
+			board := &entity.Leaderboard{Metric: metric, Period: period, UpdatedAt: now, Entries: entries}
+			if !since.IsZero() {
+				board.Since = &since
+			}
+			boards[leaderboardKey(metric, period)] = board
+		}
+	}
+
+	uc.mu.Lock()
+	uc.boards = boards
+	uc.mu.Unlock()
+
+	slog.Info("Leaderboards refreshed")
+	return nil
+}
+
+// visibleLeaderboard возвращает копию рейтинга без скрывших себя пользователей, не длиннее size мест
+func visibleLeaderboard(board *entity.Leaderboard, optedOut []string, size int) *entity.Leaderboard {
+	visible := *board
+	visible.Entries = make([]entity.LeaderboardEntry, 0, size)
+	for _, entry := range board.Entries {
+		if len(visible.Entries) == size {
+			break
+		}
+		if !slices.Contains(optedOut, entry.User) {
+			visible.Entries = append(visible.Entries, entry)
+		}
+	}
+	visible.Entries = rankEntries(visible.Entries)
+	return &visible
+}
+
+// leaderboardPeriodStart возвращает начало периода рейтинга по UTC: неделя начинается с понедельника,
+// месяц - с первого числа. Для рейтинга за все время - нулевое время
+func leaderboardPeriodStart(period string, now time.Time) time.Time {
+	switch period {
+	case entity.LeaderboardPeriodWeek:
+		day := entity.LotDate(now)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case entity.LeaderboardPeriodMonth:
+		return issuancePeriod(now)
+	default:
+		return time.Time{}
+	}
+}
+
+// rankEntries расставляет места по убыванию значения. Равные значения делят место,
+// следующее место пропускается (1, 2, 2, 4)
+func rankEntries(entries []entity.LeaderboardEntry) []entity.LeaderboardEntry {
+	for i := range entries {
+		if i > 0 && entries[i].Value == entries[i-1].Value {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	if entries == nil {
+		return []entity.LeaderboardEntry{}
+	}
+	return entries
+}
+
+func leaderboardKey(metric, period string) string {
+	return metric + "/" + period
+}
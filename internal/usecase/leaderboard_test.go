@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockLeaderboardRepository struct {
+	mock.Mock
+}
+
+func (m *MockLeaderboardRepository) GetTop(ctx context.Context, metric string, since time.Time, limit int) ([]entity.LeaderboardEntry, error) {
+	args := m.Called(ctx, metric, since, limit)
+	return args.Get(0).([]entity.LeaderboardEntry), args.Error(1)
+}
+
+func (m *MockLeaderboardRepository) GetOptedOut(ctx context.Context, userNames []string) ([]string, error) {
+	args := m.Called(ctx, userNames)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockLeaderboardRepository) SetOptOut(ctx context.Context, userName string, optOut bool) error {
+	args := m.Called(ctx, userName, optOut)
+	return args.Error(0)
+}
+
+func TestLeaderboardUseCase_GetLeaderboard(t *testing.T) {
+	mockRepo := new(MockLeaderboardRepository)
+	mockRepo.On("GetTop", mock.Anything, entity.LeaderboardMetricReceived, mock.Anything, 3*leaderboardHeadroom).
+		Return([]entity.LeaderboardEntry{{User: "alice", Value: 50}, {User: "bob", Value: 50}, {User: "carol", Value: 10}, {User: "dave", Value: 5}}, nil)
+	mockRepo.On("GetTop", mock.Anything, mock.Anything, mock.Anything, 3*leaderboardHeadroom).
+		Return([]entity.LeaderboardEntry(nil), nil)
+	mockRepo.On("GetOptedOut", mock.Anything, mock.Anything).Return([]string(nil), nil)
+
+	uc := NewLeaderboardUseCase(mockRepo, 3)
+
+	// До первого пересчета рейтинг не считается на запросе
+	_, err := uc.GetLeaderboard(context.Background(), entity.LeaderboardMetricReceived, entity.LeaderboardPeriodWeek)
+	assert.ErrorIs(t, err, ErrLeaderboardNotReady)
+	mockRepo.AssertNotCalled(t, "GetTop", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	require.NoError(t, uc.RefreshLeaderboards(context.Background()))
+
+	board, err := uc.GetLeaderboard(context.Background(), entity.LeaderboardMetricReceived, entity.LeaderboardPeriodWeek)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.LeaderboardEntry{
+		{Rank: 1, User: "alice", Value: 50},
+		{Rank: 1, User: "bob", Value: 50},
+		{Rank: 3, User: "carol", Value: 10},
+	}, board.Entries)
+	assert.NotNil(t, board.Since)
+
+	board, err = uc.GetLeaderboard(context.Background(), entity.LeaderboardMetricPurchases, entity.LeaderboardPeriodAll)
+	assert.NoError(t, err)
+	assert.Empty(t, board.Entries)
+	assert.Nil(t, board.Since)
+	mockRepo.AssertNumberOfCalls(t, "GetTop", len(leaderboardMetrics)*len(leaderboardPeriods))
+
+	_, err = uc.GetLeaderboard(context.Background(), "likes", entity.LeaderboardPeriodWeek)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboard)
+}
+
+func TestLeaderboardUseCase_OptOutFilteredAtReadTime(t *testing.T) {
+	mockRepo := new(MockLeaderboardRepository)
+	mockRepo.On("GetTop", mock.Anything, mock.Anything, mock.Anything, 2*leaderboardHeadroom).
+		Return([]entity.LeaderboardEntry{{User: "alice", Value: 50}, {User: "bob", Value: 40}, {User: "carol", Value: 10}}, nil)
+	mockRepo.On("SetOptOut", mock.Anything, "alice", true).Return(nil)
+
+	uc := NewLeaderboardUseCase(mockRepo, 2)
+	require.NoError(t, uc.RefreshLeaderboards(context.Background()))
+	require.NoError(t, uc.SetOptOut(context.Background(), "alice", true))
+
+	mockRepo.On("GetOptedOut", mock.Anything, []string{"alice", "bob", "carol"}).Return([]string{"alice"}, nil)
+	board, err := uc.GetLeaderboard(context.Background(), entity.LeaderboardMetricSent, entity.LeaderboardPeriodMonth)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.LeaderboardEntry{
+		{Rank: 1, User: "bob", Value: 40},
+		{Rank: 2, User: "carol", Value: 10},
+	}, board.Entries)
+	// Скрытие не пересчитывает рейтинги на запросе
+	mockRepo.AssertNumberOfCalls(t, "GetTop", len(leaderboardMetrics)*len(leaderboardPeriods))
+
+	// Фильтр не меняет кэш
+	cached := uc.boards[leaderboardKey(entity.LeaderboardMetricSent, entity.LeaderboardPeriodMonth)]
+	assert.Equal(t, "alice", cached.Entries[0].User)
+}
+
+func TestLeaderboardUseCase_RefreshFailureKeepsLastSnapshot(t *testing.T) {
+	mockRepo := new(MockLeaderboardRepository)
+	mockRepo.On("GetTop", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]entity.LeaderboardEntry{{User: "alice", Value: 50}}, nil).Times(len(leaderboardMetrics) * len(leaderboardPeriods))
+	mockRepo.On("GetTop", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]entity.LeaderboardEntry(nil), errors.New("statement timeout"))
+	mockRepo.On("GetOptedOut", mock.Anything, mock.Anything).Return([]string(nil), nil)
+
+	uc := NewLeaderboardUseCase(mockRepo, 0)
+	require.NoError(t, uc.RefreshLeaderboards(context.Background()))
+	assert.Error(t, uc.RefreshLeaderboards(context.Background()))
+
+	board, err := uc.GetLeaderboard(context.Background(), entity.LeaderboardMetricReceived, entity.LeaderboardPeriodWeek)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.LeaderboardEntry{{Rank: 1, User: "alice", Value: 50}}, board.Entries)
+}
+
+func TestLeaderboardPeriodStart(t *testing.T) {
+	// Среда 19 марта 2025
+	now := time.Date(2025, 3, 19, 15, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), leaderboardPeriodStart(entity.LeaderboardPeriodWeek, now))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), leaderboardPeriodStart(entity.LeaderboardPeriodMonth, now))
+	assert.True(t, leaderboardPeriodStart(entity.LeaderboardPeriodAll, now).IsZero())
+
+	// Воскресенье относится к неделе, начавшейся в понедельник
+	sunday := time.Date(2025, 3, 23, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), leaderboardPeriodStart(entity.LeaderboardPeriodWeek, sunday))
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Коды ошибок превышения лимитов, возвращаются клиенту в поле code
+const (
+	LimitCodeTransferAmount  = "transfer_amount_limit"
+	LimitCodeDaily           = "daily_limit"
+	LimitCodeWeekly          = "weekly_limit"
+	LimitCodeRecipientDaily  = "recipient_daily_limit"
+	LimitCodeAccountTooYoung = "account_too_young"
+)
+
+// TransferLimitError перевод нарушает ограничение профиля лимитов отправителя
+type TransferLimitError struct {
+	Code    string
+	Message string
+}
+
+func (e *TransferLimitError) Error() string {
+	return e.Message
+}
+
+func limitError(code, format string, args ...interface{}) *TransferLimitError {
+	return &TransferLimitError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// limitsFor возвращает профиль лимитов для роли. Роли без собственного профиля
+// используют профиль обычного пользователя
+func (uc *SendCoinUseCase) limitsFor(role string) entity.TransferLimits {
+	if limits, ok := uc.limits[role]; ok {
+		return limits
+	}
+	return uc.limits[entity.RoleUser]
+}
+
+// enforceLimits проверяет переводы отправителя против его профиля лимитов.
+// Вызывается после блокировки строки отправителя, поэтому параллельные переводы
+// одного пользователя не могут вместе превысить лимит
+func (uc *SendCoinUseCase) enforceLimits(ctx context.Context, repos *TxRepositories, fromUsername string, items []BatchTransferItem) error {
+	sender, err := repos.Users.GetUserByUsername(ctx, fromUsername)
+	if err != nil {
+		return fmt.Errorf("failed to get sender: %w", err)
+	}
+	if sender == nil {
+		return fmt.Errorf("sender does not exist: %s", fromUsername)
+	}
+
+	limits := uc.limitsFor(sender.Role)
+	if limits == (entity.TransferLimits{}) {
+		return nil
+	}
+
+	now := time.Now()
+	totals := &entity.SentTotals{}
+	if limits.Daily > 0 || limits.Weekly > 0 || limits.DailyPerRecipient > 0 {
+		recipients := make([]string, len(items))
+		for i, item := range items {
+			recipients[i] = item.ToUser
+		}
+		totals, err = repos.Transfers.GetSentTotals(ctx, fromUsername, recipients, now.Add(-24*time.Hour), now.Add(-7*24*time.Hour))
+		if err != nil {
+			return err
+		}
+	}
+
+	return checkTransferLimits(limits, sender, items, totals, now)
+}
+
+// checkTransferLimits проверяет переводы items с учетом уже отправленного за окна лимитов
+func checkTransferLimits(limits entity.TransferLimits, sender *entity.User, items []BatchTransferItem, totals *entity.SentTotals, now time.Time) error {
+	if limits.MinAccountAge > 0 && now.Sub(sender.CreatedAt) < limits.MinAccountAge {
+		return limitError(LimitCodeAccountTooYoung, "transfers are allowed %s after registration", limits.MinAccountAge)
+	}
+
+	total := 0
+	for _, item := range items {
+		if limits.MaxPerTransfer > 0 && item.Amount > limits.MaxPerTransfer {
+			return limitError(LimitCodeTransferAmount, "transfer amount %d exceeds the limit of %d", item.Amount, limits.MaxPerTransfer)
+		}
+		if limits.DailyPerRecipient > 0 && totals.DailyByRecipient[item.ToUser]+item.Amount > limits.DailyPerRecipient {
+			return limitError(LimitCodeRecipientDaily, "daily limit of %d for recipient %s exceeded", limits.DailyPerRecipient, item.ToUser)
+		}
+		total += item.Amount
+	}
+
+	if limits.Daily > 0 && totals.Daily+total > limits.Daily {
+		return limitError(LimitCodeDaily, "daily limit of %d exceeded, %d left", limits.Daily, max(limits.Daily-totals.Daily, 0))
+	}
+	if limits.Weekly > 0 && totals.Weekly+total > limits.Weekly {
+		return limitError(LimitCodeWeekly, "weekly limit of %d exceeded, %d left", limits.Weekly, max(limits.Weekly-totals.Weekly, 0))
+	}
+	return nil
+}
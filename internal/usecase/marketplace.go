@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidListing  = errors.New("invalid listing request")
+	ErrListingNotFound = errors.New("listing not found")
+	ErrListingClosed   = errors.New("listing is not active")
+)
+
+// MarketplaceRepository объявления и продажи маркетплейса
+type MarketplaceRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateListing(ctx context.Context, listing *entity.Listing) error
+	GetListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error)
+	LockListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error)
+	GetActiveListings(ctx context.Context, filter entity.ListingFilter) ([]entity.Listing, error)
+	GetListingsBySeller(ctx context.Context, seller string) ([]entity.Listing, error)
+	AddSale(ctx context.Context, sale *entity.Sale) error
+	CancelListing(ctx context.Context, id uuid.UUID) error
+}
+
+// MarketplaceUseCase сделки между пользователями. Оплата покупки ограничена лимитами переводов покупателя
+type MarketplaceUseCase struct {
+	marketplaceRepo MarketplaceRepository
+	sendCoinUseCase *SendCoinUseCase
+	txRepos         func(tx pgx.Tx) *TxRepositories
+}
+
+func NewMarketplaceUseCase(marketplaceRepo MarketplaceRepository, sendCoinUseCase *SendCoinUseCase) *MarketplaceUseCase {
+	return &MarketplaceUseCase{marketplaceRepo: marketplaceRepo, sendCoinUseCase: sendCoinUseCase, txRepos: NewTxRepositories}
+}
+
+// CreateListing выставляет товар из инвентаря продавца на продажу по цене price за единицу.
+// Выставленные единицы сразу списываются из инвентаря, поэтому их нельзя продать дважды
+func (uc *MarketplaceUseCase) CreateListing(ctx context.Context, seller, itemName string, quantity, price int) (*entity.Listing, error) {
+	listing := &entity.Listing{
+		Seller:   seller,
+		ItemName: strings.TrimSpace(itemName),
+		Price:    price,
+		Quantity: quantity,
+	}
+	if err := validateListing(listing); err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.marketplaceRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	if err := repos.Items.RemoveFromInventory(ctx, seller, listing.ItemName, quantity); err != nil {
+		return nil, err
+	}
+	if err := repos.Marketplace.CreateListing(ctx, listing); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Listing created", "listingID", listing.ID, "seller", seller, "item", listing.ItemName,
+		"quantity", quantity, "price", price)
+	return listing, nil
+}
+
+// GetListings возвращает активные объявления по фильтру
+func (uc *MarketplaceUseCase) GetListings(ctx context.Context, filter entity.ListingFilter) ([]entity.Listing, error) {
+	return uc.marketplaceRepo.GetActiveListings(ctx, filter)
+}
+
+// GetMyListings возвращает все объявления пользователя, включая проданные и снятые
+func (uc *MarketplaceUseCase) GetMyListings(ctx context.Context, seller string) ([]entity.Listing, error) {
+	return uc.marketplaceRepo.GetListingsBySeller(ctx, seller)
+}
+
+// GetListing возвращает объявление
+func (uc *MarketplaceUseCase) GetListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error) {
+	listing, err := uc.marketplaceRepo.GetListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, ErrListingNotFound
+	}
+	return listing, nil
+}
+
+// Buy покупает quantity единиц по объявлению: монеты переходят от покупателя продавцу, товар -
+// в инвентарь покупателя, все в одной транзакции. Покупки по одному объявлению сериализуются
+// блокировкой объявления, поэтому одна единица не продается дважды
+func (uc *MarketplaceUseCase) Buy(ctx context.Context, buyer string, id uuid.UUID, quantity int) (*entity.Sale, error) {
+	tx, err := uc.marketplaceRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	marketplaceRepo := repos.Marketplace
+	userRepo := repos.Users
+
+	listing, err := marketplaceRepo.LockListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil {
+		return nil, ErrListingNotFound
+	}
+	if err := checkPurchase(listing, buyer, quantity); err != nil {
+		return nil, err
+	}
+
+	locked, err := userRepo.LockUsers(ctx, buyer, listing.Seller)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(locked, buyer) {
+		return nil, fmt.Errorf("user does not exist: %s", buyer)
+	}
+	// Лоты деактивированного продавца больше не продаются
+	if !slices.Contains(locked, listing.Seller) {
+		return nil, ErrListingClosed
+	}
+
+	sale := &entity.Sale{
+		ListingID: listing.ID,
+		Seller:    listing.Seller,
+		Buyer:     buyer,
+		ItemName:  listing.ItemName,
+		Quantity:  quantity,
+		Amount:    listing.Price * quantity,
+	}
+	// Оплата - перевод продавцу
+	items := []BatchTransferItem{{ToUser: listing.Seller, Amount: sale.Amount}}
+	if err := uc.sendCoinUseCase.enforceLimits(ctx, repos, buyer, items); err != nil {
+		return nil, err
+	}
+	// Монеты переходят продавцу с исходной датой получения, как при переводе
+	lots, err := userRepo.DebitCoins(ctx, buyer, sale.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update buyer balance: %w", err)
+	}
+	if err := userRepo.CreditLots(ctx, listing.Seller, lots); err != nil {
+		return nil, err
+	}
+	if err := repos.Items.AddToInventory(ctx, buyer, listing.ItemName, quantity); err != nil {
+		return nil, fmt.Errorf("failed to add item to inventory: %w", err)
+	}
+	if err := marketplaceRepo.AddSale(ctx, sale); err != nil {
+		return nil, err
+	}
+	if err := repos.Ledger.RecordSale(ctx, sale); err != nil {
+		return nil, fmt.Errorf("failed to record sale in ledger: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	publishEvents(ctx, uc.sendCoinUseCase.events, entity.Event{Kind: entity.EventPurchase, UserName: buyer})
+
+	slog.Info("Listing purchased", "listingID", id, "buyer", buyer, "seller", listing.Seller,
+		"item", listing.ItemName, "quantity", quantity, "amount", sale.Amount)
+	return sale, nil
+}
+
+// CancelListing снимает объявление продавца с продажи и возвращает непроданный остаток в его инвентарь
+func (uc *MarketplaceUseCase) CancelListing(ctx context.Context, seller string, id uuid.UUID) (*entity.Listing, error) {
+	tx, err := uc.marketplaceRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	listing, err := repos.Marketplace.LockListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Чужое объявление для продавца не существует
+	if listing == nil || listing.Seller != seller {
+		return nil, ErrListingNotFound
+	}
+	if listing.Status != entity.ListingStatusActive {
+		return nil, ErrListingClosed
+	}
+
+	if err := repos.Items.AddToInventory(ctx, seller, listing.ItemName, listing.Remaining); err != nil {
+		return nil, fmt.Errorf("failed to return item to inventory: %w", err)
+	}
+	if err := repos.Marketplace.CancelListing(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	listing.Status = entity.ListingStatusCancelled
+	slog.Info("Listing cancelled", "listingID", id, "seller", seller, "returned", listing.Remaining)
+	return listing, nil
+}
+
+// checkPurchase проверяет, можно ли купить quantity единиц по объявлению
+func checkPurchase(listing *entity.Listing, buyer string, quantity int) error {
+	switch {
+	case listing.Status != entity.ListingStatusActive:
+		return ErrListingClosed
+	case listing.Seller == buyer:
+		return fmt.Errorf("%w: cannot buy own listing", ErrInvalidListing)
+	case quantity <= 0:
+		return fmt.Errorf("%w: quantity must be positive: %d", ErrInvalidListing, quantity)
+	case quantity > listing.Remaining:
+		return fmt.Errorf("%w: only %d left", ErrInvalidListing, listing.Remaining)
+	}
+	return nil
+}
+
+func validateListing(listing *entity.Listing) error {
+	switch {
+	case listing.ItemName == "":
+		return fmt.Errorf("%w: item is required", ErrInvalidListing)
+	case listing.Quantity <= 0:
+		return fmt.Errorf("%w: quantity must be positive: %d", ErrInvalidListing, listing.Quantity)
+	case listing.Price <= 0:
+		return fmt.Errorf("%w: price must be positive: %d", ErrInvalidListing, listing.Price)
+	// Сумма сделки хранится в INT
+	case listing.Price > math.MaxInt32/listing.Quantity:
+		return fmt.Errorf("%w: total price is too large", ErrInvalidListing)
+	}
+	return nil
+}
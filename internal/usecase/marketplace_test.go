@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMarketplaceRepository struct {
+	mock.Mock
+}
+
+func (m *MockMarketplaceRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockMarketplaceRepository) CreateListing(ctx context.Context, listing *entity.Listing) error {
+	args := m.Called(ctx, listing)
+	return args.Error(0)
+}
+
+func (m *MockMarketplaceRepository) GetListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Listing), args.Error(1)
+}
+
+func (m *MockMarketplaceRepository) LockListing(ctx context.Context, id uuid.UUID) (*entity.Listing, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Listing), args.Error(1)
+}
+
+func (m *MockMarketplaceRepository) GetActiveListings(ctx context.Context, filter entity.ListingFilter) ([]entity.Listing, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]entity.Listing), args.Error(1)
+}
+
+func (m *MockMarketplaceRepository) GetListingsBySeller(ctx context.Context, seller string) ([]entity.Listing, error) {
+	args := m.Called(ctx, seller)
+	return args.Get(0).([]entity.Listing), args.Error(1)
+}
+
+func (m *MockMarketplaceRepository) AddSale(ctx context.Context, sale *entity.Sale) error {
+	args := m.Called(ctx, sale)
+	return args.Error(0)
+}
+
+func (m *MockMarketplaceRepository) CancelListing(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestValidateListing(t *testing.T) {
+	valid := entity.Listing{Seller: "alice", ItemName: "pen", Quantity: 5, Price: 10}
+
+	for name, tc := range map[string]struct {
+		modify func(l *entity.Listing)
+		valid  bool
+	}{
+		"valid":              {func(l *entity.Listing) {}, true},
+		"empty item":         {func(l *entity.Listing) { l.ItemName = "" }, false},
+		"zero quantity":      {func(l *entity.Listing) { l.Quantity = 0 }, false},
+		"negative quantity":  {func(l *entity.Listing) { l.Quantity = -1 }, false},
+		"zero price":         {func(l *entity.Listing) { l.Price = 0 }, false},
+		"total overflows":    {func(l *entity.Listing) { l.Price = math.MaxInt32/l.Quantity + 1 }, false},
+		"max total price ok": {func(l *entity.Listing) { l.Quantity = 1; l.Price = math.MaxInt32 }, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			listing := valid
+			tc.modify(&listing)
+			err := validateListing(&listing)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidListing)
+			}
+		})
+	}
+}
+
+func TestCheckPurchase(t *testing.T) {
+	listing := &entity.Listing{Seller: "alice", ItemName: "pen", Price: 10, Quantity: 5, Remaining: 3, Status: entity.ListingStatusActive}
+
+	assert.NoError(t, checkPurchase(listing, "bob", 1))
+	assert.NoError(t, checkPurchase(listing, "bob", 3))
+	assert.ErrorIs(t, checkPurchase(listing, "bob", 4), ErrInvalidListing)
+	assert.ErrorIs(t, checkPurchase(listing, "bob", 0), ErrInvalidListing)
+	assert.ErrorIs(t, checkPurchase(listing, "alice", 1), ErrInvalidListing)
+
+	for _, status := range []string{entity.ListingStatusSold, entity.ListingStatusCancelled} {
+		closed := *listing
+		closed.Status = status
+		assert.ErrorIs(t, checkPurchase(&closed, "bob", 1), ErrListingClosed)
+	}
+}
+
+func TestMarketplaceUseCase_CreateListing(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+
+	repos.items.On("RemoveFromInventory", mock.Anything, "alice", "pen", 2).Return(nil)
+	repos.marketplace.On("CreateListing", mock.Anything, mock.MatchedBy(func(l *entity.Listing) bool {
+		return l.Seller == "alice" && l.ItemName == "pen" && l.Quantity == 2 && l.Price == 10
+	})).Return(nil)
+
+	_, err := uc.CreateListing(context.Background(), "alice", " pen ", 2, 10)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestMarketplaceUseCase_CreateListing_NotEnoughInInventory(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+	repos.items.On("RemoveFromInventory", mock.Anything, "alice", "pen", 2).Return(repository.ErrNotEnoughInInventory)
+
+	_, err := uc.CreateListing(context.Background(), "alice", "pen", 2, 10)
+
+	assert.ErrorIs(t, err, repository.ErrNotEnoughInInventory)
+	assert.False(t, repos.tx.committed)
+	repos.marketplace.AssertNotCalled(t, "CreateListing", mock.Anything, mock.Anything)
+}
+
+func TestMarketplaceUseCase_Buy(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, dailyLimits)
+	handler := &recordingEventHandler{}
+	uc.sendCoinUseCase.events = handler
+	listing := activeListing()
+	lots := []entity.CoinLot{{GrantedOn: entity.LotDate(time.Now()), Amount: 20}}
+
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "alice"}).Return([]string{"alice", "bob"}, nil)
+	expectSentToday(repos, "bob", "alice", 80)
+	repos.users.On("DebitCoins", mock.Anything, "bob", 20).Return(lots, nil)
+	repos.users.On("CreditLots", mock.Anything, "alice", lots).Return(nil)
+	repos.items.On("AddToInventory", mock.Anything, "bob", "pen", 2).Return(nil)
+	repos.marketplace.On("AddSale", mock.Anything, mock.MatchedBy(func(s *entity.Sale) bool {
+		return s.Buyer == "bob" && s.Seller == "alice" && s.Quantity == 2 && s.Amount == 20
+	})).Return(nil)
+	repos.ledger.On("RecordSale", mock.Anything, mock.Anything).Return(nil)
+
+	sale, err := uc.Buy(context.Background(), "bob", listing.ID, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, 20, sale.Amount)
+	assert.True(t, repos.tx.committed)
+	assert.Equal(t, []entity.Event{{Kind: entity.EventPurchase, UserName: "bob"}}, handler.events)
+	repos.assertExpectations(t)
+}
+
+func TestMarketplaceUseCase_Buy_DailyLimit(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, dailyLimits)
+	listing := activeListing()
+
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "alice"}).Return([]string{"alice", "bob"}, nil)
+	expectSentToday(repos, "bob", "alice", 80)
+
+	_, err := uc.Buy(context.Background(), "bob", listing.ID, 3)
+
+	var limitErr *TransferLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitCodeDaily, limitErr.Code)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "DebitCoins", mock.Anything, mock.Anything, mock.Anything)
+	repos.items.AssertNotCalled(t, "AddToInventory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMarketplaceUseCase_Buy_OwnListing(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+	listing := activeListing()
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+
+	_, err := uc.Buy(context.Background(), "alice", listing.ID, 1)
+
+	assert.ErrorIs(t, err, ErrInvalidListing)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "LockUsers", mock.Anything, mock.Anything)
+}
+
+func TestMarketplaceUseCase_Buy_DeactivatedSeller(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+	listing := activeListing()
+
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "alice"}).Return([]string{"bob"}, nil)
+
+	_, err := uc.Buy(context.Background(), "bob", listing.ID, 1)
+
+	assert.ErrorIs(t, err, ErrListingClosed)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "DebitCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMarketplaceUseCase_CancelListing(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+	listing := activeListing()
+
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+	repos.items.On("AddToInventory", mock.Anything, "alice", "pen", 3).Return(nil)
+	repos.marketplace.On("CancelListing", mock.Anything, listing.ID).Return(nil)
+
+	cancelled, err := uc.CancelListing(context.Background(), "alice", listing.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.ListingStatusCancelled, cancelled.Status)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestMarketplaceUseCase_CancelListing_NotSeller(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestMarketplaceUseCase(repos, SendCoinConfig{})
+	listing := activeListing()
+	repos.marketplace.On("LockListing", mock.Anything, listing.ID).Return(listing, nil)
+
+	_, err := uc.CancelListing(context.Background(), "bob", listing.ID)
+
+	assert.ErrorIs(t, err, ErrListingNotFound)
+	assert.False(t, repos.tx.committed)
+	repos.items.AssertNotCalled(t, "AddToInventory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
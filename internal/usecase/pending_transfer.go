@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	holdReasonEscrow = "escrow"
+	// defaultPendingTransferTTL срок подтверждения перевода, если он не задан в настройках
+	defaultPendingTransferTTL = 7 * 24 * time.Hour
+	// pendingTransferBatchSize сколько просроченных переводов обрабатывается за одну транзакцию фоновой задачи
+	pendingTransferBatchSize = 100
+)
+
+var (
+	ErrTransferNotPending   = errors.New("transfer is not pending")
+	ErrTransferNotCompleted = errors.New("transfer is not completed")
+)
+
+// createPendingTransfer замораживает монеты отправителя и создает перевод, ожидающий решения получателя.
+// Лимиты проверяются при создании, поэтому принятие перевода их уже не нарушит
+func (uc *SendCoinUseCase) createPendingTransfer(ctx context.Context, tx pgx.Tx, fromUsername string, toUsername string, amount int, memo string) (*entity.Transaction, error) {
+	memo, err := validateTransfer(fromUsername, toUsername, amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := uc.txRepos(tx)
+
+	locked, err := repos.Users.LockUsers(ctx, fromUsername, toUsername)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingUsers([]string{toUsername}, locked)) > 0 {
+		return nil, fmt.Errorf("recipient does not exist")
+	}
+
+	if err := uc.enforceLimits(ctx, repos, fromUsername, []BatchTransferItem{{ToUser: toUsername, Amount: amount}}); err != nil {
+		return nil, err
+	}
+
+	if err := repos.Users.HoldCoins(ctx, fromUsername, amount); err != nil {
+		return nil, fmt.Errorf("failed to hold coins: %w", err)
+	}
+	hold := &entity.Hold{UserName: fromUsername, Amount: amount, Reason: holdReasonEscrow}
+	if err := repos.Holds.CreateHold(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	expiresAt := time.Now().Add(uc.pendingTTL)
+	transfer := &entity.Transaction{
+		FromUser:  fromUsername,
+		ToUser:    toUsername,
+		Amount:    amount,
+		Memo:      memo,
+		Status:    entity.TransferStatusPending,
+		HoldID:    &hold.ID,
+		ExpiresAt: &expiresAt,
+	}
+	if err := repos.Transfers.CreateTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to create transfer record: %w", err)
+	}
+	return transfer, nil
+}
+
+// AcceptPendingTransfer получатель принимает перевод: замороженные монеты списываются у отправителя
+// и зачисляются получателю
+func (uc *SendCoinUseCase) AcceptPendingTransfer(ctx context.Context, recipient string, id uuid.UUID) (*entity.Transaction, error) {
+	return uc.resolvePendingTransfer(ctx, id, func(transfer *entity.Transaction) bool {
+		return transfer.ToUser == recipient
+	}, entity.TransferStatusCompleted, nil)
+}
+
+// RejectPendingTransfer получатель отклоняет перевод, монеты размораживаются у отправителя
+func (uc *SendCoinUseCase) RejectPendingTransfer(ctx context.Context, recipient string, id uuid.UUID) (*entity.Transaction, error) {
+	return uc.resolvePendingTransfer(ctx, id, func(transfer *entity.Transaction) bool {
+		return transfer.ToUser == recipient
+	}, entity.TransferStatusRejected, nil)
+}
+
+// ApprovePendingTransfer руководитель принимает перевод вместо получателя. Решение записывается
+// в журнал аудита
+func (uc *SendCoinUseCase) ApprovePendingTransfer(ctx context.Context, manager string, id uuid.UUID) (*entity.Transaction, error) {
+	return uc.resolvePendingTransfer(ctx, id, anyParty, entity.TransferStatusCompleted, &entity.AuditEntry{
+		Actor:  manager,
+		Action: entity.AuditActionTransferApproved,
+	})
+}
+
+// DeclinePendingTransfer руководитель отклоняет перевод вместо получателя
+func (uc *SendCoinUseCase) DeclinePendingTransfer(ctx context.Context, manager string, id uuid.UUID) (*entity.Transaction, error) {
+	return uc.resolvePendingTransfer(ctx, id, anyParty, entity.TransferStatusRejected, &entity.AuditEntry{
+		Actor:  manager,
+		Action: entity.AuditActionTransferRejected,
+	})
+}
+
+// CancelPendingTransfer отправитель отзывает перевод, пока получатель его не принял
+func (uc *SendCoinUseCase) CancelPendingTransfer(ctx context.Context, sender string, id uuid.UUID) (*entity.Transaction, error) {
+	return uc.resolvePendingTransfer(ctx, id, func(transfer *entity.Transaction) bool {
+		return transfer.FromUser == sender
+	}, entity.TransferStatusCancelled, nil)
+}
+
+// ExpirePendingTransfers возвращает отправителям монеты переводов, которые не приняли вовремя
+func (uc *SendCoinUseCase) ExpirePendingTransfers(ctx context.Context) error {
+	tx, err := uc.transactionRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	transfers, err := repos.Transfers.LockExpiredPendingTransfers(ctx, time.Now(), pendingTransferBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range transfers {
+		if err := settlePendingTransfer(ctx, repos, &transfers[i], entity.TransferStatusExpired); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(transfers) > 0 {
+		slog.Info("Pending transfers expired", "count", len(transfers))
+	}
+	return nil
+}
+
+// anyParty разрешает решение по любому переводу, его используют руководители
+func anyParty(*entity.Transaction) bool {
+	return true
+}
+
+// resolvePendingTransfer завершает ожидающий перевод, если isParty разрешает пользователю это сделать.
+// Чужие переводы неотличимы от несуществующих. Если передан audit, решение записывается в журнал аудита
+func (uc *SendCoinUseCase) resolvePendingTransfer(ctx context.Context, id uuid.UUID, isParty func(*entity.Transaction) bool, status string, audit *entity.AuditEntry) (*entity.Transaction, error) {
+	tx, err := uc.transactionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	// Блокировка перевода не дает принять и отменить его одновременно
+	repos := uc.txRepos(tx)
+	transfer, err := repos.Transfers.GetTransferForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil || !isParty(transfer) {
+		return nil, ErrTransferNotFound
+	}
+	if transfer.Status != entity.TransferStatusPending {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrTransferNotPending, transfer.Status)
+	}
+	// Просроченный перевод ждет фоновой задачи, принять его уже нельзя
+	if status == entity.TransferStatusCompleted && !transfer.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: transfer has expired", ErrTransferNotPending)
+	}
+
+	if err := settlePendingTransfer(ctx, repos, transfer, status); err != nil {
+		return nil, err
+	}
+	if audit != nil {
+		audit.TargetID = &transfer.ID
+		audit.Details = map[string]any{"from": transfer.FromUser, "to": transfer.ToUser, "amount": transfer.Amount}
+		if err := repos.Audit.Record(ctx, audit); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Pending transfer resolved", "transferID", id, "status", status)
+	if status == entity.TransferStatusCompleted {
+		uc.publishTransfer(ctx, transfer.FromUser, transfer.ToUser)
+	}
+	return transfer, nil
+}
+
+// settlePendingTransfer размораживает монеты перевода и завершает его со статусом status.
+// При принятии монеты переходят получателю, а перевод проводится по главной книге
+func settlePendingTransfer(ctx context.Context, repos *TxRepositories, transfer *entity.Transaction, status string) error {
+	if _, err := repos.Users.LockUsers(ctx, transfer.FromUser, transfer.ToUser); err != nil {
+		return err
+	}
+
+	holdStatus := entity.HoldStatusReleased
+	if status == entity.TransferStatusCompleted {
+		holdStatus = entity.HoldStatusCaptured
+	}
+	hold, err := repos.Holds.ResolveHold(ctx, *transfer.HoldID, holdStatus)
+	if err != nil {
+		return err
+	}
+	if err := repos.Users.ReleaseHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+		return err
+	}
+
+	if status == entity.TransferStatusCompleted {
+		if err := repos.Users.UpdateUserAfterTransfer(ctx, transfer.FromUser, transfer.ToUser, transfer.Amount); err != nil {
+			return fmt.Errorf("failed to update balances: %w", err)
+		}
+		if err := repos.Ledger.RecordTransfer(ctx, transfer); err != nil {
+			return fmt.Errorf("failed to record transfer in ledger: %w", err)
+		}
+	}
+
+	return repos.Transfers.ResolvePendingTransfer(ctx, transfer, status)
+}
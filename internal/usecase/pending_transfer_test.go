@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCoinUseCase_AcceptPendingTransfer(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+	transfer := pendingTransfer()
+	expectSettlement(repos, transfer, entity.TransferStatusCompleted)
+
+	_, err := uc.AcceptPendingTransfer(context.Background(), "bob", transfer.ID)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestSendCoinUseCase_AcceptPendingTransfer_NotRecipient(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+	transfer := pendingTransfer()
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, transfer.ID).Return(transfer, nil)
+
+	_, err := uc.AcceptPendingTransfer(context.Background(), "carol", transfer.ID)
+
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+	assert.False(t, repos.tx.committed)
+}
+
+func TestSendCoinUseCase_ApprovePendingTransfer_ByManager(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+	transfer := pendingTransfer()
+	expectSettlement(repos, transfer, entity.TransferStatusCompleted)
+	repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+		return e.Actor == "manager" && e.Action == entity.AuditActionTransferApproved && *e.TargetID == transfer.ID
+	})).Return(nil)
+
+	_, err := uc.ApprovePendingTransfer(context.Background(), "manager", transfer.ID)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestSendCoinUseCase_DeclinePendingTransfer_ByManager(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+	transfer := pendingTransfer()
+	expectSettlement(repos, transfer, entity.TransferStatusRejected)
+	repos.audit.On("Record", mock.Anything, mock.MatchedBy(func(e *entity.AuditEntry) bool {
+		return e.Actor == "manager" && e.Action == entity.AuditActionTransferRejected
+	})).Return(nil)
+
+	_, err := uc.DeclinePendingTransfer(context.Background(), "manager", transfer.ID)
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestSendCoinUseCase_ApprovePendingTransfer_Expired(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestSendCoinUseCase(repos, SendCoinConfig{})
+	transfer := pendingTransfer()
+	expired := time.Now().Add(-time.Minute)
+	transfer.ExpiresAt = &expired
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, transfer.ID).Return(transfer, nil)
+
+	_, err := uc.ApprovePendingTransfer(context.Background(), "manager", transfer.ID)
+
+	assert.ErrorIs(t, err, ErrTransferNotPending)
+	assert.False(t, repos.tx.committed)
+	repos.audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+}
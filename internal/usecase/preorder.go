@@ -0,0 +1,315 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	holdReasonPreorder = "preorder"
+	// preorderBatchSize сколько предзаказов обрабатывается за один запуск фоновой задачи
+	preorderBatchSize = 100
+)
+
+var (
+	ErrPreorderNotFound = errors.New("preorder not found")
+	ErrItemInStock      = errors.New("item is in stock, buy it directly")
+)
+
+// PreorderRepository предзаказы товаров, которых нет на складе
+type PreorderRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreatePreorder(ctx context.Context, preorder *entity.Preorder) error
+	GetPendingPreorderForUpdate(ctx context.Context, id uuid.UUID, userName string) (*entity.Preorder, error)
+	GetPreordersByUsername(ctx context.Context, userName string) ([]entity.Preorder, error)
+	LockFulfillablePreorders(ctx context.Context, after entity.Preorder, limit int) ([]entity.Preorder, error)
+	LockExpiredPreorders(ctx context.Context, now time.Time, limit int) ([]entity.Preorder, error)
+	SetStatus(ctx context.Context, id uuid.UUID, status string) error
+}
+
+type PreorderUseCase struct {
+	preorderRepo PreorderRepository
+	txRepos      func(tx pgx.Tx) *TxRepositories
+	ttl          time.Duration
+}
+
+func NewPreorderUseCase(preorderRepo PreorderRepository, ttl time.Duration) *PreorderUseCase {
+	return &PreorderUseCase{preorderRepo: preorderRepo, txRepos: NewTxRepositories, ttl: ttl}
+}
+
+// CreatePreorder замораживает стоимость товара, которого нет на складе
+func (uc *PreorderUseCase) CreatePreorder(ctx context.Context, userName, itemName string) (*entity.Preorder, error) {
+	tx, err := uc.preorderRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	item, err := repos.Items.GetItemByName(ctx, itemName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil {
+		return nil, fmt.Errorf("item not found: %s", itemName)
+	}
+	// Предзаказ возможен только для товаров с отслеживаемым и нулевым остатком
+	if item.Stock == nil || *item.Stock > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrItemInStock, itemName)
+	}
+
+	if err := repos.Users.HoldCoins(ctx, userName, item.Price); err != nil {
+		return nil, fmt.Errorf("failed to hold coins: %w", err)
+	}
+
+	hold := &entity.Hold{UserName: userName, Amount: item.Price, Reason: holdReasonPreorder}
+	if err := repos.Holds.CreateHold(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	preorder := &entity.Preorder{
+		UserName:  userName,
+		ItemName:  item.Name,
+		Price:     item.Price,
+		HoldID:    hold.ID,
+		ExpiresAt: time.Now().Add(uc.ttl),
+	}
+	if err := repos.Preorders.CreatePreorder(ctx, preorder); err != nil {
+		return nil, fmt.Errorf("failed to create preorder: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Preorder placed", "userName", userName, "item", itemName, "amount", item.Price)
+	return preorder, nil
+}
+
+// CancelPreorder отменяет ожидающий предзаказ и размораживает монеты
+func (uc *PreorderUseCase) CancelPreorder(ctx context.Context, userName string, id uuid.UUID) error {
+	tx, err := uc.preorderRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	preorder, err := repos.Preorders.GetPendingPreorderForUpdate(ctx, id, userName)
+	if err != nil {
+		return err
+	}
+	if preorder == nil {
+		return ErrPreorderNotFound
+	}
+
+	if err := releasePreorder(ctx, repos, preorder, entity.PreorderStatusCancelled); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Preorder cancelled", "userName", userName, "preorderID", id)
+	return nil
+}
+
+// GetPreorders возвращает предзаказы пользователя
+func (uc *PreorderUseCase) GetPreorders(ctx context.Context, userName string) ([]entity.Preorder, error) {
+	preorders, err := uc.preorderRepo.GetPreordersByUsername(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	if preorders == nil {
+		preorders = []entity.Preorder{}
+	}
+	return preorders, nil
+}
+
+// FulfillPreorders превращает предзаказы в покупки, когда товар появился на складе.
+// Предзаказы обслуживаются в порядке создания, не больше preorderBatchSize за запуск
+func (uc *PreorderUseCase) FulfillPreorders(ctx context.Context) error {
+	var after entity.Preorder
+	processed, fulfilled := 0, 0
+	for processed < preorderBatchSize {
+		preorder, ok, err := uc.fulfillNextPreorder(ctx, after)
+		if err != nil {
+			return err
+		}
+		if preorder == nil {
+			break
+		}
+		// Следующий предзаказ ищется после обработанного, поэтому оставшийся ожидать
+		// предзаказ не выбирается повторно в этом же запуске
+		after = *preorder
+		processed++
+		if ok {
+			fulfilled++
+		}
+	}
+
+	if fulfilled > 0 {
+		slog.Info("Preorders fulfilled", "count", fulfilled)
+	}
+	return nil
+}
+
+// fulfillNextPreorder выполняет в отдельной транзакции первый после after предзаказ, товар которого есть на складе.
+// Если товара не хватило, предзаказ остается ожидать. Если выполнить его не удалось по другой причине,
+// монеты размораживаются, а предзаказ завершается со статусом failed, чтобы не блокировать очередь.
+// Возвращает обработанный предзаказ или nil, если таких больше нет, и признак выполнения
+func (uc *PreorderUseCase) fulfillNextPreorder(ctx context.Context, after entity.Preorder) (*entity.Preorder, bool, error) {
+	tx, err := uc.preorderRepo.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	preorders, err := uc.txRepos(tx).Preorders.LockFulfillablePreorders(ctx, after, 1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(preorders) == 0 {
+		return nil, false, nil
+	}
+	preorder := &preorders[0]
+
+	// Попытка выполняется в точке сохранения: ее изменения откатываются целиком
+	fulfillErr := uc.fulfillPreorder(ctx, tx, preorder)
+	switch {
+	case fulfillErr == nil:
+	case errors.Is(fulfillErr, repository.ErrOutOfStock):
+		slog.Info("Preorder is waiting for stock", "preorderID", preorder.ID, "error", fulfillErr)
+	default:
+		slog.Error("Failed to fulfill preorder", "preorderID", preorder.ID, "error", fulfillErr)
+		if err := releasePreorder(ctx, uc.txRepos(tx), preorder, entity.PreorderStatusFailed); err != nil {
+			return nil, false, fmt.Errorf("failed to mark preorder %s as failed: %w", preorder.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return preorder, fulfillErr == nil, nil
+}
+
+// ExpirePreorders отменяет просроченные предзаказы и размораживает монеты
+func (uc *PreorderUseCase) ExpirePreorders(ctx context.Context) error {
+	tx, err := uc.preorderRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	preorders, err := repos.Preorders.LockExpiredPreorders(ctx, time.Now(), preorderBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range preorders {
+		if err := releasePreorder(ctx, repos, &preorders[i], entity.PreorderStatusExpired); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(preorders) > 0 {
+		slog.Info("Preorders expired", "count", len(preorders))
+	}
+	return nil
+}
+
+// fulfillPreorder списывает замороженные монеты и выдает товар в точке сохранения
+func (uc *PreorderUseCase) fulfillPreorder(ctx context.Context, tx pgx.Tx, preorder *entity.Preorder) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer func() {
+		if err := savepoint.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(savepoint)
+
+	item, err := repos.Items.GetItemByName(ctx, preorder.ItemName)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil {
+		return fmt.Errorf("item not found: %s", preorder.ItemName)
+	}
+
+	if err := deliverItem(ctx, repos.Items, preorder.UserName, item); err != nil {
+		return err
+	}
+
+	hold, err := repos.Holds.ResolveHold(ctx, preorder.HoldID, entity.HoldStatusCaptured)
+	if err != nil {
+		return err
+	}
+	if err := repos.Users.CaptureHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+		return err
+	}
+	purchase := &entity.Purchase{
+		UserName: preorder.UserName,
+		ItemName: preorder.ItemName,
+		Price:    preorder.Price,
+	}
+	if err := repos.Transfers.CreatePurchase(ctx, purchase); err != nil {
+		return err
+	}
+	if err := repos.Ledger.RecordPurchase(ctx, purchase); err != nil {
+		return err
+	}
+	if err := repos.Preorders.SetStatus(ctx, preorder.ID, entity.PreorderStatusFulfilled); err != nil {
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+// releasePreorder размораживает монеты предзаказа и завершает его с указанным статусом
+func releasePreorder(ctx context.Context, repos *TxRepositories, preorder *entity.Preorder, status string) error {
+	hold, err := repos.Holds.ResolveHold(ctx, preorder.HoldID, entity.HoldStatusReleased)
+	if err != nil {
+		return err
+	}
+	if err := repos.Users.ReleaseHeldCoins(ctx, hold.UserName, hold.Amount); err != nil {
+		return err
+	}
+	return repos.Preorders.SetStatus(ctx, preorder.ID, status)
+}
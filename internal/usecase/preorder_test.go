@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPreorderRepository struct {
+	mock.Mock
+}
+
+func (m *MockPreorderRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockPreorderRepository) CreatePreorder(ctx context.Context, preorder *entity.Preorder) error {
+	args := m.Called(ctx, preorder)
+	return args.Error(0)
+}
+
+func (m *MockPreorderRepository) GetPendingPreorderForUpdate(ctx context.Context, id uuid.UUID, userName string) (*entity.Preorder, error) {
+	args := m.Called(ctx, id, userName)
+	return args.Get(0).(*entity.Preorder), args.Error(1)
+}
+
+func (m *MockPreorderRepository) GetPreordersByUsername(ctx context.Context, userName string) ([]entity.Preorder, error) {
+	args := m.Called(ctx, userName)
+	return args.Get(0).([]entity.Preorder), args.Error(1)
+}
+
+func (m *MockPreorderRepository) LockFulfillablePreorders(ctx context.Context, after entity.Preorder, limit int) ([]entity.Preorder, error) {
+	args := m.Called(ctx, after, limit)
+	return args.Get(0).([]entity.Preorder), args.Error(1)
+}
+
+func (m *MockPreorderRepository) LockExpiredPreorders(ctx context.Context, now time.Time, limit int) ([]entity.Preorder, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]entity.Preorder), args.Error(1)
+}
+
+func (m *MockPreorderRepository) SetStatus(ctx context.Context, id uuid.UUID, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func TestPreorderUseCase_CreatePreorder(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	stock := 0
+	repos.items.On("GetItemByName", mock.Anything, "hoody").Return(&entity.Item{Name: "hoody", Price: 300, Stock: &stock}, nil)
+	repos.users.On("HoldCoins", mock.Anything, "alice", 300).Return(nil)
+	repos.holds.On("CreateHold", mock.Anything, mock.MatchedBy(func(h *entity.Hold) bool {
+		return h.UserName == "alice" && h.Amount == 300 && h.Reason == holdReasonPreorder
+	})).Return(nil)
+	repos.preorders.On("CreatePreorder", mock.Anything, mock.MatchedBy(func(p *entity.Preorder) bool {
+		return p.UserName == "alice" && p.ItemName == "hoody" && p.Price == 300
+	})).Return(nil)
+
+	preorder, err := uc.CreatePreorder(context.Background(), "alice", "hoody")
+
+	require.NoError(t, err)
+	assert.Equal(t, 300, preorder.Price)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestPreorderUseCase_CreatePreorder_InStock(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	stock := 3
+	repos.items.On("GetItemByName", mock.Anything, "hoody").Return(&entity.Item{Name: "hoody", Price: 300, Stock: &stock}, nil)
+
+	_, err := uc.CreatePreorder(context.Background(), "alice", "hoody")
+
+	assert.ErrorIs(t, err, ErrItemInStock)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "HoldCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPreorderUseCase_CancelPreorder(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	preorder := pendingPreorder("alice", "hoody", time.Now())
+	repos.preorders.On("GetPendingPreorderForUpdate", mock.Anything, preorder.ID, "alice").Return(&preorder, nil)
+	expectRelease(repos, preorder, entity.PreorderStatusCancelled)
+
+	require.NoError(t, uc.CancelPreorder(context.Background(), "alice", preorder.ID))
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestPreorderUseCase_CancelPreorder_NotFound(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	id := uuid.New()
+	repos.preorders.On("GetPendingPreorderForUpdate", mock.Anything, id, "bob").Return((*entity.Preorder)(nil), nil)
+
+	err := uc.CancelPreorder(context.Background(), "bob", id)
+
+	assert.ErrorIs(t, err, ErrPreorderNotFound)
+	repos.holds.AssertNotCalled(t, "ResolveHold", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPreorderUseCase_FulfillPreorders_FailureIsIsolated(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	now := time.Now()
+	broken := pendingPreorder("alice", "hoody", now.Add(-2*time.Minute))
+	waiting := pendingPreorder("bob", "welcome-pack", now.Add(-time.Minute))
+	healthy := pendingPreorder("carol", "cup", now)
+
+	// Каждый предзаказ выбирается после предыдущего в своей транзакции
+	repos.preorders.On("LockFulfillablePreorders", mock.Anything, entity.Preorder{}, 1).Return([]entity.Preorder{broken}, nil).Once()
+	repos.preorders.On("LockFulfillablePreorders", mock.Anything, broken, 1).Return([]entity.Preorder{waiting}, nil).Once()
+	repos.preorders.On("LockFulfillablePreorders", mock.Anything, waiting, 1).Return([]entity.Preorder{healthy}, nil).Once()
+	repos.preorders.On("LockFulfillablePreorders", mock.Anything, healthy, 1).Return([]entity.Preorder{}, nil).Once()
+
+	// Товар предзаказа удален из каталога: предзаказ завершается, монеты размораживаются
+	repos.items.On("GetItemByName", mock.Anything, "hoody").Return((*entity.Item)(nil), nil)
+	expectRelease(repos, broken, entity.PreorderStatusFailed)
+
+	// Компонента набора не хватило: предзаказ остается ожидать
+	repos.items.On("GetItemByName", mock.Anything, "welcome-pack").Return(&entity.Item{Name: "welcome-pack", Price: 20}, nil)
+	repos.items.On("ReserveStock", mock.Anything, "welcome-pack", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "welcome-pack").
+		Return([]entity.BundleComponent{{ItemName: "pen", Quantity: 2}}, nil)
+	repos.items.On("ReserveStock", mock.Anything, "pen", 2).Return(repository.ErrOutOfStock)
+
+	repos.items.On("GetItemByName", mock.Anything, "cup").Return(&entity.Item{Name: "cup", Price: 20}, nil)
+	repos.items.On("ReserveStock", mock.Anything, "cup", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "cup").Return([]entity.BundleComponent(nil), nil)
+	repos.items.On("AddToInventory", mock.Anything, "carol", "cup", 1).Return(nil)
+	repos.holds.On("ResolveHold", mock.Anything, healthy.HoldID, entity.HoldStatusCaptured).
+		Return(&entity.Hold{ID: healthy.HoldID, UserName: "carol", Amount: 20}, nil)
+	repos.users.On("CaptureHeldCoins", mock.Anything, "carol", 20).Return(nil)
+	repos.transfers.On("CreatePurchase", mock.Anything, mock.MatchedBy(func(p *entity.Purchase) bool {
+		return p.UserName == "carol" && p.ItemName == "cup"
+	})).Return(nil)
+	repos.ledger.On("RecordPurchase", mock.Anything, mock.Anything).Return(nil)
+	repos.preorders.On("SetStatus", mock.Anything, healthy.ID, entity.PreorderStatusFulfilled).Return(nil)
+
+	require.NoError(t, uc.FulfillPreorders(context.Background()))
+	assert.True(t, repos.tx.committed)
+	repos.preorders.AssertNotCalled(t, "SetStatus", mock.Anything, waiting.ID, mock.Anything)
+	repos.users.AssertNotCalled(t, "ReleaseHeldCoins", mock.Anything, "bob", mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestPreorderUseCase_FulfillPreorders_MarkFailedError(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestPreorderUseCase(repos)
+	broken := pendingPreorder("alice", "hoody", time.Now())
+
+	repos.preorders.On("LockFulfillablePreorders", mock.Anything, entity.Preorder{}, 1).Return([]entity.Preorder{broken}, nil).Once()
+	repos.items.On("GetItemByName", mock.Anything, "hoody").Return((*entity.Item)(nil), errors.New("connection reset"))
+	repos.holds.On("ResolveHold", mock.Anything, broken.HoldID, entity.HoldStatusReleased).
+		Return((*entity.Hold)(nil), errors.New("connection reset"))
+
+	err := uc.FulfillPreorders(context.Background())
+
+	assert.Error(t, err)
+	assert.False(t, repos.tx.committed)
+}
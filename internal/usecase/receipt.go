@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrPurchaseNotFound = errors.New("purchase not found")
+
+// ReceiptUseCase просмотр отдельных переводов и покупок и квитанции по ним.
+// Перевод видят его участники, покупку - покупатель; аудиторы видят все
+type ReceiptUseCase struct {
+	transactionRepo *repository.TransactionRepository
+}
+
+func NewReceiptUseCase(transactionRepo *repository.TransactionRepository) *ReceiptUseCase {
+	return &ReceiptUseCase{transactionRepo: transactionRepo}
+}
+
+// GetTransfer возвращает перевод, если viewer - его участник или аудитор.
+// Чужой перевод неотличим от несуществующего
+func (uc *ReceiptUseCase) GetTransfer(ctx context.Context, viewer, role string, id uuid.UUID) (*entity.Transaction, error) {
+	transfer, err := uc.transactionRepo.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil || (role != entity.RoleAuditor && viewer != transfer.FromUser && viewer != transfer.ToUser) {
+		return nil, ErrTransferNotFound
+	}
+	return transfer, nil
+}
+
+// GetTransferReceipt возвращает квитанцию о переводе
+func (uc *ReceiptUseCase) GetTransferReceipt(ctx context.Context, viewer, role string, id uuid.UUID) (*entity.Receipt, error) {
+	transfer, err := uc.GetTransfer(ctx, viewer, role, id)
+	if err != nil {
+		return nil, err
+	}
+	// Квитанция подтверждает движение монет, поэтому выдается только по завершенным переводам
+	if transfer.Status != entity.TransferStatusCompleted {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrTransferNotCompleted, transfer.Status)
+	}
+	return &entity.Receipt{
+		Number:         transfer.ID,
+		Kind:           entity.ReceiptKindTransfer,
+		CreatedAt:      transfer.CreatedAt,
+		IssuedAt:       time.Now().UTC(),
+		FromUser:       transfer.FromUser,
+		ToUser:         transfer.ToUser,
+		Amount:         transfer.Amount,
+		Memo:           transfer.Memo,
+		ReversedAmount: transfer.ReversedAmount,
+	}, nil
+}
+
+// GetPurchaseReceipt возвращает квитанцию о покупке, если viewer - покупатель или аудитор
+func (uc *ReceiptUseCase) GetPurchaseReceipt(ctx context.Context, viewer, role string, id uuid.UUID) (*entity.Receipt, error) {
+	purchase, err := uc.transactionRepo.GetPurchase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if purchase == nil || (role != entity.RoleAuditor && viewer != purchase.UserName) {
+		return nil, ErrPurchaseNotFound
+	}
+	return &entity.Receipt{
+		Number:    purchase.ID,
+		Kind:      entity.ReceiptKindPurchase,
+		CreatedAt: purchase.CreatedAt,
+		IssuedAt:  time.Now().UTC(),
+		FromUser:  purchase.UserName,
+		Item:      purchase.ItemName,
+		Amount:    purchase.Price,
+	}, nil
+}
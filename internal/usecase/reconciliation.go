@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Метрики сверки, доступны на /debug/vars
+var (
+	reconciliationRuns       = expvar.NewInt("reconciliation_runs_total")
+	reconciliationFailed     = expvar.NewInt("reconciliation_failed_runs_total")
+	reconciliationOK         = expvar.NewInt("reconciliation_last_ok")
+	reconciliationMismatches = expvar.NewInt("reconciliation_last_mismatches")
+	reconciliationLastRun    = expvar.NewInt("reconciliation_last_run_timestamp")
+)
+
+const (
+	InvariantLedgerBalanced  = "ledger_balanced"
+	InvariantCirculation     = "circulation_equals_minted_minus_spent"
+	InvariantUserBalances    = "user_balances_match_ledger"
+	InvariantUsersHaveLedger = "users_have_ledger_accounts"
+	InvariantLotsMatch       = "coin_lots_match_balances"
+	InvariantTeamBalances    = "team_balances_match_ledger"
+	InvariantCampaignTotals  = "campaign_totals_match_ledger"
+)
+
+type ReconciliationRepository interface {
+	GetSnapshot(ctx context.Context) (*entity.ReconciliationSnapshot, error)
+	SaveReport(ctx context.Context, report *entity.ReconciliationReport) error
+	GetLastReport(ctx context.Context) (*entity.ReconciliationReport, error)
+}
+
+type ReconciliationUseCase struct {
+	reconciliationRepo ReconciliationRepository
+}
+
+func NewReconciliationUseCase(reconciliationRepo ReconciliationRepository) *ReconciliationUseCase {
+	return &ReconciliationUseCase{reconciliationRepo: reconciliationRepo}
+}
+
+// Reconcile сверяет балансы пользователей с главной книгой, проверяет глобальные
+// инварианты и сохраняет отчет
+func (uc *ReconciliationUseCase) Reconcile(ctx context.Context) (*entity.ReconciliationReport, error) {
+	startedAt := time.Now()
+
+	snapshot, err := uc.reconciliationRepo.GetSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation snapshot: %w", err)
+	}
+
+	report := buildReconciliationReport(snapshot)
+	report.StartedAt = startedAt
+	report.FinishedAt = time.Now()
+
+	if err := uc.reconciliationRepo.SaveReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	reconciliationRuns.Add(1)
+	reconciliationMismatches.Set(int64(len(report.Mismatches)))
+	reconciliationLastRun.Set(report.FinishedAt.Unix())
+	if report.OK {
+		reconciliationOK.Set(1)
+		slog.Info("Reconciliation passed", "usersChecked", report.UsersChecked)
+	} else {
+		reconciliationOK.Set(0)
+		reconciliationFailed.Add(1)
+		for _, check := range report.Invariants {
+			if !check.OK {
+				slog.Error("Reconciliation invariant violated", "invariant", check.Name, "details", check.Details)
+			}
+		}
+		for _, m := range report.Mismatches {
+			slog.Error("Balance mismatch", "username", m.UserName, "cached", m.Cached, "expected", m.Expected)
+		}
+	}
+
+	return report, nil
+}
+
+// RunReconciliation вариант Reconcile для фоновой задачи
+func (uc *ReconciliationUseCase) RunReconciliation(ctx context.Context) error {
+	_, err := uc.Reconcile(ctx)
+	return err
+}
+
+// GetLastReport возвращает отчет последней сверки
+func (uc *ReconciliationUseCase) GetLastReport(ctx context.Context) (*entity.ReconciliationReport, error) {
+	return uc.reconciliationRepo.GetLastReport(ctx)
+}
+
+func buildReconciliationReport(snapshot *entity.ReconciliationSnapshot) *entity.ReconciliationReport {
+	report := &entity.ReconciliationReport{
+		UsersChecked:   snapshot.UsersChecked,
+		Circulation:    snapshot.Circulation,
+		Minted:         -snapshot.SystemBalances[entity.AccountMint],
+		Spent:          snapshot.SystemBalances[entity.AccountShop],
+		Donated:        snapshot.Donated,
+		SystemBalances: snapshot.SystemBalances,
+		Mismatches:     snapshot.Mismatches,
+	}
+	if report.Mismatches == nil {
+		report.Mismatches = []entity.BalanceMismatch{}
+	}
+
+	// Монеты, осевшие на прочих системных счетах, тоже выбыли из обращения
+	var withdrawn int64
+	for account, balance := range snapshot.SystemBalances {
+		if account != entity.AccountMint && account != entity.AccountShop {
+			withdrawn += balance
+		}
+	}
+
+	report.Invariants = []entity.InvariantCheck{
+		{
+			Name: InvariantLedgerBalanced,
+			OK:   snapshot.LedgerTotal == 0 && snapshot.UnbalancedEntries == 0,
+			Details: fmt.Sprintf("ledger total %d, unbalanced entries %d",
+				snapshot.LedgerTotal, snapshot.UnbalancedEntries),
+		},
+		{
+			Name: InvariantCirculation,
+			OK:   report.Circulation == report.Minted-report.Spent-report.Donated-withdrawn,
+			Details: fmt.Sprintf("circulation %d, minted %d, spent %d, donated %d, held on other system accounts %d",
+				report.Circulation, report.Minted, report.Spent, report.Donated, withdrawn),
+		},
+		{
+			Name:    InvariantUserBalances,
+			OK:      len(snapshot.Mismatches) == 0,
+			Details: fmt.Sprintf("%d mismatched users", len(snapshot.Mismatches)),
+		},
+		{
+			Name:    InvariantUsersHaveLedger,
+			OK:      snapshot.UsersWithoutAccount == 0,
+			Details: fmt.Sprintf("%d users without ledger account", snapshot.UsersWithoutAccount),
+		},
+		{
+			Name:    InvariantLotsMatch,
+			OK:      snapshot.LotMismatches == 0,
+			Details: fmt.Sprintf("%d users with coin lots not matching balance", snapshot.LotMismatches),
+		},
+		{
+			Name:    InvariantTeamBalances,
+			OK:      snapshot.TeamMismatches == 0,
+			Details: fmt.Sprintf("%d team wallets not matching ledger or coin lots", snapshot.TeamMismatches),
+		},
+		{
+			Name:    InvariantCampaignTotals,
+			OK:      snapshot.CampaignMismatches == 0,
+			Details: fmt.Sprintf("%d campaigns with raised total not matching ledger", snapshot.CampaignMismatches),
+		},
+	}
+
+	report.OK = true
+	for _, check := range report.Invariants {
+		report.OK = report.OK && check.OK
+	}
+	return report
+}
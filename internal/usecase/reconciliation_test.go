@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReconciliationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReconciliationRepository) GetSnapshot(ctx context.Context) (*entity.ReconciliationSnapshot, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*entity.ReconciliationSnapshot), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) SaveReport(ctx context.Context, report *entity.ReconciliationReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) GetLastReport(ctx context.Context) (*entity.ReconciliationReport, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*entity.ReconciliationReport), args.Error(1)
+}
+
+func TestReconciliationUseCase_Reconcile_Consistent(t *testing.T) {
+	mockRepo := new(MockReconciliationRepository)
+
+	mockRepo.On("GetSnapshot", mock.Anything).Return(&entity.ReconciliationSnapshot{
+		UsersChecked: 2,
+		Circulation:  1900,
+		SystemBalances: map[string]int64{
+			entity.AccountMint: -2000,
+			entity.AccountShop: 100,
+		},
+	}, nil)
+	mockRepo.On("SaveReport", mock.Anything, mock.Anything).Return(nil)
+
+	uc := NewReconciliationUseCase(mockRepo)
+
+	report, err := uc.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, report.OK)
+	assert.Equal(t, int64(2000), report.Minted)
+	assert.Equal(t, int64(100), report.Spent)
+	assert.Empty(t, report.Mismatches)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReconciliationUseCase_Reconcile_Donations(t *testing.T) {
+	mockRepo := new(MockReconciliationRepository)
+
+	mockRepo.On("GetSnapshot", mock.Anything).Return(&entity.ReconciliationSnapshot{
+		UsersChecked: 2,
+		Circulation:  1600,
+		Donated:      300,
+		SystemBalances: map[string]int64{
+			entity.AccountMint: -2000,
+			entity.AccountShop: 100,
+		},
+		CampaignMismatches: 1,
+	}, nil)
+	mockRepo.On("SaveReport", mock.Anything, mock.Anything).Return(nil)
+
+	uc := NewReconciliationUseCase(mockRepo)
+
+	report, err := uc.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), report.Donated)
+
+	failed := map[string]bool{}
+	for _, check := range report.Invariants {
+		failed[check.Name] = !check.OK
+	}
+	assert.False(t, failed[InvariantCirculation])
+	assert.True(t, failed[InvariantCampaignTotals])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReconciliationUseCase_Reconcile_Drift(t *testing.T) {
+	mockRepo := new(MockReconciliationRepository)
+
+	mockRepo.On("GetSnapshot", mock.Anything).Return(&entity.ReconciliationSnapshot{
+		UsersChecked: 2,
+		Circulation:  1950,
+		SystemBalances: map[string]int64{
+			entity.AccountMint: -2000,
+			entity.AccountShop: 100,
+		},
+		Mismatches: []entity.BalanceMismatch{
+			{UserName: "testuser", Cached: 950, Expected: 900, Difference: 50},
+		},
+	}, nil)
+	mockRepo.On("SaveReport", mock.Anything, mock.Anything).Return(nil)
+
+	uc := NewReconciliationUseCase(mockRepo)
+
+	report, err := uc.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, report.OK)
+
+	failed := map[string]bool{}
+	for _, check := range report.Invariants {
+		failed[check.Name] = !check.OK
+	}
+	assert.True(t, failed[InvariantCirculation])
+	assert.True(t, failed[InvariantUserBalances])
+	assert.False(t, failed[InvariantLedgerBalanced])
+
+	mockRepo.AssertExpectations(t)
+}
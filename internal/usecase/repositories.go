@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// UserAccountRepository балансы, блокировки и замороженные монеты пользователей
+type UserAccountRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
+	LockUsers(ctx context.Context, usernames ...string) ([]string, error)
+	UpdateUserAfterTransfer(ctx context.Context, fromUsername, toUsername string, amount int) error
+	UpdateUsersAfterBatchTransfer(ctx context.Context, fromUsername string, recipients []string, amounts []int) error
+	DebitCoins(ctx context.Context, username string, amount int) ([]entity.CoinLot, error)
+	CreditCoins(ctx context.Context, username string, amount int) error
+	CreditLots(ctx context.Context, username string, lots []entity.CoinLot) error
+	HoldCoins(ctx context.Context, username string, amount int) error
+	ReleaseHeldCoins(ctx context.Context, username string, amount int) error
+	CaptureHeldCoins(ctx context.Context, username string, amount int) error
+	SpendGivingBudget(ctx context.Context, username string, period time.Time, budget, amount int) error
+	ExpireCoins(ctx context.Context, username string, cutoff time.Time) (int, error)
+}
+
+// TransferRepository записи о переводах и покупках
+type TransferRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateTransfer(ctx context.Context, transfer *entity.Transaction) error
+	GetTransferForUpdate(ctx context.Context, id uuid.UUID) (*entity.Transaction, error)
+	GetReversal(ctx context.Context, originalID uuid.UUID) (*entity.Transaction, error)
+	GetSentTotals(ctx context.Context, fromUsername string, recipients []string, daySince, weekSince time.Time) (*entity.SentTotals, error)
+	LockExpiredPendingTransfers(ctx context.Context, now time.Time, limit int) ([]entity.Transaction, error)
+	ResolvePendingTransfer(ctx context.Context, transfer *entity.Transaction, status string) error
+	CreatePurchase(ctx context.Context, purchase *entity.Purchase) error
+}
+
+// LedgerRepository проводки по главной книге
+type LedgerRepository interface {
+	RecordTransfer(ctx context.Context, transfer *entity.Transaction) error
+	RecordReversal(ctx context.Context, reversal *entity.Transaction, description string) error
+	RecordPurchase(ctx context.Context, purchase *entity.Purchase) error
+	RecordIssuance(ctx context.Context, kind, userName string, amount int, referenceID *uuid.UUID, description string) error
+	RecordTeamOperation(ctx context.Context, op *entity.TeamOperation) error
+	RecordDonation(ctx context.Context, donation *entity.Donation, description string) error
+	RecordSale(ctx context.Context, sale *entity.Sale) error
+	RecordExpiry(ctx context.Context, userName string, amount int, description string) error
+	RecordTeamExpiry(ctx context.Context, teamID uuid.UUID, amount int, description string) error
+}
+
+// ItemRepository каталог, склад и инвентарь пользователей
+type ItemRepository interface {
+	GetItemByName(ctx context.Context, name string) (*entity.Item, error)
+	GetBundleComponents(ctx context.Context, bundleName string) ([]entity.BundleComponent, error)
+	ReserveStock(ctx context.Context, itemName string, quantity int) error
+	ReleaseStock(ctx context.Context, itemName string, quantity int) error
+	AddToInventory(ctx context.Context, userName string, itemName string, quantity int) error
+	RemoveFromInventory(ctx context.Context, userName string, itemName string, quantity int) error
+}
+
+// HoldRepository записи о замороженных монетах
+type HoldRepository interface {
+	CreateHold(ctx context.Context, hold *entity.Hold) error
+	ResolveHold(ctx context.Context, id uuid.UUID, status string) (*entity.Hold, error)
+}
+
+// AuditRepository журнал действий администраторов
+type AuditRepository interface {
+	Record(ctx context.Context, entry *entity.AuditEntry) error
+}
+
+// TxRepositories репозитории, работающие в рамках одной транзакции
+type TxRepositories struct {
+	Users              UserAccountRepository
+	Transfers          TransferRepository
+	Ledger             LedgerRepository
+	Holds              HoldRepository
+	CoinRequests       CoinRequestRepository
+	ScheduledTransfers ScheduledTransferRepository
+	Audit              AuditRepository
+	Grants             GrantRepository
+	Allowance          AllowanceRepository
+	CoinLots           ExpiringLotRepository
+	Teams              TeamRepository
+	Campaigns          CampaignRepository
+	Items              ItemRepository
+	Auctions           AuctionRepository
+	Marketplace        MarketplaceRepository
+	Preorders          PreorderRepository
+}
+
+// NewTxRepositories создает репозитории транзакции tx. Сценарии получают их через поле txRepos,
+// которое тесты подменяют на моки
+func NewTxRepositories(tx pgx.Tx) *TxRepositories {
+	return &TxRepositories{
+		Users:              repository.UserRepoWithTx(tx),
+		Transfers:          repository.TransactionRepoWithTx(tx),
+		Ledger:             repository.LedgerRepoWithTx(tx),
+		Holds:              repository.HoldRepoWithTx(tx),
+		CoinRequests:       repository.CoinRequestRepoWithTx(tx),
+		ScheduledTransfers: repository.ScheduledTransferRepoWithTx(tx),
+		Audit:              repository.AuditRepoWithTx(tx),
+		Grants:             repository.GrantRepoWithTx(tx),
+		Allowance:          repository.AllowanceRepoWithTx(tx),
+		CoinLots:           repository.CoinLotRepoWithTx(tx),
+		Teams:              repository.TeamRepoWithTx(tx),
+		Campaigns:          repository.CampaignRepoWithTx(tx),
+		Items:              repository.ItemRepoWithTx(tx),
+		Auctions:           repository.AuctionRepoWithTx(tx),
+		Marketplace:        repository.MarketplaceRepoWithTx(tx),
+		Preorders:          repository.PreorderRepoWithTx(tx),
+	}
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeTx транзакция для тестов сценариев: запоминает фиксацию, остальные методы не нужны,
+// потому что репозитории транзакции заменены моками
+type fakeTx struct {
+	pgx.Tx
+	committed bool
+}
+
+// Begin создает точку сохранения
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if tx.committed {
+		return pgx.ErrTxClosed
+	}
+	return nil
+}
+
+type MockUserAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserAccountRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockUserAccountRepository) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(*entity.User), args.Error(1)
+}
+
+func (m *MockUserAccountRepository) LockUsers(ctx context.Context, usernames ...string) ([]string, error) {
+	args := m.Called(ctx, usernames)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUserAccountRepository) UpdateUserAfterTransfer(ctx context.Context, fromUsername, toUsername string, amount int) error {
+	args := m.Called(ctx, fromUsername, toUsername, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) UpdateUsersAfterBatchTransfer(ctx context.Context, fromUsername string, recipients []string, amounts []int) error {
+	args := m.Called(ctx, fromUsername, recipients, amounts)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) DebitCoins(ctx context.Context, username string, amount int) ([]entity.CoinLot, error) {
+	args := m.Called(ctx, username, amount)
+	return args.Get(0).([]entity.CoinLot), args.Error(1)
+}
+
+func (m *MockUserAccountRepository) CreditCoins(ctx context.Context, username string, amount int) error {
+	args := m.Called(ctx, username, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) CreditLots(ctx context.Context, username string, lots []entity.CoinLot) error {
+	args := m.Called(ctx, username, lots)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) HoldCoins(ctx context.Context, username string, amount int) error {
+	args := m.Called(ctx, username, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) ReleaseHeldCoins(ctx context.Context, username string, amount int) error {
+	args := m.Called(ctx, username, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) CaptureHeldCoins(ctx context.Context, username string, amount int) error {
+	args := m.Called(ctx, username, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) SpendGivingBudget(ctx context.Context, username string, period time.Time, budget, amount int) error {
+	args := m.Called(ctx, username, period, budget, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserAccountRepository) ExpireCoins(ctx context.Context, username string, cutoff time.Time) (int, error) {
+	args := m.Called(ctx, username, cutoff)
+	return args.Int(0), args.Error(1)
+}
+
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockTransferRepository) CreateTransfer(ctx context.Context, transfer *entity.Transaction) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) GetTransferForUpdate(ctx context.Context, id uuid.UUID) (*entity.Transaction, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Transaction), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetReversal(ctx context.Context, originalID uuid.UUID) (*entity.Transaction, error) {
+	args := m.Called(ctx, originalID)
+	return args.Get(0).(*entity.Transaction), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetSentTotals(ctx context.Context, fromUsername string, recipients []string, daySince, weekSince time.Time) (*entity.SentTotals, error) {
+	args := m.Called(ctx, fromUsername, recipients, daySince, weekSince)
+	return args.Get(0).(*entity.SentTotals), args.Error(1)
+}
+
+func (m *MockTransferRepository) LockExpiredPendingTransfers(ctx context.Context, now time.Time, limit int) ([]entity.Transaction, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]entity.Transaction), args.Error(1)
+}
+
+func (m *MockTransferRepository) ResolvePendingTransfer(ctx context.Context, transfer *entity.Transaction, status string) error {
+	args := m.Called(ctx, transfer, status)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) CreatePurchase(ctx context.Context, purchase *entity.Purchase) error {
+	args := m.Called(ctx, purchase)
+	return args.Error(0)
+}
+
+type MockLedgerRepository struct {
+	mock.Mock
+}
+
+func (m *MockLedgerRepository) RecordTransfer(ctx context.Context, transfer *entity.Transaction) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordReversal(ctx context.Context, reversal *entity.Transaction, description string) error {
+	args := m.Called(ctx, reversal, description)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordPurchase(ctx context.Context, purchase *entity.Purchase) error {
+	args := m.Called(ctx, purchase)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordIssuance(ctx context.Context, kind, userName string, amount int, referenceID *uuid.UUID, description string) error {
+	args := m.Called(ctx, kind, userName, amount, referenceID, description)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordTeamOperation(ctx context.Context, op *entity.TeamOperation) error {
+	args := m.Called(ctx, op)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordDonation(ctx context.Context, donation *entity.Donation, description string) error {
+	args := m.Called(ctx, donation, description)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordSale(ctx context.Context, sale *entity.Sale) error {
+	args := m.Called(ctx, sale)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordExpiry(ctx context.Context, userName string, amount int, description string) error {
+	args := m.Called(ctx, userName, amount, description)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepository) RecordTeamExpiry(ctx context.Context, teamID uuid.UUID, amount int, description string) error {
+	args := m.Called(ctx, teamID, amount, description)
+	return args.Error(0)
+}
+
+type MockItemRepository struct {
+	mock.Mock
+}
+
+func (m *MockItemRepository) GetItemByName(ctx context.Context, name string) (*entity.Item, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*entity.Item), args.Error(1)
+}
+
+func (m *MockItemRepository) GetBundleComponents(ctx context.Context, bundleName string) ([]entity.BundleComponent, error) {
+	args := m.Called(ctx, bundleName)
+	return args.Get(0).([]entity.BundleComponent), args.Error(1)
+}
+
+func (m *MockItemRepository) ReserveStock(ctx context.Context, itemName string, quantity int) error {
+	args := m.Called(ctx, itemName, quantity)
+	return args.Error(0)
+}
+
+func (m *MockItemRepository) ReleaseStock(ctx context.Context, itemName string, quantity int) error {
+	args := m.Called(ctx, itemName, quantity)
+	return args.Error(0)
+}
+
+func (m *MockItemRepository) AddToInventory(ctx context.Context, userName string, itemName string, quantity int) error {
+	args := m.Called(ctx, userName, itemName, quantity)
+	return args.Error(0)
+}
+
+func (m *MockItemRepository) RemoveFromInventory(ctx context.Context, userName string, itemName string, quantity int) error {
+	args := m.Called(ctx, userName, itemName, quantity)
+	return args.Error(0)
+}
+
+type MockHoldRepository struct {
+	mock.Mock
+}
+
+func (m *MockHoldRepository) CreateHold(ctx context.Context, hold *entity.Hold) error {
+	args := m.Called(ctx, hold)
+	return args.Error(0)
+}
+
+func (m *MockHoldRepository) ResolveHold(ctx context.Context, id uuid.UUID, status string) (*entity.Hold, error) {
+	args := m.Called(ctx, id, status)
+	return args.Get(0).(*entity.Hold), args.Error(1)
+}
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Record(ctx context.Context, entry *entity.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// mockRepos моки репозиториев одной транзакции
+type mockRepos struct {
+	tx           *fakeTx
+	users        *MockUserAccountRepository
+	transfers    *MockTransferRepository
+	ledger       *MockLedgerRepository
+	holds        *MockHoldRepository
+	coinRequests *MockCoinRequestRepository
+	scheduled    *MockScheduledTransferRepository
+	audit        *MockAuditRepository
+	grants       *MockGrantRepository
+	allowance    *MockAllowanceRepository
+	coinLots     *MockExpiringLotRepository
+	teams        *MockTeamRepository
+	campaigns    *MockCampaignRepository
+	items        *MockItemRepository
+	auctions     *MockAuctionRepository
+	marketplace  *MockMarketplaceRepository
+	preorders    *MockPreorderRepository
+}
+
+func newMockRepos() *mockRepos {
+	return &mockRepos{
+		tx:           &fakeTx{},
+		users:        new(MockUserAccountRepository),
+		transfers:    new(MockTransferRepository),
+		ledger:       new(MockLedgerRepository),
+		holds:        new(MockHoldRepository),
+		coinRequests: new(MockCoinRequestRepository),
+		scheduled:    new(MockScheduledTransferRepository),
+		audit:        new(MockAuditRepository),
+		grants:       new(MockGrantRepository),
+		allowance:    new(MockAllowanceRepository),
+		coinLots:     new(MockExpiringLotRepository),
+		teams:        new(MockTeamRepository),
+		campaigns:    new(MockCampaignRepository),
+		items:        new(MockItemRepository),
+		auctions:     new(MockAuctionRepository),
+		marketplace:  new(MockMarketplaceRepository),
+		preorders:    new(MockPreorderRepository),
+	}
+}
+
+// txRepos подменяет NewTxRepositories в сценариях
+func (m *mockRepos) txRepos(pgx.Tx) *TxRepositories {
+	return &TxRepositories{
+		Users:              m.users,
+		Transfers:          m.transfers,
+		Ledger:             m.ledger,
+		Holds:              m.holds,
+		CoinRequests:       m.coinRequests,
+		ScheduledTransfers: m.scheduled,
+		Audit:              m.audit,
+		Grants:             m.grants,
+		Allowance:          m.allowance,
+		CoinLots:           m.coinLots,
+		Teams:              m.teams,
+		Campaigns:          m.campaigns,
+		Items:              m.items,
+		Auctions:           m.auctions,
+		Marketplace:        m.marketplace,
+		Preorders:          m.preorders,
+	}
+}
+
+func (m *mockRepos) assertExpectations(t *testing.T) {
+	t.Helper()
+	m.users.AssertExpectations(t)
+	m.transfers.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.holds.AssertExpectations(t)
+	m.coinRequests.AssertExpectations(t)
+	m.scheduled.AssertExpectations(t)
+	m.audit.AssertExpectations(t)
+	m.grants.AssertExpectations(t)
+	m.allowance.AssertExpectations(t)
+	m.coinLots.AssertExpectations(t)
+	m.teams.AssertExpectations(t)
+	m.campaigns.AssertExpectations(t)
+	m.items.AssertExpectations(t)
+	m.auctions.AssertExpectations(t)
+	m.marketplace.AssertExpectations(t)
+	m.preorders.AssertExpectations(t)
+}
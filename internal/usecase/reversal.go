@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTransferNotFound          = errors.New("transfer not found")
+	ErrReversalNotAllowed        = errors.New("transfer cannot be reversed")
+	ErrReversalInsufficientFunds = errors.New("recipient has already spent the coins")
+	ErrReversalReasonRequired    = errors.New("reversal reason is required")
+)
+
+type ReversalUseCase struct {
+	transactionRepo TransferRepository
+	txRepos         func(tx pgx.Tx) *TxRepositories
+}
+
+func NewReversalUseCase(transactionRepo TransferRepository) *ReversalUseCase {
+	return &ReversalUseCase{transactionRepo: transactionRepo, txRepos: NewTxRepositories}
+}
+
+// ReverseTransfer возвращает монеты перевода отправителю компенсирующим переводом.
+// Повторный вызов для уже сторнированного перевода возвращает существующее сторнирование.
+// Если получатель успел потратить часть монет, то при allowPartial возвращается
+// доступная часть, иначе сторнирование отклоняется: уходить в минус баланс не может
+func (uc *ReversalUseCase) ReverseTransfer(ctx context.Context, admin string, id uuid.UUID, reason string, allowPartial bool) (*entity.Reversal, error) {
+	reason, err := sanitizeMemo(reason)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, ErrReversalReasonRequired
+	}
+
+	tx, err := uc.transactionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	transactionRepo := repos.Transfers
+	userRepo := repos.Users
+
+	// Блокировка исходного перевода упорядочивает параллельные сторнирования
+	original, err := transactionRepo.GetTransferForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrTransferNotFound
+	}
+	if original.ReversalOf != nil {
+		return nil, fmt.Errorf("%w: transfer is itself a reversal", ErrReversalNotAllowed)
+	}
+	// Незавершенный перевод не двигал монеты, его отклоняют или отменяют, а не сторнируют
+	if original.Status != entity.TransferStatusCompleted {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrReversalNotAllowed, original.Status)
+	}
+	// Монеты благодарности выпущены со счета эмиссии, отправитель их не тратил
+	if original.Kudos {
+		return nil, fmt.Errorf("%w: kudos cannot be reversed", ErrReversalNotAllowed)
+	}
+
+	existing, err := transactionRepo.GetReversal(ctx, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		original.ReversedAmount = existing.Amount
+		return &entity.Reversal{
+			TransferID:      original.ID,
+			Original:        *original,
+			Reversal:        *existing,
+			Partial:         existing.Amount < original.Amount,
+			AlreadyReversed: true,
+		}, nil
+	}
+
+	locked, err := userRepo.LockUsers(ctx, original.FromUser, original.ToUser)
+	if err != nil {
+		return nil, err
+	}
+	// Деактивированному участнику монеты не возвращаются и не списываются
+	if missing := missingUsers([]string{original.FromUser, original.ToUser}, locked); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: user no longer exists: %s", ErrReversalNotAllowed, strings.Join(missing, ", "))
+	}
+	recipient, err := userRepo.GetUserByUsername(ctx, original.ToUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if recipient == nil {
+		return nil, fmt.Errorf("%w: recipient no longer exists", ErrReversalNotAllowed)
+	}
+
+	amount := original.Amount
+	if available := recipient.AvailableCoins(); available < amount {
+		if !allowPartial || available <= 0 {
+			return nil, fmt.Errorf("%w: %d of %d coins available", ErrReversalInsufficientFunds, max(available, 0), amount)
+		}
+		amount = available
+	}
+
+	if err := userRepo.UpdateUserAfterTransfer(ctx, original.ToUser, original.FromUser, amount); err != nil {
+		return nil, fmt.Errorf("failed to update balances: %w", err)
+	}
+
+	reversal := &entity.Transaction{
+		FromUser:   original.ToUser,
+		ToUser:     original.FromUser,
+		Amount:     amount,
+		Memo:       reason,
+		ReversalOf: &original.ID,
+	}
+	if err := transactionRepo.CreateTransfer(ctx, reversal); err != nil {
+		return nil, fmt.Errorf("failed to create reversal record: %w", err)
+	}
+	if err := repos.Ledger.RecordReversal(ctx, reversal, "reversed by "+admin); err != nil {
+		return nil, fmt.Errorf("failed to record reversal in ledger: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Transfer reversed",
+		"transferID", original.ID,
+		"admin", admin,
+		"amount", amount,
+		"originalAmount", original.Amount,
+	)
+
+	original.ReversedAmount = amount
+	return &entity.Reversal{
+		TransferID: original.ID,
+		Original:   *original,
+		Reversal:   *reversal,
+		Partial:    amount < original.Amount,
+	}, nil
+}
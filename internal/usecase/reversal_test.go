@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReversalUseCase_ReverseTransfer_Full(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+	original := completedTransfer()
+	expectReversal(repos, original, 100, 50)
+
+	reversal, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", false)
+
+	require.NoError(t, err)
+	assert.False(t, reversal.Partial)
+	assert.Equal(t, 50, reversal.Reversal.Amount)
+	assert.Equal(t, 50, reversal.Original.ReversedAmount)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestReversalUseCase_ReverseTransfer_Partial(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+	original := completedTransfer()
+	expectReversal(repos, original, 20, 20)
+
+	reversal, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", true)
+
+	require.NoError(t, err)
+	assert.True(t, reversal.Partial)
+	assert.Equal(t, 20, reversal.Reversal.Amount)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestReversalUseCase_ReverseTransfer_InsufficientFunds(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+	original := completedTransfer()
+	expectReversal(repos, original, 20, 0)
+
+	_, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", false)
+
+	assert.ErrorIs(t, err, ErrReversalInsufficientFunds)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReversalUseCase_ReverseTransfer_DeactivatedSender(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+	original := completedTransfer()
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, original.ID).Return(original, nil)
+	repos.transfers.On("GetReversal", mock.Anything, original.ID).Return((*entity.Transaction)(nil), nil)
+	repos.users.On("LockUsers", mock.Anything, []string{original.FromUser, original.ToUser}).
+		Return([]string{original.ToUser}, nil)
+
+	_, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", false)
+
+	assert.ErrorIs(t, err, ErrReversalNotAllowed)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReversalUseCase_ReverseTransfer_AlreadyReversed(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+	original := completedTransfer()
+	existing := &entity.Transaction{ID: uuid.New(), FromUser: "bob", ToUser: "alice", Amount: 30, ReversalOf: &original.ID}
+	repos.transfers.On("GetTransferForUpdate", mock.Anything, original.ID).Return(original, nil)
+	repos.transfers.On("GetReversal", mock.Anything, original.ID).Return(existing, nil)
+
+	reversal, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", false)
+
+	require.NoError(t, err)
+	assert.True(t, reversal.AlreadyReversed)
+	assert.True(t, reversal.Partial)
+	assert.Equal(t, existing.ID, reversal.Reversal.ID)
+	repos.users.AssertNotCalled(t, "UpdateUserAfterTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repos.assertExpectations(t)
+}
+
+func TestReversalUseCase_ReverseTransfer_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *entity.Transaction)
+	}{
+		{"kudos", func(t *entity.Transaction) { t.Kudos = true }},
+		{"pending", func(t *entity.Transaction) { t.Status = entity.TransferStatusPending }},
+		{"reversal", func(t *entity.Transaction) { id := uuid.New(); t.ReversalOf = &id }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newMockRepos()
+			uc := newTestReversalUseCase(repos)
+			original := completedTransfer()
+			tt.modify(original)
+			repos.transfers.On("GetTransferForUpdate", mock.Anything, original.ID).Return(original, nil)
+
+			_, err := uc.ReverseTransfer(context.Background(), "admin", original.ID, "ошибочный перевод", true)
+
+			assert.ErrorIs(t, err, ErrReversalNotAllowed)
+			assert.False(t, repos.tx.committed)
+		})
+	}
+}
+
+func TestReversalUseCase_ReverseTransfer_ReasonRequired(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestReversalUseCase(repos)
+
+	_, err := uc.ReverseTransfer(context.Background(), "admin", uuid.New(), "  ", false)
+
+	assert.ErrorIs(t, err, ErrReversalReasonRequired)
+	repos.transfers.AssertNotCalled(t, "Begin", mock.Anything)
+}
@@ -0,0 +1,512 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/pkg/cron"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// scheduledTransferBatchSize сколько переводов выполняется за один запуск планировщика
+	scheduledTransferBatchSize = 100
+	// maxScheduledTransferFailures после стольких неудач подряд повторяющийся перевод отключается
+	maxScheduledTransferFailures = 3
+	// scheduledTransferRunsLimit сколько последних срабатываний возвращается в истории
+	scheduledTransferRunsLimit = 50
+)
+
+var (
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	ErrScheduledTransferFinished = errors.New("scheduled transfer is already finished")
+	ErrInvalidScheduledTransfer  = errors.New("invalid scheduled transfer")
+)
+
+// ScheduledTransferParams параметры нового перевода. Задается либо RunAt
+// для разового перевода, либо Schedule для повторяющегося
+type ScheduledTransferParams struct {
+	ToUser   string
+	Amount   int
+	Memo     string
+	Schedule string
+	Timezone string
+	RunAt    *time.Time
+}
+
+// ScheduledTransferUpdate изменяемые поля перевода, nil - поле не меняется.
+// Status принимает значения active и paused
+type ScheduledTransferUpdate struct {
+	Amount   *int
+	Memo     *string
+	Schedule *string
+	Timezone *string
+	RunAt    *time.Time
+	Status   *string
+}
+
+type ScheduledTransferRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error
+	GetScheduledTransfer(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error)
+	GetScheduledTransfers(ctx context.Context, owner string) ([]entity.ScheduledTransfer, error)
+	LockDueTransfers(ctx context.Context, now time.Time, limit int) ([]entity.ScheduledTransfer, error)
+	Update(ctx context.Context, transfer *entity.ScheduledTransfer) error
+	CreateRun(ctx context.Context, scheduledTransferID uuid.UUID, run *entity.ScheduledTransferRun) error
+	GetRuns(ctx context.Context, scheduledTransferID uuid.UUID, limit int) ([]entity.ScheduledTransferRun, error)
+}
+
+type ScheduledTransferUseCase struct {
+	userRepo              UserAccountRepository
+	scheduledTransferRepo ScheduledTransferRepository
+	sendCoinUseCase       *SendCoinUseCase
+	txRepos               func(tx pgx.Tx) *TxRepositories
+}
+
+func NewScheduledTransferUseCase(
+	userRepo UserAccountRepository,
+	scheduledTransferRepo ScheduledTransferRepository,
+	sendCoinUseCase *SendCoinUseCase,
+) *ScheduledTransferUseCase {
+	return &ScheduledTransferUseCase{
+		userRepo:              userRepo,
+		scheduledTransferRepo: scheduledTransferRepo,
+		sendCoinUseCase:       sendCoinUseCase,
+		txRepos:               NewTxRepositories,
+	}
+}
+
+// CreateScheduledTransfer планирует разовый или повторяющийся перевод
+func (uc *ScheduledTransferUseCase) CreateScheduledTransfer(ctx context.Context, fromUser string, params ScheduledTransferParams) (*entity.ScheduledTransfer, error) {
+	if params.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive: %d", params.Amount)
+	}
+	if fromUser == params.ToUser {
+		return nil, fmt.Errorf("cannot send coins to yourself: %s", params.ToUser)
+	}
+
+	memo, err := sanitizeMemo(params.Memo)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, err := uc.userRepo.GetUserByUsername(ctx, params.ToUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if recipient == nil || recipient.DeactivatedAt != nil {
+		return nil, fmt.Errorf("recipient does not exist: %s", params.ToUser)
+	}
+
+	transfer := &entity.ScheduledTransfer{
+		FromUser: fromUser,
+		ToUser:   params.ToUser,
+		Amount:   params.Amount,
+		Memo:     memo,
+		Schedule: params.Schedule,
+		Timezone: params.Timezone,
+	}
+	if transfer.Timezone == "" {
+		transfer.Timezone = "UTC"
+	}
+	if err := planNextRun(transfer, params.RunAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := uc.scheduledTransferRepo.CreateScheduledTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetScheduledTransfers возвращает запланированные переводы пользователя
+func (uc *ScheduledTransferUseCase) GetScheduledTransfers(ctx context.Context, owner string) ([]entity.ScheduledTransfer, error) {
+	transfers, err := uc.scheduledTransferRepo.GetScheduledTransfers(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if transfers == nil {
+		transfers = []entity.ScheduledTransfer{}
+	}
+	return transfers, nil
+}
+
+// GetScheduledTransfer возвращает перевод пользователя
+func (uc *ScheduledTransferUseCase) GetScheduledTransfer(ctx context.Context, owner string, id uuid.UUID) (*entity.ScheduledTransfer, error) {
+	transfer, err := uc.scheduledTransferRepo.GetScheduledTransfer(ctx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, ErrScheduledTransferNotFound
+	}
+	return transfer, nil
+}
+
+// GetRuns возвращает последние срабатывания перевода пользователя
+func (uc *ScheduledTransferUseCase) GetRuns(ctx context.Context, owner string, id uuid.UUID) ([]entity.ScheduledTransferRun, error) {
+	if _, err := uc.GetScheduledTransfer(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	runs, err := uc.scheduledTransferRepo.GetRuns(ctx, id, scheduledTransferRunsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []entity.ScheduledTransferRun{}
+	}
+	return runs, nil
+}
+
+// UpdateScheduledTransfer меняет параметры, приостанавливает или возобновляет перевод.
+// Отключенный после неудач перевод можно возобновить, завершенный или отмененный - нельзя
+func (uc *ScheduledTransferUseCase) UpdateScheduledTransfer(ctx context.Context, owner string, id uuid.UUID, update ScheduledTransferUpdate) (*entity.ScheduledTransfer, error) {
+	return uc.modify(ctx, owner, id, func(transfer *entity.ScheduledTransfer) error {
+		if update.Amount != nil {
+			if *update.Amount <= 0 {
+				return fmt.Errorf("amount must be positive: %d", *update.Amount)
+			}
+			transfer.Amount = *update.Amount
+		}
+		if update.Memo != nil {
+			memo, err := sanitizeMemo(*update.Memo)
+			if err != nil {
+				return err
+			}
+			transfer.Memo = memo
+		}
+		if update.Timezone != nil {
+			transfer.Timezone = *update.Timezone
+		}
+
+		status := transfer.Status
+		if update.Status != nil {
+			switch *update.Status {
+			case entity.ScheduledTransferStatusActive, entity.ScheduledTransferStatusPaused:
+				status = *update.Status
+			default:
+				return fmt.Errorf("%w: status must be active or paused", ErrInvalidScheduledTransfer)
+			}
+		}
+		if status == entity.ScheduledTransferStatusFailed {
+			return fmt.Errorf("%w: resume it by setting status to active", ErrScheduledTransferFinished)
+		}
+
+		// Расписание пересчитывается при любом изменении времени запуска,
+		// а также при возобновлении, чтобы не выполнять пропущенные срабатывания
+		if update.Schedule != nil || update.RunAt != nil || update.Timezone != nil || status != transfer.Status {
+			if update.Schedule != nil {
+				transfer.Schedule = *update.Schedule
+			} else if update.RunAt != nil {
+				transfer.Schedule = ""
+			}
+			runAt := update.RunAt
+			if runAt == nil && !transfer.Recurring() {
+				runAt = transfer.NextRunAt
+			}
+			if err := planNextRun(transfer, runAt, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if status == entity.ScheduledTransferStatusActive && transfer.Status != status {
+			transfer.FailureCount = 0
+			transfer.LastError = ""
+		}
+		transfer.Status = status
+		return nil
+	})
+}
+
+// CancelScheduledTransfer отменяет перевод, последующие срабатывания не выполняются
+func (uc *ScheduledTransferUseCase) CancelScheduledTransfer(ctx context.Context, owner string, id uuid.UUID) (*entity.ScheduledTransfer, error) {
+	return uc.modify(ctx, owner, id, func(transfer *entity.ScheduledTransfer) error {
+		transfer.Status = entity.ScheduledTransferStatusCancelled
+		transfer.NextRunAt = nil
+		return nil
+	})
+}
+
+// ExecuteDueTransfers выполняет переводы, время которых наступило, не больше scheduledTransferBatchSize за запуск.
+// Каждый перевод выполняется в своей транзакции, поэтому блокировки его участников не удерживаются
+// до конца всего пакета и не пересекаются с блокировками обычных переводов в другом порядке
+func (uc *ScheduledTransferUseCase) ExecuteDueTransfers(ctx context.Context) error {
+	now := time.Now()
+	executed, failed := 0, 0
+	for executed < scheduledTransferBatchSize {
+		run, found, err := uc.executeNextDueTransfer(ctx, now)
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+		executed++
+		if run == nil || run.Error != "" {
+			failed++
+		}
+	}
+
+	if executed > 0 {
+		slog.Info("Scheduled transfers executed", "count", executed, "failed", failed)
+	}
+	return nil
+}
+
+// executeNextDueTransfer выполняет в отдельной транзакции один перевод, время которого наступило.
+// Строка перевода блокируется до конца транзакции, а заблокированные другими экземплярами
+// сервиса пропускаются, поэтому каждое срабатывание выполняется не более одного раза.
+// Возвращает nil вместо срабатывания, если обработать его не удалось и перевод отключен.
+// О выполненном переводе сообщается после фиксации транзакции
+func (uc *ScheduledTransferUseCase) executeNextDueTransfer(ctx context.Context, now time.Time) (*entity.ScheduledTransferRun, bool, error) {
+	tx, err := uc.scheduledTransferRepo.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	transfers, err := uc.txRepos(tx).ScheduledTransfers.LockDueTransfers(ctx, now, 1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(transfers) == 0 {
+		return nil, false, nil
+	}
+	transfer := &transfers[0]
+
+	// Срабатывание выполняется в точке сохранения: если его не удалось записать,
+	// перевод отключается, а не выбирается повторно
+	run, err := uc.runDueTransfer(ctx, tx, transfer, now)
+	if err != nil {
+		slog.Error("Failed to run scheduled transfer", "scheduledTransferID", transfer.ID, "error", err)
+		if err := uc.markFailed(ctx, tx, transfer, err); err != nil {
+			return nil, false, fmt.Errorf("failed to mark scheduled transfer %s as failed: %w", transfer.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if run != nil && run.TransferID != nil {
+		uc.sendCoinUseCase.publishTransfer(ctx, transfer.FromUser, transfer.ToUser)
+	}
+	return run, true, nil
+}
+
+// runDueTransfer выполняет срабатывание перевода, записывает его результат и сдвигает расписание
+// в точке сохранения. Нехватка монет записывается как неудачное срабатывание, а не как ошибка
+func (uc *ScheduledTransferUseCase) runDueTransfer(ctx context.Context, tx pgx.Tx, transfer *entity.ScheduledTransfer, now time.Time) (*entity.ScheduledTransferRun, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer func() {
+		if err := savepoint.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	scheduledTransferRepo := uc.txRepos(savepoint).ScheduledTransfers
+	run := &entity.ScheduledTransferRun{ScheduledFor: *transfer.NextRunAt}
+
+	transferID, sendErr := uc.executeTransfer(ctx, savepoint, transfer)
+	if sendErr != nil {
+		run.Error = sendErr.Error()
+		slog.Error("Scheduled transfer failed", "scheduledTransferID", transfer.ID, "error", sendErr)
+	}
+	run.TransferID = transferID
+
+	if err := scheduledTransferRepo.CreateRun(ctx, transfer.ID, run); err != nil {
+		return nil, err
+	}
+	advanceSchedule(transfer, run, now)
+	if err := scheduledTransferRepo.Update(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return run, nil
+}
+
+// markFailed отключает перевод, срабатывание которого не удалось обработать, чтобы планировщик
+// не выбирал его повторно. Изменения срабатывания к этому моменту уже откачены
+func (uc *ScheduledTransferUseCase) markFailed(ctx context.Context, tx pgx.Tx, transfer *entity.ScheduledTransfer, cause error) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer func() {
+		if err := savepoint.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	transfer.Status = entity.ScheduledTransferStatusFailed
+	transfer.LastError = cause.Error()
+	if err := uc.txRepos(savepoint).ScheduledTransfers.Update(ctx, transfer); err != nil {
+		return err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// executeTransfer выполняет перевод в точке сохранения и возвращает его идентификатор
+func (uc *ScheduledTransferUseCase) executeTransfer(ctx context.Context, tx pgx.Tx, transfer *entity.ScheduledTransfer) (*uuid.UUID, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer func() {
+		if err := savepoint.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	sent, err := uc.sendCoinUseCase.SendCoinsInTx(ctx, savepoint, transfer.FromUser, transfer.ToUser, transfer.Amount, transfer.Memo)
+	if err != nil {
+		return nil, err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return &sent.ID, nil
+}
+
+// modify блокирует перевод владельца и сохраняет изменения, внесенные change
+func (uc *ScheduledTransferUseCase) modify(
+	ctx context.Context,
+	owner string,
+	id uuid.UUID,
+	change func(transfer *entity.ScheduledTransfer) error,
+) (*entity.ScheduledTransfer, error) {
+	tx, err := uc.scheduledTransferRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	scheduledTransferRepo := uc.txRepos(tx).ScheduledTransfers
+
+	transfer, err := scheduledTransferRepo.GetScheduledTransferForUpdate(ctx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, ErrScheduledTransferNotFound
+	}
+	switch transfer.Status {
+	case entity.ScheduledTransferStatusCompleted, entity.ScheduledTransferStatusCancelled:
+		return nil, fmt.Errorf("%w: %s", ErrScheduledTransferFinished, transfer.Status)
+	}
+
+	if err := change(transfer); err != nil {
+		return nil, err
+	}
+
+	if err := scheduledTransferRepo.Update(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Scheduled transfer updated", "scheduledTransferID", transfer.ID, "status", transfer.Status)
+	return transfer, nil
+}
+
+// planNextRun проверяет расписание и вычисляет время ближайшего запуска:
+// runAt для разового перевода или следующее срабатывание после now для повторяющегося
+func planNextRun(transfer *entity.ScheduledTransfer, runAt *time.Time, now time.Time) error {
+	if transfer.Recurring() == (runAt != nil) {
+		return fmt.Errorf("%w: exactly one of schedule and runAt must be set", ErrInvalidScheduledTransfer)
+	}
+
+	if !transfer.Recurring() {
+		if !runAt.After(now) {
+			return fmt.Errorf("%w: runAt must be in the future", ErrInvalidScheduledTransfer)
+		}
+		next := *runAt
+		transfer.NextRunAt = &next
+		return nil
+	}
+
+	next, err := nextOccurrence(transfer, now)
+	if err != nil {
+		return err
+	}
+	transfer.NextRunAt = &next
+	return nil
+}
+
+// nextOccurrence возвращает первое срабатывание расписания перевода после after
+func nextOccurrence(transfer *entity.ScheduledTransfer, after time.Time) (time.Time, error) {
+	location, err := time.LoadLocation(transfer.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: unknown timezone %q", ErrInvalidScheduledTransfer, transfer.Timezone)
+	}
+	schedule, err := cron.Parse(transfer.Schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidScheduledTransfer, err)
+	}
+	next := schedule.Next(after.In(location))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: schedule never fires", ErrInvalidScheduledTransfer)
+	}
+	return next.UTC(), nil
+}
+
+// advanceSchedule обновляет состояние перевода по результату срабатывания.
+// Разовый перевод завершается, повторяющийся переходит к следующему срабатыванию
+// после now (пропущенные за время простоя срабатывания не выполняются).
+// Неудачный разовый перевод и повторяющийся после maxScheduledTransferFailures
+// неудач подряд отключаются
+func advanceSchedule(transfer *entity.ScheduledTransfer, run *entity.ScheduledTransferRun, now time.Time) {
+	runAt := run.ScheduledFor
+	transfer.LastRunAt = &runAt
+	transfer.LastError = run.Error
+
+	if run.Error == "" {
+		transfer.FailureCount = 0
+	} else {
+		transfer.FailureCount++
+	}
+
+	if !transfer.Recurring() {
+		transfer.NextRunAt = nil
+		transfer.Status = entity.ScheduledTransferStatusCompleted
+		if run.Error != "" {
+			transfer.Status = entity.ScheduledTransferStatusFailed
+		}
+		return
+	}
+
+	after := now
+	if runAt.After(after) {
+		after = runAt
+	}
+	next, err := nextOccurrence(transfer, after)
+	if err != nil || transfer.FailureCount >= maxScheduledTransferFailures {
+		transfer.Status = entity.ScheduledTransferStatusFailed
+		return
+	}
+	transfer.NextRunAt = &next
+}
@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockScheduledTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockScheduledTransferRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockScheduledTransferRepository) CreateScheduledTransfer(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockScheduledTransferRepository) GetScheduledTransfer(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error) {
+	args := m.Called(ctx, id, owner)
+	return args.Get(0).(*entity.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockScheduledTransferRepository) GetScheduledTransferForUpdate(ctx context.Context, id uuid.UUID, owner string) (*entity.ScheduledTransfer, error) {
+	args := m.Called(ctx, id, owner)
+	return args.Get(0).(*entity.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockScheduledTransferRepository) GetScheduledTransfers(ctx context.Context, owner string) ([]entity.ScheduledTransfer, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).([]entity.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockScheduledTransferRepository) LockDueTransfers(ctx context.Context, now time.Time, limit int) ([]entity.ScheduledTransfer, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]entity.ScheduledTransfer), args.Error(1)
+}
+
+func (m *MockScheduledTransferRepository) Update(ctx context.Context, transfer *entity.ScheduledTransfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockScheduledTransferRepository) CreateRun(ctx context.Context, scheduledTransferID uuid.UUID, run *entity.ScheduledTransferRun) error {
+	args := m.Called(ctx, scheduledTransferID, run)
+	return args.Error(0)
+}
+
+func (m *MockScheduledTransferRepository) GetRuns(ctx context.Context, scheduledTransferID uuid.UUID, limit int) ([]entity.ScheduledTransferRun, error) {
+	args := m.Called(ctx, scheduledTransferID, limit)
+	return args.Get(0).([]entity.ScheduledTransferRun), args.Error(1)
+}
+
+func TestPlanNextRun(t *testing.T) {
+	now := time.Date(2025, 2, 12, 10, 30, 0, 0, time.UTC)
+
+	t.Run("Recurring in timezone", func(t *testing.T) {
+		transfer := &entity.ScheduledTransfer{Schedule: "0 9 * * 1", Timezone: "Europe/Moscow"}
+		require.NoError(t, planNextRun(transfer, nil, now))
+		assert.Equal(t, time.Date(2025, 2, 17, 6, 0, 0, 0, time.UTC), *transfer.NextRunAt)
+	})
+
+	t.Run("One-off", func(t *testing.T) {
+		runAt := now.Add(time.Hour)
+		transfer := &entity.ScheduledTransfer{Timezone: "UTC"}
+		require.NoError(t, planNextRun(transfer, &runAt, now))
+		assert.Equal(t, runAt, *transfer.NextRunAt)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		past := now.Add(-time.Hour)
+		for name, tc := range map[string]struct {
+			transfer *entity.ScheduledTransfer
+			runAt    *time.Time
+		}{
+			"Neither":      {transfer: &entity.ScheduledTransfer{Timezone: "UTC"}},
+			"Both":         {transfer: &entity.ScheduledTransfer{Schedule: "* * * * *", Timezone: "UTC"}, runAt: &past},
+			"Past":         {transfer: &entity.ScheduledTransfer{Timezone: "UTC"}, runAt: &past},
+			"Bad schedule": {transfer: &entity.ScheduledTransfer{Schedule: "every monday", Timezone: "UTC"}},
+			"Bad timezone": {transfer: &entity.ScheduledTransfer{Schedule: "* * * * *", Timezone: "Mars/Olympus"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert.ErrorIs(t, planNextRun(tc.transfer, tc.runAt, now), ErrInvalidScheduledTransfer)
+			})
+		}
+	})
+}
+
+func TestAdvanceSchedule(t *testing.T) {
+	now := time.Date(2025, 2, 12, 10, 30, 0, 0, time.UTC)
+	scheduledFor := time.Date(2025, 2, 12, 10, 0, 0, 0, time.UTC)
+
+	t.Run("One-off completes", func(t *testing.T) {
+		transfer := &entity.ScheduledTransfer{Status: entity.ScheduledTransferStatusActive, NextRunAt: &scheduledFor}
+		advanceSchedule(transfer, &entity.ScheduledTransferRun{ScheduledFor: scheduledFor}, now)
+		assert.Equal(t, entity.ScheduledTransferStatusCompleted, transfer.Status)
+		assert.Nil(t, transfer.NextRunAt)
+	})
+
+	t.Run("One-off fails", func(t *testing.T) {
+		transfer := &entity.ScheduledTransfer{Status: entity.ScheduledTransferStatusActive, NextRunAt: &scheduledFor}
+		advanceSchedule(transfer, &entity.ScheduledTransferRun{ScheduledFor: scheduledFor, Error: "insufficient coins"}, now)
+		assert.Equal(t, entity.ScheduledTransferStatusFailed, transfer.Status)
+		assert.Equal(t, "insufficient coins", transfer.LastError)
+	})
+
+	t.Run("Recurring skips missed runs", func(t *testing.T) {
+		transfer := &entity.ScheduledTransfer{
+			Schedule:  "0 * * * *",
+			Timezone:  "UTC",
+			Status:    entity.ScheduledTransferStatusActive,
+			NextRunAt: &scheduledFor,
+		}
+		advanceSchedule(transfer, &entity.ScheduledTransferRun{ScheduledFor: scheduledFor}, now)
+		assert.Equal(t, entity.ScheduledTransferStatusActive, transfer.Status)
+		assert.Equal(t, time.Date(2025, 2, 12, 11, 0, 0, 0, time.UTC), *transfer.NextRunAt)
+		assert.Equal(t, scheduledFor, *transfer.LastRunAt)
+	})
+
+	t.Run("Recurring disabled after repeated failures", func(t *testing.T) {
+		transfer := &entity.ScheduledTransfer{
+			Schedule:     "0 * * * *",
+			Timezone:     "UTC",
+			Status:       entity.ScheduledTransferStatusActive,
+			NextRunAt:    &scheduledFor,
+			FailureCount: maxScheduledTransferFailures - 2,
+		}
+		advanceSchedule(transfer, &entity.ScheduledTransferRun{ScheduledFor: scheduledFor, Error: "insufficient coins"}, now)
+		assert.Equal(t, entity.ScheduledTransferStatusActive, transfer.Status)
+
+		advanceSchedule(transfer, &entity.ScheduledTransferRun{ScheduledFor: *transfer.NextRunAt, Error: "insufficient coins"}, now)
+		assert.Equal(t, entity.ScheduledTransferStatusFailed, transfer.Status)
+		assert.Equal(t, maxScheduledTransferFailures, transfer.FailureCount)
+	})
+}
+
+func TestScheduledTransferUseCase_ExecuteDueTransfers_FailureIsIsolated(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestScheduledTransferUseCase(repos)
+	handler := &recordingEventHandler{}
+	uc.sendCoinUseCase.events = handler
+
+	dueAt := time.Now().Add(-time.Minute)
+	broken := entity.ScheduledTransfer{ID: uuid.New(), FromUser: "alice", ToUser: "bob", Amount: 10,
+		Timezone: "UTC", Status: entity.ScheduledTransferStatusActive, NextRunAt: &dueAt}
+	healthy := entity.ScheduledTransfer{ID: uuid.New(), FromUser: "carol", ToUser: "dave", Amount: 20,
+		Timezone: "UTC", Status: entity.ScheduledTransferStatusActive, NextRunAt: &dueAt}
+	// Каждый перевод выбирается и выполняется в своей транзакции
+	repos.scheduled.On("LockDueTransfers", mock.Anything, mock.Anything, 1).Return([]entity.ScheduledTransfer{broken}, nil).Once()
+	repos.scheduled.On("LockDueTransfers", mock.Anything, mock.Anything, 1).Return([]entity.ScheduledTransfer{healthy}, nil).Once()
+	repos.scheduled.On("LockDueTransfers", mock.Anything, mock.Anything, 1).Return([]entity.ScheduledTransfer{}, nil).Once()
+
+	expectTransfer(repos, "alice", "bob", 10, uuid.New())
+	expectTransfer(repos, "carol", "dave", 20, uuid.New())
+	repos.scheduled.On("CreateRun", mock.Anything, broken.ID, mock.Anything).Return(errors.New("duplicate run"))
+	repos.scheduled.On("CreateRun", mock.Anything, healthy.ID, mock.Anything).Return(nil)
+
+	isTransfer := func(id uuid.UUID, status string) interface{} {
+		return mock.MatchedBy(func(t *entity.ScheduledTransfer) bool { return t.ID == id && t.Status == status })
+	}
+	repos.scheduled.On("Update", mock.Anything, isTransfer(broken.ID, entity.ScheduledTransferStatusFailed)).Return(nil).Once()
+	repos.scheduled.On("Update", mock.Anything, isTransfer(healthy.ID, entity.ScheduledTransferStatusCompleted)).Return(nil).Once()
+
+	require.NoError(t, uc.ExecuteDueTransfers(context.Background()))
+	assert.True(t, repos.tx.committed)
+	repos.scheduled.AssertNumberOfCalls(t, "Begin", 3)
+	// Откаченный перевод не публикует событий
+	assert.Equal(t, []entity.Event{
+		{Kind: entity.EventTransferSent, UserName: "carol"},
+		{Kind: entity.EventTransferReceived, UserName: "dave"},
+	}, handler.events)
+	repos.assertExpectations(t)
+}
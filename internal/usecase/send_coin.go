@@ -2,36 +2,86 @@ package usecase
 
 import (
 	"avito-merch/internal/entity"
-	"avito-merch/internal/repository"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
-type SendCoinUseCase struct {
-	userRepo        *repository.UserRepository
-	transactionRepo *repository.TransactionRepository
+// SendCoinConfig настройки переводов
+type SendCoinConfig struct {
+	// Limits профили лимитов на отправку по ролям
+	Limits map[string]entity.TransferLimits
+	// EscrowThreshold сумма, начиная с которой перевод требует подтверждения получателя, 0 - без порога
+	EscrowThreshold int
+	// PendingTransferTTL срок, в течение которого получатель может принять перевод
+	PendingTransferTTL time.Duration
+	// KudosBudget месячный бюджет благодарностей пользователя, 0 - благодарности отключены
+	KudosBudget int
 }
 
-func NewSendCoinUseCase(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository) *SendCoinUseCase {
-	return &SendCoinUseCase{userRepo: userRepo, transactionRepo: transactionRepo}
+type SendCoinUseCase struct {
+	userRepo        UserAccountRepository
+	transactionRepo TransferRepository
+	txRepos         func(tx pgx.Tx) *TxRepositories
+	limits          map[string]entity.TransferLimits
+	escrowThreshold int
+	pendingTTL      time.Duration
+	kudosBudget     int
+	events          EventHandler
 }
 
-// SendCoins выполняет перевод монет
-func (uc *SendCoinUseCase) SendCoins(ctx context.Context, fromUsername string, toUsername string, amount int) error {
-	if amount <= 0 {
-		return fmt.Errorf("amount must be positive: %d", amount)
+func NewSendCoinUseCase(
+	userRepo UserAccountRepository,
+	transactionRepo TransferRepository,
+	cfg SendCoinConfig,
+	events EventHandler,
+) *SendCoinUseCase {
+	pendingTTL := cfg.PendingTransferTTL
+	if pendingTTL <= 0 {
+		pendingTTL = defaultPendingTransferTTL
 	}
-
-	if fromUsername == toUsername {
-		return fmt.Errorf("cannot send coins to yourself: %s", toUsername)
+	return &SendCoinUseCase{
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		txRepos:         NewTxRepositories,
+		limits:          cfg.Limits,
+		escrowThreshold: cfg.EscrowThreshold,
+		pendingTTL:      pendingTTL,
+		kudosBudget:     cfg.KudosBudget,
+		events:          events,
 	}
+}
+
+// maxBatchSize максимальное число получателей в пакетном переводе
+const maxBatchSize = 100
 
+var (
+	ErrMemoTooLong  = fmt.Errorf("memo must be at most %d characters", entity.MaxMemoLength)
+	ErrInvalidBatch = errors.New("invalid batch transfer")
+)
+
+// BatchTransferItem перевод одному получателю в составе пакета
+type BatchTransferItem struct {
+	ToUser string
+	Amount int
+	Memo   string
+}
+
+// SendCoins выполняет перевод монет с необязательным сообщением получателю и возвращает запись о переводе.
+// Если получатель должен подтвердить перевод (requireAcceptance или сумма не меньше порога),
+// монеты замораживаются у отправителя, а перевод возвращается в статусе pending
+func (uc *SendCoinUseCase) SendCoins(ctx context.Context, fromUsername string, toUsername string, amount int, memo string, requireAcceptance bool) (*entity.Transaction, error) {
 	tx, err := uc.transactionRepo.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() {
 		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
@@ -39,33 +89,254 @@ func (uc *SendCoinUseCase) SendCoins(ctx context.Context, fromUsername string, t
 		}
 	}()
 
-	userRepo := repository.UserRepoWithTx(tx)
-	transactionRepo := repository.TransactionRepoWithTx(tx)
+	transfer, err := uc.sendInTx(ctx, tx, fromUsername, toUsername, amount, memo, requireAcceptance)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Логируем успешный перевод
+	slog.Info("Coins transferred successfully",
+		"fromUserName", fromUsername,
+		"toUserName", toUsername,
+		"amount", amount,
+		"status", transfer.Status,
+	)
+
+	if transfer.Status == entity.TransferStatusCompleted {
+		uc.publishTransfer(ctx, fromUsername, toUsername)
+	}
+	return transfer, nil
+}
+
+// sendInTx выполняет перевод в рамках транзакции вызывающего по тем же правилам, что и SendCoins:
+// перевод с requireAcceptance или на сумму не меньше порога ожидает решения получателя
+func (uc *SendCoinUseCase) sendInTx(ctx context.Context, tx pgx.Tx, fromUsername string, toUsername string, amount int, memo string, requireAcceptance bool) (*entity.Transaction, error) {
+	if requireAcceptance || (uc.escrowThreshold > 0 && amount >= uc.escrowThreshold) {
+		return uc.createPendingTransfer(ctx, tx, fromUsername, toUsername, amount, memo)
+	}
+	return uc.SendCoinsInTx(ctx, tx, fromUsername, toUsername, amount, memo)
+}
+
+// SendCoinsInTx выполняет перевод в рамках транзакции вызывающего.
+// Используется сценариями, которым перевод нужно совместить с собственными изменениями атомарно
+func (uc *SendCoinUseCase) SendCoinsInTx(ctx context.Context, tx pgx.Tx, fromUsername string, toUsername string, amount int, memo string) (*entity.Transaction, error) {
+	memo, err := validateTransfer(fromUsername, toUsername, amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := uc.txRepos(tx)
+
+	// Блокируем участников в том же порядке, что и пакетные переводы
+	locked, err := repos.Users.LockUsers(ctx, fromUsername, toUsername)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingUsers([]string{toUsername}, locked)) > 0 {
+		return nil, fmt.Errorf("recipient does not exist")
+	}
+
+	if err := uc.enforceLimits(ctx, repos, fromUsername, []BatchTransferItem{{ToUser: toUsername, Amount: amount}}); err != nil {
+		return nil, err
+	}
 
 	// Обновляем балансы обоих пользователей
-	if err := userRepo.UpdateUserAfterTransfer(ctx, fromUsername, toUsername, amount); err != nil {
-		return fmt.Errorf("failed to update sender balance: %w", err)
+	if err := repos.Users.UpdateUserAfterTransfer(ctx, fromUsername, toUsername, amount); err != nil {
+		return nil, fmt.Errorf("failed to update sender balance: %w", err)
 	}
 
 	// Создаем запись о переводе
-	if err := transactionRepo.CreateTransfer(ctx, &entity.Transaction{
+	transfer := &entity.Transaction{
 		FromUser: fromUsername,
 		ToUser:   toUsername,
 		Amount:   amount,
-	}); err != nil {
-		return fmt.Errorf("failed to create transfer record: %w", err)
+		Memo:     memo,
+	}
+	if err := repos.Transfers.CreateTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to create transfer record: %w", err)
+	}
+
+	// Проводим перевод по главной книге
+	if err := repos.Ledger.RecordTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to record transfer in ledger: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// SendBatch выполняет переводы нескольким получателям в одной транзакции:
+// либо проходят все переводы пакета, либо ни один
+func (uc *SendCoinUseCase) SendBatch(ctx context.Context, fromUsername string, items []BatchTransferItem) (*entity.TransferBatch, error) {
+	items, err := validateBatch(fromUsername, items)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.transactionRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+
+	recipients := make([]string, len(items))
+	amounts := make([]int, len(items))
+	batch := &entity.TransferBatch{ID: uuid.New(), FromUser: fromUsername}
+	for i, item := range items {
+		recipients[i] = item.ToUser
+		amounts[i] = item.Amount
+		batch.Total += item.Amount
+	}
+
+	// Блокируем отправителя и всех получателей в порядке имен,
+	// чтобы пересекающиеся пакеты не взаимоблокировались
+	locked, err := repos.Users.LockUsers(ctx, append([]string{fromUsername}, recipients...)...)
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingUsers(recipients, locked); len(missing) > 0 {
+		return nil, fmt.Errorf("recipients do not exist: %s", strings.Join(missing, ", "))
+	}
+
+	if err := uc.enforceLimits(ctx, repos, fromUsername, items); err != nil {
+		return nil, err
+	}
+
+	if err := repos.Users.UpdateUsersAfterBatchTransfer(ctx, fromUsername, recipients, amounts); err != nil {
+		return nil, fmt.Errorf("failed to update balances: %w", err)
+	}
+
+	batch.Transfers = make([]entity.Transaction, len(items))
+	for i, item := range items {
+		transfer := &batch.Transfers[i]
+		*transfer = entity.Transaction{
+			FromUser: fromUsername,
+			ToUser:   item.ToUser,
+			Amount:   item.Amount,
+			Memo:     item.Memo,
+			BatchID:  &batch.ID,
+		}
+		if err := repos.Transfers.CreateTransfer(ctx, transfer); err != nil {
+			return nil, fmt.Errorf("failed to create transfer record: %w", err)
+		}
+		if err := repos.Ledger.RecordTransfer(ctx, transfer); err != nil {
+			return nil, fmt.Errorf("failed to record transfer in ledger: %w", err)
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// Логируем успешный перевод
-	slog.Info("Coins transferred successfully",
+	slog.Info("Batch transfer completed",
 		"fromUserName", fromUsername,
-		"toUserName", toUsername,
-		"amount", amount,
+		"batchID", batch.ID,
+		"recipients", len(items),
+		"total", batch.Total,
+	)
+	for _, item := range items {
+		uc.publishTransfer(ctx, fromUsername, item.ToUser)
+	}
+	return batch, nil
+}
+
+// publishTransfer сообщает о завершенном переводе отправителю и получателю
+func (uc *SendCoinUseCase) publishTransfer(ctx context.Context, fromUsername string, toUsername string) {
+	publishEvents(ctx, uc.events,
+		entity.Event{Kind: entity.EventTransferSent, UserName: fromUsername},
+		entity.Event{Kind: entity.EventTransferReceived, UserName: toUsername},
 	)
+}
+
+// validateTransfer проверяет одиночный перевод и возвращает очищенное сообщение
+func validateTransfer(fromUsername, toUsername string, amount int, memo string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("amount must be positive: %d", amount)
+	}
+	if fromUsername == toUsername {
+		return "", fmt.Errorf("cannot send coins to yourself: %s", toUsername)
+	}
+	return sanitizeMemo(memo)
+}
+
+// validateBatch проверяет пакет до обращения к базе и возвращает его с очищенными сообщениями
+func validateBatch(fromUsername string, items []BatchTransferItem) ([]BatchTransferItem, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: at least one transfer is required", ErrInvalidBatch)
+	}
+	if len(items) > maxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d transfers are allowed", ErrInvalidBatch, maxBatchSize)
+	}
+
+	seen := make(map[string]bool, len(items))
+	sanitized := make([]BatchTransferItem, len(items))
+	for i, item := range items {
+		switch {
+		case item.ToUser == "":
+			return nil, fmt.Errorf("%w: transfer %d: toUser is required", ErrInvalidBatch, i)
+		case item.ToUser == fromUsername:
+			return nil, fmt.Errorf("%w: cannot send coins to yourself: %s", ErrInvalidBatch, item.ToUser)
+		case item.Amount <= 0:
+			return nil, fmt.Errorf("%w: amount must be positive: %d", ErrInvalidBatch, item.Amount)
+		case seen[item.ToUser]:
+			return nil, fmt.Errorf("%w: duplicate recipient: %s", ErrInvalidBatch, item.ToUser)
+		}
+		seen[item.ToUser] = true
 
-	return nil
+		memo, err := sanitizeMemo(item.Memo)
+		if err != nil {
+			return nil, err
+		}
+		sanitized[i] = BatchTransferItem{ToUser: item.ToUser, Amount: item.Amount, Memo: memo}
+	}
+	return sanitized, nil
+}
+
+// missingUsers возвращает имена из wanted, которых нет среди found
+func missingUsers(wanted, found []string) []string {
+	exists := make(map[string]bool, len(found))
+	for _, name := range found {
+		exists[name] = true
+	}
+	var missing []string
+	for _, name := range wanted {
+		if !exists[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// sanitizeMemo убирает из сообщения управляющие и невидимые символы,
+// схлопывает пробелы и проверяет длину
+func sanitizeMemo(memo string) (string, error) {
+	memo = strings.ToValidUTF8(memo, "")
+	memo = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		// Невидимые символы форматирования (в т.ч. управление направлением текста),
+		// кроме соединителя, без которого ломаются составные эмодзи
+		case unicode.Is(unicode.Cf, r) && r != '\u200d':
+			return -1
+		}
+		return r
+	}, memo)
+	memo = strings.Join(strings.Fields(memo), " ")
+
+	if utf8.RuneCountInString(memo) > entity.MaxMemoLength {
+		return "", ErrMemoTooLong
+	}
+	return memo, nil
 }
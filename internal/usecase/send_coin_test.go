@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeMemo(t *testing.T) {
+	tests := []struct {
+		name     string
+		memo     string
+		expected string
+	}{
+		{name: "empty", memo: "", expected: ""},
+		{name: "plain", memo: "thanks for the code review!", expected: "thanks for the code review!"},
+		{name: "whitespace collapsed", memo: "  thanks\n\tfor\r\nlunch  ", expected: "thanks for lunch"},
+		{name: "control characters removed", memo: "te\x00st\x1b[31m", expected: "test[31m"},
+		{name: "bidi override removed", memo: "abc\u202edef", expected: "abcdef"},
+		{name: "emoji kept", memo: "спасибо 👩\u200d💻", expected: "спасибо 👩\u200d💻"},
+		{name: "invalid utf8 dropped", memo: "ok\xff", expected: "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memo, err := sanitizeMemo(tt.memo)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, memo)
+		})
+	}
+}
+
+func TestSanitizeMemo_TooLong(t *testing.T) {
+	_, err := sanitizeMemo(strings.Repeat("я", 141))
+	assert.ErrorIs(t, err, ErrMemoTooLong)
+
+	memo, err := sanitizeMemo(strings.Repeat("я", 140))
+	assert.NoError(t, err)
+	assert.Len(t, []rune(memo), 140)
+}
+
+func TestValidateBatch(t *testing.T) {
+	items, err := validateBatch("alice", []BatchTransferItem{
+		{ToUser: "bob", Amount: 10, Memo: "  great\tjob "},
+		{ToUser: "carol", Amount: 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchTransferItem{
+		{ToUser: "bob", Amount: 10, Memo: "great job"},
+		{ToUser: "carol", Amount: 5},
+	}, items)
+
+	tooMany := make([]BatchTransferItem, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = BatchTransferItem{ToUser: fmt.Sprintf("user%d", i), Amount: 1}
+	}
+
+	for name, items := range map[string][]BatchTransferItem{
+		"empty":          nil,
+		"too many":       tooMany,
+		"self":           {{ToUser: "alice", Amount: 1}},
+		"no recipient":   {{Amount: 1}},
+		"zero amount":    {{ToUser: "bob", Amount: 0}},
+		"duplicate":      {{ToUser: "bob", Amount: 1}, {ToUser: "bob", Amount: 2}},
+		"negative later": {{ToUser: "bob", Amount: 1}, {ToUser: "carol", Amount: -1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := validateBatch("alice", items)
+			assert.ErrorIs(t, err, ErrInvalidBatch)
+		})
+	}
+}
+
+func TestMissingUsers(t *testing.T) {
+	assert.Equal(t, []string{"dave"}, missingUsers([]string{"bob", "dave", "carol"}, []string{"alice", "bob", "carol"}))
+	assert.Empty(t, missingUsers([]string{"bob"}, []string{"alice", "bob"}))
+}
+
+func TestCheckTransferLimits(t *testing.T) {
+	now := time.Date(2025, 2, 12, 10, 0, 0, 0, time.UTC)
+	sender := &entity.User{Name: "alice", CreatedAt: now.Add(-30 * 24 * time.Hour)}
+	limits := entity.TransferLimits{
+		MaxPerTransfer:    100,
+		Daily:             300,
+		Weekly:            1000,
+		DailyPerRecipient: 150,
+		MinAccountAge:     7 * 24 * time.Hour,
+	}
+	totals := &entity.SentTotals{Daily: 200, Weekly: 850, DailyByRecipient: map[string]int{"bob": 100}}
+
+	tests := []struct {
+		name     string
+		sender   *entity.User
+		items    []BatchTransferItem
+		expected string
+	}{
+		{name: "within limits", sender: sender, items: []BatchTransferItem{{ToUser: "carol", Amount: 100}}},
+		{name: "account too young", sender: &entity.User{CreatedAt: now.Add(-time.Hour)}, items: []BatchTransferItem{{ToUser: "carol", Amount: 1}}, expected: LimitCodeAccountTooYoung},
+		{name: "per transfer", sender: sender, items: []BatchTransferItem{{ToUser: "carol", Amount: 101}}, expected: LimitCodeTransferAmount},
+		{name: "per recipient", sender: sender, items: []BatchTransferItem{{ToUser: "bob", Amount: 51}}, expected: LimitCodeRecipientDaily},
+		{name: "daily across batch", sender: sender, items: []BatchTransferItem{{ToUser: "carol", Amount: 60}, {ToUser: "dave", Amount: 60}}, expected: LimitCodeDaily},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTransferLimits(limits, tt.sender, tt.items, totals, now)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var limitErr *TransferLimitError
+			if assert.ErrorAs(t, err, &limitErr) {
+				assert.Equal(t, tt.expected, limitErr.Code)
+			}
+		})
+	}
+
+	t.Run("weekly exceeded", func(t *testing.T) {
+		err := checkTransferLimits(entity.TransferLimits{Weekly: 1000}, sender, []BatchTransferItem{{ToUser: "carol", Amount: 151}}, totals, now)
+		var limitErr *TransferLimitError
+		if assert.ErrorAs(t, err, &limitErr) {
+			assert.Equal(t, LimitCodeWeekly, limitErr.Code)
+		}
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		assert.NoError(t, checkTransferLimits(entity.TransferLimits{}, sender, []BatchTransferItem{{ToUser: "bob", Amount: 1 << 20}}, &entity.SentTotals{}, now))
+	})
+}
@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxTeamNameLength максимальная длина названия команды в символах
+	maxTeamNameLength = 64
+	// teamHistoryLimit сколько последних операций с кошельком возвращается в информации о команде
+	teamHistoryLimit = 100
+)
+
+var (
+	ErrInvalidTeam           = errors.New("invalid team request")
+	ErrTeamNotFound          = errors.New("team not found")
+	ErrTeamMemberNotFound    = errors.New("user is not a team member")
+	ErrTeamForbidden         = errors.New("only team owners can do this")
+	ErrLastTeamOwner         = errors.New("team must keep at least one owner")
+	ErrTeamNameTaken         = repository.ErrTeamNameTaken
+	ErrInsufficientTeamCoins = repository.ErrInsufficientTeamCoins
+)
+
+// TeamUseCase команды с общим кошельком. Взносы, переводы из кошелька и покупки выполняются
+// в одной транзакции с проводками, как переводы между пользователями: пользователи блокируются
+// в порядке имен, затем блокируется строка команды. Взносы и переводы из кошелька ограничены
+// лимитами переводов участника, выполняющего операцию
+type TeamUseCase struct {
+	teamRepo        TeamRepository
+	sendCoinUseCase *SendCoinUseCase
+	txRepos         func(tx pgx.Tx) *TxRepositories
+}
+
+// TeamRepository команды, их участники и кошельки
+type TeamRepository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	CreateTeam(ctx context.Context, team *entity.Team) error
+	GetTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error)
+	LockTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error)
+	GetUserTeams(ctx context.Context, userName string) ([]entity.Team, error)
+	SetMember(ctx context.Context, teamID uuid.UUID, userName, role string) error
+	RemoveMember(ctx context.Context, teamID uuid.UUID, userName string) error
+	CreditCoins(ctx context.Context, teamID uuid.UUID, lots []entity.CoinLot) error
+	DebitCoins(ctx context.Context, teamID uuid.UUID, amount int) ([]entity.CoinLot, error)
+	CreateOperation(ctx context.Context, op *entity.TeamOperation) error
+	GetHistory(ctx context.Context, teamID uuid.UUID, limit int) ([]entity.TeamOperation, error)
+	LockTeamsWithExpiredLots(ctx context.Context, cutoff time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error)
+	ExpireCoins(ctx context.Context, teamID uuid.UUID, cutoff time.Time) (int, error)
+}
+
+func NewTeamUseCase(teamRepo TeamRepository, sendCoinUseCase *SendCoinUseCase) *TeamUseCase {
+	return &TeamUseCase{teamRepo: teamRepo, sendCoinUseCase: sendCoinUseCase, txRepos: NewTxRepositories}
+}
+
+// CreateTeam создает команду, создатель становится ее владельцем
+func (uc *TeamUseCase) CreateTeam(ctx context.Context, creator, name string) (*entity.Team, error) {
+	name, err := validateTeamName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := uc.teamRepo.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	team := &entity.Team{Name: name, CreatedBy: creator}
+	if err := uc.txRepos(tx).Teams.CreateTeam(ctx, team); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Team created", "teamID", team.ID, "name", team.Name, "owner", creator)
+	return team, nil
+}
+
+// GetTeams возвращает команды пользователя
+func (uc *TeamUseCase) GetTeams(ctx context.Context, userName string) ([]entity.Team, error) {
+	return uc.teamRepo.GetUserTeams(ctx, userName)
+}
+
+// GetTeamInfo возвращает команду с последними операциями с ее кошельком участнику команды
+// или аудитору. Чужая команда неотличима от несуществующей
+func (uc *TeamUseCase) GetTeamInfo(ctx context.Context, viewer, role string, id uuid.UUID) (*entity.TeamInfo, error) {
+	team, err := uc.teamRepo.GetTeam(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil || (role != entity.RoleAuditor && team.Role(viewer) == "") {
+		return nil, ErrTeamNotFound
+	}
+
+	history, err := uc.teamRepo.GetHistory(ctx, id, teamHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.TeamInfo{Team: *team, History: history}, nil
+}
+
+// SetMember добавляет пользователя в команду или меняет его роль. Доступно владельцам
+func (uc *TeamUseCase) SetMember(ctx context.Context, actor string, id uuid.UUID, userName, role string) (*entity.Team, error) {
+	if role != entity.TeamRoleOwner && role != entity.TeamRoleMember {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidTeam, role)
+	}
+
+	var updated *entity.Team
+	err := uc.inTeamTx(ctx, id, []string{userName}, func(tx pgx.Tx, team *entity.Team) error {
+		if err := checkMemberChange(team, actor, userName, role); err != nil {
+			return err
+		}
+		teamRepo := uc.txRepos(tx).Teams
+		if err := teamRepo.SetMember(ctx, id, userName, role); err != nil {
+			return err
+		}
+		var err error
+		updated, err = teamRepo.GetTeam(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Team member set", "teamID", id, "actor", actor, "user", userName, "role", role)
+	return updated, nil
+}
+
+// RemoveMember исключает пользователя из команды. Владельцы исключают любого участника,
+// остальные могут только выйти сами
+func (uc *TeamUseCase) RemoveMember(ctx context.Context, actor string, id uuid.UUID, userName string) error {
+	err := uc.inTeamTx(ctx, id, nil, func(tx pgx.Tx, team *entity.Team) error {
+		if err := checkMemberChange(team, actor, userName, ""); err != nil {
+			return err
+		}
+		return uc.txRepos(tx).Teams.RemoveMember(ctx, id, userName)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Team member removed", "teamID", id, "actor", actor, "user", userName)
+	return nil
+}
+
+// Deposit переводит монеты участника в кошелек команды. Монеты сохраняют дату получения
+func (uc *TeamUseCase) Deposit(ctx context.Context, userName string, id uuid.UUID, amount int, memo string) (*entity.TeamOperation, error) {
+	memo, err := validateTeamAmount(amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &entity.TeamOperation{
+		TeamID:   id,
+		Kind:     entity.TeamOperationDeposit,
+		Member:   userName,
+		UserName: userName,
+		Amount:   amount,
+		Memo:     memo,
+	}
+	err = uc.inTeamTx(ctx, id, []string{userName}, func(tx pgx.Tx, team *entity.Team) error {
+		if team.Role(userName) == "" {
+			return ErrTeamNotFound
+		}
+
+		repos := uc.txRepos(tx)
+		// Взнос - перевод на счет команды
+		items := []BatchTransferItem{{ToUser: entity.TeamAccount(id), Amount: amount}}
+		if err := uc.sendCoinUseCase.enforceLimits(ctx, repos, userName, items); err != nil {
+			return err
+		}
+
+		lots, err := repos.Users.DebitCoins(ctx, userName, amount)
+		if err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+		if err := repos.Teams.CreditCoins(ctx, id, lots); err != nil {
+			return err
+		}
+		return recordTeamOperation(ctx, repos, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Coins deposited to team wallet", "teamID", id, "userName", userName, "amount", amount)
+	return op, nil
+}
+
+// Withdraw переводит монеты из кошелька команды пользователю. Доступно владельцам
+func (uc *TeamUseCase) Withdraw(ctx context.Context, owner string, id uuid.UUID, toUser string, amount int, memo string) (*entity.TeamOperation, error) {
+	memo, err := validateTeamAmount(amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &entity.TeamOperation{
+		TeamID:   id,
+		Kind:     entity.TeamOperationWithdrawal,
+		Member:   owner,
+		UserName: toUser,
+		Amount:   amount,
+		Memo:     memo,
+	}
+	// Перевод из кошелька учитывается лимитами владельца, поэтому блокируется и его строка
+	err = uc.inTeamTx(ctx, id, []string{owner, toUser}, func(tx pgx.Tx, team *entity.Team) error {
+		if err := requireTeamOwner(team, owner); err != nil {
+			return err
+		}
+
+		repos := uc.txRepos(tx)
+		items := []BatchTransferItem{{ToUser: toUser, Amount: amount}}
+		if err := uc.sendCoinUseCase.enforceLimits(ctx, repos, owner, items); err != nil {
+			return err
+		}
+
+		lots, err := repos.Teams.DebitCoins(ctx, id, amount)
+		if err != nil {
+			return err
+		}
+		if err := repos.Users.CreditLots(ctx, toUser, lots); err != nil {
+			return err
+		}
+		return recordTeamOperation(ctx, repos, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Coins withdrawn from team wallet", "teamID", id, "owner", owner, "toUser", toUser, "amount", amount)
+	return op, nil
+}
+
+// BuyItem покупает товар из кошелька команды и выдает его участнику команды. Доступно владельцам
+func (uc *TeamUseCase) BuyItem(ctx context.Context, owner string, id uuid.UUID, itemName, receiver string) (*entity.TeamOperation, error) {
+	if receiver == "" {
+		receiver = owner
+	}
+
+	op := &entity.TeamOperation{
+		TeamID:   id,
+		Kind:     entity.TeamOperationPurchase,
+		Member:   owner,
+		UserName: receiver,
+	}
+	err := uc.inTeamTx(ctx, id, []string{receiver}, func(tx pgx.Tx, team *entity.Team) error {
+		if err := requireTeamOwner(team, owner); err != nil {
+			return err
+		}
+		if team.Role(receiver) == "" {
+			return fmt.Errorf("%w: %s", ErrTeamMemberNotFound, receiver)
+		}
+
+		repos := uc.txRepos(tx)
+		item, err := repos.Items.GetItemByName(ctx, itemName)
+		if err != nil {
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if item == nil {
+			return fmt.Errorf("item not found: %s", itemName)
+		}
+		op.ItemName = item.Name
+		op.Amount = item.Price
+
+		if _, err := repos.Teams.DebitCoins(ctx, id, item.Price); err != nil {
+			return err
+		}
+		if err := deliverItem(ctx, repos.Items, receiver, item); err != nil {
+			return err
+		}
+		return recordTeamOperation(ctx, repos, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishEvents(ctx, uc.sendCoinUseCase.events, entity.Event{Kind: entity.EventPurchase, UserName: receiver})
+
+	slog.Info("Item purchased from team wallet", "teamID", id, "owner", owner, "receiver", receiver, "item", itemName)
+	return op, nil
+}
+
+// inTeamTx выполняет fn в транзакции, заблокировав пользователей users и затем строку команды
+func (uc *TeamUseCase) inTeamTx(ctx context.Context, id uuid.UUID, users []string, fn func(tx pgx.Tx, team *entity.Team) error) error {
+	tx, err := uc.teamRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	repos := uc.txRepos(tx)
+	if len(users) > 0 {
+		locked, err := repos.Users.LockUsers(ctx, users...)
+		if err != nil {
+			return err
+		}
+		if missing := missingUsers(users, locked); len(missing) > 0 {
+			return fmt.Errorf("%w: user does not exist: %s", ErrInvalidTeam, strings.Join(missing, ", "))
+		}
+	}
+
+	team, err := repos.Teams.LockTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		return ErrTeamNotFound
+	}
+
+	if err := fn(tx, team); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// recordTeamOperation записывает операцию в историю команды и проводит ее по главной книге
+func recordTeamOperation(ctx context.Context, repos *TxRepositories, op *entity.TeamOperation) error {
+	if err := repos.Teams.CreateOperation(ctx, op); err != nil {
+		return err
+	}
+	if err := repos.Ledger.RecordTeamOperation(ctx, op); err != nil {
+		return fmt.Errorf("failed to record team operation in ledger: %w", err)
+	}
+	return nil
+}
+
+// requireTeamOwner проверяет, что пользователь - владелец команды. Не участнику команда не видна
+func requireTeamOwner(team *entity.Team, userName string) error {
+	switch team.Role(userName) {
+	case entity.TeamRoleOwner:
+		return nil
+	case "":
+		return ErrTeamNotFound
+	default:
+		return ErrTeamForbidden
+	}
+}
+
+// checkMemberChange проверяет, может ли actor назначить userName роль role
+// или исключить его из команды (пустая role). В команде всегда остается владелец
+func checkMemberChange(team *entity.Team, actor, userName, role string) error {
+	actorRole := team.Role(actor)
+	if actorRole == "" {
+		return ErrTeamNotFound
+	}
+	// Выйти из команды может любой участник
+	if actorRole != entity.TeamRoleOwner && (role != "" || actor != userName) {
+		return ErrTeamForbidden
+	}
+
+	current := team.Role(userName)
+	if role == "" && current == "" {
+		return fmt.Errorf("%w: %s", ErrTeamMemberNotFound, userName)
+	}
+	if current == entity.TeamRoleOwner && role != entity.TeamRoleOwner && team.Owners() == 1 {
+		return ErrLastTeamOwner
+	}
+	return nil
+}
+
+// validateTeamName возвращает название команды без лишних пробелов
+func validateTeamName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidTeam)
+	}
+	if utf8.RuneCountInString(name) > maxTeamNameLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidTeam, maxTeamNameLength)
+	}
+	return name, nil
+}
+
+// validateTeamAmount проверяет сумму операции с кошельком и возвращает очищенное сообщение
+func validateTeamAmount(amount int, memo string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("%w: amount must be positive: %d", ErrInvalidTeam, amount)
+	}
+	return sanitizeMemo(memo)
+}
@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTeamRepository struct {
+	mock.Mock
+}
+
+func (m *MockTeamRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(pgx.Tx), args.Error(1)
+}
+
+func (m *MockTeamRepository) CreateTeam(ctx context.Context, team *entity.Team) error {
+	args := m.Called(ctx, team)
+	return args.Error(0)
+}
+
+func (m *MockTeamRepository) GetTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Team), args.Error(1)
+}
+
+func (m *MockTeamRepository) LockTeam(ctx context.Context, id uuid.UUID) (*entity.Team, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Team), args.Error(1)
+}
+
+func (m *MockTeamRepository) GetUserTeams(ctx context.Context, userName string) ([]entity.Team, error) {
+	args := m.Called(ctx, userName)
+	return args.Get(0).([]entity.Team), args.Error(1)
+}
+
+func (m *MockTeamRepository) SetMember(ctx context.Context, teamID uuid.UUID, userName, role string) error {
+	args := m.Called(ctx, teamID, userName, role)
+	return args.Error(0)
+}
+
+func (m *MockTeamRepository) RemoveMember(ctx context.Context, teamID uuid.UUID, userName string) error {
+	args := m.Called(ctx, teamID, userName)
+	return args.Error(0)
+}
+
+func (m *MockTeamRepository) CreditCoins(ctx context.Context, teamID uuid.UUID, lots []entity.CoinLot) error {
+	args := m.Called(ctx, teamID, lots)
+	return args.Error(0)
+}
+
+func (m *MockTeamRepository) DebitCoins(ctx context.Context, teamID uuid.UUID, amount int) ([]entity.CoinLot, error) {
+	args := m.Called(ctx, teamID, amount)
+	return args.Get(0).([]entity.CoinLot), args.Error(1)
+}
+
+func (m *MockTeamRepository) CreateOperation(ctx context.Context, op *entity.TeamOperation) error {
+	args := m.Called(ctx, op)
+	return args.Error(0)
+}
+
+func (m *MockTeamRepository) GetHistory(ctx context.Context, teamID uuid.UUID, limit int) ([]entity.TeamOperation, error) {
+	args := m.Called(ctx, teamID, limit)
+	return args.Get(0).([]entity.TeamOperation), args.Error(1)
+}
+
+func (m *MockTeamRepository) LockTeamsWithExpiredLots(ctx context.Context, cutoff time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, cutoff, after, limit)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockTeamRepository) ExpireCoins(ctx context.Context, teamID uuid.UUID, cutoff time.Time) (int, error) {
+	args := m.Called(ctx, teamID, cutoff)
+	return args.Int(0), args.Error(1)
+}
+
+func TestTeamUseCase_Deposit(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	team := testTeam()
+	lots := []entity.CoinLot{{GrantedOn: entity.LotDate(time.Now()), Amount: 20}}
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+	expectSentToday(repos, "bob", entity.TeamAccount(team.ID), 80)
+	repos.users.On("DebitCoins", mock.Anything, "bob", 20).Return(lots, nil)
+	repos.teams.On("CreditCoins", mock.Anything, team.ID, lots).Return(nil)
+	repos.teams.On("CreateOperation", mock.Anything, mock.Anything).Return(nil)
+	repos.ledger.On("RecordTeamOperation", mock.Anything, mock.MatchedBy(func(op *entity.TeamOperation) bool {
+		return op.Kind == entity.TeamOperationDeposit && op.UserName == "bob" && op.Amount == 20
+	})).Return(nil)
+
+	op, err := uc.Deposit(context.Background(), "bob", team.ID, 20, "на пиццу")
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.TeamOperationDeposit, op.Kind)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestTeamUseCase_Deposit_DailyLimit(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	team := testTeam()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+	expectSentToday(repos, "bob", entity.TeamAccount(team.ID), 80)
+
+	_, err := uc.Deposit(context.Background(), "bob", team.ID, 21, "")
+
+	var limitErr *TransferLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitCodeDaily, limitErr.Code)
+	assert.False(t, repos.tx.committed)
+	repos.users.AssertNotCalled(t, "DebitCoins", mock.Anything, mock.Anything, mock.Anything)
+	repos.teams.AssertNotCalled(t, "CreditCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTeamUseCase_Withdraw(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	team := testTeam()
+	lots := []entity.CoinLot{{GrantedOn: entity.LotDate(time.Now()), Amount: 20}}
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "carol"}).Return([]string{"alice", "carol"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+	expectSentToday(repos, "alice", "carol", 80)
+	repos.teams.On("DebitCoins", mock.Anything, team.ID, 20).Return(lots, nil)
+	repos.users.On("CreditLots", mock.Anything, "carol", lots).Return(nil)
+	repos.teams.On("CreateOperation", mock.Anything, mock.Anything).Return(nil)
+	repos.ledger.On("RecordTeamOperation", mock.Anything, mock.MatchedBy(func(op *entity.TeamOperation) bool {
+		return op.Kind == entity.TeamOperationWithdrawal && op.Member == "alice" && op.UserName == "carol"
+	})).Return(nil)
+
+	_, err := uc.Withdraw(context.Background(), "alice", team.ID, "carol", 20, "")
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	repos.assertExpectations(t)
+}
+
+func TestTeamUseCase_BuyItem(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	handler := &recordingEventHandler{}
+	uc.sendCoinUseCase.events = handler
+	team := testTeam()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob"}).Return([]string{"bob"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+	repos.items.On("GetItemByName", mock.Anything, "cup").Return(&entity.Item{Name: "cup", Price: 20}, nil)
+	repos.teams.On("DebitCoins", mock.Anything, team.ID, 20).Return([]entity.CoinLot(nil), nil)
+	repos.items.On("ReserveStock", mock.Anything, "cup", 1).Return(nil)
+	repos.items.On("GetBundleComponents", mock.Anything, "cup").Return([]entity.BundleComponent(nil), nil)
+	repos.items.On("AddToInventory", mock.Anything, "bob", "cup", 1).Return(nil)
+	repos.teams.On("CreateOperation", mock.Anything, mock.Anything).Return(nil)
+	repos.ledger.On("RecordTeamOperation", mock.Anything, mock.MatchedBy(func(op *entity.TeamOperation) bool {
+		return op.Kind == entity.TeamOperationPurchase && op.UserName == "bob" && op.ItemName == "cup"
+	})).Return(nil)
+
+	_, err := uc.BuyItem(context.Background(), "alice", team.ID, "cup", "bob")
+
+	require.NoError(t, err)
+	assert.True(t, repos.tx.committed)
+	assert.Equal(t, []entity.Event{{Kind: entity.EventPurchase, UserName: "bob"}}, handler.events)
+	repos.assertExpectations(t)
+}
+
+func TestTeamUseCase_Withdraw_DailyLimit(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	team := testTeam()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"alice", "carol"}).Return([]string{"alice", "carol"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+	expectSentToday(repos, "alice", "carol", 80)
+
+	_, err := uc.Withdraw(context.Background(), "alice", team.ID, "carol", 30, "")
+
+	var limitErr *TransferLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitCodeDaily, limitErr.Code)
+	assert.False(t, repos.tx.committed)
+	repos.teams.AssertNotCalled(t, "DebitCoins", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTeamUseCase_Withdraw_NotOwner(t *testing.T) {
+	repos := newMockRepos()
+	uc := newTestTeamUseCase(repos, dailyLimits)
+	team := testTeam()
+
+	repos.users.On("LockUsers", mock.Anything, []string{"bob", "carol"}).Return([]string{"bob", "carol"}, nil)
+	repos.teams.On("LockTeam", mock.Anything, team.ID).Return(team, nil)
+
+	_, err := uc.Withdraw(context.Background(), "bob", team.ID, "carol", 10, "")
+
+	assert.ErrorIs(t, err, ErrTeamForbidden)
+	assert.False(t, repos.tx.committed)
+	repos.transfers.AssertNotCalled(t, "GetSentTotals", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheckMemberChange(t *testing.T) {
+	team := &entity.Team{Members: []entity.TeamMember{
+		{UserName: "alice", Role: entity.TeamRoleOwner},
+		{UserName: "bob", Role: entity.TeamRoleMember},
+	}}
+
+	for name, tc := range map[string]struct {
+		actor, user, role string
+		err               error
+	}{
+		"owner adds member":         {"alice", "carol", entity.TeamRoleMember, nil},
+		"owner promotes member":     {"alice", "bob", entity.TeamRoleOwner, nil},
+		"owner removes member":      {"alice", "bob", "", nil},
+		"member leaves":             {"bob", "bob", "", nil},
+		"member adds member":        {"bob", "carol", entity.TeamRoleMember, ErrTeamForbidden},
+		"member promotes self":      {"bob", "bob", entity.TeamRoleOwner, ErrTeamForbidden},
+		"member removes owner":      {"bob", "alice", "", ErrTeamForbidden},
+		"outsider":                  {"carol", "carol", "", ErrTeamNotFound},
+		"remove non-member":         {"alice", "carol", "", ErrTeamMemberNotFound},
+		"last owner leaves":         {"alice", "alice", "", ErrLastTeamOwner},
+		"last owner steps down":     {"alice", "alice", entity.TeamRoleMember, ErrLastTeamOwner},
+		"last owner stays an owner": {"alice", "alice", entity.TeamRoleOwner, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := checkMemberChange(team, tc.actor, tc.user, tc.role)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+
+	// Со вторым владельцем первый может выйти
+	team.Members = append(team.Members, entity.TeamMember{UserName: "dave", Role: entity.TeamRoleOwner})
+	assert.NoError(t, checkMemberChange(team, "alice", "alice", ""))
+}
+
+func TestRequireTeamOwner(t *testing.T) {
+	team := &entity.Team{Members: []entity.TeamMember{
+		{UserName: "alice", Role: entity.TeamRoleOwner},
+		{UserName: "bob", Role: entity.TeamRoleMember},
+	}}
+	assert.NoError(t, requireTeamOwner(team, "alice"))
+	assert.ErrorIs(t, requireTeamOwner(team, "bob"), ErrTeamForbidden)
+	assert.ErrorIs(t, requireTeamOwner(team, "carol"), ErrTeamNotFound)
+}
+
+func TestValidateTeamName(t *testing.T) {
+	name, err := validateTeamName("  Platform \t team ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Platform team", name)
+
+	_, err = validateTeamName("   ")
+	assert.ErrorIs(t, err, ErrInvalidTeam)
+	_, err = validateTeamName(strings.Repeat("я", maxTeamNameLength+1))
+	assert.ErrorIs(t, err, ErrInvalidTeam)
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"avito-merch/internal/entity"
+	"avito-merch/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUserNotFound = errors.New("user not found or already deactivated")
+
+// UserUseCase управление учетными записями. Пользователи не удаляются: счет в главной книге
+// и история переводов сохраняются, а учетная запись деактивируется
+type UserUseCase struct {
+	userRepo *repository.UserRepository
+}
+
+func NewUserUseCase(userRepo *repository.UserRepository) *UserUseCase {
+	return &UserUseCase{userRepo: userRepo}
+}
+
+// DeactivateUser запрещает пользователю вход. Баланс и история остаются в главной книге
+func (uc *UserUseCase) DeactivateUser(ctx context.Context, admin, userName string) error {
+	tx, err := uc.userRepo.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			slog.Error("rollback error")
+		}
+	}()
+
+	deactivated, err := repository.UserRepoWithTx(tx).Deactivate(ctx, userName)
+	if err != nil {
+		return err
+	}
+	if !deactivated {
+		return ErrUserNotFound
+	}
+	if err := repository.AuditRepoWithTx(tx).Record(ctx, &entity.AuditEntry{
+		Actor:   admin,
+		Action:  entity.AuditActionUserDeactivated,
+		Details: map[string]any{"user": userName},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("User deactivated", "userName", userName, "admin", admin)
+	return nil
+}
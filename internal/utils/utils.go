@@ -8,6 +8,8 @@ import (
 
 type ErrorResponse struct {
 	Errors string `json:"errors"`
+	// Code машиночитаемый код ошибки, если клиенту нужно различать причины
+	Code string `json:"code,omitempty"`
 }
 
 func WriteError(w http.ResponseWriter, statusCode int, message string) {
@@ -18,3 +20,20 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	}
 
 }
+
+// WriteErrorCode пишет ошибку с машиночитаемым кодом
+func WriteErrorCode(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Errors: message, Code: code}); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
+
+func WriteJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode JSON response")
+	}
+}
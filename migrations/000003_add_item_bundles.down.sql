@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bundle_components;
+DELETE FROM merch_items WHERE name = 'welcome-pack';
+ALTER TABLE merch_items DROP COLUMN IF EXISTS stock;
@@ -0,0 +1,18 @@
+-- Остаток на складе (NULL - остаток не отслеживается)
+ALTER TABLE merch_items ADD COLUMN IF NOT EXISTS stock INT CHECK (stock >= 0);
+-- Состав наборов: набор - это обычная позиция каталога со своей ценой,
+-- при покупке которой пользователь получает входящие в нее товары
+CREATE TABLE IF NOT EXISTS bundle_components (
+    bundle_name VARCHAR(50) REFERENCES merch_items(name) ON DELETE CASCADE,
+    item_name VARCHAR(50) REFERENCES merch_items(name) ON DELETE RESTRICT,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_name, item_name),
+    CHECK (bundle_name <> item_name)
+);
+-- Приветственный набор со скидкой
+INSERT INTO merch_items (name, price)
+VALUES ('welcome-pack', 95) ON CONFLICT DO NOTHING;
+INSERT INTO bundle_components (bundle_name, item_name, quantity)
+VALUES ('welcome-pack', 't-shirt', 1),
+    ('welcome-pack', 'cup', 1),
+    ('welcome-pack', 'pen', 1) ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS preorders;
+DROP TABLE IF EXISTS coin_holds;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_held_coins_check;
+ALTER TABLE users DROP COLUMN IF EXISTS held_coins;
@@ -0,0 +1,28 @@
+-- Замороженные монеты: не доступны для трат, но еще не списаны
+ALTER TABLE users ADD COLUMN IF NOT EXISTS held_coins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD CONSTRAINT users_held_coins_check CHECK (held_coins >= 0 AND held_coins <= coins);
+-- Холды монет
+CREATE TABLE IF NOT EXISTS coin_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    reason VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_coin_holds_active_user ON coin_holds(user_name) WHERE status = 'active';
+-- Предзаказы товаров, которых нет на складе
+CREATE TABLE IF NOT EXISTS preorders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name) ON DELETE CASCADE,
+    price INT NOT NULL CHECK (price > 0),
+    hold_id UUID NOT NULL REFERENCES coin_holds(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'fulfilled', 'cancelled', 'expired')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_preorders_pending_item ON preorders(item_name, created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_preorders_user ON preorders(user_name, created_at);
@@ -0,0 +1,5 @@
+-- Сообщение к переводу монет
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS memo VARCHAR(140) NOT NULL DEFAULT '';
+-- Поиск по подстроке в сообщениях
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_memo ON transfer_history USING gin (memo gin_trgm_ops);
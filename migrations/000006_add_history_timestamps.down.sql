@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS purchase_history;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS created_at;
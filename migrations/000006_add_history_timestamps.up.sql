@@ -0,0 +1,17 @@
+-- Время создания перевода
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+-- Точное время старых переводов неизвестно, поэтому считаем их совершенными
+-- в момент миграции. Порядок между ними определяется по id
+UPDATE transfer_history SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE transfer_history ALTER COLUMN created_at SET DEFAULT now();
+ALTER TABLE transfer_history ALTER COLUMN created_at SET NOT NULL;
+-- История покупок. Название товара не ссылается на каталог,
+-- чтобы история сохранялась после удаления товара
+CREATE TABLE IF NOT EXISTS purchase_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    item_name VARCHAR(50) NOT NULL,
+    price INT NOT NULL CHECK (price > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_history_user ON purchase_history(user_name, created_at DESC, id DESC);
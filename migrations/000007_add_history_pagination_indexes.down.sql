@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user ON transfer_history(from_user_name);
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user ON transfer_history(to_user_name);
+DROP INDEX IF EXISTS idx_transfer_history_from_user_created;
+DROP INDEX IF EXISTS idx_transfer_history_to_user_created;
@@ -0,0 +1,6 @@
+-- Индексы для постраничной выборки истории по ключу (created_at, id).
+-- Заменяют одноколоночные индексы, которые покрываются их префиксом
+CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user_created ON transfer_history(from_user_name, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user_created ON transfer_history(to_user_name, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_transfer_history_from_user;
+DROP INDEX IF EXISTS idx_transfer_history_to_user;
@@ -0,0 +1,84 @@
+-- Счета главной книги: по одному на пользователя и системные счета
+-- (эмиссия монет, выручка магазина)
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id TEXT PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('user', 'system')),
+    user_name VARCHAR(255) UNIQUE REFERENCES users(username) ON DELETE RESTRICT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((kind = 'user') = (user_name IS NOT NULL))
+);
+-- Проводки: неизменяемые записи о движении монет
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(30) NOT NULL,
+    reference_id UUID,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries(reference_id);
+-- Движения по счетам. Сумма движений каждой проводки равна нулю
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id),
+    account_id TEXT NOT NULL REFERENCES ledger_accounts(id),
+    amount INT NOT NULL CHECK (amount <> 0)
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account ON ledger_postings(account_id);
+-- Баланс проводки проверяется при коммите, когда все движения уже вставлены
+CREATE OR REPLACE FUNCTION ledger_check_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    total BIGINT;
+    postings INT;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0), COUNT(*) INTO total, postings
+    FROM ledger_postings WHERE entry_id = NEW.entry_id;
+    IF total <> 0 OR postings < 2 THEN
+        RAISE EXCEPTION 'ledger entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_entry_balanced();
+-- Проводки и движения нельзя изменять или удалять, только сторнировать новыми проводками
+CREATE OR REPLACE FUNCTION ledger_forbid_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger records are immutable';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS ledger_entries_immutable ON ledger_entries;
+CREATE TRIGGER ledger_entries_immutable
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON ledger_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_forbid_modification();
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+CREATE TRIGGER ledger_postings_immutable
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON ledger_postings
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_forbid_modification();
+-- Системные счета
+INSERT INTO ledger_accounts (id, kind)
+VALUES ('system:mint', 'system'),
+    ('system:shop', 'system') ON CONFLICT DO NOTHING;
+-- Счета существующих пользователей. Прошлые движения монет не восстановить,
+-- поэтому текущий баланс каждого пользователя заводится проводкой открытия
+-- со счета эмиссии
+INSERT INTO ledger_accounts (id, kind, user_name)
+SELECT 'user:' || username, 'user', username FROM users ON CONFLICT DO NOTHING;
+DO $$
+DECLARE
+    u RECORD;
+    entry UUID;
+BEGIN
+    FOR u IN SELECT username, coins FROM users WHERE coins > 0 LOOP
+        INSERT INTO ledger_entries (kind, description)
+        VALUES ('opening_balance', 'Opening balance on ledger introduction')
+        RETURNING id INTO entry;
+        INSERT INTO ledger_postings (entry_id, account_id, amount)
+        VALUES (entry, 'system:mint', -u.coins),
+            (entry, 'user:' || u.username, u.coins);
+    END LOOP;
+END;
+$$;
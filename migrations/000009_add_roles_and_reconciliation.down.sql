@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reconciliation_runs;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,12 @@
+-- Роли пользователей: администраторы, аудиторы и сервисные учетные записи
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin', 'auditor', 'service'));
+-- Результаты сверки балансов с главной книгой
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    ok BOOLEAN NOT NULL,
+    report JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_finished ON reconciliation_runs(finished_at DESC);
@@ -0,0 +1,18 @@
+-- Запросы монет: получатель просит плательщика перевести ему монеты
+CREATE TABLE IF NOT EXISTS coin_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    payer_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(140) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled', 'expired')),
+    transfer_id UUID REFERENCES transfer_history(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    CHECK (requester_name <> payer_name),
+    CHECK ((status = 'accepted') = (transfer_id IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_payer ON coin_requests(payer_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_requester ON coin_requests(requester_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_pending_expiry ON coin_requests(expires_at) WHERE status = 'pending';
@@ -0,0 +1,32 @@
+-- Запланированные переводы: разовые (schedule пуст) и повторяющиеся по cron-расписанию
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    to_user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(140) NOT NULL DEFAULT '',
+    schedule VARCHAR(100) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'completed', 'failed', 'cancelled')),
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    failure_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (from_user_name <> to_user_name),
+    CHECK (status NOT IN ('active', 'paused') OR next_run_at IS NOT NULL)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers(next_run_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_owner ON scheduled_transfers(from_user_name, created_at DESC);
+-- Журнал выполнения запланированных переводов. Уникальность по плановому времени
+-- гарантирует, что одно срабатывание не будет выполнено дважды
+CREATE TABLE IF NOT EXISTS scheduled_transfer_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scheduled_transfer_id UUID NOT NULL REFERENCES scheduled_transfers(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    transfer_id UUID REFERENCES transfer_history(id),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (scheduled_transfer_id, scheduled_for)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transfer_history_batch;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS batch_id;
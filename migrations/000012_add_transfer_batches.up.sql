@@ -0,0 +1,3 @@
+-- Пакетные переводы: переводы одного пакета связаны общим идентификатором
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS batch_id UUID;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_batch ON transfer_history(batch_id) WHERE batch_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Дата регистрации для ограничения переводов с новых учетных записей.
+-- Существующие пользователи считаются зарегистрированными давно
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
+ALTER TABLE users ALTER COLUMN created_at SET DEFAULT now();
@@ -0,0 +1 @@
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS reversal_of;
@@ -0,0 +1,3 @@
+-- Сторнирование переводов: компенсирующий перевод ссылается на исходный.
+-- Уникальность не дает сторнировать перевод дважды
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS reversal_of UUID UNIQUE REFERENCES transfer_history(id);
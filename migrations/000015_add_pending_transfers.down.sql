@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_transfer_history_pending_to;
+DROP INDEX IF EXISTS idx_transfer_history_pending_from;
+DROP INDEX IF EXISTS idx_transfer_history_pending_expiry;
+ALTER TABLE transfer_history DROP CONSTRAINT IF EXISTS transfer_history_pending_check;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS resolved_at;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS hold_id;
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS status;
@@ -0,0 +1,12 @@
+-- Переводы, ожидающие подтверждения получателем. Монеты отправителя заморожены холдом
+-- до принятия, отклонения, отмены или истечения срока
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('pending', 'completed', 'rejected', 'cancelled', 'expired'));
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS hold_id UUID REFERENCES coin_holds(id);
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ;
+ALTER TABLE transfer_history ADD CONSTRAINT transfer_history_pending_check
+    CHECK (status <> 'pending' OR (hold_id IS NOT NULL AND expires_at IS NOT NULL));
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_expiry ON transfer_history(expires_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_from ON transfer_history(from_user_name) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_to ON transfer_history(to_user_name) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS issuance_budget;
+DROP TABLE IF EXISTS coin_grant_items;
+DROP TABLE IF EXISTS coin_grants;
@@ -0,0 +1,37 @@
+-- Начисления монет администраторами со счета эмиссии. Начисление от порога
+-- ждет подтверждения другим администратором
+CREATE TABLE IF NOT EXISTS coin_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    total INT NOT NULL CHECK (total > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'completed', 'rejected')),
+    resolved_by VARCHAR(255) REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_created ON coin_grants(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_pending ON coin_grants(created_at) WHERE status = 'pending';
+CREATE TABLE IF NOT EXISTS coin_grant_items (
+    grant_id UUID NOT NULL REFERENCES coin_grants(id) ON DELETE CASCADE,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (grant_id, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_coin_grant_items_user ON coin_grant_items(user_name);
+-- Выпуск монет начислениями по месяцам. Строка периода обновляется атомарно,
+-- поэтому параллельные начисления не превысят бюджет
+CREATE TABLE IF NOT EXISTS issuance_budget (
+    period DATE PRIMARY KEY,
+    issued INT NOT NULL DEFAULT 0 CHECK (issued >= 0)
+);
+-- Журнал действий администраторов
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_id UUID,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_id) WHERE target_id IS NOT NULL;
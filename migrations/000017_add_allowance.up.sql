@@ -0,0 +1,12 @@
+-- Ежемесячное пособие. Первичный ключ (period, user_name) гарантирует одну выплату
+-- пользователю за период при повторных запусках и на нескольких репликах
+CREATE TABLE IF NOT EXISTS allowance_payments (
+    period DATE NOT NULL,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount >= 0),
+    prorated BOOLEAN NOT NULL DEFAULT false,
+    run_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (period, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_allowance_payments_run ON allowance_payments(run_id);
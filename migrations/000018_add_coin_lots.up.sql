@@ -0,0 +1,16 @@
+-- Монеты пользователей партиями по дню получения (UTC). Траты списывают самые старые партии,
+-- переводы переносят партии получателю с исходной датой, поэтому перевод не продлевает срок жизни монет
+CREATE TABLE IF NOT EXISTS coin_lots (
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    granted_on DATE NOT NULL,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    -- warned_at когда пользователя предупредили о скором сгорании партии
+    warned_at TIMESTAMPTZ,
+    PRIMARY KEY (user_name, granted_on)
+);
+CREATE INDEX IF NOT EXISTS idx_coin_lots_granted ON coin_lots(granted_on) WHERE remaining > 0;
+-- Монеты на балансах до введения партий считаются полученными в день миграции
+INSERT INTO coin_lots (user_name, granted_on, remaining)
+SELECT username, (now() AT TIME ZONE 'UTC')::date, coins FROM users WHERE coins > 0
+ON CONFLICT DO NOTHING;
+INSERT INTO ledger_accounts (id, kind) VALUES ('system:expired', 'system') ON CONFLICT DO NOTHING;
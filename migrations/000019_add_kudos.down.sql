@@ -0,0 +1,3 @@
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS kudos;
+ALTER TABLE users DROP COLUMN IF EXISTS giving_period;
+ALTER TABLE users DROP COLUMN IF EXISTS giving_budget;
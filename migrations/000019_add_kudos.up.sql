@@ -0,0 +1,6 @@
+-- Бюджет благодарностей: монеты, которые можно только подарить. Остаток относится к периоду
+-- giving_period и сбрасывается при первой трате в новом периоде
+ALTER TABLE users ADD COLUMN IF NOT EXISTS giving_budget INT NOT NULL DEFAULT 0 CHECK (giving_budget >= 0);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS giving_period DATE;
+-- Благодарность хранится как перевод, но монеты получателю выпускаются со счета эмиссии
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS kudos BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_purchase_history_created;
+DROP INDEX IF EXISTS idx_transfer_history_created;
+ALTER TABLE users DROP COLUMN IF EXISTS leaderboard_opt_out;
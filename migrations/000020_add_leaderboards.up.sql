@@ -0,0 +1,5 @@
+-- Пользователь может скрыть себя из рейтингов
+ALTER TABLE users ADD COLUMN IF NOT EXISTS leaderboard_opt_out BOOLEAN NOT NULL DEFAULT false;
+-- Индексы для агрегации рейтингов за неделю и месяц
+CREATE INDEX IF NOT EXISTS idx_transfer_history_created ON transfer_history(created_at) WHERE status = 'completed';
+CREATE INDEX IF NOT EXISTS idx_purchase_history_created ON purchase_history(created_at);
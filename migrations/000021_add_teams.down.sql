@@ -0,0 +1,7 @@
+-- Счета команд остаются в неизменяемой главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system')) NOT VALID;
+DROP TABLE IF EXISTS team_history;
+DROP TABLE IF EXISTS team_coin_lots;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
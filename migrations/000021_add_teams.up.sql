@@ -0,0 +1,42 @@
+-- Команды с общим кошельком. teams.coins - кэш баланса счета команды в главной книге
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    coins INT NOT NULL DEFAULT 0 CHECK (coins >= 0),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- Участники команды. Тратить из кошелька и управлять составом могут только владельцы
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (team_id, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members(user_name);
+-- Партии монет в кошельке команды с датой получения исходным владельцем,
+-- поэтому монеты, прошедшие через кошелек, не продлевают срок жизни
+CREATE TABLE IF NOT EXISTS team_coin_lots (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    granted_on DATE NOT NULL,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    PRIMARY KEY (team_id, granted_on)
+);
+-- Операции с кошельком команды: взносы участников, переводы из кошелька и покупки
+CREATE TABLE IF NOT EXISTS team_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('deposit', 'withdrawal', 'purchase')),
+    -- member участник, выполнивший операцию; user_name вносивший, получатель перевода или товара
+    member VARCHAR(255) NOT NULL REFERENCES users(username),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(255),
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_team_history_team ON team_history(team_id, created_at DESC);
+-- Счета команд в главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system', 'team'));
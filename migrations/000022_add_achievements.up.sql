@@ -0,0 +1,12 @@
+-- Полученные пользователями достижения. Уникальность (пользователь, достижение) гарантирует,
+-- что достижение и награда за него выдаются один раз
+CREATE TABLE IF NOT EXISTS user_achievements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    achievement_id VARCHAR(64) NOT NULL,
+    -- name название на момент получения: правила задаются в настройках и могут меняться
+    name VARCHAR(255) NOT NULL,
+    bounty INT NOT NULL DEFAULT 0 CHECK (bounty >= 0),
+    earned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_name, achievement_id)
+);
@@ -0,0 +1,5 @@
+-- Счета кампаний остаются в неизменяемой главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system', 'team')) NOT VALID;
+DROP TABLE IF EXISTS charity_donations;
+DROP TABLE IF EXISTS charity_campaigns;
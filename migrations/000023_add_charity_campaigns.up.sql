@@ -0,0 +1,29 @@
+-- Благотворительные кампании. raised - кэш баланса счета кампании в главной книге,
+-- summary - итоговый отчет, сохраняемый при закрытии
+CREATE TABLE IF NOT EXISTS charity_campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    goal INT NOT NULL CHECK (goal > 0),
+    raised INT NOT NULL DEFAULT 0 CHECK (raised >= 0),
+    deadline TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ,
+    summary JSONB
+);
+CREATE INDEX IF NOT EXISTS idx_charity_campaigns_open ON charity_campaigns(deadline) WHERE status = 'open';
+-- Пожертвования пользователей
+CREATE TABLE IF NOT EXISTS charity_donations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id UUID NOT NULL REFERENCES charity_campaigns(id),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_charity_donations_campaign ON charity_donations(campaign_id, user_name);
+CREATE INDEX IF NOT EXISTS idx_charity_donations_user ON charity_donations(user_name, created_at);
+-- Счета кампаний в главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system', 'team', 'campaign'));
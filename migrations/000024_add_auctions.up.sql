@@ -0,0 +1,31 @@
+-- Аукционы уникальных товаров. Лидирующая ставка заморожена холдом top_hold_id,
+-- reserve_price - минимальная цена, при которой товар продается
+CREATE TABLE IF NOT EXISTS auctions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    reserve_price INT NOT NULL CHECK (reserve_price > 0),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sold', 'unsold', 'cancelled')),
+    top_bid INT NOT NULL DEFAULT 0 CHECK (top_bid >= 0),
+    top_bidder VARCHAR(255) REFERENCES users(username),
+    top_hold_id UUID REFERENCES coin_holds(id),
+    bid_count INT NOT NULL DEFAULT 0,
+    extensions INT NOT NULL DEFAULT 0,
+    purchase_id UUID REFERENCES purchase_history(id),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    settled_at TIMESTAMPTZ,
+    CHECK (ends_at > starts_at),
+    CHECK ((top_bidder IS NULL) = (top_bid = 0))
+);
+CREATE INDEX IF NOT EXISTS idx_auctions_active ON auctions(ends_at) WHERE status = 'active';
+-- Ставки аукционов
+CREATE TABLE IF NOT EXISTS auction_bids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_auction_bids_auction ON auction_bids(auction_id, created_at DESC);
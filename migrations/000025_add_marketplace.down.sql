@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS marketplace_sales;
+DROP TABLE IF EXISTS marketplace_listings;
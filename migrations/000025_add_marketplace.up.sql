@@ -0,0 +1,31 @@
+-- Объявления о продаже товаров из инвентаря. Выставленные единицы (remaining) списаны
+-- из инвентаря продавца и не могут быть проданы или выставлены повторно
+CREATE TABLE IF NOT EXISTS marketplace_listings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seller VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    price INT NOT NULL CHECK (price > 0),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sold', 'cancelled')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ,
+    CHECK (remaining <= quantity),
+    CHECK ((status = 'sold') = (remaining = 0))
+);
+CREATE INDEX IF NOT EXISTS idx_marketplace_listings_active ON marketplace_listings(item_name, price) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_marketplace_listings_seller ON marketplace_listings(seller, created_at DESC);
+-- Продажи по объявлениям, amount - сумма сделки
+CREATE TABLE IF NOT EXISTS marketplace_sales (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    listing_id UUID NOT NULL REFERENCES marketplace_listings(id),
+    seller VARCHAR(255) NOT NULL REFERENCES users(username),
+    buyer VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (seller <> buyer)
+);
+CREATE INDEX IF NOT EXISTS idx_marketplace_sales_seller ON marketplace_sales(seller, created_at);
+CREATE INDEX IF NOT EXISTS idx_marketplace_sales_buyer ON marketplace_sales(buyer, created_at);
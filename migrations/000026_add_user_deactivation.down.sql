@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_no_delete ON users;
+DROP FUNCTION IF EXISTS users_forbid_delete();
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,13 @@
+-- Пользователи не удаляются: счет в главной книге и история переводов должны
+-- пережить учетную запись (ledger_accounts ссылается на users с ON DELETE RESTRICT).
+-- Вместо удаления учетная запись деактивируется
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+CREATE OR REPLACE FUNCTION users_forbid_delete() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'users cannot be deleted, deactivate them instead';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS users_no_delete ON users;
+CREATE TRIGGER users_no_delete
+    BEFORE DELETE OR TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE FUNCTION users_forbid_delete();
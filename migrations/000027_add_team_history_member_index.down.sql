@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_team_history_member;
@@ -0,0 +1,2 @@
+-- Взносы в кошельки команд и переводы из них учитываются лимитами переводов участника
+CREATE INDEX IF NOT EXISTS idx_team_history_member ON team_history(member, created_at);
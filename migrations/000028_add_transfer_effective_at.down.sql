@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transfer_history_effective;
+DROP INDEX IF EXISTS idx_transfer_history_to_user_effective;
+DROP INDEX IF EXISTS idx_transfer_history_from_user_effective;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_created ON transfer_history(created_at) WHERE status = 'completed';
+ALTER TABLE transfer_history DROP COLUMN IF EXISTS effective_at;
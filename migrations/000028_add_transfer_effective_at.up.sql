@@ -0,0 +1,10 @@
+-- Время движения монет: создание для обычного перевода, принятие для ожидавшего подтверждения.
+-- История упорядочена по нему, а created_at остается временем создания и учитывается лимитами
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS effective_at TIMESTAMPTZ;
+UPDATE transfer_history SET effective_at = created_at WHERE effective_at IS NULL;
+ALTER TABLE transfer_history ALTER COLUMN effective_at SET DEFAULT now();
+ALTER TABLE transfer_history ALTER COLUMN effective_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user_effective ON transfer_history(from_user_name, effective_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user_effective ON transfer_history(to_user_name, effective_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_transfer_history_created;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_effective ON transfer_history(effective_at) WHERE status = 'completed';
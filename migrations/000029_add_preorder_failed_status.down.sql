@@ -0,0 +1,3 @@
+ALTER TABLE preorders DROP CONSTRAINT IF EXISTS preorders_status_check;
+ALTER TABLE preorders ADD CONSTRAINT preorders_status_check
+    CHECK (status IN ('pending', 'fulfilled', 'cancelled', 'expired'));
@@ -0,0 +1,5 @@
+-- Предзаказ, который не удалось выполнить, завершается с размороженными монетами,
+-- чтобы не блокировать очередь предзаказов
+ALTER TABLE preorders DROP CONSTRAINT IF EXISTS preorders_status_check;
+ALTER TABLE preorders ADD CONSTRAINT preorders_status_check
+    CHECK (status IN ('pending', 'fulfilled', 'cancelled', 'expired', 'failed'));
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS bundle_components_no_nesting ON bundle_components;
+DROP FUNCTION IF EXISTS bundle_components_forbid_nesting();
@@ -0,0 +1,19 @@
+-- Наборы одноуровневые: компонент набора не может сам быть набором. Покупка, предзаказы
+-- и проверка остатков раскрывают только один уровень состава
+CREATE OR REPLACE FUNCTION bundle_components_forbid_nesting() RETURNS trigger AS $$
+BEGIN
+    -- Параллельные изменения состава наборов сериализуются, чтобы не создать вложенность вдвоем
+    PERFORM pg_advisory_xact_lock(hashtext('bundle_components'));
+    IF EXISTS (SELECT 1 FROM bundle_components WHERE bundle_name = NEW.item_name) THEN
+        RAISE EXCEPTION 'bundle % cannot be a component of bundle %', NEW.item_name, NEW.bundle_name;
+    END IF;
+    IF EXISTS (SELECT 1 FROM bundle_components WHERE item_name = NEW.bundle_name) THEN
+        RAISE EXCEPTION 'component % cannot be a bundle', NEW.bundle_name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS bundle_components_no_nesting ON bundle_components;
+CREATE TRIGGER bundle_components_no_nesting
+    BEFORE INSERT OR UPDATE ON bundle_components
+    FOR EACH ROW EXECUTE FUNCTION bundle_components_forbid_nesting();
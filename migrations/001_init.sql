@@ -43,4 +43,519 @@ CREATE TABLE IF NOT EXISTS transfer_history (
 -- Индексы для ускорения поиска
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user ON transfer_history(from_user_name);
-CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user ON transfer_history(to_user_name);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user ON transfer_history(to_user_name);
+-- Остаток на складе (NULL - остаток не отслеживается)
+ALTER TABLE merch_items ADD COLUMN IF NOT EXISTS stock INT CHECK (stock >= 0);
+-- Состав наборов: набор - это обычная позиция каталога со своей ценой,
+-- при покупке которой пользователь получает входящие в нее товары
+CREATE TABLE IF NOT EXISTS bundle_components (
+    bundle_name VARCHAR(50) REFERENCES merch_items(name) ON DELETE CASCADE,
+    item_name VARCHAR(50) REFERENCES merch_items(name) ON DELETE RESTRICT,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_name, item_name),
+    CHECK (bundle_name <> item_name)
+);
+-- Приветственный набор со скидкой
+INSERT INTO merch_items (name, price)
+VALUES ('welcome-pack', 95) ON CONFLICT DO NOTHING;
+INSERT INTO bundle_components (bundle_name, item_name, quantity)
+VALUES ('welcome-pack', 't-shirt', 1),
+    ('welcome-pack', 'cup', 1),
+    ('welcome-pack', 'pen', 1) ON CONFLICT DO NOTHING;
+-- Замороженные монеты: не доступны для трат, но еще не списаны
+ALTER TABLE users ADD COLUMN IF NOT EXISTS held_coins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD CONSTRAINT users_held_coins_check CHECK (held_coins >= 0 AND held_coins <= coins);
+-- Холды монет
+CREATE TABLE IF NOT EXISTS coin_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    reason VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_coin_holds_active_user ON coin_holds(user_name) WHERE status = 'active';
+-- Предзаказы товаров, которых нет на складе
+CREATE TABLE IF NOT EXISTS preorders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name) ON DELETE CASCADE,
+    price INT NOT NULL CHECK (price > 0),
+    hold_id UUID NOT NULL REFERENCES coin_holds(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'fulfilled', 'cancelled', 'expired')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_preorders_pending_item ON preorders(item_name, created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_preorders_user ON preorders(user_name, created_at);
+-- Сообщение к переводу монет
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS memo VARCHAR(140) NOT NULL DEFAULT '';
+-- Поиск по подстроке в сообщениях
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_memo ON transfer_history USING gin (memo gin_trgm_ops);
+-- Время создания перевода
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+-- Точное время старых переводов неизвестно, поэтому считаем их совершенными
+-- в момент миграции. Порядок между ними определяется по id
+UPDATE transfer_history SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE transfer_history ALTER COLUMN created_at SET DEFAULT now();
+ALTER TABLE transfer_history ALTER COLUMN created_at SET NOT NULL;
+-- История покупок. Название товара не ссылается на каталог,
+-- чтобы история сохранялась после удаления товара
+CREATE TABLE IF NOT EXISTS purchase_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    item_name VARCHAR(50) NOT NULL,
+    price INT NOT NULL CHECK (price > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_history_user ON purchase_history(user_name, created_at DESC, id DESC);
+-- Индексы для постраничной выборки истории по ключу (created_at, id).
+-- Заменяют одноколоночные индексы, которые покрываются их префиксом
+CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user_created ON transfer_history(from_user_name, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user_created ON transfer_history(to_user_name, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_transfer_history_from_user;
+DROP INDEX IF EXISTS idx_transfer_history_to_user;
+-- Счета главной книги: по одному на пользователя и системные счета
+-- (эмиссия монет, выручка магазина)
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id TEXT PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('user', 'system')),
+    user_name VARCHAR(255) UNIQUE REFERENCES users(username) ON DELETE RESTRICT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((kind = 'user') = (user_name IS NOT NULL))
+);
+-- Проводки: неизменяемые записи о движении монет
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(30) NOT NULL,
+    reference_id UUID,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries(reference_id);
+-- Движения по счетам. Сумма движений каждой проводки равна нулю
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id),
+    account_id TEXT NOT NULL REFERENCES ledger_accounts(id),
+    amount INT NOT NULL CHECK (amount <> 0)
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account ON ledger_postings(account_id);
+-- Баланс проводки проверяется при коммите, когда все движения уже вставлены
+CREATE OR REPLACE FUNCTION ledger_check_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    total BIGINT;
+    postings INT;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0), COUNT(*) INTO total, postings
+    FROM ledger_postings WHERE entry_id = NEW.entry_id;
+    IF total <> 0 OR postings < 2 THEN
+        RAISE EXCEPTION 'ledger entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_entry_balanced();
+-- Проводки и движения нельзя изменять или удалять, только сторнировать новыми проводками
+CREATE OR REPLACE FUNCTION ledger_forbid_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger records are immutable';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS ledger_entries_immutable ON ledger_entries;
+CREATE TRIGGER ledger_entries_immutable
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON ledger_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_forbid_modification();
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+CREATE TRIGGER ledger_postings_immutable
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON ledger_postings
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_forbid_modification();
+-- Системные счета
+INSERT INTO ledger_accounts (id, kind)
+VALUES ('system:mint', 'system'),
+    ('system:shop', 'system') ON CONFLICT DO NOTHING;
+-- Счета существующих пользователей. Прошлые движения монет не восстановить,
+-- поэтому текущий баланс каждого пользователя заводится проводкой открытия
+-- со счета эмиссии
+INSERT INTO ledger_accounts (id, kind, user_name)
+SELECT 'user:' || username, 'user', username FROM users ON CONFLICT DO NOTHING;
+DO $$
+DECLARE
+    u RECORD;
+    entry UUID;
+BEGIN
+    FOR u IN SELECT username, coins FROM users WHERE coins > 0 LOOP
+        INSERT INTO ledger_entries (kind, description)
+        VALUES ('opening_balance', 'Opening balance on ledger introduction')
+        RETURNING id INTO entry;
+        INSERT INTO ledger_postings (entry_id, account_id, amount)
+        VALUES (entry, 'system:mint', -u.coins),
+            (entry, 'user:' || u.username, u.coins);
+    END LOOP;
+END;
+$$;
+-- Роли пользователей: администраторы, аудиторы и сервисные учетные записи
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin', 'auditor', 'service'));
+-- Результаты сверки балансов с главной книгой
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    ok BOOLEAN NOT NULL,
+    report JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_finished ON reconciliation_runs(finished_at DESC);
+-- Запросы монет: получатель просит плательщика перевести ему монеты
+CREATE TABLE IF NOT EXISTS coin_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    payer_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(140) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled', 'expired')),
+    transfer_id UUID REFERENCES transfer_history(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    CHECK (requester_name <> payer_name),
+    CHECK ((status = 'accepted') = (transfer_id IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_payer ON coin_requests(payer_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_requester ON coin_requests(requester_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_pending_expiry ON coin_requests(expires_at) WHERE status = 'pending';
+-- Запланированные переводы: разовые (schedule пуст) и повторяющиеся по cron-расписанию
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    to_user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(140) NOT NULL DEFAULT '',
+    schedule VARCHAR(100) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'completed', 'failed', 'cancelled')),
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    failure_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (from_user_name <> to_user_name),
+    CHECK (status NOT IN ('active', 'paused') OR next_run_at IS NOT NULL)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers(next_run_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_owner ON scheduled_transfers(from_user_name, created_at DESC);
+-- Журнал выполнения запланированных переводов. Уникальность по плановому времени
+-- гарантирует, что одно срабатывание не будет выполнено дважды
+CREATE TABLE IF NOT EXISTS scheduled_transfer_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scheduled_transfer_id UUID NOT NULL REFERENCES scheduled_transfers(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    transfer_id UUID REFERENCES transfer_history(id),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (scheduled_transfer_id, scheduled_for)
+);
+-- Пакетные переводы: переводы одного пакета связаны общим идентификатором
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS batch_id UUID;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_batch ON transfer_history(batch_id) WHERE batch_id IS NOT NULL;
+-- Дата регистрации для ограничения переводов с новых учетных записей.
+-- Существующие пользователи считаются зарегистрированными давно
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
+ALTER TABLE users ALTER COLUMN created_at SET DEFAULT now();
+-- Сторнирование переводов: компенсирующий перевод ссылается на исходный.
+-- Уникальность не дает сторнировать перевод дважды
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS reversal_of UUID UNIQUE REFERENCES transfer_history(id);
+-- Переводы, ожидающие подтверждения получателем. Монеты отправителя заморожены холдом
+-- до принятия, отклонения, отмены или истечения срока
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('pending', 'completed', 'rejected', 'cancelled', 'expired'));
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS hold_id UUID REFERENCES coin_holds(id);
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ;
+ALTER TABLE transfer_history ADD CONSTRAINT transfer_history_pending_check
+    CHECK (status <> 'pending' OR (hold_id IS NOT NULL AND expires_at IS NOT NULL));
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_expiry ON transfer_history(expires_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_from ON transfer_history(from_user_name) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_transfer_history_pending_to ON transfer_history(to_user_name) WHERE status = 'pending';
+-- Начисления монет администраторами со счета эмиссии. Начисление от порога
+-- ждет подтверждения другим администратором
+CREATE TABLE IF NOT EXISTS coin_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    total INT NOT NULL CHECK (total > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'completed', 'rejected')),
+    resolved_by VARCHAR(255) REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_created ON coin_grants(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coin_grants_pending ON coin_grants(created_at) WHERE status = 'pending';
+CREATE TABLE IF NOT EXISTS coin_grant_items (
+    grant_id UUID NOT NULL REFERENCES coin_grants(id) ON DELETE CASCADE,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (grant_id, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_coin_grant_items_user ON coin_grant_items(user_name);
+-- Выпуск монет начислениями по месяцам. Строка периода обновляется атомарно,
+-- поэтому параллельные начисления не превысят бюджет
+CREATE TABLE IF NOT EXISTS issuance_budget (
+    period DATE PRIMARY KEY,
+    issued INT NOT NULL DEFAULT 0 CHECK (issued >= 0)
+);
+-- Журнал действий администраторов
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_id UUID,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_id) WHERE target_id IS NOT NULL;
+-- Ежемесячное пособие. Первичный ключ (period, user_name) гарантирует одну выплату
+-- пользователю за период при повторных запусках и на нескольких репликах
+CREATE TABLE IF NOT EXISTS allowance_payments (
+    period DATE NOT NULL,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount >= 0),
+    prorated BOOLEAN NOT NULL DEFAULT false,
+    run_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (period, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_allowance_payments_run ON allowance_payments(run_id);
+-- Монеты пользователей партиями по дню получения (UTC). Траты списывают самые старые партии,
+-- переводы переносят партии получателю с исходной датой, поэтому перевод не продлевает срок жизни монет
+CREATE TABLE IF NOT EXISTS coin_lots (
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    granted_on DATE NOT NULL,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    -- warned_at когда пользователя предупредили о скором сгорании партии
+    warned_at TIMESTAMPTZ,
+    PRIMARY KEY (user_name, granted_on)
+);
+CREATE INDEX IF NOT EXISTS idx_coin_lots_granted ON coin_lots(granted_on) WHERE remaining > 0;
+-- Монеты на балансах до введения партий считаются полученными в день миграции
+INSERT INTO coin_lots (user_name, granted_on, remaining)
+SELECT username, (now() AT TIME ZONE 'UTC')::date, coins FROM users WHERE coins > 0
+ON CONFLICT DO NOTHING;
+INSERT INTO ledger_accounts (id, kind) VALUES ('system:expired', 'system') ON CONFLICT DO NOTHING;
+-- Бюджет благодарностей: монеты, которые можно только подарить. Остаток относится к периоду
+-- giving_period и сбрасывается при первой трате в новом периоде
+ALTER TABLE users ADD COLUMN IF NOT EXISTS giving_budget INT NOT NULL DEFAULT 0 CHECK (giving_budget >= 0);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS giving_period DATE;
+-- Благодарность хранится как перевод, но монеты получателю выпускаются со счета эмиссии
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS kudos BOOLEAN NOT NULL DEFAULT false;
+-- Пользователь может скрыть себя из рейтингов
+ALTER TABLE users ADD COLUMN IF NOT EXISTS leaderboard_opt_out BOOLEAN NOT NULL DEFAULT false;
+-- Индексы для агрегации рейтингов за неделю и месяц
+CREATE INDEX IF NOT EXISTS idx_transfer_history_created ON transfer_history(created_at) WHERE status = 'completed';
+CREATE INDEX IF NOT EXISTS idx_purchase_history_created ON purchase_history(created_at);
+-- Команды с общим кошельком. teams.coins - кэш баланса счета команды в главной книге
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    coins INT NOT NULL DEFAULT 0 CHECK (coins >= 0),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- Участники команды. Тратить из кошелька и управлять составом могут только владельцы
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (team_id, user_name)
+);
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members(user_name);
+-- Партии монет в кошельке команды с датой получения исходным владельцем,
+-- поэтому монеты, прошедшие через кошелек, не продлевают срок жизни
+CREATE TABLE IF NOT EXISTS team_coin_lots (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    granted_on DATE NOT NULL,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    PRIMARY KEY (team_id, granted_on)
+);
+-- Операции с кошельком команды: взносы участников, переводы из кошелька и покупки
+CREATE TABLE IF NOT EXISTS team_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('deposit', 'withdrawal', 'purchase')),
+    -- member участник, выполнивший операцию; user_name вносивший, получатель перевода или товара
+    member VARCHAR(255) NOT NULL REFERENCES users(username),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(255),
+    amount INT NOT NULL CHECK (amount > 0),
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_team_history_team ON team_history(team_id, created_at DESC);
+-- Счета команд в главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system', 'team'));
+-- Полученные пользователями достижения. Уникальность (пользователь, достижение) гарантирует,
+-- что достижение и награда за него выдаются один раз
+CREATE TABLE IF NOT EXISTS user_achievements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+    achievement_id VARCHAR(64) NOT NULL,
+    -- name название на момент получения: правила задаются в настройках и могут меняться
+    name VARCHAR(255) NOT NULL,
+    bounty INT NOT NULL DEFAULT 0 CHECK (bounty >= 0),
+    earned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_name, achievement_id)
+);
+-- Благотворительные кампании. raised - кэш баланса счета кампании в главной книге,
+-- summary - итоговый отчет, сохраняемый при закрытии
+CREATE TABLE IF NOT EXISTS charity_campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    goal INT NOT NULL CHECK (goal > 0),
+    raised INT NOT NULL DEFAULT 0 CHECK (raised >= 0),
+    deadline TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ,
+    summary JSONB
+);
+CREATE INDEX IF NOT EXISTS idx_charity_campaigns_open ON charity_campaigns(deadline) WHERE status = 'open';
+-- Пожертвования пользователей
+CREATE TABLE IF NOT EXISTS charity_donations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    campaign_id UUID NOT NULL REFERENCES charity_campaigns(id),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_charity_donations_campaign ON charity_donations(campaign_id, user_name);
+CREATE INDEX IF NOT EXISTS idx_charity_donations_user ON charity_donations(user_name, created_at);
+-- Счета кампаний в главной книге
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('user', 'system', 'team', 'campaign'));
+-- Аукционы уникальных товаров. Лидирующая ставка заморожена холдом top_hold_id,
+-- reserve_price - минимальная цена, при которой товар продается
+CREATE TABLE IF NOT EXISTS auctions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    reserve_price INT NOT NULL CHECK (reserve_price > 0),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sold', 'unsold', 'cancelled')),
+    top_bid INT NOT NULL DEFAULT 0 CHECK (top_bid >= 0),
+    top_bidder VARCHAR(255) REFERENCES users(username),
+    top_hold_id UUID REFERENCES coin_holds(id),
+    bid_count INT NOT NULL DEFAULT 0,
+    extensions INT NOT NULL DEFAULT 0,
+    purchase_id UUID REFERENCES purchase_history(id),
+    created_by VARCHAR(255) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    settled_at TIMESTAMPTZ,
+    CHECK (ends_at > starts_at),
+    CHECK ((top_bidder IS NULL) = (top_bid = 0))
+);
+CREATE INDEX IF NOT EXISTS idx_auctions_active ON auctions(ends_at) WHERE status = 'active';
+-- Ставки аукционов
+CREATE TABLE IF NOT EXISTS auction_bids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    user_name VARCHAR(255) NOT NULL REFERENCES users(username),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_auction_bids_auction ON auction_bids(auction_id, created_at DESC);
+-- Объявления о продаже товаров из инвентаря. Выставленные единицы (remaining) списаны
+-- из инвентаря продавца и не могут быть проданы или выставлены повторно
+CREATE TABLE IF NOT EXISTS marketplace_listings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seller VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    price INT NOT NULL CHECK (price > 0),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sold', 'cancelled')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ,
+    CHECK (remaining <= quantity),
+    CHECK ((status = 'sold') = (remaining = 0))
+);
+CREATE INDEX IF NOT EXISTS idx_marketplace_listings_active ON marketplace_listings(item_name, price) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_marketplace_listings_seller ON marketplace_listings(seller, created_at DESC);
+-- Продажи по объявлениям, amount - сумма сделки
+CREATE TABLE IF NOT EXISTS marketplace_sales (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    listing_id UUID NOT NULL REFERENCES marketplace_listings(id),
+    seller VARCHAR(255) NOT NULL REFERENCES users(username),
+    buyer VARCHAR(255) NOT NULL REFERENCES users(username),
+    item_name VARCHAR(50) NOT NULL REFERENCES merch_items(name),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (seller <> buyer)
+);
+CREATE INDEX IF NOT EXISTS idx_marketplace_sales_seller ON marketplace_sales(seller, created_at);
+CREATE INDEX IF NOT EXISTS idx_marketplace_sales_buyer ON marketplace_sales(buyer, created_at);
+-- Пользователи не удаляются: счет в главной книге и история переводов должны
+-- пережить учетную запись (ledger_accounts ссылается на users с ON DELETE RESTRICT).
+-- Вместо удаления учетная запись деактивируется
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+CREATE OR REPLACE FUNCTION users_forbid_delete() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'users cannot be deleted, deactivate them instead';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS users_no_delete ON users;
+CREATE TRIGGER users_no_delete
+    BEFORE DELETE OR TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE FUNCTION users_forbid_delete();
+
+-- Взносы в кошельки команд и переводы из них учитываются лимитами переводов участника
+CREATE INDEX IF NOT EXISTS idx_team_history_member ON team_history(member, created_at);
+
+-- Время движения монет: создание для обычного перевода, принятие для ожидавшего подтверждения.
+-- История упорядочена по нему, а created_at остается временем создания и учитывается лимитами
+ALTER TABLE transfer_history ADD COLUMN IF NOT EXISTS effective_at TIMESTAMPTZ;
+UPDATE transfer_history SET effective_at = created_at WHERE effective_at IS NULL;
+ALTER TABLE transfer_history ALTER COLUMN effective_at SET DEFAULT now();
+ALTER TABLE transfer_history ALTER COLUMN effective_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_from_user_effective ON transfer_history(from_user_name, effective_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfer_history_to_user_effective ON transfer_history(to_user_name, effective_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_transfer_history_created;
+CREATE INDEX IF NOT EXISTS idx_transfer_history_effective ON transfer_history(effective_at) WHERE status = 'completed';
+
+-- Предзаказ, который не удалось выполнить, завершается с размороженными монетами,
+-- чтобы не блокировать очередь предзаказов
+ALTER TABLE preorders DROP CONSTRAINT IF EXISTS preorders_status_check;
+ALTER TABLE preorders ADD CONSTRAINT preorders_status_check
+    CHECK (status IN ('pending', 'fulfilled', 'cancelled', 'expired', 'failed'));
+-- Наборы одноуровневые: компонент набора не может сам быть набором. Покупка, предзаказы
+-- и проверка остатков раскрывают только один уровень состава
+CREATE OR REPLACE FUNCTION bundle_components_forbid_nesting() RETURNS trigger AS $$
+BEGIN
+    -- Параллельные изменения состава наборов сериализуются, чтобы не создать вложенность вдвоем
+    PERFORM pg_advisory_xact_lock(hashtext('bundle_components'));
+    IF EXISTS (SELECT 1 FROM bundle_components WHERE bundle_name = NEW.item_name) THEN
+        RAISE EXCEPTION 'bundle % cannot be a component of bundle %', NEW.item_name, NEW.bundle_name;
+    END IF;
+    IF EXISTS (SELECT 1 FROM bundle_components WHERE item_name = NEW.bundle_name) THEN
+        RAISE EXCEPTION 'component % cannot be a bundle', NEW.bundle_name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS bundle_components_no_nesting ON bundle_components;
+CREATE TRIGGER bundle_components_no_nesting
+    BEFORE INSERT OR UPDATE ON bundle_components
+    FOR EACH ROW EXECUTE FUNCTION bundle_components_forbid_nesting();
@@ -15,13 +15,15 @@ var (
 
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // Создает JWT-токен
-func GenerateToken(userName string) (string, error) {
+func GenerateToken(userName string, role string) (string, error) {
 	claims := Claims{
 		Username: userName,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 		},
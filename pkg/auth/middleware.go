@@ -3,6 +3,8 @@ package auth
 import (
 	"avito-merch/internal/utils"
 	"avito-merch/pkg/context"
+	stdcontext "context"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -25,6 +27,50 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		// Используем кастомный тип для ключа контекста
 		ctx := context.WithUserName(r.Context(), claims.Username)
+		ctx = context.WithRole(ctx, claims.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireRole пропускает запрос, только если роль пользователя из токена входит в roles.
+// Должен стоять после AuthMiddleware
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := context.GetRole(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			utils.WriteError(w, http.StatusForbidden, "Forbidden")
+		})
+	}
+}
+
+// ActiveUsers проверяет, что пользователь существует и не деактивирован
+type ActiveUsers interface {
+	IsActive(ctx stdcontext.Context, username string) (bool, error)
+}
+
+// RequireActiveUser отклоняет запросы деактивированных пользователей, даже если их токен еще не истек.
+// Должен стоять после AuthMiddleware
+func RequireActiveUser(users ActiveUsers) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userName, _ := context.GetUserName(r.Context())
+			active, err := users.IsActive(r.Context(), userName)
+			if err != nil {
+				slog.Error("failed to check user status", "user", userName, "error", err)
+				utils.WriteError(w, http.StatusInternalServerError, "Failed to check user status")
+				return
+			}
+			if !active {
+				utils.WriteError(w, http.StatusUnauthorized, "User is deactivated")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
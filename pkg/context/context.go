@@ -6,7 +6,10 @@ import (
 
 type contextKey string
 
-const userNameKey contextKey = "userName"
+const (
+	userNameKey contextKey = "userName"
+	roleKey     contextKey = "role"
+)
 
 // WithUserName добавляет userName в контекст
 func WithUserName(ctx context.Context, userName string) context.Context {
@@ -22,3 +25,14 @@ func GetUserName(ctx context.Context) (string, bool) {
 	userName, ok := value.(string)
 	return userName, ok
 }
+
+// WithRole добавляет роль пользователя в контекст
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// GetRole возвращает роль пользователя из контекста
+func GetRole(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleKey).(string)
+	return role, ok
+}
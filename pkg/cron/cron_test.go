@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Среда
+	base := time.Date(2025, 2, 12, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{expr: "* * * * *", expected: time.Date(2025, 2, 12, 10, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", expected: time.Date(2025, 2, 12, 10, 45, 0, 0, time.UTC)},
+		{expr: "0 9 * * *", expected: time.Date(2025, 2, 13, 9, 0, 0, 0, time.UTC)},
+		{expr: "0 9 * * 1", expected: time.Date(2025, 2, 17, 9, 0, 0, 0, time.UTC)},
+		{expr: "0 18 * * 1-5", expected: time.Date(2025, 2, 12, 18, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", expected: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 0", expected: time.Date(2025, 2, 16, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 7", expected: time.Date(2025, 2, 16, 12, 0, 0, 0, time.UTC)},
+		{expr: "30 10,14 * * *", expected: time.Date(2025, 2, 12, 14, 30, 0, 0, time.UTC)},
+		// Ограничены оба поля дня: достаточно совпадения любого
+		{expr: "0 0 15 * 5", expected: time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule.Next(base))
+		})
+	}
+}
+
+func TestSchedule_NextNever(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, expr)
+	}
+}
@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"avito-merch/internal/entity"
 	"avito-merch/internal/handlers"
 	"avito-merch/internal/repository"
 	"avito-merch/internal/usecase"
@@ -41,9 +42,18 @@ type ErrorResponse struct {
 }
 
 type InfoResponse struct {
-	Coins       int                 `json:"coins"`
-	Inventory   []InventoryItem     `json:"inventory"`
-	CoinHistory CoinHistoryResponse `json:"coinHistory"`
+	Coins        int                   `json:"coins"`
+	Inventory    []InventoryItem       `json:"inventory"`
+	CoinHistory  CoinHistoryResponse   `json:"coinHistory"`
+	HeldCoins    int                   `json:"heldCoins"`
+	GivingBudget int                   `json:"givingBudget"`
+	Achievements []AchievementResponse `json:"achievements"`
+}
+
+type AchievementResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Bounty int    `json:"bounty,omitempty"`
 }
 
 type InventoryItem struct {
@@ -65,6 +75,7 @@ type TransactionResponse struct {
 type SendCoinRequest struct {
 	ToUser string `json:"toUser"`
 	Amount int    `json:"amount"`
+	Kudos  bool   `json:"kudos,omitempty"`
 }
 
 type SendCoinResponse struct {
@@ -77,6 +88,93 @@ type BuyItemResponse struct {
 	Errors  string `json:"errors,omitempty"`
 }
 
+type GrantResponse struct {
+	ID         string             `json:"id"`
+	Total      int                `json:"total"`
+	Status     string             `json:"status"`
+	Recipients []entity.GrantItem `json:"recipients"`
+	ResolvedBy string             `json:"resolvedBy,omitempty"`
+	Errors     string             `json:"errors,omitempty"`
+}
+
+type AllowanceRunResponse struct {
+	RunID         string `json:"runId"`
+	UsersPaid     int    `json:"usersPaid"`
+	UsersProrated int    `json:"usersProrated"`
+	TotalAmount   int    `json:"totalAmount"`
+}
+
+type LeaderboardResponse struct {
+	Metric  string `json:"metric"`
+	Period  string `json:"period"`
+	Entries []struct {
+		Rank  int    `json:"rank"`
+		User  string `json:"user"`
+		Value int    `json:"value"`
+	} `json:"entries"`
+}
+
+type TeamResponse struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Coins   int    `json:"coins"`
+	Members []struct {
+		User string `json:"user"`
+		Role string `json:"role"`
+	} `json:"members"`
+	History []TeamOperationResponse `json:"history"`
+}
+
+type TeamOperationResponse struct {
+	Kind   string `json:"kind"`
+	Member string `json:"member"`
+	User   string `json:"user"`
+	Item   string `json:"item,omitempty"`
+	Amount int    `json:"amount"`
+}
+
+type CampaignResponse struct {
+	ID          string `json:"id"`
+	Goal        int    `json:"goal"`
+	Raised      int    `json:"raised"`
+	Progress    int    `json:"progress"`
+	Donors      int    `json:"donors"`
+	Status      string `json:"status"`
+	GoalReached bool   `json:"goalReached"`
+	Donations   int    `json:"donations"`
+	ClosedBy    string `json:"closedBy,omitempty"`
+}
+
+type AuctionResponse struct {
+	ID        string `json:"id"`
+	Item      string `json:"item"`
+	Status    string `json:"status"`
+	TopBid    int    `json:"topBid,omitempty"`
+	TopBidder string `json:"topBidder,omitempty"`
+	BidCount  int    `json:"bidCount"`
+}
+
+type ListingResponse struct {
+	ID        string `json:"id"`
+	Seller    string `json:"seller"`
+	Item      string `json:"item"`
+	Price     int    `json:"price"`
+	Quantity  int    `json:"quantity"`
+	Remaining int    `json:"remaining"`
+	Status    string `json:"status"`
+}
+
+// Пороги начислений и размер пособия в тестовом окружении
+const (
+	grantMonthlyBudget     = 5000
+	grantApprovalThreshold = 500
+	allowanceAmount        = 300
+	kudosMonthlyBudget     = 50
+	auctionMinIncrement    = 10
+	// leaderboardRefreshInterval рейтинги пересчитываются часто, чтобы тесты не ждали фоновую задачу
+	leaderboardRefreshInterval = 100 * time.Millisecond
+)
+
 func setupTestServer(t *testing.T) (*httptest.Server, func()) {
 	ctx := context.Background()
 
@@ -121,15 +219,43 @@ func setupTestServer(t *testing.T) (*httptest.Server, func()) {
 	itemRepo := repository.NewItemRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
 
+	achievementRepo := repository.NewAchievementRepository(db)
+
 	authUseCase := usecase.NewAuthUseCase(userRepo)
-	buyUseCase := usecase.NewBuyUseCase(userRepo, itemRepo)
-	infoUseCase := usecase.NewInfoUseCase(userRepo, transactionRepo)
-	sendCoinUseCase := usecase.NewSendCoinUseCase(userRepo, transactionRepo)
+	achievementUseCase := usecase.NewAchievementUseCase(achievementRepo, usecase.DefaultAchievements)
+	buyUseCase := usecase.NewBuyUseCase(userRepo, itemRepo, achievementUseCase)
+	infoUseCase := usecase.NewInfoUseCase(userRepo, transactionRepo, repository.NewCoinLotRepository(db), achievementRepo, usecase.InfoConfig{
+		KudosBudget: kudosMonthlyBudget,
+	})
+	sendCoinUseCase := usecase.NewSendCoinUseCase(userRepo, transactionRepo, usecase.SendCoinConfig{
+		KudosBudget: kudosMonthlyBudget,
+	}, achievementUseCase)
+	grantUseCase := usecase.NewGrantUseCase(repository.NewGrantRepository(db), usecase.GrantConfig{
+		MonthlyBudget:     grantMonthlyBudget,
+		ApprovalThreshold: grantApprovalThreshold,
+	})
+	allowanceUseCase := usecase.NewAllowanceUseCase(repository.NewAllowanceRepository(db), usecase.AllowanceConfig{
+		Amount: allowanceAmount,
+		Roles:  []string{entity.RoleUser},
+	})
 
 	authHandler := handlers.NewAuthHandler(authUseCase)
 	buyHandler := handlers.NewBuyHandler(buyUseCase)
 	infoHandler := handlers.NewInfoHandler(infoUseCase)
 	sendCoinHandler := handlers.NewSendCoinHandler(sendCoinUseCase)
+	leaderboardUseCase := usecase.NewLeaderboardUseCase(repository.NewLeaderboardRepository(db), 0)
+	teamUseCase := usecase.NewTeamUseCase(repository.NewTeamRepository(db), sendCoinUseCase)
+	campaignUseCase := usecase.NewCampaignUseCase(repository.NewCampaignRepository(db))
+	auctionUseCase := usecase.NewAuctionUseCase(repository.NewAuctionRepository(db), auctionMinIncrement, time.Minute, achievementUseCase)
+	marketplaceUseCase := usecase.NewMarketplaceUseCase(repository.NewMarketplaceRepository(db), sendCoinUseCase)
+
+	grantHandler := handlers.NewGrantHandler(grantUseCase)
+	allowanceHandler := handlers.NewAllowanceHandler(allowanceUseCase)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardUseCase)
+	teamHandler := handlers.NewTeamHandler(teamUseCase)
+	campaignHandler := handlers.NewCampaignHandler(campaignUseCase)
+	auctionHandler := handlers.NewAuctionHandler(auctionUseCase)
+	marketplaceHandler := handlers.NewMarketplaceHandler(marketplaceUseCase)
 
 	r := mux.NewRouter()
 
@@ -137,19 +263,72 @@ func setupTestServer(t *testing.T) (*httptest.Server, func()) {
 	authRouter.HandleFunc("", authHandler.Authenticate).Methods(http.MethodPost)
 
 	apiRouter := r.PathPrefix("/api").Subrouter()
-	apiRouter.Use(auth.AuthMiddleware)
+	apiRouter.Use(auth.AuthMiddleware, auth.RequireActiveUser(userRepo))
 	apiRouter.HandleFunc("/buy/{item}", buyHandler.BuyItem).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/sendCoin", sendCoinHandler.SendCoins).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/info", infoHandler.GetUserInfo).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/leaderboards", leaderboardHandler.GetLeaderboard).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/leaderboards/optOut", leaderboardHandler.SetOptOut).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/teams", teamHandler.CreateTeam).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams/{id}", teamHandler.GetTeam).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/teams/{id}/members", teamHandler.SetMember).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/teams/{id}/deposit", teamHandler.Deposit).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams/{id}/withdraw", teamHandler.Withdraw).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/teams/{id}/buy/{item}", teamHandler.BuyItem).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/campaigns/{id}", campaignHandler.GetCampaign).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/campaigns/{id}/donate", campaignHandler.Donate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auctions/{id}", auctionHandler.GetAuction).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/auctions/{id}/bids", auctionHandler.PlaceBid).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/marketplace", marketplaceHandler.CreateListing).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/marketplace/{id}", marketplaceHandler.GetListing).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/marketplace/{id}/buy", marketplaceHandler.Buy).Methods(http.MethodPost)
+
+	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
+	adminOrAuditor := auth.RequireRole(entity.RoleAdmin, entity.RoleAuditor)
+	adminOnly := auth.RequireRole(entity.RoleAdmin)
+	adminRouter.Handle("/grants", adminOnly(http.HandlerFunc(grantHandler.CreateGrant))).Methods(http.MethodPost)
+	adminRouter.Handle("/grants/{id}/approve", adminOnly(http.HandlerFunc(grantHandler.ApproveGrant))).Methods(http.MethodPost)
+	adminRouter.Handle("/allowance/runs", adminOrAuditor(http.HandlerFunc(allowanceHandler.GetRuns))).Methods(http.MethodGet)
+	adminRouter.Handle("/allowance/runs", adminOnly(http.HandlerFunc(allowanceHandler.PayAllowance))).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns", adminOnly(http.HandlerFunc(campaignHandler.CreateCampaign))).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns/{id}/close", adminOnly(http.HandlerFunc(campaignHandler.CloseCampaign))).Methods(http.MethodPost)
+	adminRouter.Handle("/auctions", adminOnly(http.HandlerFunc(auctionHandler.CreateAuction))).Methods(http.MethodPost)
+
+	// Фоновый пересчет рейтингов, как в приложении
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(leaderboardRefreshInterval)
+		defer ticker.Stop()
+		for {
+			if err := leaderboardUseCase.RefreshLeaderboards(jobsCtx); err != nil && jobsCtx.Err() == nil {
+				log.Printf("Failed to refresh leaderboards: %v", err)
+			}
+			select {
+			case <-jobsCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
 
 	server := httptest.NewServer(r)
 
 	return server, func() {
+		stopJobs()
 		server.Close()
 		cleanup()
 	}
 }
 
+// roleToken выпускает токен с нужной ролью для уже существующего пользователя
+func roleToken(t *testing.T, username, role string) string {
+	token, err := auth.GenerateToken(username, role)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
+
 func StartPostgresContainer(ctx context.Context, cfg *ContainerConfig) (string, func(), error) {
 	postgresContainer, err := postgres.Run(ctx,
 		"postgres:13-alpine",
@@ -1,10 +1,12 @@
 package e2e
 
 import (
+	"avito-merch/internal/entity"
 	"encoding/json"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +37,15 @@ func TestE2E(t *testing.T) {
 		return resp
 	}
 
+	authenticate := func(username string) string {
+		reqBody := `{"username": "` + username + `", "password": "password123"}`
+		var authResponse AuthResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/auth", reqBody, "", &authResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotEmpty(t, authResponse.Token)
+		return authResponse.Token
+	}
+
 	t.Run("Auth_SuccessNewUser", func(t *testing.T) {
 		reqBody := `{"username": "newuser", "password": "password123"}`
 
@@ -387,4 +398,361 @@ func TestE2E(t *testing.T) {
 		require.Equal(t, "Invalid token", buyItemResponse.Errors)
 	})
 
+	t.Run("Grant_Success", func(t *testing.T) {
+		authenticate("grantadmin")
+		adminToken := roleToken(t, "grantadmin", entity.RoleAdmin)
+		userToken := authenticate("grantee")
+
+		reqBody := `{"reason": "bonus", "recipients": [{"user": "grantee", "amount": 100}]}`
+		var grantResponse GrantResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/grants", reqBody, adminToken, &grantResponse)
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, entity.GrantStatusCompleted, grantResponse.Status)
+		require.Equal(t, 100, grantResponse.Total)
+
+		var infoResponse InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", userToken, &infoResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1100, infoResponse.Coins)
+	})
+
+	t.Run("Grant_RequiresSecondAdmin", func(t *testing.T) {
+		authenticate("grantadmin")
+		authenticate("grantapprover")
+		adminToken := roleToken(t, "grantadmin", entity.RoleAdmin)
+		approverToken := roleToken(t, "grantapprover", entity.RoleAdmin)
+		userToken := authenticate("bigGrantee")
+
+		reqBody := `{"reason": "annual bonus", "recipients": [{"user": "bigGrantee", "amount": 500}]}`
+		var grantResponse GrantResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/grants", reqBody, adminToken, &grantResponse)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.Equal(t, entity.GrantStatusPending, grantResponse.Status)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, server.URL+"/api/admin/grants/"+grantResponse.ID+"/approve", "", adminToken, &errorResponse)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var approved GrantResponse
+		resp = makeRequest(http.MethodPost, server.URL+"/api/admin/grants/"+grantResponse.ID+"/approve", "", approverToken, &approved)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, entity.GrantStatusCompleted, approved.Status)
+		require.Equal(t, "grantapprover", approved.ResolvedBy)
+
+		var infoResponse InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", userToken, &infoResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1500, infoResponse.Coins)
+	})
+
+	t.Run("Grant_ForbiddenForUser", func(t *testing.T) {
+		token := authenticate("grantee")
+
+		reqBody := `{"reason": "self bonus", "recipients": [{"user": "grantee", "amount": 100}]}`
+		var errorResponse ErrorResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/grants", reqBody, token, &errorResponse)
+
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Equal(t, "Forbidden", errorResponse.Errors)
+	})
+
+	t.Run("Allowance_PaidOncePerPeriod", func(t *testing.T) {
+		authenticate("allowanceadmin")
+		authenticate("allowanceauditor")
+		adminToken := roleToken(t, "allowanceadmin", entity.RoleAdmin)
+		auditorToken := roleToken(t, "allowanceauditor", entity.RoleAuditor)
+		userToken := authenticate("allowanceuser")
+
+		var firstRun AllowanceRunResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/allowance/runs", "", adminToken, &firstRun)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Positive(t, firstRun.UsersPaid)
+		require.Positive(t, firstRun.TotalAmount)
+
+		var infoResponse InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", userToken, &infoResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Greater(t, infoResponse.Coins, 1000)
+		require.LessOrEqual(t, infoResponse.Coins, 1000+allowanceAmount)
+
+		var secondRun AllowanceRunResponse
+		resp = makeRequest(http.MethodPost, server.URL+"/api/admin/allowance/runs", "", adminToken, &secondRun)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Zero(t, secondRun.UsersPaid)
+		require.Zero(t, secondRun.TotalAmount)
+
+		var runs []AllowanceRunResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/admin/allowance/runs", "", auditorToken, &runs)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotEmpty(t, runs)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, server.URL+"/api/admin/allowance/runs", "", auditorToken, &errorResponse)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Kudos_SpentFromGivingBudget", func(t *testing.T) {
+		senderToken := authenticate("kudossender")
+		recipientToken := authenticate("kudosrecipient")
+
+		reqBody, err := json.Marshal(SendCoinRequest{ToUser: "kudosrecipient", Amount: 30, Kudos: true})
+		require.NoError(t, err)
+		var sendCoinResponse SendCoinResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/sendCoin", string(reqBody), senderToken, &sendCoinResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "Coins transferred successfully", sendCoinResponse.Message)
+
+		var senderInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", senderToken, &senderInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1000, senderInfo.Coins)
+		require.Equal(t, kudosMonthlyBudget-30, senderInfo.GivingBudget)
+
+		var recipientInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", recipientToken, &recipientInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1030, recipientInfo.Coins)
+		require.Equal(t, kudosMonthlyBudget, recipientInfo.GivingBudget)
+
+		reqBody, err = json.Marshal(SendCoinRequest{ToUser: "kudosrecipient", Amount: kudosMonthlyBudget, Kudos: true})
+		require.NoError(t, err)
+		resp = makeRequest(http.MethodPost, server.URL+"/api/sendCoin", string(reqBody), senderToken, &sendCoinResponse)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Contains(t, sendCoinResponse.Errors, "giving budget exceeded")
+	})
+
+	t.Run("Leaderboard_TopReceiverAndOptOut", func(t *testing.T) {
+		giverToken := authenticate("leaderboardgiver")
+		starToken := authenticate("leaderboardstar")
+
+		reqBody, err := json.Marshal(SendCoinRequest{ToUser: "leaderboardstar", Amount: 500})
+		require.NoError(t, err)
+		var sendCoinResponse SendCoinResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/sendCoin", string(reqBody), giverToken, &sendCoinResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		url := server.URL + "/api/leaderboards?metric=received&period=week"
+		require.Eventually(t, func() bool {
+			var board LeaderboardResponse
+			resp := makeRequest(http.MethodGet, url, "", giverToken, &board)
+			return resp.StatusCode == http.StatusOK && len(board.Entries) > 0 &&
+				board.Entries[0].User == "leaderboardstar" && board.Entries[0].Value == 500
+		}, 5*time.Second, leaderboardRefreshInterval)
+
+		var optOut map[string]bool
+		resp = makeRequest(http.MethodPut, server.URL+"/api/leaderboards/optOut", `{"optOut": true}`, starToken, &optOut)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var board LeaderboardResponse
+		resp = makeRequest(http.MethodGet, url, "", giverToken, &board)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		for _, entry := range board.Entries {
+			require.NotEqual(t, "leaderboardstar", entry.User)
+		}
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/leaderboards?metric=unknown", "", giverToken, &errorResponse)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Contains(t, errorResponse.Errors, "invalid leaderboard")
+	})
+
+	t.Run("Team_WalletAndPurchase", func(t *testing.T) {
+		ownerToken := authenticate("teamowner")
+		memberToken := authenticate("teammember")
+
+		var team TeamResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/teams", `{"name": "Offsite crew"}`, ownerToken, &team)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.NotEmpty(t, team.ID)
+		teamURL := server.URL + "/api/teams/" + team.ID
+
+		resp = makeRequest(http.MethodPut, teamURL+"/members", `{"user": "teammember", "role": "member"}`, ownerToken, &team)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, team.Members, 2)
+
+		var op TeamOperationResponse
+		resp = makeRequest(http.MethodPost, teamURL+"/deposit", `{"amount": 200}`, memberToken, &op)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, entity.TeamOperationDeposit, op.Kind)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, teamURL+"/withdraw", `{"toUser": "teammember", "amount": 50}`, memberToken, &errorResponse)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Contains(t, errorResponse.Errors, "only team owners can do this")
+
+		resp = makeRequest(http.MethodPost, teamURL+"/buy/pen", `{"receiver": "teammember"}`, ownerToken, &op)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, entity.TeamOperationPurchase, op.Kind)
+		require.Equal(t, "teammember", op.User)
+
+		var info TeamResponse
+		resp = makeRequest(http.MethodGet, teamURL, "", memberToken, &info)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 200-op.Amount, info.Coins)
+		require.Len(t, info.History, 2)
+
+		var memberInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", memberToken, &memberInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 800, memberInfo.Coins)
+		require.Contains(t, memberInfo.Inventory, InventoryItem{Type: "pen", Quantity: 1})
+	})
+
+	t.Run("Achievements_AwardedOnceWithBounty", func(t *testing.T) {
+		token := authenticate("achiever")
+		authenticate("achieverfriend")
+
+		var buyItemResponse BuyItemResponse
+		resp := makeRequest(http.MethodGet, server.URL+"/api/buy/pen", "", token, &buyItemResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		reqBody, err := json.Marshal(SendCoinRequest{ToUser: "achieverfriend", Amount: 1})
+		require.NoError(t, err)
+		for i := 0; i < 11; i++ {
+			var sendCoinResponse SendCoinResponse
+			resp = makeRequest(http.MethodPost, server.URL+"/api/sendCoin", string(reqBody), token, &sendCoinResponse)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		var infoResponse InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", token, &infoResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		ids := make([]string, 0, len(infoResponse.Achievements))
+		for _, achievement := range infoResponse.Achievements {
+			ids = append(ids, achievement.ID)
+		}
+		require.ElementsMatch(t, []string{"first_purchase", "ten_transfers"}, ids)
+		// Покупка ручки, 11 переводов по монете и однократная награда за 10 переводов
+		require.Equal(t, 1000-10-11+50, infoResponse.Coins)
+	})
+
+	t.Run("Campaign_DonateAndClose", func(t *testing.T) {
+		authenticate("campaignadmin")
+		adminToken := roleToken(t, "campaignadmin", entity.RoleAdmin)
+		donorToken := authenticate("donor")
+
+		reqBody := `{"name": "Shelter", "goal": 300, "deadline": "` + time.Now().Add(24*time.Hour).UTC().Format(time.RFC3339) + `"}`
+		var campaign CampaignResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/campaigns", reqBody, adminToken, &campaign)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, entity.CampaignStatusOpen, campaign.Status)
+		campaignURL := server.URL + "/api/campaigns/" + campaign.ID
+
+		var donation map[string]any
+		resp = makeRequest(http.MethodPost, campaignURL+"/donate", `{"amount": 200}`, donorToken, &donation)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = makeRequest(http.MethodGet, campaignURL, "", donorToken, &campaign)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 200, campaign.Raised)
+		require.Equal(t, 1, campaign.Donors)
+
+		var summary CampaignResponse
+		resp = makeRequest(http.MethodPost, server.URL+"/api/admin/campaigns/"+campaign.ID+"/close", "", adminToken, &summary)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, entity.CampaignStatusClosed, summary.Status)
+		require.Equal(t, "campaignadmin", summary.ClosedBy)
+		require.False(t, summary.GoalReached)
+		require.Equal(t, 1, summary.Donations)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, campaignURL+"/donate", `{"amount": 100}`, donorToken, &errorResponse)
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		require.Contains(t, errorResponse.Errors, "campaign is closed")
+
+		var infoResponse InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", donorToken, &infoResponse)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 800, infoResponse.Coins)
+	})
+
+	t.Run("Auction_OutbidReleasesHold", func(t *testing.T) {
+		authenticate("auctionadmin")
+		adminToken := roleToken(t, "auctionadmin", entity.RoleAdmin)
+		firstToken := authenticate("firstbidder")
+		secondToken := authenticate("secondbidder")
+
+		reqBody := `{"item": "cup", "reservePrice": 50, "endsAt": "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`
+		var auction AuctionResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/admin/auctions", reqBody, adminToken, &auction)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, entity.AuctionStatusActive, auction.Status)
+		bidsURL := server.URL + "/api/auctions/" + auction.ID + "/bids"
+
+		var bid map[string]any
+		resp = makeRequest(http.MethodPost, bidsURL, `{"amount": 100}`, firstToken, &bid)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, bidsURL, `{"amount": 105}`, secondToken, &errorResponse)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Contains(t, errorResponse.Errors, "bid is too low")
+
+		resp = makeRequest(http.MethodPost, bidsURL, `{"amount": 150}`, secondToken, &bid)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = makeRequest(http.MethodGet, server.URL+"/api/auctions/"+auction.ID, "", firstToken, &auction)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 150, auction.TopBid)
+		require.Equal(t, "secondbidder", auction.TopBidder)
+		require.Equal(t, 2, auction.BidCount)
+
+		var firstInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", firstToken, &firstInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1000, firstInfo.Coins)
+		require.Zero(t, firstInfo.HeldCoins)
+
+		var secondInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", secondToken, &secondInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 850, secondInfo.Coins)
+		require.Equal(t, 150, secondInfo.HeldCoins)
+	})
+
+	t.Run("Marketplace_ResellOwnedItems", func(t *testing.T) {
+		sellerToken := authenticate("reseller")
+		buyerToken := authenticate("resalebuyer")
+
+		for i := 0; i < 2; i++ {
+			var buyItemResponse BuyItemResponse
+			resp := makeRequest(http.MethodGet, server.URL+"/api/buy/pen", "", sellerToken, &buyItemResponse)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		var listing ListingResponse
+		resp := makeRequest(http.MethodPost, server.URL+"/api/marketplace", `{"item": "pen", "quantity": 2, "price": 15}`, sellerToken, &listing)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, entity.ListingStatusActive, listing.Status)
+		listingURL := server.URL + "/api/marketplace/" + listing.ID
+
+		var sale map[string]any
+		resp = makeRequest(http.MethodPost, listingURL+"/buy", `{"quantity": 2}`, buyerToken, &sale)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = makeRequest(http.MethodGet, listingURL, "", buyerToken, &listing)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, entity.ListingStatusSold, listing.Status)
+		require.Zero(t, listing.Remaining)
+
+		var errorResponse ErrorResponse
+		resp = makeRequest(http.MethodPost, listingURL+"/buy", `{"quantity": 1}`, buyerToken, &errorResponse)
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		require.Contains(t, errorResponse.Errors, "listing is not active")
+
+		var sellerInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", sellerToken, &sellerInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1000-20+30, sellerInfo.Coins)
+		require.Empty(t, sellerInfo.Inventory)
+
+		var buyerInfo InfoResponse
+		resp = makeRequest(http.MethodGet, server.URL+"/api/info", "", buyerToken, &buyerInfo)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 970, buyerInfo.Coins)
+		require.Equal(t, []InventoryItem{{Type: "pen", Quantity: 2}}, buyerInfo.Inventory)
+	})
+
 }